@@ -191,6 +191,7 @@ type Message struct {
 	//	*Message_Consensus
 	//	*Message_Drand
 	//	*Message_LotteryRequest
+	//	*Message_Viewchange
 	Request              isMessage_Request `protobuf_oneof:"request"`
 	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
 	XXX_unrecognized     []byte            `json:"-"`
@@ -270,6 +271,10 @@ type Message_LotteryRequest struct {
 	LotteryRequest *LotteryRequest `protobuf:"bytes,8,opt,name=lottery_request,json=lotteryRequest,proto3,oneof"`
 }
 
+type Message_Viewchange struct {
+	Viewchange *ViewChangeRequest `protobuf:"bytes,9,opt,name=viewchange,proto3,oneof"`
+}
+
 func (*Message_Staking) isMessage_Request() {}
 
 func (*Message_Consensus) isMessage_Request() {}
@@ -278,6 +283,8 @@ func (*Message_Drand) isMessage_Request() {}
 
 func (*Message_LotteryRequest) isMessage_Request() {}
 
+func (*Message_Viewchange) isMessage_Request() {}
+
 func (m *Message) GetRequest() isMessage_Request {
 	if m != nil {
 		return m.Request
@@ -313,6 +320,13 @@ func (m *Message) GetLotteryRequest() *LotteryRequest {
 	return nil
 }
 
+func (m *Message) GetViewchange() *ViewChangeRequest {
+	if x, ok := m.GetRequest().(*Message_Viewchange); ok {
+		return x.Viewchange
+	}
+	return nil
+}
+
 // XXX_OneofWrappers is for the internal use of the proto package.
 func (*Message) XXX_OneofWrappers() []interface{} {
 	return []interface{}{
@@ -320,6 +334,7 @@ func (*Message) XXX_OneofWrappers() []interface{} {
 		(*Message_Consensus)(nil),
 		(*Message_Drand)(nil),
 		(*Message_LotteryRequest)(nil),
+		(*Message_Viewchange)(nil),
 	}
 }
 
@@ -567,6 +582,7 @@ type ConsensusRequest struct {
 	BlockHash            []byte   `protobuf:"bytes,2,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
 	SenderPubkey         []byte   `protobuf:"bytes,3,opt,name=sender_pubkey,json=senderPubkey,proto3" json:"sender_pubkey,omitempty"`
 	Payload              []byte   `protobuf:"bytes,4,opt,name=payload,proto3" json:"payload,omitempty"`
+	ViewId               uint32   `protobuf:"varint,5,opt,name=view_id,json=viewId,proto3" json:"view_id,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -625,6 +641,124 @@ func (m *ConsensusRequest) GetPayload() []byte {
 	return nil
 }
 
+func (m *ConsensusRequest) GetViewId() uint32 {
+	if m != nil {
+		return m.ViewId
+	}
+	return 0
+}
+
+// ViewChangeRequest is used by both VIEWCHANGE and NEWVIEW messages.
+type ViewChangeRequest struct {
+	ViewId       uint32 `protobuf:"varint,1,opt,name=view_id,json=viewId,proto3" json:"view_id,omitempty"`
+	ConsensusId  uint32 `protobuf:"varint,2,opt,name=consensus_id,json=consensusId,proto3" json:"consensus_id,omitempty"`
+	SenderPubkey []byte `protobuf:"bytes,3,opt,name=sender_pubkey,json=senderPubkey,proto3" json:"sender_pubkey,omitempty"`
+	// signature on the view id, aggregated by the new leader
+	ViewchangeSig []byte `protobuf:"bytes,4,opt,name=viewchange_sig,json=viewchangeSig,proto3" json:"viewchange_sig,omitempty"`
+	// hash of the latest prepared block, empty if the sender has none
+	BlockHash []byte `protobuf:"bytes,5,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	// 48 bytes aggregated prepare signature followed by the prepare bitmap
+	PreparedSig []byte `protobuf:"bytes,6,opt,name=prepared_sig,json=preparedSig,proto3" json:"prepared_sig,omitempty"`
+	// the latest prepared block
+	Block []byte `protobuf:"bytes,7,opt,name=block,proto3" json:"block,omitempty"`
+	// NEWVIEW only: 48 bytes aggregated viewchange signature followed by the bitmap
+	ViewchangeMultisig []byte `protobuf:"bytes,8,opt,name=viewchange_multisig,json=viewchangeMultisig,proto3" json:"viewchange_multisig,omitempty"`
+	// the PREPARED message of the latest prepared block, signed by the leader
+	// of the view the block was prepared in
+	PreparedMessage      []byte   `protobuf:"bytes,9,opt,name=prepared_message,json=preparedMessage,proto3" json:"prepared_message,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ViewChangeRequest) Reset()         { *m = ViewChangeRequest{} }
+func (m *ViewChangeRequest) String() string { return proto.CompactTextString(m) }
+func (*ViewChangeRequest) ProtoMessage()    {}
+func (*ViewChangeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{6}
+}
+
+func (m *ViewChangeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ViewChangeRequest.Unmarshal(m, b)
+}
+func (m *ViewChangeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ViewChangeRequest.Marshal(b, m, deterministic)
+}
+func (m *ViewChangeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ViewChangeRequest.Merge(m, src)
+}
+func (m *ViewChangeRequest) XXX_Size() int {
+	return xxx_messageInfo_ViewChangeRequest.Size(m)
+}
+func (m *ViewChangeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ViewChangeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ViewChangeRequest proto.InternalMessageInfo
+
+func (m *ViewChangeRequest) GetViewId() uint32 {
+	if m != nil {
+		return m.ViewId
+	}
+	return 0
+}
+
+func (m *ViewChangeRequest) GetConsensusId() uint32 {
+	if m != nil {
+		return m.ConsensusId
+	}
+	return 0
+}
+
+func (m *ViewChangeRequest) GetSenderPubkey() []byte {
+	if m != nil {
+		return m.SenderPubkey
+	}
+	return nil
+}
+
+func (m *ViewChangeRequest) GetViewchangeSig() []byte {
+	if m != nil {
+		return m.ViewchangeSig
+	}
+	return nil
+}
+
+func (m *ViewChangeRequest) GetBlockHash() []byte {
+	if m != nil {
+		return m.BlockHash
+	}
+	return nil
+}
+
+func (m *ViewChangeRequest) GetPreparedSig() []byte {
+	if m != nil {
+		return m.PreparedSig
+	}
+	return nil
+}
+
+func (m *ViewChangeRequest) GetBlock() []byte {
+	if m != nil {
+		return m.Block
+	}
+	return nil
+}
+
+func (m *ViewChangeRequest) GetViewchangeMultisig() []byte {
+	if m != nil {
+		return m.ViewchangeMultisig
+	}
+	return nil
+}
+
+func (m *ViewChangeRequest) GetPreparedMessage() []byte {
+	if m != nil {
+		return m.PreparedMessage
+	}
+	return nil
+}
+
 type DrandRequest struct {
 	SenderPubkey         []byte   `protobuf:"bytes,1,opt,name=sender_pubkey,json=senderPubkey,proto3" json:"sender_pubkey,omitempty"`
 	BlockHash            []byte   `protobuf:"bytes,2,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
@@ -638,7 +772,7 @@ func (m *DrandRequest) Reset()         { *m = DrandRequest{} }
 func (m *DrandRequest) String() string { return proto.CompactTextString(m) }
 func (*DrandRequest) ProtoMessage()    {}
 func (*DrandRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_33c57e4bae7b9afd, []int{6}
+	return fileDescriptor_33c57e4bae7b9afd, []int{7}
 }
 
 func (m *DrandRequest) XXX_Unmarshal(b []byte) error {
@@ -691,66 +825,75 @@ func init() {
 	proto.RegisterType((*LotteryRequest)(nil), "message.LotteryRequest")
 	proto.RegisterType((*StakingRequest)(nil), "message.StakingRequest")
 	proto.RegisterType((*ConsensusRequest)(nil), "message.ConsensusRequest")
+	proto.RegisterType((*ViewChangeRequest)(nil), "message.ViewChangeRequest")
 	proto.RegisterType((*DrandRequest)(nil), "message.DrandRequest")
 }
 
 func init() { proto.RegisterFile("message.proto", fileDescriptor_33c57e4bae7b9afd) }
 
 var fileDescriptor_33c57e4bae7b9afd = []byte{
-	// 839 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x55, 0x4f, 0x6f, 0xe2, 0x46,
-	0x14, 0xc7, 0x98, 0x60, 0x78, 0x36, 0x30, 0x9d, 0x6d, 0x77, 0xdd, 0x68, 0xab, 0x52, 0x7a, 0x41,
-	0x91, 0x1a, 0xad, 0xb2, 0x87, 0xaa, 0xbd, 0x19, 0x33, 0x0a, 0x16, 0xc4, 0xa6, 0x83, 0xd9, 0xa8,
-	0xea, 0xc1, 0x72, 0x60, 0x94, 0xa0, 0x78, 0x6d, 0xea, 0x31, 0x91, 0xf8, 0x1c, 0xfd, 0x18, 0xbd,
-	0xf6, 0x03, 0xf5, 0x83, 0xf4, 0x50, 0x79, 0xc6, 0x60, 0xc3, 0xb6, 0xea, 0xb5, 0x37, 0xff, 0x7e,
-	0xef, 0xfd, 0xde, 0xbf, 0x99, 0x37, 0x86, 0xce, 0x47, 0xc6, 0x79, 0xf8, 0xc8, 0xae, 0xb7, 0x69,
-	0x92, 0x25, 0x58, 0x2b, 0xe0, 0xe0, 0x0f, 0x15, 0xb4, 0x3b, 0xf9, 0x8d, 0x7f, 0x84, 0x4e, 0xca,
-	0x56, 0x6c, 0xf3, 0xc2, 0xd2, 0x20, 0xdb, 0x6f, 0x99, 0xa9, 0xf4, 0x95, 0x61, 0xf7, 0xe6, 0x8b,
-	0xeb, 0x83, 0x96, 0x16, 0x56, 0x7f, 0xbf, 0x65, 0xd4, 0x48, 0x2b, 0x08, 0x7f, 0x0f, 0x06, 0x67,
-	0xe9, 0xcb, 0x66, 0xc5, 0xa4, 0xb4, 0x2e, 0xa4, 0x9f, 0x1f, 0xa5, 0x0b, 0x69, 0x14, 0x4a, 0x9d,
-	0x97, 0x00, 0x0f, 0xa1, 0x21, 0x04, 0xea, 0x99, 0xa0, 0x28, 0x4a, 0x08, 0x84, 0x07, 0x7e, 0x0b,
-	0x6d, 0xbe, 0x79, 0x8c, 0xc3, 0x6c, 0x97, 0x32, 0xb3, 0xd1, 0x57, 0x86, 0x06, 0x2d, 0x09, 0xfc,
-	0x1e, 0x34, 0x9e, 0x85, 0xcf, 0x9b, 0xf8, 0xd1, 0xbc, 0xe8, 0x2b, 0x43, 0xfd, 0xe6, 0x4d, 0x99,
-	0x5b, 0xf2, 0x94, 0xfd, 0xba, 0x63, 0x3c, 0x9b, 0xd4, 0xe8, 0xc1, 0x13, 0xff, 0x00, 0xed, 0x55,
-	0x12, 0x73, 0x16, 0xf3, 0x1d, 0x37, 0x9b, 0x42, 0xf6, 0xe5, 0x51, 0x66, 0x1f, 0x2c, 0xa5, 0xb0,
-	0xf4, 0xc6, 0xdf, 0xc1, 0xc5, 0x3a, 0x0d, 0xe3, 0xb5, 0xa9, 0x09, 0x59, 0x39, 0xa4, 0x71, 0xce,
-	0x96, 0x12, 0xe9, 0x85, 0x47, 0xd0, 0x8b, 0x92, 0x2c, 0x63, 0xe9, 0x3e, 0x48, 0xa5, 0xcd, 0x6c,
-	0x9d, 0x95, 0x39, 0x93, 0xf6, 0x52, 0xda, 0x8d, 0x4e, 0x98, 0x51, 0x1b, 0xb4, 0x42, 0x3b, 0xf8,
-	0x4b, 0x81, 0x16, 0x65, 0x7c, 0x9b, 0x97, 0xf3, 0x7f, 0x3f, 0x37, 0x02, 0xa8, 0x6c, 0x5d, 0x96,
-	0x2c, 0x8e, 0x4f, 0xbf, 0x31, 0x3f, 0xed, 0x5d, 0xda, 0x27, 0x35, 0xda, 0x8b, 0x4e, 0xa9, 0x11,
-	0x40, 0xeb, 0x20, 0x1f, 0xdc, 0x42, 0xef, 0x4c, 0x81, 0x4d, 0xd0, 0xb6, 0x51, 0xb8, 0x67, 0x29,
-	0x37, 0xeb, 0x7d, 0x75, 0xd8, 0xa6, 0x07, 0x88, 0x2f, 0xa1, 0xf5, 0x10, 0x46, 0x61, 0xbc, 0x62,
-	0xdc, 0x54, 0x85, 0xe9, 0x88, 0x07, 0xbf, 0x2b, 0xd0, 0x3d, 0x9d, 0x3b, 0x7e, 0x57, 0x34, 0x26,
-	0x87, 0xf8, 0xf6, 0x5f, 0x8e, 0xe7, 0xba, 0xd2, 0xe0, 0xd7, 0xa0, 0x6f, 0xd3, 0xcd, 0x4b, 0x98,
-	0xb1, 0xe0, 0x99, 0xed, 0xc5, 0x08, 0xdb, 0x14, 0x0a, 0x6a, 0xca, 0xf6, 0xf8, 0x35, 0x34, 0xc3,
-	0x8f, 0xc9, 0x2e, 0xce, 0xc4, 0xb4, 0x54, 0x5a, 0xa0, 0xc1, 0x35, 0x34, 0xc4, 0x2c, 0xdb, 0x70,
-	0x41, 0x5c, 0x9f, 0x50, 0x54, 0xc3, 0x00, 0x4d, 0x4a, 0x16, 0xcb, 0x99, 0x8f, 0x14, 0xdc, 0x03,
-	0x7d, 0xee, 0xd8, 0xd3, 0xe0, 0xde, 0x71, 0x5d, 0x42, 0x51, 0x7d, 0x30, 0x85, 0xee, 0xe9, 0x5d,
-	0xc6, 0x7d, 0xd0, 0xb3, 0x34, 0x8c, 0x79, 0xb8, 0xca, 0x36, 0x49, 0x2c, 0x6a, 0x36, 0x68, 0x95,
-	0xc2, 0x6f, 0x40, 0x8b, 0x93, 0x35, 0x0b, 0x36, 0xeb, 0xa2, 0xb0, 0x66, 0x0e, 0x9d, 0xf5, 0xe0,
-	0x37, 0x05, 0xd0, 0xf9, 0x15, 0xc7, 0xdf, 0x80, 0x71, 0xbc, 0xe2, 0xb9, 0x24, 0x0f, 0xd8, 0xa1,
-	0xfa, 0x91, 0x73, 0xd6, 0xf8, 0x2b, 0x80, 0x87, 0x28, 0x59, 0x3d, 0x07, 0x4f, 0x21, 0x7f, 0x12,
-	0x31, 0x0d, 0xda, 0x16, 0xcc, 0x24, 0xe4, 0x4f, 0xf8, 0x5b, 0xe8, 0x70, 0x16, 0xaf, 0x59, 0x1a,
-	0x6c, 0x77, 0x0f, 0xf9, 0x38, 0x54, 0xe1, 0x61, 0x48, 0x72, 0x2e, 0x38, 0x71, 0x58, 0xe1, 0x3e,
-	0x4a, 0xc2, 0x75, 0xb1, 0xc8, 0x07, 0x38, 0x88, 0xc0, 0xa8, 0x2e, 0xd0, 0xa7, 0xe1, 0x94, 0x7f,
-	0x08, 0xf7, 0x1f, 0x25, 0x55, 0xb2, 0xa9, 0x27, 0xd9, 0xae, 0x7e, 0x01, 0xa3, 0xba, 0x1b, 0x58,
-	0x07, 0xcd, 0x25, 0xf7, 0xae, 0x37, 0x26, 0xf2, 0x28, 0x66, 0xc4, 0x1a, 0x13, 0x8a, 0x14, 0xdc,
-	0x81, 0xf6, 0x07, 0x6b, 0xe6, 0x8c, 0x2d, 0xdf, 0xa3, 0xa8, 0x9e, 0x9b, 0xec, 0x99, 0x43, 0x5c,
-	0x1f, 0xa9, 0xf8, 0x0d, 0xbc, 0x92, 0x6e, 0x81, 0x47, 0x83, 0xd2, 0xa9, 0x71, 0x35, 0x01, 0xbd,
-	0xb2, 0x3d, 0x79, 0x08, 0xdb, 0x73, 0x17, 0xc4, 0x5d, 0x2c, 0x17, 0xa8, 0x96, 0xa7, 0x5a, 0xf8,
-	0xd6, 0xd4, 0x71, 0x6f, 0x91, 0x92, 0x5f, 0x80, 0x31, 0xb5, 0xdc, 0x31, 0xaa, 0x63, 0x0c, 0x5d,
-	0x19, 0x3a, 0x58, 0x2c, 0xe7, 0x73, 0x8f, 0xfa, 0x48, 0xbd, 0xfa, 0x53, 0x01, 0xbd, 0xb2, 0x57,
-	0xf8, 0x12, 0x5e, 0x17, 0x65, 0x06, 0x23, 0x62, 0xd9, 0x9e, 0x1b, 0x1c, 0x42, 0xd5, 0xb0, 0x01,
-	0x2d, 0xcb, 0x75, 0xbd, 0xa5, 0x6b, 0x13, 0xa4, 0xe4, 0x59, 0xe6, 0x94, 0xcc, 0x2d, 0x4a, 0x50,
-	0x3d, 0x37, 0x15, 0x60, 0x8c, 0x54, 0xd1, 0x83, 0x77, 0x77, 0xe7, 0xf8, 0xa8, 0x21, 0x6b, 0xcb,
-	0xbf, 0x7d, 0x32, 0x46, 0x17, 0xb8, 0x0b, 0xf0, 0xc1, 0x21, 0xf7, 0xf6, 0xc4, 0x72, 0x6f, 0x09,
-	0x6a, 0x16, 0x63, 0xc9, 0x29, 0xa4, 0xe5, 0x51, 0x5c, 0x72, 0x3f, 0x9a, 0x79, 0xf6, 0x14, 0xb5,
-	0xf2, 0x3b, 0x2a, 0x95, 0x92, 0x68, 0xe7, 0x5a, 0xd1, 0x4a, 0xe0, 0xb8, 0x8e, 0x8f, 0x00, 0x23,
-	0x30, 0x24, 0x2e, 0x92, 0xe9, 0xf8, 0x15, 0xf4, 0x66, 0x9e, 0xef, 0x13, 0xfa, 0x73, 0x40, 0xc9,
-	0x4f, 0x4b, 0xb2, 0xf0, 0x91, 0x71, 0x63, 0x41, 0xc7, 0x8e, 0x36, 0x2c, 0xce, 0x8a, 0x91, 0xe1,
-	0x77, 0xa0, 0xcd, 0xd3, 0x64, 0xc5, 0x38, 0xc7, 0xe8, 0xfc, 0x71, 0xb9, 0xfc, 0xac, 0xf2, 0xb4,
-	0x15, 0x4f, 0x42, 0xed, 0xa1, 0x29, 0x7e, 0x6d, 0xef, 0xff, 0x0e, 0x00, 0x00, 0xff, 0xff, 0x69,
-	0xaf, 0xb6, 0x34, 0xeb, 0x06, 0x00, 0x00,
+	// 964 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x56, 0x4f, 0x6f, 0xe3, 0x44,
+	0x14, 0x8f, 0xf3, 0x3f, 0xcf, 0x4e, 0x3a, 0x3b, 0x5d, 0xb6, 0xa6, 0x5a, 0x44, 0x37, 0x08, 0xa9,
+	0xaa, 0x44, 0x59, 0x75, 0x0f, 0x08, 0xc4, 0xc5, 0x75, 0x46, 0xad, 0xd5, 0xd4, 0x0e, 0x13, 0xb7,
+	0x15, 0xe2, 0x60, 0xb9, 0xf1, 0x28, 0xb5, 0xea, 0xda, 0xc1, 0xe3, 0x74, 0x95, 0xef, 0xc3, 0x0d,
+	0x3e, 0x14, 0x9f, 0x81, 0x33, 0x07, 0x34, 0x63, 0x27, 0x76, 0x13, 0x10, 0x1c, 0xb9, 0xf9, 0xfd,
+	0xde, 0xfb, 0xbd, 0xbf, 0xf3, 0x5e, 0x02, 0xfd, 0x27, 0xc6, 0xb9, 0x3f, 0x67, 0xa7, 0x8b, 0x34,
+	0xc9, 0x12, 0xdc, 0x29, 0xc4, 0xe1, 0x1f, 0x0d, 0xe8, 0x5c, 0xe7, 0xdf, 0xf8, 0x3b, 0xe8, 0xa7,
+	0x6c, 0xc6, 0xc2, 0x67, 0x96, 0x7a, 0xd9, 0x6a, 0xc1, 0x74, 0xe5, 0x48, 0x39, 0x1e, 0x9c, 0x7d,
+	0x72, 0xba, 0xe6, 0xd2, 0x42, 0xeb, 0xae, 0x16, 0x8c, 0x6a, 0x69, 0x45, 0xc2, 0xdf, 0x80, 0xc6,
+	0x59, 0xfa, 0x1c, 0xce, 0x58, 0x4e, 0xad, 0x4b, 0xea, 0xeb, 0x0d, 0x75, 0x9a, 0x2b, 0x25, 0x53,
+	0xe5, 0xa5, 0x80, 0x8f, 0xa1, 0x29, 0x09, 0x8d, 0x2d, 0x42, 0x91, 0x94, 0x24, 0x48, 0x0b, 0xfc,
+	0x16, 0x7a, 0x3c, 0x9c, 0xc7, 0x7e, 0xb6, 0x4c, 0x99, 0xde, 0x3c, 0x52, 0x8e, 0x35, 0x5a, 0x02,
+	0xf8, 0x03, 0x74, 0x78, 0xe6, 0x3f, 0x86, 0xf1, 0x5c, 0x6f, 0x1d, 0x29, 0xc7, 0xea, 0xd9, 0x41,
+	0x19, 0x3b, 0xc7, 0x29, 0xfb, 0x79, 0xc9, 0x78, 0x76, 0x59, 0xa3, 0x6b, 0x4b, 0xfc, 0x2d, 0xf4,
+	0x66, 0x49, 0xcc, 0x59, 0xcc, 0x97, 0x5c, 0x6f, 0x4b, 0xda, 0xa7, 0x1b, 0x9a, 0xb9, 0xd6, 0x94,
+	0xc4, 0xd2, 0x1a, 0x7f, 0x05, 0xad, 0x20, 0xf5, 0xe3, 0x40, 0xef, 0x48, 0x5a, 0xd9, 0xa4, 0x91,
+	0x40, 0x4b, 0x4a, 0x6e, 0x85, 0xcf, 0x61, 0x2f, 0x4a, 0xb2, 0x8c, 0xa5, 0x2b, 0x2f, 0xcd, 0x75,
+	0x7a, 0x77, 0x2b, 0xcd, 0x71, 0xae, 0x2f, 0xa9, 0x83, 0xe8, 0x05, 0x82, 0xbf, 0x07, 0x78, 0x0e,
+	0xd9, 0xc7, 0xd9, 0x83, 0x1f, 0xcf, 0x99, 0xde, 0x93, 0xf4, 0xc3, 0x0d, 0xfd, 0x36, 0x64, 0x1f,
+	0x4d, 0xa9, 0x2a, 0x3d, 0x54, 0xec, 0xcf, 0x7b, 0xd0, 0x29, 0x22, 0x0f, 0xff, 0x54, 0xa0, 0x4b,
+	0x19, 0x5f, 0x88, 0x62, 0xfe, 0xef, 0x53, 0x27, 0x80, 0xca, 0xc6, 0xe5, 0x29, 0xcb, 0xe1, 0xab,
+	0x67, 0xfa, 0x6e, 0xe7, 0x72, 0xfd, 0x65, 0x8d, 0xee, 0x45, 0x2f, 0xa1, 0x73, 0x80, 0xee, 0x9a,
+	0x3e, 0xbc, 0x80, 0xbd, 0x2d, 0x06, 0xd6, 0xa1, 0xb3, 0x88, 0xfc, 0x15, 0x4b, 0xb9, 0x5e, 0x3f,
+	0x6a, 0x1c, 0xf7, 0xe8, 0x5a, 0xc4, 0x87, 0xd0, 0xbd, 0xf7, 0x23, 0x3f, 0x9e, 0x31, 0xae, 0x37,
+	0xa4, 0x6a, 0x23, 0x0f, 0x7f, 0x55, 0x60, 0xf0, 0x72, 0x6a, 0xf8, 0x3d, 0x34, 0x2b, 0x4d, 0x7c,
+	0xfb, 0x0f, 0xc3, 0x3d, 0xad, 0x14, 0xf8, 0x39, 0xa8, 0x8b, 0x34, 0x7c, 0xf6, 0x33, 0xe6, 0x3d,
+	0xb2, 0x95, 0x6c, 0x61, 0x8f, 0x42, 0x01, 0x5d, 0xb1, 0x15, 0x7e, 0x03, 0x6d, 0xff, 0x29, 0x59,
+	0xc6, 0x99, 0xec, 0x56, 0x83, 0x16, 0xd2, 0xf0, 0x14, 0x9a, 0xb2, 0x97, 0x3d, 0x68, 0x11, 0xdb,
+	0x25, 0x14, 0xd5, 0x30, 0x40, 0x9b, 0x92, 0xe9, 0xcd, 0xd8, 0x45, 0x0a, 0xde, 0x03, 0x75, 0x62,
+	0x99, 0x57, 0xde, 0x9d, 0x65, 0xdb, 0x84, 0xa2, 0xfa, 0xf0, 0x0a, 0x06, 0x2f, 0x37, 0x01, 0x1f,
+	0x81, 0x9a, 0xa5, 0x7e, 0xcc, 0xfd, 0x59, 0x16, 0x26, 0xb1, 0xcc, 0x59, 0xa3, 0x55, 0x08, 0x1f,
+	0x40, 0x27, 0x4e, 0x02, 0xe6, 0x85, 0x41, 0x91, 0x58, 0x5b, 0x88, 0x56, 0x30, 0xfc, 0x4d, 0x01,
+	0xb4, 0xbd, 0x20, 0xf8, 0x1d, 0x68, 0x9b, 0x05, 0x11, 0x14, 0xe1, 0xb0, 0x4f, 0xd5, 0x0d, 0x66,
+	0x05, 0xf8, 0x33, 0x80, 0xfb, 0x28, 0x99, 0x3d, 0x7a, 0x0f, 0x3e, 0x7f, 0x90, 0x3e, 0x35, 0xda,
+	0x93, 0xc8, 0xa5, 0xcf, 0x1f, 0xf0, 0x17, 0xd0, 0xe7, 0x2c, 0x0e, 0x58, 0xea, 0x2d, 0x96, 0xf7,
+	0xa2, 0x1d, 0x0d, 0x69, 0xa1, 0xe5, 0xe0, 0x44, 0x62, 0x72, 0x58, 0xfe, 0x2a, 0x4a, 0xfc, 0xa0,
+	0x38, 0x03, 0x6b, 0x51, 0xa4, 0x2b, 0x5e, 0xbc, 0x88, 0xdd, 0x92, 0xb1, 0xdb, 0x42, 0xb4, 0x82,
+	0xe1, 0x2f, 0x75, 0x78, 0xb5, 0xb3, 0x20, 0x55, 0x73, 0xa5, 0x6a, 0xbe, 0x53, 0x48, 0x7d, 0xb7,
+	0x90, 0xff, 0x94, 0xe9, 0x97, 0x30, 0x28, 0x37, 0xd0, 0xe3, 0xe1, 0xbc, 0x48, 0xb8, 0x5f, 0xa2,
+	0xd3, 0x70, 0xbe, 0xd5, 0x94, 0xd6, 0x76, 0x53, 0xde, 0x81, 0xb6, 0x48, 0xd9, 0xc2, 0x4f, 0x59,
+	0x20, 0x7d, 0xb4, 0xf3, 0x39, 0xad, 0x31, 0xe1, 0xe1, 0x35, 0xb4, 0xa4, 0xbd, 0xbc, 0x46, 0x1a,
+	0xcd, 0x05, 0xfc, 0x35, 0xec, 0x57, 0xc2, 0x3f, 0x2d, 0xa3, 0x2c, 0x14, 0xfc, 0xae, 0xb4, 0xc1,
+	0xa5, 0xea, 0xba, 0xd0, 0x0c, 0x23, 0xd0, 0xaa, 0xe7, 0x6b, 0xb7, 0x48, 0xe5, 0x6f, 0x8a, 0xfc,
+	0x97, 0x91, 0x56, 0xa6, 0xd5, 0x78, 0x31, 0xad, 0x93, 0x9f, 0x40, 0xab, 0xde, 0x16, 0xac, 0x42,
+	0xc7, 0x26, 0x77, 0xb6, 0x33, 0x22, 0xf9, 0x53, 0x1e, 0x13, 0x63, 0x44, 0x28, 0x52, 0x70, 0x1f,
+	0x7a, 0xb7, 0xc6, 0xd8, 0x1a, 0x19, 0xae, 0x43, 0x51, 0x5d, 0xa8, 0xcc, 0xb1, 0x45, 0x6c, 0x17,
+	0x35, 0xf0, 0x01, 0xec, 0xe7, 0x66, 0x9e, 0x43, 0xbd, 0xd2, 0xa8, 0x79, 0x72, 0x09, 0x6a, 0xe5,
+	0xfa, 0x08, 0x17, 0xa6, 0x63, 0x4f, 0x89, 0x3d, 0xbd, 0x99, 0xa2, 0x9a, 0x08, 0x35, 0x75, 0x8d,
+	0x2b, 0xcb, 0xbe, 0x40, 0x8a, 0x58, 0xa0, 0x11, 0x35, 0xec, 0x11, 0xaa, 0x63, 0x0c, 0x83, 0xdc,
+	0xb5, 0x37, 0xbd, 0x99, 0x4c, 0x1c, 0xea, 0xa2, 0xc6, 0xc9, 0xef, 0x0a, 0xa8, 0x95, 0xbb, 0x84,
+	0x0f, 0xe1, 0x4d, 0x91, 0xa6, 0x77, 0x4e, 0x0c, 0xd3, 0xb1, 0xbd, 0xb5, 0xab, 0x1a, 0xd6, 0xa0,
+	0x6b, 0xd8, 0xb6, 0x73, 0x63, 0x9b, 0x04, 0x29, 0x22, 0xca, 0x84, 0x92, 0x89, 0x41, 0x09, 0xaa,
+	0x0b, 0x55, 0x21, 0x8c, 0x50, 0x43, 0xd6, 0xe0, 0x5c, 0x5f, 0x5b, 0x2e, 0x6a, 0xe6, 0xb9, 0x89,
+	0x6f, 0x97, 0x8c, 0x50, 0x0b, 0x0f, 0x00, 0x6e, 0x2d, 0x72, 0x67, 0x5e, 0x1a, 0xf6, 0x05, 0x41,
+	0xed, 0xa2, 0x2d, 0x02, 0x42, 0x1d, 0xe1, 0xc5, 0x26, 0x77, 0xe7, 0x63, 0xc7, 0xbc, 0x42, 0x5d,
+	0xb1, 0xe3, 0x39, 0x33, 0x07, 0x7a, 0x82, 0x2b, 0x4b, 0xf1, 0x2c, 0xdb, 0x72, 0x11, 0x60, 0x04,
+	0x5a, 0x2e, 0x17, 0xc1, 0x54, 0xbc, 0x0f, 0x7b, 0x63, 0xc7, 0x75, 0x09, 0xfd, 0xd1, 0xa3, 0xe4,
+	0x87, 0x1b, 0x32, 0x75, 0x91, 0x76, 0x66, 0x40, 0xdf, 0x8c, 0x42, 0x16, 0x67, 0x45, 0xcb, 0xf0,
+	0x7b, 0xe8, 0x4c, 0xd2, 0x64, 0xc6, 0x38, 0xc7, 0x68, 0xfb, 0x38, 0x1f, 0xbe, 0xaa, 0xfc, 0x34,
+	0x14, 0x27, 0xb5, 0x76, 0xdf, 0x96, 0x7f, 0x2c, 0x3e, 0xfc, 0x35, 0x00, 0x6e, 0x58, 0x7b, 0x5b,
+	0x69, 0x08, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -28,6 +28,9 @@ const (
 	PING // node send ip/pki to register with leader
 	PONG // node broadcast pubK
 	ShardState
+	DoubleSign // node reports a double sign evidence to the beacon chain
+	CXReceipts // node relays the proven receipts of cross-shard transactions to their destination shard
+	CrossLink  // shard leader submits the headers of the committed blocks to the beacon chain
 	// TODO: add more types
 )
 
@@ -187,3 +190,75 @@ func DeserializeEpochShardStateFromMessage(payload []byte) (*types.EpochShardSta
 
 	return epochShardState, nil
 }
+
+// ConstructDoubleSignMessage constructs double sign message to report a double signer to the beacon chain
+func ConstructDoubleSignMessage(evidence *types.DoubleSignEvidence) []byte {
+	byteBuffer := bytes.NewBuffer([]byte{byte(proto.Node)})
+	byteBuffer.WriteByte(byte(DoubleSign))
+
+	evidenceData, err := rlp.EncodeToBytes(evidence)
+	if err != nil {
+		utils.GetLogInstance().Error("[ConstructDoubleSignMessage] Encode", "error", err)
+		return nil
+	}
+	byteBuffer.Write(evidenceData)
+	return byteBuffer.Bytes()
+}
+
+// DeserializeDoubleSignFromMessage deserializes the double sign evidence from bytes payload
+func DeserializeDoubleSignFromMessage(payload []byte) (*types.DoubleSignEvidence, error) {
+	evidence := new(types.DoubleSignEvidence)
+	if err := rlp.DecodeBytes(payload, evidence); err != nil {
+		utils.GetLogInstance().Error("[DeserializeDoubleSignFromMessage] Decode", "error", err)
+		return nil, fmt.Errorf("Decode double sign evidence Error")
+	}
+	return evidence, nil
+}
+
+// ConstructCXReceiptsMessage constructs cross-shard receipts message to relay the proven receipts to their destination shard
+func ConstructCXReceiptsMessage(proofs types.CXReceiptProofs) []byte {
+	byteBuffer := bytes.NewBuffer([]byte{byte(proto.Node)})
+	byteBuffer.WriteByte(byte(CXReceipts))
+
+	proofsData, err := rlp.EncodeToBytes(proofs)
+	if err != nil {
+		utils.GetLogInstance().Error("[ConstructCXReceiptsMessage] Encode", "error", err)
+		return nil
+	}
+	byteBuffer.Write(proofsData)
+	return byteBuffer.Bytes()
+}
+
+// DeserializeCXReceiptsFromMessage deserializes the cross-shard receipt proofs from bytes payload
+func DeserializeCXReceiptsFromMessage(payload []byte) (types.CXReceiptProofs, error) {
+	proofs := types.CXReceiptProofs{}
+	if err := rlp.DecodeBytes(payload, &proofs); err != nil {
+		utils.GetLogInstance().Error("[DeserializeCXReceiptsFromMessage] Decode", "error", err)
+		return nil, fmt.Errorf("Decode cross-shard receipts Error")
+	}
+	return proofs, nil
+}
+
+// ConstructCrossLinkMessage constructs cross-link message to submit the committed shard block headers to the beacon chain
+func ConstructCrossLinkMessage(crossLinks types.CrossLinks) []byte {
+	byteBuffer := bytes.NewBuffer([]byte{byte(proto.Node)})
+	byteBuffer.WriteByte(byte(CrossLink))
+
+	crossLinksData, err := rlp.EncodeToBytes(crossLinks)
+	if err != nil {
+		utils.GetLogInstance().Error("[ConstructCrossLinkMessage] Encode", "error", err)
+		return nil
+	}
+	byteBuffer.Write(crossLinksData)
+	return byteBuffer.Bytes()
+}
+
+// DeserializeCrossLinkFromMessage deserializes the cross-links from bytes payload
+func DeserializeCrossLinkFromMessage(payload []byte) (types.CrossLinks, error) {
+	crossLinks := types.CrossLinks{}
+	if err := rlp.DecodeBytes(payload, &crossLinks); err != nil {
+		utils.GetLogInstance().Error("[DeserializeCrossLinkFromMessage] Decode", "error", err)
+		return nil, fmt.Errorf("Decode cross-links Error")
+	}
+	return crossLinks, nil
+}
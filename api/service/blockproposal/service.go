@@ -41,7 +41,7 @@ func (s *Service) Run(stopChan chan struct{}, stoppedChan chan struct{}) {
 // StopService stops block proposal service.
 func (s *Service) StopService() {
 	utils.GetLogInstance().Info("Stopping block proposal service.")
-	s.stopChan <- struct{}{}
+	close(s.stopChan)
 	<-s.stoppedChan
 	utils.GetLogInstance().Info("Role conversion stopped.")
 }
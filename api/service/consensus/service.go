@@ -30,6 +30,7 @@ func (s *Service) StartService() {
 	s.stoppedChan = make(chan struct{})
 	if s.consensus.ConsensusVersion == "v1" {
 		s.consensus.WaitForNewBlock(s.blockChannel, s.stopChan, s.stoppedChan, s.startChan)
+		s.consensus.WaitForViewChange(s.stopChan)
 	} else {
 		s.consensus.Start(s.stopChan, s.stoppedChan)
 	}
@@ -39,7 +40,7 @@ func (s *Service) StartService() {
 // StopService stops consensus service.
 func (s *Service) StopService() {
 	utils.GetLogInstance().Info("Stopping consensus service.")
-	s.stopChan <- struct{}{}
+	close(s.stopChan)
 	<-s.stoppedChan
 	utils.GetLogInstance().Info("Consensus service stopped.")
 }
@@ -17,6 +17,7 @@ import (
 	"github.com/gorilla/mux"
 	msg_pb "github.com/harmony-one/harmony/api/proto/message"
 	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/crypto/bls"
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/p2p"
 	libp2p_peer "github.com/libp2p/go-libp2p-peer"
@@ -37,15 +38,17 @@ type Service struct {
 	server            *http.Server
 	messageChan       chan *msg_pb.Message
 	GetAccountBalance func(common.Address) (*big.Int, error)
+	GetCommittee      func(blockNum uint64) (*types.Committee, error) // committee which signs the given block
 }
 
 // New returns explorer service.
-func New(selfPeer *p2p.Peer, GetNodeIDs func() []libp2p_peer.ID, GetAccountBalance func(common.Address) (*big.Int, error)) *Service {
+func New(selfPeer *p2p.Peer, GetNodeIDs func() []libp2p_peer.ID, GetAccountBalance func(common.Address) (*big.Int, error), GetCommittee func(blockNum uint64) (*types.Committee, error)) *Service {
 	return &Service{
 		IP:                selfPeer.IP,
 		Port:              selfPeer.Port,
 		GetNodeIDs:        GetNodeIDs,
 		GetAccountBalance: GetAccountBalance,
+		GetCommittee:      GetCommittee,
 	}
 }
 
@@ -179,6 +182,7 @@ func (s *Service) GetExplorerBlocks(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 		block := NewBlock(accountBlock, id+fromInt-1)
+		block.Signers = s.getSigners(accountBlock)
 		// Populate transactions
 		for _, tx := range accountBlock.Transactions() {
 			transaction := GetTransaction(tx, accountBlock)
@@ -213,6 +217,38 @@ func (s *Service) GetExplorerBlocks(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(data.Blocks)
 }
 
+// getSigners returns the BLS addresses of the committee members who signed the
+// commit of the block.
+func (s *Service) getSigners(block *types.Block) []string {
+	signers := []string{}
+	if s.GetCommittee == nil {
+		return signers
+	}
+	committee, err := s.GetCommittee(block.NumberU64())
+	if err != nil {
+		utils.GetLogInstance().Warn("[Explorer] cannot read the committee of the block", "blockNum", block.NumberU64(), "error", err)
+		return signers
+	}
+	publicKeys, err := committee.BLSPublicKeys()
+	if err != nil {
+		utils.GetLogInstance().Warn("[Explorer] invalid committee of the block", "blockNum", block.NumberU64(), "error", err)
+		return signers
+	}
+	mask, err := bls.NewMask(publicKeys, nil)
+	if err != nil {
+		utils.GetLogInstance().Warn("[Explorer] cannot create the signers mask", "blockNum", block.NumberU64(), "error", err)
+		return signers
+	}
+	if err := mask.SetMask(block.Header().CommitBitmap); err != nil {
+		utils.GetLogInstance().Warn("[Explorer] invalid commit bitmap", "blockNum", block.NumberU64(), "error", err)
+		return signers
+	}
+	for _, publicKey := range mask.GetPubKeyFromMask(true) {
+		signers = append(signers, utils.GetBlsAddress(publicKey).Hex())
+	}
+	return signers
+}
+
 // GetExplorerTransaction servers /tx end-point.
 func (s *Service) GetExplorerTransaction(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -70,7 +70,6 @@ type Shard struct {
 
 // NewBlock ...
 func NewBlock(block *types.Block, height int) *Block {
-	// TODO(ricl): use block.Header().CommitBitmap and GetPubKeyFromMask
 	return &Block{
 		Height:     strconv.Itoa(height),
 		ID:         block.Hash().Hex(),
@@ -30,7 +30,7 @@ func (s *Service) StartService() {
 // StopService stops randomness generation service.
 func (s *Service) StopService() {
 	utils.GetLogInstance().Info("Stopping random generation service.")
-	s.stopChan <- struct{}{}
+	close(s.stopChan)
 	<-s.stoppedChan
 	utils.GetLogInstance().Info("Random generation stopped.")
 }
@@ -3,11 +3,15 @@ package downloader
 import (
 	"context"
 	"fmt"
+	"io"
+	"sync/atomic"
 	"time"
 
 	pb "github.com/harmony-one/harmony/api/service/syncing/downloader/proto"
 	"github.com/harmony-one/harmony/internal/utils"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Client is the client model for downloader package.
@@ -15,6 +19,7 @@ type Client struct {
 	dlClient pb.DownloaderClient
 	opts     []grpc.DialOption
 	conn     *grpc.ClientConn
+	noStream int32 // set once the server turns out not to support streaming
 }
 
 // ClientSetup setups a Client given ip and port.
@@ -32,6 +37,45 @@ func ClientSetup(ip, port string) *Client {
 	return &client
 }
 
+// queryStream sends the request over GetBlocksStream, and gathers the payload
+// streamed back in a single response. Peers not supporting streaming are sent
+// the request over Query instead.
+func (client *Client) queryStream(ctx context.Context, request *pb.DownloaderRequest) (*pb.DownloaderResponse, error) {
+	if atomic.LoadInt32(&client.noStream) == 0 {
+		stream, err := client.dlClient.GetBlocksStream(ctx, request)
+		if err == nil {
+			var response *pb.DownloaderResponse
+			if response, err = receiveStream(stream); err == nil {
+				return response, nil
+			}
+		}
+		if status.Code(err) != codes.Unimplemented {
+			return nil, err
+		}
+		utils.GetLogInstance().Info("[SYNC] peer doesn't support streaming, using Query")
+		atomic.StoreInt32(&client.noStream, 1)
+	}
+	return client.dlClient.Query(ctx, request)
+}
+
+// receiveStream gathers the payload of the messages received from the stream
+// until it ends.
+func receiveStream(stream interface {
+	Recv() (*pb.DownloaderResponse, error)
+}) (*pb.DownloaderResponse, error) {
+	response := &pb.DownloaderResponse{}
+	for {
+		message, err := stream.Recv()
+		if err == io.EOF {
+			return response, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		response.Payload = append(response.Payload, message.Payload...)
+	}
+}
+
 // Close closes the Client.
 func (client *Client) Close() {
 	err := client.conn.Close()
@@ -44,7 +88,7 @@ func (client *Client) Close() {
 func (client *Client) GetBlockHashes(startHash []byte) *pb.DownloaderResponse {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	request := &pb.DownloaderRequest{Type: pb.DownloaderRequest_HEADER, BlockHash: startHash}
+	request := &pb.DownloaderRequest{Type: pb.DownloaderRequest_BLOCKHASH, BlockHash: startHash}
 	response, err := client.dlClient.Query(ctx, request)
 	if err != nil {
 		utils.GetLogInstance().Info("[SYNC] GetBlockHashes query failed", "error", err)
@@ -52,62 +96,105 @@ func (client *Client) GetBlockHashes(startHash []byte) *pb.DownloaderResponse {
 	return response
 }
 
-// GetBlocks gets blocks in serialization byte array by calling a grpc request.
-func (client *Client) GetBlocks(hashes [][]byte) *pb.DownloaderResponse {
+// GetBlockHeaders gets up to size serialized headers of the blocks following
+// startHash in the canonical chain of the peer.
+func (client *Client) GetBlockHeaders(startHash []byte, size uint32) *pb.DownloaderResponse {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	request := &pb.DownloaderRequest{Type: pb.DownloaderRequest_BLOCK}
-	request.Hashes = make([][]byte, len(hashes))
-	for i := range hashes {
-		request.Hashes[i] = make([]byte, len(hashes[i]))
-		copy(request.Hashes[i], hashes[i])
-	}
+	request := &pb.DownloaderRequest{Type: pb.DownloaderRequest_BLOCKHEADER, BlockHash: startHash, Size: size}
 	response, err := client.dlClient.Query(ctx, request)
 	if err != nil {
-		utils.GetLogInstance().Info("[SYNC] downloader/client.go:GetBlocks query failed.", "error", err)
+		utils.GetLogInstance().Info("[SYNC] GetBlockHeaders query failed", "error", err)
 	}
 	return response
 }
 
-// Register will register node's ip/port information to peers receive newly created blocks in future
-// hash is the bytes of "ip:port" string representation
-func (client *Client) Register(hash []byte, ip, port string) *pb.DownloaderResponse {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+// GetBlockBodies gets the serialized bodies of the blocks of the given hashes,
+// giving up after timeout. The payload has one body per hash, empty if the
+// peer doesn't have the block, and may be cut short by the peer.
+func (client *Client) GetBlockBodies(hashes [][]byte, timeout time.Duration) *pb.DownloaderResponse {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	request := &pb.DownloaderRequest{Type: pb.DownloaderRequest_REGISTER}
-	request.PeerHash = make([]byte, len(hash))
-	copy(request.PeerHash, hash)
-	request.Ip = ip
-	request.Port = port
-	response, err := client.dlClient.Query(ctx, request)
-	if err != nil || response == nil {
-		utils.GetLogInstance().Info("[SYNC] client.go:Register failed.", "error", err, "response", response)
+	request := &pb.DownloaderRequest{Type: pb.DownloaderRequest_BLOCKBODY, Hashes: hashes}
+	response, err := client.queryStream(ctx, request)
+	if err != nil {
+		utils.GetLogInstance().Info("[SYNC] GetBlockBodies query failed", "error", err)
 	}
 	return response
 }
 
-// PushNewBlock will send the lastest verified block to registered nodes
-func (client *Client) PushNewBlock(selfPeerHash [20]byte, blockHash []byte, timeout bool) *pb.DownloaderResponse {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// GetTrieNodes gets the state trie nodes of the given hashes, giving up after
+// timeout. The payload has one node per hash, empty if the peer doesn't have it,
+// and may be cut short by the peer.
+func (client *Client) GetTrieNodes(hashes [][]byte, timeout time.Duration) *pb.DownloaderResponse {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
+	request := &pb.DownloaderRequest{Type: pb.DownloaderRequest_TRIENODE, Hashes: hashes}
+	response, err := client.queryStream(ctx, request)
+	if err != nil {
+		utils.GetLogInstance().Info("[SYNC] GetTrieNodes query failed", "error", err)
+	}
+	return response
+}
 
-	request := &pb.DownloaderRequest{Type: pb.DownloaderRequest_NEWBLOCK}
-	request.BlockHash = make([]byte, len(blockHash))
-	copy(request.BlockHash, blockHash)
-	request.PeerHash = make([]byte, len(selfPeerHash))
-	copy(request.PeerHash, selfPeerHash[:])
+// GetReceipts gets the serialized receipts of the blocks of the given hashes,
+// giving up after timeout. The payload has one receipt list per hash, empty
+// if the peer doesn't have the block, and may be cut short by the peer.
+func (client *Client) GetReceipts(hashes [][]byte, timeout time.Duration) *pb.DownloaderResponse {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	request := &pb.DownloaderRequest{Type: pb.DownloaderRequest_RECEIPT, Hashes: hashes}
+	response, err := client.queryStream(ctx, request)
+	if err != nil {
+		utils.GetLogInstance().Info("[SYNC] GetReceipts query failed", "error", err)
+	}
+	return response
+}
 
-	if timeout {
-		request.Type = pb.DownloaderRequest_REGISTERTIMEOUT
+// GetBlockRange gets the serialized blocks numbered from from to to of the
+// canonical chain of the peer, giving up after timeout. The payload stops at
+// the last block the peer has, or where the peer cut the stream short.
+func (client *Client) GetBlockRange(from, to uint64, timeout time.Duration) *pb.DownloaderResponse {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	stream, err := client.dlClient.GetBlockRange(ctx, &pb.BlockRangeRequest{From: from, To: to})
+	if err == nil {
+		var response *pb.DownloaderResponse
+		if response, err = receiveStream(stream); err == nil {
+			return response
+		}
 	}
+	utils.GetLogInstance().Info("[SYNC] GetBlockRange query failed", "error", err)
+	return nil
+}
 
-	response, err := client.dlClient.Query(ctx, request)
+// GetBlocks gets blocks in serialization byte array by calling a grpc request.
+func (client *Client) GetBlocks(hashes [][]byte) *pb.DownloaderResponse {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	request := &pb.DownloaderRequest{Type: pb.DownloaderRequest_BLOCK}
+	request.Hashes = make([][]byte, len(hashes))
+	for i := range hashes {
+		request.Hashes[i] = make([]byte, len(hashes[i]))
+		copy(request.Hashes[i], hashes[i])
+	}
+	response, err := client.queryStream(ctx, request)
 	if err != nil {
-		utils.GetLogInstance().Info("[SYNC] unable to send new block to unsync node", "error", err)
+		utils.GetLogInstance().Info("[SYNC] downloader/client.go:GetBlocks query failed.", "error", err)
 	}
 	return response
 }
 
+// SubscribeNewBlocks subscribes to the blocks of the peer from fromHeight on,
+// until ctx is done.
+func (client *Client) SubscribeNewBlocks(ctx context.Context, fromHeight uint64) (pb.Downloader_SubscribeNewBlocksClient, error) {
+	stream, err := client.dlClient.SubscribeNewBlocks(ctx, &pb.SubscribeRequest{FromHeight: fromHeight})
+	if err != nil {
+		utils.GetLogInstance().Info("[SYNC] SubscribeNewBlocks failed", "error", err)
+	}
+	return stream, err
+}
+
 // GetBlockChainHeight gets the blockheight from peer
 func (client *Client) GetBlockChainHeight() *pb.DownloaderResponse {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
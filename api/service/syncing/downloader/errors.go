@@ -5,4 +5,5 @@ import "errors"
 // Errors for downloader package.
 var (
 	ErrDownloaderWithNoNode = errors.New("no node attached")
+	ErrResponseTooLarge     = errors.New("streamed response reached the size cap")
 )
@@ -1,6 +1,8 @@
 package downloader
 
 import (
+	"context"
+
 	pb "github.com/harmony-one/harmony/api/service/syncing/downloader/proto"
 )
 
@@ -8,4 +10,13 @@ import (
 type DownloadInterface interface {
 	// State Syncing server-side interface, responsible for all kinds of state syncing grpc calls
 	CalculateResponse(request *pb.DownloaderRequest) (*pb.DownloaderResponse, error)
+	// StreamResponse passes the payload of a BLOCK, BLOCKBODY, TRIENODE or RECEIPT request
+	// to send one item at a time, and stops at the first error of send
+	StreamResponse(request *pb.DownloaderRequest, send func(payload []byte) error) error
+	// StreamBlockRange passes the serialized blocks of the canonical chain numbered from from to to
+	// to send one at a time, and stops at the first error of send
+	StreamBlockRange(from, to uint64, send func(payload []byte) error) error
+	// SubscribeNewBlocks passes the serialized blocks of the canonical chain from fromHeight on
+	// to send one at a time, each new block as soon as it is committed, until ctx is done or send fails
+	SubscribeNewBlocks(ctx context.Context, fromHeight uint64, send func(payload []byte) error) error
 }
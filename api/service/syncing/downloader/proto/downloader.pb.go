@@ -25,33 +25,45 @@ const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 type DownloaderRequest_RequestType int32
 
 const (
-	DownloaderRequest_HEADER          DownloaderRequest_RequestType = 0
+	DownloaderRequest_BLOCKHASH       DownloaderRequest_RequestType = 0
 	DownloaderRequest_BLOCK           DownloaderRequest_RequestType = 1
 	DownloaderRequest_NEWBLOCK        DownloaderRequest_RequestType = 2
 	DownloaderRequest_BLOCKHEIGHT     DownloaderRequest_RequestType = 3
 	DownloaderRequest_REGISTER        DownloaderRequest_RequestType = 4
 	DownloaderRequest_REGISTERTIMEOUT DownloaderRequest_RequestType = 5
 	DownloaderRequest_UNKNOWN         DownloaderRequest_RequestType = 6
+	DownloaderRequest_BLOCKHEADER     DownloaderRequest_RequestType = 7
+	DownloaderRequest_BLOCKBODY       DownloaderRequest_RequestType = 8
+	DownloaderRequest_TRIENODE        DownloaderRequest_RequestType = 9
+	DownloaderRequest_RECEIPT         DownloaderRequest_RequestType = 10
 )
 
 var DownloaderRequest_RequestType_name = map[int32]string{
-	0: "HEADER",
-	1: "BLOCK",
-	2: "NEWBLOCK",
-	3: "BLOCKHEIGHT",
-	4: "REGISTER",
-	5: "REGISTERTIMEOUT",
-	6: "UNKNOWN",
+	0:  "BLOCKHASH",
+	1:  "BLOCK",
+	2:  "NEWBLOCK",
+	3:  "BLOCKHEIGHT",
+	4:  "REGISTER",
+	5:  "REGISTERTIMEOUT",
+	6:  "UNKNOWN",
+	7:  "BLOCKHEADER",
+	8:  "BLOCKBODY",
+	9:  "TRIENODE",
+	10: "RECEIPT",
 }
 
 var DownloaderRequest_RequestType_value = map[string]int32{
-	"HEADER":          0,
+	"BLOCKHASH":       0,
 	"BLOCK":           1,
 	"NEWBLOCK":        2,
 	"BLOCKHEIGHT":     3,
 	"REGISTER":        4,
 	"REGISTERTIMEOUT": 5,
 	"UNKNOWN":         6,
+	"BLOCKHEADER":     7,
+	"BLOCKBODY":       8,
+	"TRIENODE":        9,
+	"RECEIPT":         10,
 }
 
 func (x DownloaderRequest_RequestType) String() string {
@@ -87,19 +99,21 @@ func (x DownloaderResponse_RegisterResponseType) String() string {
 }
 
 func (DownloaderResponse_RegisterResponseType) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_6a99ec95c7ab1ff1, []int{1, 0}
+	return fileDescriptor_6a99ec95c7ab1ff1, []int{3, 0}
 }
 
 // DownloaderRequest is the generic download request.
 type DownloaderRequest struct {
 	// Request type.
 	Type DownloaderRequest_RequestType `protobuf:"varint,1,opt,name=type,proto3,enum=downloader.DownloaderRequest_RequestType" json:"type,omitempty"`
-	// The hashes of the blocks we want to download.
-	Hashes               [][]byte `protobuf:"bytes,2,rep,name=hashes,proto3" json:"hashes,omitempty"`
-	PeerHash             []byte   `protobuf:"bytes,3,opt,name=peerHash,proto3" json:"peerHash,omitempty"`
-	BlockHash            []byte   `protobuf:"bytes,4,opt,name=blockHash,proto3" json:"blockHash,omitempty"`
-	Ip                   string   `protobuf:"bytes,5,opt,name=ip,proto3" json:"ip,omitempty"`
-	Port                 string   `protobuf:"bytes,6,opt,name=port,proto3" json:"port,omitempty"`
+	// The hashes of the blocks, or of the state trie nodes, we want to download.
+	Hashes    [][]byte `protobuf:"bytes,2,rep,name=hashes,proto3" json:"hashes,omitempty"`
+	PeerHash  []byte   `protobuf:"bytes,3,opt,name=peerHash,proto3" json:"peerHash,omitempty"`
+	BlockHash []byte   `protobuf:"bytes,4,opt,name=blockHash,proto3" json:"blockHash,omitempty"`
+	Ip        string   `protobuf:"bytes,5,opt,name=ip,proto3" json:"ip,omitempty"`
+	Port      string   `protobuf:"bytes,6,opt,name=port,proto3" json:"port,omitempty"`
+	// The maximum number of headers to return for BLOCKHEADER, following blockHash.
+	Size                 uint32   `protobuf:"varint,7,opt,name=size,proto3" json:"size,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -134,7 +148,7 @@ func (m *DownloaderRequest) GetType() DownloaderRequest_RequestType {
 	if m != nil {
 		return m.Type
 	}
-	return DownloaderRequest_HEADER
+	return DownloaderRequest_BLOCKHASH
 }
 
 func (m *DownloaderRequest) GetHashes() [][]byte {
@@ -172,6 +186,101 @@ func (m *DownloaderRequest) GetPort() string {
 	return ""
 }
 
+func (m *DownloaderRequest) GetSize() uint32 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+// BlockRangeRequest is the request of the blocks numbered from from to to, included.
+type BlockRangeRequest struct {
+	From                 uint64   `protobuf:"varint,1,opt,name=from,proto3" json:"from,omitempty"`
+	To                   uint64   `protobuf:"varint,2,opt,name=to,proto3" json:"to,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BlockRangeRequest) Reset()         { *m = BlockRangeRequest{} }
+func (m *BlockRangeRequest) String() string { return proto.CompactTextString(m) }
+func (*BlockRangeRequest) ProtoMessage()    {}
+func (*BlockRangeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_6a99ec95c7ab1ff1, []int{1}
+}
+
+func (m *BlockRangeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BlockRangeRequest.Unmarshal(m, b)
+}
+func (m *BlockRangeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BlockRangeRequest.Marshal(b, m, deterministic)
+}
+func (m *BlockRangeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BlockRangeRequest.Merge(m, src)
+}
+func (m *BlockRangeRequest) XXX_Size() int {
+	return xxx_messageInfo_BlockRangeRequest.Size(m)
+}
+func (m *BlockRangeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BlockRangeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BlockRangeRequest proto.InternalMessageInfo
+
+func (m *BlockRangeRequest) GetFrom() uint64 {
+	if m != nil {
+		return m.From
+	}
+	return 0
+}
+
+func (m *BlockRangeRequest) GetTo() uint64 {
+	if m != nil {
+		return m.To
+	}
+	return 0
+}
+
+// SubscribeRequest is the request of the blocks from fromHeight on.
+type SubscribeRequest struct {
+	FromHeight           uint64   `protobuf:"varint,1,opt,name=fromHeight,proto3" json:"fromHeight,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SubscribeRequest) Reset()         { *m = SubscribeRequest{} }
+func (m *SubscribeRequest) String() string { return proto.CompactTextString(m) }
+func (*SubscribeRequest) ProtoMessage()    {}
+func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_6a99ec95c7ab1ff1, []int{2}
+}
+
+func (m *SubscribeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SubscribeRequest.Unmarshal(m, b)
+}
+func (m *SubscribeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SubscribeRequest.Marshal(b, m, deterministic)
+}
+func (m *SubscribeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SubscribeRequest.Merge(m, src)
+}
+func (m *SubscribeRequest) XXX_Size() int {
+	return xxx_messageInfo_SubscribeRequest.Size(m)
+}
+func (m *SubscribeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SubscribeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SubscribeRequest proto.InternalMessageInfo
+
+func (m *SubscribeRequest) GetFromHeight() uint64 {
+	if m != nil {
+		return m.FromHeight
+	}
+	return 0
+}
+
 // DownloaderResponse is the generic response of DownloaderRequest.
 type DownloaderResponse struct {
 	// payload of Block.
@@ -188,7 +297,7 @@ func (m *DownloaderResponse) Reset()         { *m = DownloaderResponse{} }
 func (m *DownloaderResponse) String() string { return proto.CompactTextString(m) }
 func (*DownloaderResponse) ProtoMessage()    {}
 func (*DownloaderResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_6a99ec95c7ab1ff1, []int{1}
+	return fileDescriptor_6a99ec95c7ab1ff1, []int{3}
 }
 
 func (m *DownloaderResponse) XXX_Unmarshal(b []byte) error {
@@ -234,38 +343,49 @@ func init() {
 	proto.RegisterEnum("downloader.DownloaderRequest_RequestType", DownloaderRequest_RequestType_name, DownloaderRequest_RequestType_value)
 	proto.RegisterEnum("downloader.DownloaderResponse_RegisterResponseType", DownloaderResponse_RegisterResponseType_name, DownloaderResponse_RegisterResponseType_value)
 	proto.RegisterType((*DownloaderRequest)(nil), "downloader.DownloaderRequest")
+	proto.RegisterType((*BlockRangeRequest)(nil), "downloader.BlockRangeRequest")
+	proto.RegisterType((*SubscribeRequest)(nil), "downloader.SubscribeRequest")
 	proto.RegisterType((*DownloaderResponse)(nil), "downloader.DownloaderResponse")
 }
 
 func init() { proto.RegisterFile("downloader.proto", fileDescriptor_6a99ec95c7ab1ff1) }
 
 var fileDescriptor_6a99ec95c7ab1ff1 = []byte{
-	// 388 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x92, 0xdf, 0x6f, 0x94, 0x40,
-	0x10, 0xc7, 0x6f, 0x39, 0xa0, 0x77, 0xc3, 0xa5, 0x5d, 0x47, 0x63, 0x48, 0xa3, 0x86, 0xf0, 0x84,
-	0x2f, 0x3c, 0xb4, 0x4f, 0x3e, 0xf8, 0x50, 0xe9, 0x7a, 0x90, 0x56, 0x2e, 0x2e, 0x9c, 0x8d, 0x8f,
-	0xd4, 0x6e, 0x0a, 0xb1, 0x29, 0x2b, 0x4b, 0x63, 0xf8, 0xe3, 0xfc, 0x2f, 0xfc, 0x83, 0x0c, 0x7b,
-	0x3f, 0x20, 0x51, 0xef, 0x69, 0xe7, 0xfb, 0x9d, 0x9d, 0xc9, 0xcc, 0x27, 0x03, 0xf4, 0xae, 0xfe,
-	0xf9, 0xf8, 0x50, 0x17, 0x77, 0xa2, 0x09, 0x65, 0x53, 0xb7, 0x35, 0xc2, 0xe0, 0xf8, 0xbf, 0x0c,
-	0x78, 0x76, 0xb9, 0x97, 0x5c, 0xfc, 0x78, 0x12, 0xaa, 0xc5, 0xf7, 0x60, 0xb6, 0x9d, 0x14, 0x2e,
-	0xf1, 0x48, 0x70, 0x7c, 0xf6, 0x36, 0x1c, 0xb5, 0xf8, 0xeb, 0x73, 0xb8, 0x7d, 0xf3, 0x4e, 0x0a,
-	0xae, 0xcb, 0xf0, 0x25, 0xd8, 0x65, 0xa1, 0x4a, 0xa1, 0x5c, 0xc3, 0x9b, 0x06, 0x0b, 0xbe, 0x55,
-	0x78, 0x0a, 0x33, 0x29, 0x44, 0x13, 0x17, 0xaa, 0x74, 0xa7, 0x1e, 0x09, 0x16, 0x7c, 0xaf, 0xf1,
-	0x15, 0xcc, 0x6f, 0x1f, 0xea, 0x6f, 0xdf, 0x75, 0xd2, 0xd4, 0xc9, 0xc1, 0xc0, 0x63, 0x30, 0x2a,
-	0xe9, 0x5a, 0x1e, 0x09, 0xe6, 0xdc, 0xa8, 0x24, 0x22, 0x98, 0xb2, 0x6e, 0x5a, 0xd7, 0xd6, 0x8e,
-	0x8e, 0x7d, 0x05, 0xce, 0x68, 0x14, 0x04, 0xb0, 0x63, 0x76, 0x71, 0xc9, 0x38, 0x9d, 0xe0, 0x1c,
-	0xac, 0x0f, 0xd7, 0xab, 0xe8, 0x8a, 0x12, 0x5c, 0xc0, 0x2c, 0x65, 0x37, 0x1b, 0x65, 0xe0, 0x09,
-	0x38, 0x3a, 0x8c, 0x59, 0xb2, 0x8c, 0x73, 0x3a, 0xed, 0xd3, 0x9c, 0x2d, 0x93, 0x2c, 0x67, 0x9c,
-	0x9a, 0xf8, 0x1c, 0x4e, 0x76, 0x2a, 0x4f, 0x3e, 0xb1, 0xd5, 0x3a, 0xa7, 0x16, 0x3a, 0x70, 0xb4,
-	0x4e, 0xaf, 0xd2, 0xd5, 0x4d, 0x4a, 0x6d, 0xff, 0x37, 0x01, 0x1c, 0x23, 0x51, 0xb2, 0x7e, 0x54,
-	0x02, 0x5d, 0x38, 0x92, 0x45, 0xd7, 0x9b, 0x2e, 0xd1, 0x08, 0x76, 0x12, 0x97, 0x5b, 0xb4, 0x86,
-	0x46, 0x7b, 0xfe, 0x3f, 0xb4, 0x9b, 0x3e, 0x21, 0x17, 0xf7, 0x95, 0x6a, 0x07, 0x63, 0x04, 0xd9,
-	0x03, 0x67, 0xc3, 0x47, 0x54, 0xf7, 0x65, 0xab, 0x79, 0x9a, 0x7c, 0x6c, 0xf9, 0xef, 0xe0, 0xc5,
-	0xbf, 0xea, 0xfb, 0x05, 0xb2, 0x75, 0x14, 0xb1, 0x2c, 0xa3, 0x13, 0x9c, 0x81, 0xf9, 0xf1, 0x22,
-	0xb9, 0xa6, 0xa4, 0x07, 0x96, 0xa4, 0xd9, 0xd7, 0x34, 0xa2, 0xc6, 0xd9, 0x17, 0x80, 0x61, 0x1a,
-	0x8c, 0xc1, 0xfa, 0xfc, 0x24, 0x9a, 0x0e, 0x5f, 0x1f, 0xbc, 0x84, 0xd3, 0x37, 0x87, 0xb7, 0xf1,
-	0x27, 0xb7, 0xb6, 0xbe, 0xc0, 0xf3, 0x3f, 0x01, 0x00, 0x00, 0xff, 0xff, 0x45, 0x42, 0x00, 0x51,
-	0x95, 0x02, 0x00, 0x00,
+	// 539 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x54, 0xcd, 0x6e, 0xd3, 0x40,
+	0x18, 0xcc, 0x3a, 0xce, 0xdf, 0x97, 0xa4, 0xd9, 0x7e, 0x20, 0x64, 0x55, 0xa5, 0xb2, 0x7c, 0x0a,
+	0x97, 0x08, 0xa5, 0x07, 0xc4, 0x81, 0x43, 0x7e, 0x96, 0xd8, 0x6a, 0x71, 0x60, 0xed, 0x50, 0xf5,
+	0xe8, 0xb4, 0x4b, 0x62, 0xd1, 0xc6, 0xc6, 0x76, 0x54, 0x85, 0xa7, 0xe0, 0x55, 0x78, 0x17, 0x0e,
+	0x3c, 0x0e, 0xf2, 0x3a, 0x71, 0x0c, 0x85, 0xaa, 0x12, 0xa7, 0xec, 0xcc, 0xee, 0x4c, 0xbe, 0xdd,
+	0x19, 0x19, 0xe8, 0x75, 0x70, 0xb7, 0xba, 0x09, 0xbc, 0x6b, 0x11, 0xf5, 0xc2, 0x28, 0x48, 0x02,
+	0x84, 0x3d, 0x63, 0x7c, 0x2b, 0xc3, 0xe1, 0x38, 0x87, 0x5c, 0x7c, 0x59, 0x8b, 0x38, 0xc1, 0x37,
+	0xa0, 0x26, 0x9b, 0x50, 0x68, 0x44, 0x27, 0xdd, 0x83, 0xfe, 0x8b, 0x5e, 0xc1, 0xe2, 0xde, 0xe1,
+	0xde, 0xf6, 0xd7, 0xdd, 0x84, 0x82, 0x4b, 0x19, 0x3e, 0x83, 0xea, 0xd2, 0x8b, 0x97, 0x22, 0xd6,
+	0x14, 0xbd, 0xdc, 0x6d, 0xf1, 0x2d, 0xc2, 0x23, 0xa8, 0x87, 0x42, 0x44, 0xa6, 0x17, 0x2f, 0xb5,
+	0xb2, 0x4e, 0xba, 0x2d, 0x9e, 0x63, 0x3c, 0x86, 0xc6, 0xfc, 0x26, 0xb8, 0xfa, 0x2c, 0x37, 0x55,
+	0xb9, 0xb9, 0x27, 0xf0, 0x00, 0x14, 0x3f, 0xd4, 0x2a, 0x3a, 0xe9, 0x36, 0xb8, 0xe2, 0x87, 0x88,
+	0xa0, 0x86, 0x41, 0x94, 0x68, 0x55, 0xc9, 0xc8, 0x75, 0xca, 0xc5, 0xfe, 0x57, 0xa1, 0xd5, 0x74,
+	0xd2, 0x6d, 0x73, 0xb9, 0x36, 0xbe, 0x13, 0x68, 0x16, 0xe6, 0xc3, 0x36, 0x34, 0x86, 0xe7, 0xd3,
+	0xd1, 0x99, 0x39, 0x70, 0x4c, 0x5a, 0xc2, 0x06, 0x54, 0x24, 0xa4, 0x04, 0x5b, 0x50, 0xb7, 0xd9,
+	0x45, 0x86, 0x14, 0xec, 0x40, 0x33, 0x3b, 0xc7, 0xac, 0x89, 0xe9, 0xd2, 0x72, 0xba, 0xcd, 0xd9,
+	0xc4, 0x72, 0x5c, 0xc6, 0xa9, 0x8a, 0x4f, 0xa0, 0xb3, 0x43, 0xae, 0xf5, 0x8e, 0x4d, 0x67, 0x2e,
+	0xad, 0x60, 0x13, 0x6a, 0x33, 0xfb, 0xcc, 0x9e, 0x5e, 0xd8, 0xb4, 0x5a, 0x30, 0x18, 0x8c, 0x19,
+	0xa7, 0xb5, 0xfc, 0x9f, 0x87, 0xd3, 0xf1, 0x25, 0xad, 0xa7, 0x7e, 0x2e, 0xb7, 0x98, 0x3d, 0x1d,
+	0x33, 0xda, 0x48, 0xa5, 0x9c, 0x8d, 0x98, 0xf5, 0xde, 0xa5, 0x60, 0xbc, 0x82, 0xc3, 0x61, 0x7a,
+	0x71, 0xee, 0xad, 0x16, 0x62, 0x97, 0x08, 0x82, 0xfa, 0x29, 0x0a, 0x6e, 0x65, 0x22, 0x2a, 0x97,
+	0xeb, 0xf4, 0x51, 0x92, 0x40, 0x53, 0x24, 0xa3, 0x24, 0x81, 0xd1, 0x07, 0xea, 0xac, 0xe7, 0xf1,
+	0x55, 0xe4, 0xcf, 0x73, 0xdd, 0x09, 0x40, 0x7a, 0xd6, 0x14, 0xfe, 0x62, 0x99, 0x6c, 0xd5, 0x05,
+	0xc6, 0xf8, 0x41, 0x00, 0x8b, 0x91, 0xc6, 0x61, 0xb0, 0x8a, 0x05, 0x6a, 0x50, 0x0b, 0xbd, 0x4d,
+	0x4a, 0x6a, 0x44, 0x46, 0xb8, 0x83, 0x38, 0xd9, 0x56, 0x43, 0x91, 0xd5, 0x38, 0xfd, 0x57, 0x35,
+	0x32, 0x9f, 0x1e, 0x17, 0x0b, 0x3f, 0x4e, 0xf6, 0x44, 0xa1, 0x24, 0x3a, 0x34, 0xb3, 0x7c, 0xb3,
+	0xd1, 0xca, 0x72, 0xb4, 0x22, 0x65, 0xbc, 0x86, 0xa7, 0x7f, 0xd3, 0xa7, 0xaf, 0xe5, 0xcc, 0x46,
+	0x23, 0xe6, 0x38, 0xb4, 0x84, 0x75, 0x50, 0xdf, 0x0e, 0xac, 0x73, 0x4a, 0x10, 0xa0, 0x6a, 0xd9,
+	0xce, 0xa5, 0x3d, 0xa2, 0x4a, 0xff, 0xa7, 0x02, 0xb0, 0x1f, 0x07, 0x4d, 0xa8, 0x7c, 0x58, 0x8b,
+	0x68, 0x83, 0xcf, 0x1f, 0xac, 0xf2, 0xd1, 0xc9, 0xc3, 0xd7, 0x31, 0x4a, 0xe8, 0x42, 0x67, 0x22,
+	0x12, 0x99, 0x4f, 0xec, 0x24, 0x91, 0xf0, 0x6e, 0xff, 0xdb, 0xf3, 0x25, 0x41, 0x0e, 0xed, 0x9d,
+	0xab, 0x4c, 0xfd, 0x77, 0xcf, 0x7b, 0x6d, 0x78, 0x94, 0xe7, 0x47, 0xc0, 0xbc, 0x0d, 0xb6, 0xb8,
+	0xcb, 0x46, 0xc6, 0xe3, 0xa2, 0xf2, 0xcf, 0xb6, 0x3c, 0xc6, 0x77, 0x5e, 0x95, 0x1f, 0x91, 0xd3,
+	0x5f, 0x03, 0x00, 0xd1, 0x76, 0x92, 0x69, 0x58, 0x04, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -281,6 +401,13 @@ const _ = grpc.SupportPackageIsVersion4
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type DownloaderClient interface {
 	Query(ctx context.Context, in *DownloaderRequest, opts ...grpc.CallOption) (*DownloaderResponse, error)
+	// GetBlocksStream streams the payload of a BLOCK, BLOCKBODY, TRIENODE or RECEIPT request.
+	GetBlocksStream(ctx context.Context, in *DownloaderRequest, opts ...grpc.CallOption) (Downloader_GetBlocksStreamClient, error)
+	// GetBlockRange streams the blocks of the canonical chain in the given range.
+	GetBlockRange(ctx context.Context, in *BlockRangeRequest, opts ...grpc.CallOption) (Downloader_GetBlockRangeClient, error)
+	// SubscribeNewBlocks streams the blocks of the canonical chain from the given height on,
+	// each new block as soon as it is committed.
+	SubscribeNewBlocks(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (Downloader_SubscribeNewBlocksClient, error)
 }
 
 type downloaderClient struct {
@@ -300,9 +427,112 @@ func (c *downloaderClient) Query(ctx context.Context, in *DownloaderRequest, opt
 	return out, nil
 }
 
+func (c *downloaderClient) GetBlocksStream(ctx context.Context, in *DownloaderRequest, opts ...grpc.CallOption) (Downloader_GetBlocksStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Downloader_serviceDesc.Streams[0], "/downloader.Downloader/GetBlocksStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &downloaderGetBlocksStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Downloader_GetBlocksStreamClient interface {
+	Recv() (*DownloaderResponse, error)
+	grpc.ClientStream
+}
+
+type downloaderGetBlocksStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *downloaderGetBlocksStreamClient) Recv() (*DownloaderResponse, error) {
+	m := new(DownloaderResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *downloaderClient) GetBlockRange(ctx context.Context, in *BlockRangeRequest, opts ...grpc.CallOption) (Downloader_GetBlockRangeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Downloader_serviceDesc.Streams[1], "/downloader.Downloader/GetBlockRange", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &downloaderGetBlockRangeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Downloader_GetBlockRangeClient interface {
+	Recv() (*DownloaderResponse, error)
+	grpc.ClientStream
+}
+
+type downloaderGetBlockRangeClient struct {
+	grpc.ClientStream
+}
+
+func (x *downloaderGetBlockRangeClient) Recv() (*DownloaderResponse, error) {
+	m := new(DownloaderResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *downloaderClient) SubscribeNewBlocks(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (Downloader_SubscribeNewBlocksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Downloader_serviceDesc.Streams[2], "/downloader.Downloader/SubscribeNewBlocks", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &downloaderSubscribeNewBlocksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Downloader_SubscribeNewBlocksClient interface {
+	Recv() (*DownloaderResponse, error)
+	grpc.ClientStream
+}
+
+type downloaderSubscribeNewBlocksClient struct {
+	grpc.ClientStream
+}
+
+func (x *downloaderSubscribeNewBlocksClient) Recv() (*DownloaderResponse, error) {
+	m := new(DownloaderResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // DownloaderServer is the server API for Downloader service.
 type DownloaderServer interface {
 	Query(context.Context, *DownloaderRequest) (*DownloaderResponse, error)
+	// GetBlocksStream streams the payload of a BLOCK, BLOCKBODY, TRIENODE or RECEIPT request.
+	GetBlocksStream(*DownloaderRequest, Downloader_GetBlocksStreamServer) error
+	// GetBlockRange streams the blocks of the canonical chain in the given range.
+	GetBlockRange(*BlockRangeRequest, Downloader_GetBlockRangeServer) error
+	// SubscribeNewBlocks streams the blocks of the canonical chain from the given height on,
+	// each new block as soon as it is committed.
+	SubscribeNewBlocks(*SubscribeRequest, Downloader_SubscribeNewBlocksServer) error
 }
 
 func RegisterDownloaderServer(s *grpc.Server, srv DownloaderServer) {
@@ -327,6 +557,69 @@ func _Downloader_Query_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Downloader_GetBlocksStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloaderRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DownloaderServer).GetBlocksStream(m, &downloaderGetBlocksStreamServer{stream})
+}
+
+type Downloader_GetBlocksStreamServer interface {
+	Send(*DownloaderResponse) error
+	grpc.ServerStream
+}
+
+type downloaderGetBlocksStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *downloaderGetBlocksStreamServer) Send(m *DownloaderResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Downloader_GetBlockRange_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BlockRangeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DownloaderServer).GetBlockRange(m, &downloaderGetBlockRangeServer{stream})
+}
+
+type Downloader_GetBlockRangeServer interface {
+	Send(*DownloaderResponse) error
+	grpc.ServerStream
+}
+
+type downloaderGetBlockRangeServer struct {
+	grpc.ServerStream
+}
+
+func (x *downloaderGetBlockRangeServer) Send(m *DownloaderResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Downloader_SubscribeNewBlocks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DownloaderServer).SubscribeNewBlocks(m, &downloaderSubscribeNewBlocksServer{stream})
+}
+
+type Downloader_SubscribeNewBlocksServer interface {
+	Send(*DownloaderResponse) error
+	grpc.ServerStream
+}
+
+type downloaderSubscribeNewBlocksServer struct {
+	grpc.ServerStream
+}
+
+func (x *downloaderSubscribeNewBlocksServer) Send(m *DownloaderResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 var _Downloader_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "downloader.Downloader",
 	HandlerType: (*DownloaderServer)(nil),
@@ -336,6 +629,22 @@ var _Downloader_serviceDesc = grpc.ServiceDesc{
 			Handler:    _Downloader_Query_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetBlocksStream",
+			Handler:       _Downloader_GetBlocksStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetBlockRange",
+			Handler:       _Downloader_GetBlockRange_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeNewBlocks",
+			Handler:       _Downloader_SubscribeNewBlocks_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "downloader.proto",
 }
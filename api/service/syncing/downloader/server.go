@@ -11,7 +11,9 @@ import (
 
 // Constants for downloader server.
 const (
-	DefaultDownloadPort = "6666"
+	DefaultDownloadPort   = "6666"
+	MaxStreamMessageSize  = 1 << 20  // payload size of a streamed message, in bytes
+	MaxStreamResponseSize = 64 << 20 // payload size of a streamed response, in bytes
 )
 
 // Server is the Server struct for downloader package.
@@ -29,6 +31,81 @@ func (s *Server) Query(ctx context.Context, request *pb.DownloaderRequest) (*pb.
 	return response, nil
 }
 
+// GetBlocksStream streams the payload of the request over as many messages as
+// needed. The stream is cut at MaxStreamResponseSize, leaving out the items
+// following, as if the server didn't have them.
+func (s *Server) GetBlocksStream(request *pb.DownloaderRequest, stream pb.Downloader_GetBlocksStreamServer) error {
+	sender := &payloadSender{send: stream.Send}
+	return sender.finish(s.downloadInterface.StreamResponse(request, sender.add))
+}
+
+// GetBlockRange streams the blocks of the requested range over as many
+// messages as needed. The stream is cut at MaxStreamResponseSize.
+func (s *Server) GetBlockRange(request *pb.BlockRangeRequest, stream pb.Downloader_GetBlockRangeServer) error {
+	sender := &payloadSender{send: stream.Send}
+	return sender.finish(s.downloadInterface.StreamBlockRange(request.From, request.To, sender.add))
+}
+
+// SubscribeNewBlocks streams the blocks from the requested height on, one
+// block per message, until the client cancels. A client not reading the
+// stream makes sending block, the server holding off through the flow
+// control of the stream instead of queueing blocks.
+func (s *Server) SubscribeNewBlocks(request *pb.SubscribeRequest, stream pb.Downloader_SubscribeNewBlocksServer) error {
+	return s.downloadInterface.SubscribeNewBlocks(stream.Context(), request.FromHeight, func(payload []byte) error {
+		return stream.Send(&pb.DownloaderResponse{Payload: [][]byte{payload}})
+	})
+}
+
+// payloadSender packs the items of a streamed response into messages of
+// about MaxStreamMessageSize. A message being sent only once the previous one
+// is taken by the flow control of the stream, no more than a message is held
+// in memory at a time however slow the client.
+type payloadSender struct {
+	send        func(*pb.DownloaderResponse) error
+	message     *pb.DownloaderResponse
+	messageSize int
+	totalSize   int
+}
+
+// add adds the item to the message being packed, sending the message first
+// if the item doesn't fit in.
+func (sender *payloadSender) add(payload []byte) error {
+	if sender.totalSize+len(payload) > MaxStreamResponseSize {
+		return ErrResponseTooLarge
+	}
+	if sender.message != nil && sender.messageSize+len(payload) > MaxStreamMessageSize {
+		if err := sender.flush(); err != nil {
+			return err
+		}
+	}
+	if sender.message == nil {
+		sender.message = &pb.DownloaderResponse{}
+	}
+	sender.message.Payload = append(sender.message.Payload, payload)
+	sender.messageSize += len(payload)
+	sender.totalSize += len(payload)
+	return nil
+}
+
+// flush sends the message being packed.
+func (sender *payloadSender) flush() error {
+	if sender.message == nil {
+		return nil
+	}
+	message := sender.message
+	sender.message = nil
+	sender.messageSize = 0
+	return sender.send(message)
+}
+
+// finish sends the last message given the error which ended the response.
+func (sender *payloadSender) finish(err error) error {
+	if err != nil && err != ErrResponseTooLarge {
+		return err
+	}
+	return sender.flush()
+}
+
 // Start starts the Server on given ip and port.
 func (s *Server) Start(ip, port string) (*grpc.Server, error) {
 	addr := net.JoinHostPort("", port)
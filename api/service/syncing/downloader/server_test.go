@@ -0,0 +1,46 @@
+package downloader
+
+import (
+	"testing"
+
+	pb "github.com/harmony-one/harmony/api/service/syncing/downloader/proto"
+)
+
+func TestPayloadSender(t *testing.T) {
+	messages := []*pb.DownloaderResponse{}
+	sender := &payloadSender{send: func(message *pb.DownloaderResponse) error {
+		messages = append(messages, message)
+		return nil
+	}}
+	item := make([]byte, MaxStreamMessageSize/3+1)
+	numItems := 0
+	var err error
+	for err == nil {
+		if err = sender.add(item); err == nil {
+			numItems++
+		}
+	}
+	if err != ErrResponseTooLarge {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := sender.finish(err); err != nil {
+		t.Fatalf("cannot finish the response: %v", err)
+	}
+	if numItems != MaxStreamResponseSize/len(item) {
+		t.Errorf("wrong number of items %v sent before the cap", numItems)
+	}
+	received := 0
+	for _, message := range messages {
+		size := 0
+		for _, payload := range message.Payload {
+			size += len(payload)
+		}
+		if size > MaxStreamMessageSize {
+			t.Errorf("message of %v bytes", size)
+		}
+		received += len(message.Payload)
+	}
+	if received != numItems {
+		t.Errorf("%v items received, %v sent", received, numItems)
+	}
+}
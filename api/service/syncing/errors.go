@@ -4,7 +4,10 @@ import "errors"
 
 // Errors ...
 var (
-	ErrRegistrationFail = errors.New("[SYNC]: registration failed")
-	ErrGetBlock         = errors.New("[SYNC]: get block failed")
-	ErrGetBlockHash     = errors.New("[SYNC]: get blockhash failed")
+	ErrGetBlock       = errors.New("[SYNC]: get block failed")
+	ErrGetBlockHash   = errors.New("[SYNC]: get blockhash failed")
+	ErrGetBlockHeader = errors.New("[SYNC]: get block header failed")
+	ErrGetBlockBody   = errors.New("[SYNC]: get block body failed")
+	ErrGetTrieNode    = errors.New("[SYNC]: get state trie node failed")
+	ErrGetReceipt     = errors.New("[SYNC]: get block receipts failed")
 )
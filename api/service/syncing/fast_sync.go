@@ -0,0 +1,290 @@
+package syncing
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/ctxerror"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/node/worker"
+)
+
+// Fast sync
+//
+// A node starting from genesis, new or resharded, doesn't need to execute
+// the whole chain to catch up. With fast sync it downloads the headers,
+// bodies and receipts of the blocks up to a pivot block FastSyncPivotDistance
+// blocks behind the head of the peers, checking the bodies and receipts
+// against the headers, then the state trie of the pivot block node by node,
+// each node being checked against its hash from the state root of the pivot
+// header down. The pivot block becomes the current block, and the blocks
+// following it are downloaded and executed as usual.
+
+// Constants for fast sync.
+const (
+	FastSyncPivotDistance = 64   // number of blocks between the pivot block and the head of the peers
+	ReceiptBatchSize      = 128  // maximum number of block receipts requested at once from a peer
+	TrieNodeBatchSize     = 384  // maximum number of state trie nodes requested at once from a peer
+	stateRoundSize        = 4096 // maximum number of state trie nodes downloaded per round
+	headerCheckFrequency  = 100  // one in headerCheckFrequency headers gets its seal checked on insertion
+)
+
+// SetFastSync sets whether the node syncs the state at a pivot block instead
+// of executing all the blocks when starting from genesis.
+func (ss *StateSync) SetFastSync(enabled bool) {
+	ss.fastSync = enabled
+}
+
+// GetReceipts gets the receipts of the blocks of the given hashes by calling
+// grpc request to the peer. The receipts the peer doesn't have, or didn't send,
+// are nil.
+func (peerConfig *SyncPeerConfig) GetReceipts(hashes [][]byte, timeout time.Duration) ([]types.Receipts, error) {
+	response := peerConfig.client.GetReceipts(hashes, timeout)
+	if response == nil || len(response.Payload) > len(hashes) {
+		return nil, ErrGetReceipt
+	}
+	receipts := make([]types.Receipts, len(hashes))
+	for i, payload := range response.Payload {
+		if len(payload) == 0 {
+			continue
+		}
+		blockReceipts := types.Receipts{}
+		if err := rlp.DecodeBytes(payload, &blockReceipts); err != nil {
+			return nil, ctxerror.New("[SYNC] cannot decode block receipts").WithCause(err)
+		}
+		receipts[i] = blockReceipts
+	}
+	return receipts, nil
+}
+
+// GetTrieNodes gets the state trie nodes of the given hashes by calling grpc
+// request to the peer. A node the peer doesn't have, or didn't send, is nil.
+func (peerConfig *SyncPeerConfig) GetTrieNodes(hashes [][]byte, timeout time.Duration) ([][]byte, error) {
+	response := peerConfig.client.GetTrieNodes(hashes, timeout)
+	if response == nil || len(response.Payload) > len(hashes) {
+		return nil, ErrGetTrieNode
+	}
+	nodes := make([][]byte, len(hashes))
+	for i, payload := range response.Payload {
+		if len(payload) > 0 {
+			nodes[i] = payload
+		}
+	}
+	return nodes, nil
+}
+
+// verifyReceipts checks that the receipts match the header.
+func verifyReceipts(header *types.Header, receipts types.Receipts) error {
+	if hash := types.DeriveSha(receipts); hash != header.ReceiptHash {
+		return ctxerror.New("[SYNC] receipts don't match the header",
+			"blockNum", header.Number,
+			"receiptHash", hash,
+			"expectedReceiptHash", header.ReceiptHash)
+	}
+	return nil
+}
+
+// fastSyncPivot returns the number of the pivot block given the height of the
+// peers, and false if the chain is too short for fast sync to be of any use.
+func fastSyncPivot(maxPeerHeight uint64) (uint64, bool) {
+	if maxPeerHeight <= FastSyncPivotDistance {
+		return 0, false
+	}
+	return maxPeerHeight - FastSyncPivotDistance, true
+}
+
+// downloadReceipts downloads the receipts of the blocks of the given headers
+// from all the peers in parallel.
+func (ss *StateSync) downloadReceipts(headers []*types.Header) ([]types.Receipts, error) {
+	receipts := make([]types.Receipts, len(headers))
+	ss.downloadFromPeers(newTaskQueue(len(headers)), ReceiptBatchSize, func(peerConfig *SyncPeerConfig, indexes []int, timeout time.Duration) ([]int, error) {
+		hashes := make([][]byte, len(indexes))
+		for i, index := range indexes {
+			hash := headers[index].Hash()
+			hashes[i] = hash[:]
+		}
+		received, err := peerConfig.GetReceipts(hashes, timeout)
+		if err != nil {
+			return nil, err
+		}
+		for i, index := range indexes {
+			if received[i] == nil {
+				continue
+			}
+			if err := verifyReceipts(headers[index], received[i]); err != nil {
+				return nil, badPeerError{err}
+			}
+		}
+		missing := []int{}
+		for i, index := range indexes {
+			if received[i] == nil {
+				missing = append(missing, index)
+			} else {
+				receipts[index] = received[i]
+			}
+		}
+		return missing, nil
+	})
+	for i, blockReceipts := range receipts {
+		if blockReceipts == nil {
+			return nil, ctxerror.New("[SYNC] cannot download block receipts",
+				"blockNum", headers[i].Number,
+			).WithCause(ErrGetReceipt)
+		}
+	}
+	return receipts, nil
+}
+
+// downloadState downloads from all the peers in parallel the nodes of the
+// state trie of the given root missing in db, round after round.
+func (ss *StateSync) downloadState(db ethdb.Database, root common.Hash) error {
+	sched := state.NewStateSync(root, db)
+	retry := []common.Hash{}
+	numNodes := 0
+	for sched.Pending() > 0 {
+		hashes := append(retry, sched.Missing(stateRoundSize)...)
+		nodes := make([][]byte, len(hashes))
+		ss.downloadFromPeers(newTaskQueue(len(hashes)), TrieNodeBatchSize, func(peerConfig *SyncPeerConfig, indexes []int, timeout time.Duration) ([]int, error) {
+			requested := make([][]byte, len(indexes))
+			for i, index := range indexes {
+				requested[i] = hashes[index][:]
+			}
+			received, err := peerConfig.GetTrieNodes(requested, timeout)
+			if err != nil {
+				return nil, err
+			}
+			for i, index := range indexes {
+				if received[i] == nil {
+					continue
+				}
+				if hash := crypto.Keccak256Hash(received[i]); hash != hashes[index] {
+					return nil, badPeerError{ctxerror.New("[SYNC] state trie node doesn't match its hash",
+						"hash", hash,
+						"expectedHash", hashes[index])}
+				}
+			}
+			missing := []int{}
+			for i, index := range indexes {
+				if received[i] == nil {
+					missing = append(missing, index)
+				} else {
+					nodes[index] = received[i]
+				}
+			}
+			return missing, nil
+		})
+
+		results := []trie.SyncResult{}
+		retry = []common.Hash{}
+		for i, hash := range hashes {
+			if nodes[i] == nil {
+				retry = append(retry, hash)
+			} else {
+				results = append(results, trie.SyncResult{Hash: hash, Data: nodes[i]})
+			}
+		}
+		if len(results) == 0 {
+			return ctxerror.New("[SYNC] no state trie node downloaded",
+				"numMissing", len(hashes),
+			).WithCause(ErrGetTrieNode)
+		}
+		if _, index, err := sched.Process(results); err != nil {
+			return ctxerror.New("[SYNC] cannot process state trie node",
+				"hash", results[index].Hash,
+			).WithCause(err)
+		}
+		batch := db.NewBatch()
+		if _, err := sched.Commit(batch); err != nil {
+			return ctxerror.New("[SYNC] cannot commit state trie nodes").WithCause(err)
+		}
+		if err := batch.Write(); err != nil {
+			return ctxerror.New("[SYNC] cannot write state trie nodes").WithCause(err)
+		}
+		numNodes += len(results)
+		utils.GetLogInstance().Info("[SYNC] downloaded state trie nodes", "numNodes", numNodes, "pending", sched.Pending())
+	}
+	return nil
+}
+
+// fastSync downloads the blocks up to the pivot block with their receipts
+// but without executing them, then the state of the pivot block, which
+// becomes the current block.
+func (ss *StateSync) fastSync(bc *core.BlockChain, pivotNumber uint64) error {
+	parent := bc.CurrentBlock().Header()
+	for parent.Number.Uint64() < pivotNumber {
+		parentHash := parent.Hash()
+		headers, peers, ok := ss.getConsensusHeaders(parentHash[:])
+		if !ok {
+			return ErrGetBlockHeader
+		}
+		if len(headers) == 0 {
+			return ctxerror.New("[SYNC] peers have no block up to the pivot",
+				"blockNum", parent.Number,
+				"pivotNum", pivotNumber)
+		}
+		if n := pivotNumber - parent.Number.Uint64(); uint64(len(headers)) > n {
+			headers = headers[:n]
+		}
+		headers = untilEpochBlock(headers)
+		if err := verifyHeaderChain(bc, parent, headers); err != nil {
+			ss.penalizePeers(peers, PenaltyBadBlock, err)
+			return err
+		}
+		if _, err := bc.InsertHeaderChain(headers, headerCheckFrequency); err != nil {
+			ss.penalizePeers(peers, PenaltyBadBlock, err)
+			return ctxerror.New("[SYNC] cannot insert block headers").WithCause(err)
+		}
+		blocks := ss.downloadBodies(headers)
+		if len(blocks) < len(headers) {
+			return ctxerror.New("[SYNC] cannot download block bodies",
+				"blockNum", headers[len(blocks)].Number,
+			).WithCause(ErrGetBlockBody)
+		}
+		receipts, err := ss.downloadReceipts(headers)
+		if err != nil {
+			return err
+		}
+		if _, err := bc.InsertReceiptChain(blocks, receipts); err != nil {
+			return ctxerror.New("[SYNC] cannot insert block receipts").WithCause(err)
+		}
+		parent = headers[len(headers)-1]
+		utils.GetLogInstance().Info("[SYNC] fast sync downloaded blocks", "blockHeight", parent.Number, "pivotNum", pivotNumber)
+	}
+
+	utils.GetLogInstance().Info("[SYNC] fast sync downloading state", "pivotNum", pivotNumber, "stateRoot", parent.Root.Hex())
+	if err := ss.downloadState(bc.ChainDb(), parent.Root); err != nil {
+		return err
+	}
+	if err := bc.FastSyncCommitHead(parent.Hash()); err != nil {
+		return ctxerror.New("[SYNC] cannot set the pivot block as current block",
+			"pivotNum", pivotNumber,
+		).WithCause(err)
+	}
+	return nil
+}
+
+// fastSyncToPivot fast syncs the chain up to the pivot block given by the
+// height of the peers. On failure the blocks are executed from the current
+// block as without fast sync.
+func (ss *StateSync) fastSyncToPivot(bc *core.BlockChain, worker *worker.Worker) {
+	pivotNumber, ok := fastSyncPivot(ss.getMaxPeerHeight())
+	if !ok {
+		return
+	}
+	utils.GetLogInstance().Info("[SYNC] starting fast sync", "pivotNum", pivotNumber)
+	if err := ss.fastSync(bc, pivotNumber); err != nil {
+		ctxerror.Log15(utils.GetLogInstance().Warn, ctxerror.New("[SYNC] fast sync failed, executing the blocks instead").WithCause(err))
+		return
+	}
+	ss.syncMux.Lock()
+	worker.UpdateCurrent()
+	ss.syncMux.Unlock()
+	utils.GetLogInstance().Info("[SYNC] fast sync done", "blockHeight", bc.CurrentBlock().NumberU64(), "blockHex", bc.CurrentBlock().Hash().Hex())
+}
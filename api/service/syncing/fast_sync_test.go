@@ -0,0 +1,39 @@
+package syncing
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/harmony-one/harmony/core/types"
+)
+
+func TestVerifyReceipts(t *testing.T) {
+	receipts := types.Receipts{
+		types.NewReceipt(nil, false, 21000),
+		types.NewReceipt(nil, false, 42000),
+	}
+	header := &types.Header{Number: big.NewInt(1), ReceiptHash: types.DeriveSha(receipts)}
+	if err := verifyReceipts(header, receipts); err != nil {
+		t.Errorf("receipts matching the header not verified: %v", err)
+	}
+	if err := verifyReceipts(header, receipts[:1]); err == nil {
+		t.Error("verified receipts not matching the header")
+	}
+	empty := &types.Header{Number: big.NewInt(2), ReceiptHash: types.DeriveSha(types.Receipts{})}
+	if err := verifyReceipts(empty, types.Receipts{}); err != nil {
+		t.Errorf("empty receipts not verified: %v", err)
+	}
+	if err := verifyReceipts(&types.Header{Number: big.NewInt(3), ReceiptHash: common.Hash{1}}, types.Receipts{}); err == nil {
+		t.Error("verified empty receipts against a non-empty receipt hash")
+	}
+}
+
+func TestFastSyncPivot(t *testing.T) {
+	if _, ok := fastSyncPivot(FastSyncPivotDistance); ok {
+		t.Error("fast sync on a chain no longer than the pivot distance")
+	}
+	if pivot, ok := fastSyncPivot(1000); !ok || pivot != 1000-FastSyncPivotDistance {
+		t.Errorf("wrong pivot %v for height 1000", pivot)
+	}
+}
@@ -0,0 +1,508 @@
+package syncing
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/ctxerror"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/node/worker"
+)
+
+// Header-first syncing
+//
+// The chain is downloaded by batches of headers, which the peers vote on as
+// they did on block hashes, and which are verified to link to the current
+// block and to be signed by the committee before any body is requested.
+// The bodies of a batch are then downloaded in parallel from all the peers,
+// each peer being asked for as many bodies as its measured throughput allows
+// to fetch in about targetRequestTime. A peer timing out or failing gets
+// its bodies handed over to the other peers, and is left out after
+// MaxPeerFailures; a peer serving bodies not matching the headers is left out
+// at once. The header batch following the one whose bodies are downloaded is
+// fetched meanwhile. All along, the peers are scored as described in
+// reputation.go.
+
+// Constants for header-first syncing.
+const (
+	HeaderBatchSize     = 512 // number of headers requested at once
+	MaxBodyBatchSize    = 128 // maximum number of bodies requested at once from a peer
+	MinBatchSize        = 4   // minimum number of items requested at once from a peer
+	MaxPeerFailures     = 3   // number of failed requests after which a peer is left out
+	targetRequestTime   = 2 * time.Second
+	minRequestTimeout   = 2 * time.Second
+	maxRequestTimeout   = 10 * time.Second
+	pollInterval        = 100 * time.Millisecond
+	throughputSmoothing = 0.3 // weight of the last measure in the throughput moving average
+)
+
+// batchSize returns the number of items to request at once from the peer,
+// up to max.
+func (peerConfig *SyncPeerConfig) batchSize(max int) int {
+	peerConfig.mux.Lock()
+	defer peerConfig.mux.Unlock()
+	size := int(peerConfig.throughput * targetRequestTime.Seconds())
+	if size < MinBatchSize {
+		return MinBatchSize
+	}
+	if size > max {
+		return max
+	}
+	return size
+}
+
+// requestTimeout returns how long the peer is given to serve the given
+// number of items, according to its throughput.
+func (peerConfig *SyncPeerConfig) requestTimeout(numItems int) time.Duration {
+	peerConfig.mux.Lock()
+	defer peerConfig.mux.Unlock()
+	if peerConfig.throughput == 0 {
+		return maxRequestTimeout
+	}
+	timeout := time.Duration(3 * float64(numItems) / peerConfig.throughput * float64(time.Second))
+	if timeout < minRequestTimeout {
+		return minRequestTimeout
+	}
+	if timeout > maxRequestTimeout {
+		return maxRequestTimeout
+	}
+	return timeout
+}
+
+// updateThroughput accounts the given number of items served in elapsed
+// time in the throughput of the peer.
+func (peerConfig *SyncPeerConfig) updateThroughput(numBodies int, elapsed time.Duration) {
+	if elapsed <= 0 {
+		elapsed = time.Millisecond
+	}
+	measured := float64(numBodies) / elapsed.Seconds()
+	peerConfig.mux.Lock()
+	defer peerConfig.mux.Unlock()
+	if peerConfig.throughput == 0 {
+		peerConfig.throughput = measured
+	} else {
+		peerConfig.throughput = (1-throughputSmoothing)*peerConfig.throughput + throughputSmoothing*measured
+	}
+}
+
+// fail counts a failed request of the peer and returns whether it is to be
+// left out.
+func (peerConfig *SyncPeerConfig) fail() bool {
+	peerConfig.mux.Lock()
+	defer peerConfig.mux.Unlock()
+	peerConfig.failures++
+	return peerConfig.failures > MaxPeerFailures
+}
+
+// GetBlockHeaders gets the headers following startHash by calling grpc request to the peer.
+func (peerConfig *SyncPeerConfig) GetBlockHeaders(startHash []byte, size uint32) ([]*types.Header, error) {
+	response := peerConfig.client.GetBlockHeaders(startHash, size)
+	if response == nil {
+		return nil, ErrGetBlockHeader
+	}
+	headers := []*types.Header{}
+	for _, payload := range response.Payload {
+		header := &types.Header{}
+		if err := rlp.DecodeBytes(payload, header); err != nil {
+			return nil, ctxerror.New("[SYNC] cannot decode block header").WithCause(err)
+		}
+		headers = append(headers, header)
+	}
+	return headers, nil
+}
+
+// GetBlockBodies gets the bodies of the blocks of the given hashes by calling
+// grpc request to the peer. A body the peer doesn't have, or didn't send, is nil.
+func (peerConfig *SyncPeerConfig) GetBlockBodies(hashes [][]byte, timeout time.Duration) ([]*types.Body, error) {
+	response := peerConfig.client.GetBlockBodies(hashes, timeout)
+	if response == nil || len(response.Payload) > len(hashes) {
+		return nil, ErrGetBlockBody
+	}
+	bodies := make([]*types.Body, len(hashes))
+	for i, payload := range response.Payload {
+		if len(payload) == 0 {
+			continue
+		}
+		body := &types.Body{}
+		if err := rlp.DecodeBytes(payload, body); err != nil {
+			return nil, ctxerror.New("[SYNC] cannot decode block body").WithCause(err)
+		}
+		bodies[i] = body
+	}
+	return bodies, nil
+}
+
+// getConsensusHeaders gets the batch of headers following parentHash the
+// peers agree on, leaving out the peers which don't, and returns the peers
+// which served it.
+func (ss *StateSync) getConsensusHeaders(parentHash []byte) ([]*types.Header, []*SyncPeerConfig, bool) {
+	for count := 0; ; count++ {
+		var wg sync.WaitGroup
+		ss.syncConfig.ForEachPeer(func(peerConfig *SyncPeerConfig) (brk bool) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				headers, err := peerConfig.GetBlockHeaders(parentHash, HeaderBatchSize)
+				if err != nil {
+					ss.penalizePeer(peerConfig, PenaltyTimeout, err.Error())
+					headers = nil
+				}
+				hashes := [][]byte{}
+				for _, header := range headers {
+					hash := header.Hash()
+					hashes = append(hashes, hash[:])
+				}
+				peerConfig.mux.Lock()
+				peerConfig.headers = headers
+				peerConfig.blockHashes = hashes
+				peerConfig.mux.Unlock()
+			}()
+			return
+		})
+		wg.Wait()
+		if ss.syncConfig.GetBlockHashesConsensusAndCleanUp() {
+			break
+		}
+		if count >= TimesToFail {
+			utils.GetLogInstance().Info("[SYNC] getConsensusHeaders: reached retry limit")
+			return nil, nil, false
+		}
+		time.Sleep(SleepTimeAfterNonConsensusBlockHashes)
+	}
+	var headers []*types.Header
+	var peers []*SyncPeerConfig
+	ss.syncConfig.ForEachPeer(func(peerConfig *SyncPeerConfig) (brk bool) {
+		peerConfig.mux.Lock()
+		headers = peerConfig.headers
+		peerConfig.mux.Unlock()
+		peers = append(peers, peerConfig)
+		return
+	})
+	return headers, peers, true
+}
+
+// verifyHeaderChain checks that the headers link one to the other from the
+// given parent, and that the chain is signed by the committee.
+// As the headers are linked by their hashes, the seal of the last header
+// whose committee is known vouches for all of them; the seals are verified
+// again one by one when the blocks are inserted. A chain none of whose seals
+// can be checked is rejected.
+func verifyHeaderChain(bc *core.BlockChain, parent *types.Header, headers []*types.Header) error {
+	for _, header := range headers {
+		if header.Number.Uint64() != parent.Number.Uint64()+1 || header.ParentHash != parent.Hash() {
+			return ctxerror.New("[SYNC] header doesn't link to its parent",
+				"blockNum", header.Number,
+				"parentHash", header.ParentHash,
+				"expectedParentHash", parent.Hash())
+		}
+		parent = header
+	}
+	for i := len(headers) - 1; i >= 0; i-- {
+		epoch := core.GetSignerEpochFromBlockNumber(headers[i].Number.Uint64())
+		if _, err := bc.ReadShardState(epoch); err != nil {
+			continue
+		}
+		if err := bc.Engine().VerifySeal(bc, headers[i]); err != nil {
+			return ctxerror.New("[SYNC] invalid header seal",
+				"blockNum", headers[i].Number,
+			).WithCause(err)
+		}
+		return nil
+	}
+	return ctxerror.New("[SYNC] no committee known to verify the header seals",
+		"firstBlockNum", headers[0].Number,
+		"numHeaders", len(headers))
+}
+
+// verifyBody checks that the body matches the header.
+func verifyBody(header *types.Header, body *types.Body) error {
+	if err := core.ValidateBodyHashes(header, body); err != nil {
+		return ctxerror.New("[SYNC] body doesn't match the header",
+			"blockNum", header.Number,
+		).WithCause(err)
+	}
+	return nil
+}
+
+// taskQueue hands out to the peers the indexes of the items to download.
+type taskQueue struct {
+	mutex    sync.Mutex
+	pending  []int // indexes of the items to be requested
+	inFlight int   // number of items requested but not delivered yet
+}
+
+func newTaskQueue(numItems int) *taskQueue {
+	queue := &taskQueue{pending: make([]int, numItems)}
+	for i := range queue.pending {
+		queue.pending[i] = i
+	}
+	return queue
+}
+
+// take returns the indexes of up to n items to be requested.
+func (queue *taskQueue) take(n int) []int {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	if n > len(queue.pending) {
+		n = len(queue.pending)
+	}
+	indexes := queue.pending[:n:n]
+	queue.pending = queue.pending[n:]
+	queue.inFlight += n
+	return indexes
+}
+
+// giveBack puts the indexes of items which weren't delivered back for the
+// other peers to take.
+func (queue *taskQueue) giveBack(indexes []int) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	queue.pending = append(queue.pending, indexes...)
+	queue.inFlight -= len(indexes)
+}
+
+// delivered accounts the given number of items as delivered.
+func (queue *taskQueue) delivered(n int) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	queue.inFlight -= n
+}
+
+// done returns whether no item is left to request nor being requested.
+func (queue *taskQueue) done() bool {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	return len(queue.pending) == 0 && queue.inFlight == 0
+}
+
+// badPeerError is returned when a peer serves invalid data, which leaves the
+// peer out at once.
+type badPeerError struct {
+	error
+}
+
+// fetchFunc requests from the peer the items of the given indexes, and
+// delivers them. It returns the indexes of the items the peer didn't serve.
+// An error means that none was delivered.
+type fetchFunc func(peerConfig *SyncPeerConfig, indexes []int, timeout time.Duration) (missing []int, err error)
+
+// downloadFromPeers has all the peers download the items of the queue in
+// parallel with fetch, until all are downloaded or all the peers are left out.
+func (ss *StateSync) downloadFromPeers(queue *taskQueue, maxBatchSize int, fetch fetchFunc) {
+	var wg sync.WaitGroup
+	ss.syncConfig.ForEachPeer(func(peerConfig *SyncPeerConfig) (brk bool) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ss.downloadFromPeer(peerConfig, queue, maxBatchSize, fetch)
+		}()
+		return
+	})
+	wg.Wait()
+}
+
+// downloadFromPeer keeps requesting items from the peer until all are
+// downloaded, or the peer is left out or banned.
+func (ss *StateSync) downloadFromPeer(peerConfig *SyncPeerConfig, queue *taskQueue, maxBatchSize int, fetch fetchFunc) {
+	for !queue.done() {
+		if ss.isPeerBanned(peerConfig) {
+			return
+		}
+		indexes := queue.take(peerConfig.batchSize(maxBatchSize))
+		if len(indexes) == 0 {
+			// wait for items given back by the other peers
+			time.Sleep(pollInterval)
+			continue
+		}
+		start := time.Now()
+		missing, err := fetch(peerConfig, indexes, peerConfig.requestTimeout(len(indexes)))
+		elapsed := time.Since(start)
+		if err != nil {
+			queue.giveBack(indexes)
+			if err, ok := err.(badPeerError); ok {
+				ctxerror.Log15(utils.GetLogInstance().Warn, ctxerror.New("[SYNC] leaving out peer serving invalid data",
+					"ip", peerConfig.ip,
+					"port", peerConfig.port,
+				).WithCause(err.error))
+				ss.penalizePeer(peerConfig, PenaltyBadBlock, err.Error())
+				return
+			}
+			utils.GetLogInstance().Debug("[SYNC] request to peer failed", "ip", peerConfig.ip, "port", peerConfig.port, "error", err)
+			if ss.penalizePeer(peerConfig, PenaltyTimeout, err.Error()) || peerConfig.fail() {
+				utils.GetLogInstance().Info("[SYNC] leaving out failing peer", "ip", peerConfig.ip, "port", peerConfig.port)
+				return
+			}
+			continue
+		}
+		queue.delivered(len(indexes) - len(missing))
+		peerConfig.updateThroughput(len(indexes)-len(missing), elapsed)
+		if len(missing) > 0 {
+			queue.giveBack(missing)
+			if ss.penalizePeer(peerConfig, PenaltyMissingData, "missing data") || peerConfig.fail() {
+				utils.GetLogInstance().Info("[SYNC] leaving out peer missing data", "ip", peerConfig.ip, "port", peerConfig.port)
+				return
+			}
+		} else if elapsed > slowResponseTime {
+			ss.penalizePeer(peerConfig, PenaltySlowResponse, "slow response")
+		} else {
+			ss.rewardPeer(peerConfig)
+		}
+	}
+}
+
+// downloadBodies downloads the bodies of the given headers from all the
+// peers in parallel, and returns the blocks of the longest run of headers
+// whose bodies could be downloaded.
+func (ss *StateSync) downloadBodies(headers []*types.Header) []*types.Block {
+	bodies := make([]*types.Body, len(headers))
+	ss.downloadFromPeers(newTaskQueue(len(headers)), MaxBodyBatchSize, func(peerConfig *SyncPeerConfig, indexes []int, timeout time.Duration) ([]int, error) {
+		hashes := make([][]byte, len(indexes))
+		for i, index := range indexes {
+			hash := headers[index].Hash()
+			hashes[i] = hash[:]
+		}
+		received, err := peerConfig.GetBlockBodies(hashes, timeout)
+		if err != nil {
+			return nil, err
+		}
+		for i, index := range indexes {
+			if received[i] == nil {
+				continue
+			}
+			if err := verifyBody(headers[index], received[i]); err != nil {
+				return nil, badPeerError{err}
+			}
+		}
+		missing := []int{}
+		for i, index := range indexes {
+			if received[i] == nil {
+				missing = append(missing, index)
+			} else {
+				bodies[index] = received[i]
+			}
+		}
+		return missing, nil
+	})
+	blocks := assembleBlocks(headers, bodies)
+	utils.GetLogInstance().Info("[SYNC] Finished downloading block bodies", "numHeaders", len(headers), "numBlocks", len(blocks))
+	return blocks
+}
+
+// assembleBlocks returns the blocks of the longest run of headers, from the
+// first, whose bodies are given.
+func assembleBlocks(headers []*types.Header, bodies []*types.Body) []*types.Block {
+	blocks := []*types.Block{}
+	for i, header := range headers {
+		body := bodies[i]
+		if body == nil {
+			break
+		}
+		block := types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles)
+		if len(body.DoubleSigns) > 0 {
+			block = block.WithDoubleSigns(body.DoubleSigns)
+		}
+		if len(body.IncomingReceipts) > 0 {
+			block = block.WithIncomingReceipts(body.IncomingReceipts)
+		}
+		if len(body.CrossLinks) > 0 {
+			block = block.WithCrossLinks(body.CrossLinks)
+		}
+		if len(body.ShardState) > 0 {
+			block = block.WithShardState(body.ShardState)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// headerBatch is a batch of headers and the peers which served it, or the
+// error which stopped fetching headers.
+type headerBatch struct {
+	headers []*types.Header
+	peers   []*SyncPeerConfig
+	err     error
+}
+
+// untilEpochBlock truncates the headers after the first epoch block, as the
+// seals of the following headers can only be verified with the shard state
+// of the inserted epoch block.
+func untilEpochBlock(headers []*types.Header) []*types.Header {
+	for i, header := range headers {
+		if header.Number.Uint64()%core.BlocksPerEpoch == 0 {
+			return headers[:i+1]
+		}
+	}
+	return headers
+}
+
+// fetchHeaders fetches the batches of headers following parent one after the
+// other, until a batch isn't full or stop is closed. A batch doesn't go past
+// an epoch block.
+func (ss *StateSync) fetchHeaders(parent *types.Header, batches chan<- headerBatch, stop <-chan struct{}) {
+	defer close(batches)
+	for {
+		parentHash := parent.Hash()
+		batch := headerBatch{}
+		headers, peers, ok := ss.getConsensusHeaders(parentHash[:])
+		if !ok {
+			batch.err = ErrGetBlockHeader
+		} else if len(headers) == 0 {
+			return
+		} else {
+			batch.headers, batch.peers = untilEpochBlock(headers), peers
+		}
+		select {
+		case batches <- batch:
+		case <-stop:
+			return
+		}
+		if batch.err != nil || (len(headers) < HeaderBatchSize && len(batch.headers) == len(headers)) {
+			return
+		}
+		parent = batch.headers[len(batch.headers)-1]
+	}
+}
+
+// downloadChain downloads and inserts the blocks following the current block,
+// header first. The headers are verified once the blocks before them are
+// inserted, so that the committee signing them is known.
+func (ss *StateSync) downloadChain(bc *core.BlockChain, worker *worker.Worker) {
+	batches := make(chan headerBatch, 1)
+	stop := make(chan struct{})
+	defer close(stop)
+	parent := bc.CurrentBlock().Header()
+	go ss.fetchHeaders(parent, batches, stop)
+
+	for batch := range batches {
+		if batch.err != nil {
+			ctxerror.Log15(utils.GetLogInstance().Info, ctxerror.New("[SYNC] cannot get block headers").WithCause(batch.err))
+			return
+		}
+		if err := verifyHeaderChain(bc, parent, batch.headers); err != nil {
+			ctxerror.Log15(utils.GetLogInstance().Warn, ctxerror.New("[SYNC] cannot verify block headers").WithCause(err))
+			ss.penalizePeers(batch.peers, PenaltyBadBlock, err)
+			return
+		}
+		blocks := ss.downloadBodies(batch.headers)
+		if len(blocks) > 0 {
+			if _, err := bc.InsertChain(blocks); err != nil {
+				ctxerror.Log15(utils.GetLogInstance().Warn, ctxerror.New("[SYNC] cannot insert downloaded blocks").WithCause(err))
+				// the bodies match the headers, so the peers serving the
+				// headers served the invalid blocks
+				ss.penalizePeers(batch.peers, PenaltyBadBlock, err)
+				return
+			}
+			ss.syncMux.Lock()
+			worker.UpdateCurrent()
+			ss.syncMux.Unlock()
+			utils.GetLogInstance().Info("[SYNC] downloaded blocks added to blockchain", "blockHeight", bc.CurrentBlock().NumberU64(), "blockHex", bc.CurrentBlock().Hash().Hex())
+		}
+		if len(blocks) < len(batch.headers) {
+			return
+		}
+		parent = batch.headers[len(batch.headers)-1]
+	}
+}
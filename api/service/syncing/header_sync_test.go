@@ -0,0 +1,118 @@
+package syncing
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/types"
+)
+
+func makeHeaders(parent *types.Header, n int) []*types.Header {
+	headers := []*types.Header{}
+	for i := 0; i < n; i++ {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, big.NewInt(1)),
+			TxHash:     types.DeriveSha(types.Transactions{}),
+		}
+		headers = append(headers, header)
+		parent = header
+	}
+	return headers
+}
+
+func TestVerifyHeaderChainLinks(t *testing.T) {
+	parent := &types.Header{Number: big.NewInt(10)}
+	headers := makeHeaders(parent, 3)
+	headers[1].Extra = []byte("forged")
+	if err := verifyHeaderChain(nil, parent, headers); err == nil {
+		t.Error("verified a header chain with a broken link")
+	}
+	headers = makeHeaders(parent, 3)
+	if err := verifyHeaderChain(nil, &types.Header{Number: big.NewInt(11)}, headers); err == nil {
+		t.Error("verified a header chain not following the parent")
+	}
+}
+
+func TestUntilEpochBlock(t *testing.T) {
+	parent := &types.Header{Number: new(big.Int).SetUint64(core.BlocksPerEpoch - 3)}
+	headers := makeHeaders(parent, 6)
+	if truncated := untilEpochBlock(headers); len(truncated) != 3 || truncated[2].Number.Uint64() != core.BlocksPerEpoch {
+		t.Errorf("headers not truncated after the epoch block: %v", truncated)
+	}
+	if truncated := untilEpochBlock(headers[3:]); len(truncated) != 3 {
+		t.Errorf("headers without epoch block truncated to %d", len(truncated))
+	}
+}
+
+func TestTaskQueue(t *testing.T) {
+	queue := newTaskQueue(5)
+	first := queue.take(2)
+	second := queue.take(10)
+	if len(first) != 2 || len(second) != 3 || len(queue.take(1)) != 0 {
+		t.Fatalf("wrong tasks taken: %v, %v", first, second)
+	}
+	queue.delivered(len(first))
+	// the second peer fails, its items go to the first one
+	queue.giveBack(second)
+	if queue.done() {
+		t.Fatal("done with items left to download")
+	}
+	if retaken := queue.take(MaxBodyBatchSize); len(retaken) != 3 {
+		t.Fatalf("retook %v items, expected 3", len(retaken))
+	}
+	queue.delivered(3)
+	if !queue.done() {
+		t.Fatal("not done with all items delivered")
+	}
+}
+
+func TestAssembleBlocks(t *testing.T) {
+	headers := makeHeaders(&types.Header{Number: big.NewInt(0)}, 3)
+	bodies := []*types.Body{{}, {}, nil}
+	blocks := assembleBlocks(headers, bodies)
+	if len(blocks) != 2 {
+		t.Fatalf("got %v blocks, expected the 2 first ones", len(blocks))
+	}
+	for i, block := range blocks {
+		if block.Hash() != headers[i].Hash() {
+			t.Errorf("block %v doesn't match its header", i)
+		}
+	}
+}
+
+func TestVerifyBody(t *testing.T) {
+	header := makeHeaders(&types.Header{Number: big.NewInt(0)}, 1)[0]
+	if err := verifyBody(header, &types.Body{}); err != nil {
+		t.Errorf("cannot verify matching body: %v", err)
+	}
+	header.TxHash[0]++
+	if err := verifyBody(header, &types.Body{}); err == nil {
+		t.Error("verified body not matching the header")
+	}
+}
+
+func TestPeerThroughput(t *testing.T) {
+	peerConfig := &SyncPeerConfig{}
+	if peerConfig.batchSize(MaxBodyBatchSize) != MinBatchSize || peerConfig.requestTimeout(MinBatchSize) != maxRequestTimeout {
+		t.Error("unmeasured peer not given the smallest batches and the longest timeout")
+	}
+	peerConfig.updateThroughput(100, time.Second)
+	if size := peerConfig.batchSize(MaxBodyBatchSize); size != MaxBodyBatchSize {
+		t.Errorf("fast peer given batches of %v bodies, expected %v", size, MaxBodyBatchSize)
+	}
+	peerConfig.updateThroughput(0, time.Second)
+	if peerConfig.throughput != 70 {
+		t.Errorf("throughput %v, expected 70", peerConfig.throughput)
+	}
+	for i := 0; i < MaxPeerFailures; i++ {
+		if peerConfig.fail() {
+			t.Fatal("peer left out too early")
+		}
+	}
+	if !peerConfig.fail() {
+		t.Error("failing peer not left out")
+	}
+}
@@ -0,0 +1,223 @@
+package syncing
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/harmony-one/harmony/internal/utils"
+)
+
+// Peer reputation
+//
+// Every syncing peer has a score, raised a little by every request it serves
+// well and lowered by its misbehaviors: serving invalid blocks or data, failing
+// or timing out requests, responding slowly, or claiming a height out of line
+// with the other peers. A peer whose score falls to BanScore is banned: it is
+// dropped from the syncing peers and not connected to again until the ban
+// expires. Every ban of the same peer lasts twice as long as the previous one.
+// The scores are kept across sync configs, so that a misbehaving peer found
+// again among the neighbors stays banned.
+
+// Constants for peer reputation.
+const (
+	PenaltyBadBlock     = 50 // block failing validation, or data not matching the headers
+	PenaltyWrongHeight  = 25 // height out of line with the other peers
+	PenaltyTimeout      = 10 // request failed or timed out
+	PenaltyMissingData  = 5  // request served partially
+	PenaltySlowResponse = 2  // request served in more than slowResponseTime
+	RewardGoodResponse  = 1  // request served well
+	MaxPeerScore        = 50 // a peer can't build up more credit than this
+	BanScore            = -100
+	BanDuration         = 10 * time.Minute // duration of the first ban of a peer
+	MaxBanDuration      = 24 * time.Hour
+	MaxHeightLead       = 1024 // number of blocks a peer may be ahead of most peers
+	slowResponseTime    = 2 * targetRequestTime
+)
+
+// peerScore is the reputation of a peer.
+type peerScore struct {
+	score       int
+	bans        int // number of times the peer was banned
+	bannedUntil time.Time
+	lastReason  string // reason of the last penalty
+}
+
+// PeerScore is the reputation of a peer as reported.
+type PeerScore struct {
+	IP          string
+	Port        string
+	Score       int
+	Bans        int
+	BannedUntil time.Time // zero if the peer isn't banned
+	LastReason  string
+}
+
+// PeerReputation keeps the scores of the syncing peers, and bans the peers
+// misbehaving repeatedly.
+type PeerReputation struct {
+	mutex sync.Mutex
+	peers map[string]*peerScore // by address of the peer
+	now   func() time.Time
+}
+
+// NewPeerReputation returns an empty peer reputation table.
+func NewPeerReputation() *PeerReputation {
+	return &PeerReputation{peers: make(map[string]*peerScore), now: time.Now}
+}
+
+func peerAddress(ip, port string) string {
+	return net.JoinHostPort(ip, port)
+}
+
+// get returns the score of the peer, lifting its ban if expired.
+// Caller shall hold the mutex.
+func (reputation *PeerReputation) get(ip, port string) *peerScore {
+	address := peerAddress(ip, port)
+	score, ok := reputation.peers[address]
+	if !ok {
+		score = &peerScore{}
+		reputation.peers[address] = score
+	}
+	if !score.bannedUntil.IsZero() && !reputation.now().Before(score.bannedUntil) {
+		score.bannedUntil = time.Time{}
+		score.score = 0
+	}
+	return score
+}
+
+// IsBanned returns whether the peer is banned.
+func (reputation *PeerReputation) IsBanned(ip, port string) bool {
+	reputation.mutex.Lock()
+	defer reputation.mutex.Unlock()
+	return !reputation.get(ip, port).bannedUntil.IsZero()
+}
+
+// reward raises the score of the peer for a request served well.
+func (reputation *PeerReputation) reward(ip, port string) {
+	reputation.mutex.Lock()
+	defer reputation.mutex.Unlock()
+	score := reputation.get(ip, port)
+	if score.bannedUntil.IsZero() && score.score < MaxPeerScore {
+		score.score += RewardGoodResponse
+	}
+}
+
+// penalize lowers the score of the peer for the given reason, banning the peer
+// if its score falls to BanScore. It returns whether the peer is banned.
+func (reputation *PeerReputation) penalize(ip, port string, penalty int, reason string) bool {
+	reputation.mutex.Lock()
+	defer reputation.mutex.Unlock()
+	score := reputation.get(ip, port)
+	if !score.bannedUntil.IsZero() {
+		return true
+	}
+	score.score -= penalty
+	score.lastReason = reason
+	if score.score > BanScore {
+		return false
+	}
+	duration := BanDuration << uint(score.bans)
+	if duration > MaxBanDuration || duration <= 0 {
+		duration = MaxBanDuration
+	}
+	score.bans++
+	score.bannedUntil = reputation.now().Add(duration)
+	utils.GetLogInstance().Warn("[SYNC] banning peer", "ip", ip, "port", port, "reason", reason, "duration", duration, "bans", score.bans)
+	return true
+}
+
+// Scores returns the reputation of all the peers known, by address.
+func (reputation *PeerReputation) Scores() []PeerScore {
+	reputation.mutex.Lock()
+	defer reputation.mutex.Unlock()
+	addresses := []string{}
+	for address := range reputation.peers {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	scores := []PeerScore{}
+	for _, address := range addresses {
+		ip, port, _ := net.SplitHostPort(address)
+		score := reputation.get(ip, port)
+		scores = append(scores, PeerScore{
+			IP:          ip,
+			Port:        port,
+			Score:       score.score,
+			Bans:        score.bans,
+			BannedUntil: score.bannedUntil,
+			LastReason:  score.lastReason,
+		})
+	}
+	return scores
+}
+
+// rewardPeer raises the score of the peer for a request served well.
+func (ss *StateSync) rewardPeer(peerConfig *SyncPeerConfig) {
+	ss.reputation.reward(peerConfig.ip, peerConfig.port)
+}
+
+// penalizePeer lowers the score of the peer for the given reason, and returns
+// whether the peer is banned.
+func (ss *StateSync) penalizePeer(peerConfig *SyncPeerConfig, penalty int, reason string) bool {
+	return ss.reputation.penalize(peerConfig.ip, peerConfig.port, penalty, reason)
+}
+
+// penalizePeers penalizes each of the given peers for the same error.
+func (ss *StateSync) penalizePeers(peers []*SyncPeerConfig, penalty int, err error) {
+	for _, peerConfig := range peers {
+		ss.penalizePeer(peerConfig, penalty, err.Error())
+	}
+}
+
+// isPeerBanned returns whether the peer is banned.
+func (ss *StateSync) isPeerBanned(peerConfig *SyncPeerConfig) bool {
+	return ss.reputation.IsBanned(peerConfig.ip, peerConfig.port)
+}
+
+// removeBannedPeers drops the banned peers from the syncing peers.
+func (ss *StateSync) removeBannedPeers() {
+	sc := ss.syncConfig
+	if sc == nil {
+		return
+	}
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+	peers := sc.peers[:0]
+	for _, peerConfig := range sc.peers {
+		if ss.isPeerBanned(peerConfig) {
+			utils.GetLogInstance().Info("[SYNC] dropping banned peer", "ip", peerConfig.ip, "port", peerConfig.port)
+			peerConfig.unsubscribe()
+			peerConfig.client.Close()
+			continue
+		}
+		peers = append(peers, peerConfig)
+	}
+	for i := len(peers); i < len(sc.peers); i++ {
+		sc.peers[i] = nil
+	}
+	sc.peers = peers
+}
+
+// checkPeerHeights returns the maximum of the heights of the peers, leaving
+// out the heights more than MaxHeightLead blocks ahead of the median height,
+// whose indexes are returned as wrong.
+func checkPeerHeights(heights []uint64) (maxHeight uint64, wrong []int) {
+	if len(heights) == 0 {
+		return 0, nil
+	}
+	sorted := append([]uint64{}, heights...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	median := sorted[len(sorted)/2]
+	for i, height := range heights {
+		if height > median+MaxHeightLead {
+			wrong = append(wrong, i)
+			continue
+		}
+		if height > maxHeight {
+			maxHeight = height
+		}
+	}
+	return maxHeight, wrong
+}
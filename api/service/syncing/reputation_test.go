@@ -0,0 +1,59 @@
+package syncing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeerBan(t *testing.T) {
+	now := time.Unix(1000, 0)
+	reputation := NewPeerReputation()
+	reputation.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		reputation.reward("1.2.3.4", "6000")
+	}
+	if reputation.penalize("1.2.3.4", "6000", PenaltyBadBlock, "bad block") {
+		t.Fatal("peer banned after a single bad block")
+	}
+	if !reputation.penalize("1.2.3.4", "6000", PenaltyBadBlock+3, "bad block") {
+		t.Fatal("peer not banned at the ban score")
+	}
+	if !reputation.IsBanned("1.2.3.4", "6000") || reputation.IsBanned("1.2.3.4", "6001") {
+		t.Error("wrong peer banned")
+	}
+	scores := reputation.Scores()
+	if len(scores) != 2 || scores[0].Port != "6000" || scores[0].Bans != 1 || scores[0].LastReason != "bad block" {
+		t.Errorf("wrong scores: %+v", scores)
+	}
+
+	// the ban expires, and the next one lasts twice as long
+	now = now.Add(BanDuration)
+	if reputation.IsBanned("1.2.3.4", "6000") {
+		t.Fatal("ban not expired")
+	}
+	if !reputation.penalize("1.2.3.4", "6000", -BanScore, "timeout") {
+		t.Fatal("peer not banned again")
+	}
+	now = now.Add(BanDuration)
+	if !reputation.IsBanned("1.2.3.4", "6000") {
+		t.Error("second ban not longer than the first")
+	}
+	now = now.Add(BanDuration)
+	if reputation.IsBanned("1.2.3.4", "6000") {
+		t.Error("second ban not expired")
+	}
+}
+
+func TestCheckPeerHeights(t *testing.T) {
+	maxHeight, wrong := checkPeerHeights([]uint64{100, 102, 101, 100 + MaxHeightLead + 1, 0})
+	if maxHeight != 102 {
+		t.Errorf("wrong max height %v", maxHeight)
+	}
+	if len(wrong) != 1 || wrong[0] != 3 {
+		t.Errorf("wrong heights %v", wrong)
+	}
+	if maxHeight, wrong := checkPeerHeights(nil); maxHeight != 0 || len(wrong) != 0 {
+		t.Errorf("heights found without peers: %v %v", maxHeight, wrong)
+	}
+}
@@ -0,0 +1,162 @@
+package syncing
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	pb "github.com/harmony-one/harmony/api/service/syncing/downloader/proto"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/ctxerror"
+	"github.com/harmony-one/harmony/internal/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// New block subscription
+//
+// Once the old blocks are downloaded, the node subscribes to the new blocks
+// of SubscriptionNumber of its peers: each peer streams its blocks from the
+// block following the current block on, then every block as soon as it is
+// committed. The blocks received are queued for generateNewState, up to
+// maxPendingNewBlocks per peer; past that, the node stops reading the stream
+// and the peer holds off, through the flow control of the stream, until the
+// queue drains. A broken subscription is opened again from the block
+// following the last one received. The subscriptions are closed once the node
+// is in sync.
+
+// Constants for new block subscription.
+const (
+	SubscriptionNumber  = 3  // number of peers subscribed to
+	maxPendingNewBlocks = 64 // maximum number of blocks received from a peer waiting to be added
+	resubscribeDelay    = 2 * time.Second
+)
+
+// subscribeNewBlocks subscribes to the new blocks of up to SubscriptionNumber
+// peers, from the block following the current block on.
+func (ss *StateSync) subscribeNewBlocks(bc *core.BlockChain) {
+	fromHeight := bc.CurrentBlock().NumberU64() + 1
+	count := 0
+	ss.syncConfig.ForEachPeer(func(peerConfig *SyncPeerConfig) (brk bool) {
+		if count >= SubscriptionNumber {
+			brk = true
+			return
+		}
+		if peerConfig.ip == ss.selfip && peerConfig.port == GetSyncingPort(ss.selfport) {
+			utils.GetLogInstance().Debug("[SYNC] skip self", "peerport", peerConfig.port, "selfport", ss.selfport, "selfsyncport", GetSyncingPort(ss.selfport))
+			return
+		}
+		peerConfig.subscribe(fromHeight)
+		count++
+		return
+	})
+	utils.GetLogInstance().Debug("[SYNC] subscribed to new blocks", "numPeers", count, "fromHeight", fromHeight)
+}
+
+// subscribe opens the new block subscription of the peer from the given
+// height on, unless already open.
+func (peerConfig *SyncPeerConfig) subscribe(fromHeight uint64) {
+	peerConfig.mux.Lock()
+	defer peerConfig.mux.Unlock()
+	if peerConfig.cancelSubscription != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	peerConfig.cancelSubscription = cancel
+	go peerConfig.receiveNewBlocks(ctx, fromHeight)
+}
+
+// unsubscribe closes the new block subscription of the peer, if open.
+func (peerConfig *SyncPeerConfig) unsubscribe() {
+	peerConfig.mux.Lock()
+	defer peerConfig.mux.Unlock()
+	if peerConfig.cancelSubscription != nil {
+		peerConfig.cancelSubscription()
+		peerConfig.cancelSubscription = nil
+	}
+}
+
+// receiveNewBlocks receives the new blocks of the peer from the given height
+// on, opening the subscription again whenever broken, until ctx is done.
+func (peerConfig *SyncPeerConfig) receiveNewBlocks(ctx context.Context, next uint64) {
+	for {
+		stream, err := peerConfig.client.SubscribeNewBlocks(ctx, next)
+		if err == nil {
+			next, err = peerConfig.readNewBlocks(ctx, stream, next)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if status.Code(err) == codes.Unimplemented {
+			utils.GetLogInstance().Info("[SYNC] peer doesn't serve new block subscriptions", "ip", peerConfig.ip, "port", peerConfig.port)
+			return
+		}
+		utils.GetLogInstance().Debug("[SYNC] new block subscription broken, resubscribing", "ip", peerConfig.ip, "port", peerConfig.port, "fromHeight", next, "error", err)
+		select {
+		case <-time.After(resubscribeDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// readNewBlocks queues the blocks received from the stream, starting at the
+// given height, until the stream breaks. It returns the height of the block
+// expected next.
+func (peerConfig *SyncPeerConfig) readNewBlocks(ctx context.Context, stream pb.Downloader_SubscribeNewBlocksClient, next uint64) (uint64, error) {
+	for {
+		message, err := stream.Recv()
+		if err != nil {
+			return next, err
+		}
+		for _, payload := range message.Payload {
+			block := &types.Block{}
+			if err := rlp.DecodeBytes(payload, block); err != nil {
+				return next, ctxerror.New("[SYNC] cannot decode new block").WithCause(err)
+			}
+			if block.NumberU64() != next {
+				return next, ctxerror.New("[SYNC] new block out of order",
+					"blockNum", block.NumberU64(),
+					"expectedBlockNum", next)
+			}
+			if !peerConfig.addNewBlock(ctx, block) {
+				return next, ctx.Err()
+			}
+			next++
+		}
+	}
+}
+
+// addNewBlock queues the block received from the peer, waiting for room in
+// the queue. It returns false if ctx is done meanwhile.
+func (peerConfig *SyncPeerConfig) addNewBlock(ctx context.Context, block *types.Block) bool {
+	for {
+		peerConfig.mux.Lock()
+		if len(peerConfig.newBlocks) < maxPendingNewBlocks {
+			peerConfig.newBlocks = append(peerConfig.newBlocks, block)
+			peerConfig.mux.Unlock()
+			utils.GetLogInstance().Debug("[SYNC] new block received", "ip", peerConfig.ip, "port", peerConfig.port, "blockHeight", block.NumberU64())
+			return true
+		}
+		peerConfig.mux.Unlock()
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// pruneNewBlocks drops the blocks received from the peer up to the given height.
+func (peerConfig *SyncPeerConfig) pruneNewBlocks(height uint64) {
+	peerConfig.mux.Lock()
+	defer peerConfig.mux.Unlock()
+	blocks := []*types.Block{}
+	for _, block := range peerConfig.newBlocks {
+		if block.NumberU64() > height {
+			blocks = append(blocks, block)
+		}
+	}
+	peerConfig.newBlocks = blocks
+}
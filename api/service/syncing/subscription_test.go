@@ -0,0 +1,31 @@
+package syncing
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/harmony-one/harmony/core/types"
+)
+
+func TestNewBlockQueue(t *testing.T) {
+	peerConfig := &SyncPeerConfig{}
+	ctx, cancel := context.WithCancel(context.Background())
+	for i := 0; i < maxPendingNewBlocks; i++ {
+		block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(int64(i + 1))})
+		if !peerConfig.addNewBlock(ctx, block) {
+			t.Fatalf("block %v not queued", i+1)
+		}
+	}
+	// the queue is full, the block waits until the subscription is closed
+	cancel()
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(maxPendingNewBlocks + 1)})
+	if peerConfig.addNewBlock(ctx, block) {
+		t.Error("block queued past maxPendingNewBlocks")
+	}
+
+	peerConfig.pruneNewBlocks(10)
+	if len(peerConfig.newBlocks) != maxPendingNewBlocks-10 || peerConfig.newBlocks[0].NumberU64() != 11 {
+		t.Errorf("wrong blocks left after pruning: %v", len(peerConfig.newBlocks))
+	}
+}
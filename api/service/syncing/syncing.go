@@ -2,6 +2,7 @@ package syncing
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"reflect"
 	"sort"
@@ -9,9 +10,7 @@ import (
 	"sync"
 	"time"
 
-	"github.com/Workiva/go-datastructures/queue"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/harmony-one/harmony/api/service/syncing/downloader"
 	pb "github.com/harmony-one/harmony/api/service/syncing/downloader/proto"
 	"github.com/harmony-one/harmony/core"
@@ -27,7 +26,6 @@ const (
 	ConsensusRatio                        = float64(0.66)
 	SleepTimeAfterNonConsensusBlockHashes = time.Second * 30
 	TimesToFail                           = 5 // Downloadblocks service retry limit
-	RegistrationNumber                    = 3
 	SyncingPortDifference                 = 3000
 	inSyncThreshold                       = 0 // when peerBlockHeight - myBlockHeight <= inSyncThreshold, it's ready to join consensus
 )
@@ -38,9 +36,14 @@ type SyncPeerConfig struct {
 	port        string
 	peerHash    []byte
 	client      *downloader.Client
-	blockHashes [][]byte       // block hashes before node doing sync
-	newBlocks   []*types.Block // blocks after node doing sync
+	blockHashes [][]byte        // block hashes before node doing sync
+	headers     []*types.Header // block headers before node doing sync
+	newBlocks   []*types.Block  // blocks after node doing sync
+	throughput  float64         // items served per second, moving average
+	failures    int             // number of failed requests
 	mux         sync.Mutex
+
+	cancelSubscription context.CancelFunc // closes the new block subscription, nil if none
 }
 
 // GetClient returns client pointer of downloader.Client
@@ -48,12 +51,6 @@ func (peerConfig *SyncPeerConfig) GetClient() *downloader.Client {
 	return peerConfig.client
 }
 
-// SyncBlockTask is the task struct to sync a specific block.
-type SyncBlockTask struct {
-	index     int
-	blockHash []byte
-}
-
 // SyncConfig contains an array of SyncPeerConfig.
 type SyncConfig struct {
 	// mtx locks peers, and *SyncPeerConfig pointers in peers.
@@ -88,21 +85,27 @@ func CreateStateSync(ip string, port string, peerHash [20]byte) *StateSync {
 	stateSync.selfip = ip
 	stateSync.selfport = port
 	stateSync.selfPeerHash = peerHash
-	stateSync.commonBlocks = make(map[int]*types.Block)
 	stateSync.lastMileBlocks = []*types.Block{}
+	stateSync.reputation = NewPeerReputation()
 	return stateSync
 }
 
 // StateSync is the struct that implements StateSyncInterface.
 type StateSync struct {
-	selfip             string
-	selfport           string
-	selfPeerHash       [20]byte // hash of ip and address combination
-	commonBlocks       map[int]*types.Block
-	lastMileBlocks     []*types.Block // last mile blocks to catch up with the consensus
-	syncConfig         *SyncConfig
-	stateSyncTaskQueue *queue.Queue
-	syncMux            sync.Mutex
+	selfip         string
+	selfport       string
+	selfPeerHash   [20]byte       // hash of ip and address combination
+	lastMileBlocks []*types.Block // last mile blocks to catch up with the consensus
+	syncConfig     *SyncConfig
+	fastSync       bool // download the state at a pivot block when starting from genesis
+	reputation     *PeerReputation
+	syncMux        sync.Mutex
+}
+
+// SetPeerReputation sets the peer reputation table, to share it with other
+// state syncs.
+func (ss *StateSync) SetPeerReputation(reputation *PeerReputation) {
+	ss.reputation = reputation
 }
 
 // AddLastMileBlock add the lastest a few block into queue for syncing
@@ -117,6 +120,7 @@ func (sc *SyncConfig) CloseConnections() {
 	sc.mtx.RLock()
 	defer sc.mtx.RUnlock()
 	for _, pc := range sc.peers {
+		pc.unsubscribe()
 		pc.client.Close()
 	}
 }
@@ -133,21 +137,6 @@ func (sc *SyncConfig) FindPeerByHash(peerHash []byte) *SyncPeerConfig {
 	return nil
 }
 
-// AddNewBlock will add newly received block into state syncing queue
-func (ss *StateSync) AddNewBlock(peerHash []byte, block *types.Block) {
-	pc := ss.syncConfig.FindPeerByHash(peerHash)
-	if pc == nil {
-		// Received a block with no active peer; just ignore.
-		return
-	}
-	// TODO ek – we shouldn't mess with SyncPeerConfig's mutex.
-	//  Factor this into a method, like pc.AddNewBlock(block)
-	pc.mux.Lock()
-	defer pc.mux.Unlock()
-	pc.newBlocks = append(pc.newBlocks, block)
-	utils.GetLogInstance().Debug("[SYNC] new block received", "total", len(pc.newBlocks), "blockHeight", block.NumberU64())
-}
-
 // CreateTestSyncPeerConfig used for testing.
 func CreateTestSyncPeerConfig(client *downloader.Client, blockHashes [][]byte) *SyncPeerConfig {
 	return &SyncPeerConfig{
@@ -172,15 +161,6 @@ func CompareSyncPeerConfigByblockHashes(a *SyncPeerConfig, b *SyncPeerConfig) in
 	return 0
 }
 
-// GetBlocks gets blocks by calling grpc request to the corresponding peer.
-func (peerConfig *SyncPeerConfig) GetBlocks(hashes [][]byte) ([][]byte, error) {
-	response := peerConfig.client.GetBlocks(hashes)
-	if response == nil {
-		return nil, ErrGetBlock
-	}
-	return response.Payload, nil
-}
-
 // CreateSyncConfig creates SyncConfig for StateSync object.
 func (ss *StateSync) CreateSyncConfig(peers []p2p.Peer, isBeacon bool) error {
 	utils.GetLogInstance().Debug("CreateSyncConfig: len of peers", "len", len(peers), "isBeacon", isBeacon)
@@ -189,7 +169,12 @@ func (ss *StateSync) CreateSyncConfig(peers []p2p.Peer, isBeacon bool) error {
 	}
 	ss.syncConfig = &SyncConfig{}
 	var wg sync.WaitGroup
+	numBanned := 0
 	for _, peer := range peers {
+		if ss.reputation.IsBanned(peer.IP, peer.Port) {
+			numBanned++
+			continue
+		}
 		wg.Add(1)
 		go func(peer p2p.Peer) {
 			defer wg.Done()
@@ -206,7 +191,10 @@ func (ss *StateSync) CreateSyncConfig(peers []p2p.Peer, isBeacon bool) error {
 		}(peer)
 	}
 	wg.Wait()
-	utils.GetLogInstance().Info("[SYNC] Finished making connection to peers.", "len", len(ss.syncConfig.peers), "isBeacon", isBeacon)
+	utils.GetLogInstance().Info("[SYNC] Finished making connection to peers.", "len", len(ss.syncConfig.peers), "numBanned", numBanned, "isBeacon", isBeacon)
+	if numBanned == len(peers) {
+		return ctxerror.New("[SYNC] all peers are banned", "numPeers", len(peers))
+	}
 
 	return nil
 }
@@ -263,6 +251,7 @@ func (sc *SyncConfig) cleanUpPeers(maxFirstID int) {
 			// TODO: move it into a util delete func.
 			// See tip https://github.com/golang/go/wiki/SliceTricks
 			// Close the client and remove the peer out of the
+			sc.peers[i].unsubscribe()
 			sc.peers[i].client.Close()
 			copy(sc.peers[i:], sc.peers[i+1:])
 			sc.peers[len(sc.peers)-1] = nil
@@ -288,101 +277,6 @@ func (sc *SyncConfig) GetBlockHashesConsensusAndCleanUp() bool {
 	return false
 }
 
-// GetConsensusHashes gets all hashes needed to download.
-func (ss *StateSync) GetConsensusHashes(startHash []byte) bool {
-	count := 0
-	for {
-		var wg sync.WaitGroup
-		ss.syncConfig.ForEachPeer(func(peerConfig *SyncPeerConfig) (brk bool) {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				response := peerConfig.client.GetBlockHashes(startHash)
-				if response == nil {
-					return
-				}
-				peerConfig.blockHashes = response.Payload
-			}()
-			return
-		})
-		wg.Wait()
-		if ss.syncConfig.GetBlockHashesConsensusAndCleanUp() {
-			break
-		}
-		if count > TimesToFail {
-			utils.GetLogInstance().Info("GetConsensusHashes: reached retry limit")
-			return false
-		}
-		count++
-		time.Sleep(SleepTimeAfterNonConsensusBlockHashes)
-	}
-	utils.GetLogInstance().Info("syncing: Finished getting consensus block hashes.")
-	return true
-}
-
-func (ss *StateSync) generateStateSyncTaskQueue(bc *core.BlockChain) {
-	ss.stateSyncTaskQueue = queue.New(0)
-	ss.syncConfig.ForEachPeer(func(configPeer *SyncPeerConfig) (brk bool) {
-		for id, blockHash := range configPeer.blockHashes {
-			ss.stateSyncTaskQueue.Put(SyncBlockTask{index: id, blockHash: blockHash})
-		}
-		brk = true
-		return
-	})
-	utils.GetLogInstance().Info("syncing: Finished generateStateSyncTaskQueue", "length", ss.stateSyncTaskQueue.Len())
-}
-
-// downloadBlocks downloads blocks from state sync task queue.
-func (ss *StateSync) downloadBlocks(bc *core.BlockChain) {
-	// Initialize blockchain
-	var wg sync.WaitGroup
-	count := 0
-	ss.syncConfig.ForEachPeer(func(peerConfig *SyncPeerConfig) (brk bool) {
-		wg.Add(1)
-		go func(stateSyncTaskQueue *queue.Queue, bc *core.BlockChain) {
-			defer wg.Done()
-			for !stateSyncTaskQueue.Empty() {
-				task, err := ss.stateSyncTaskQueue.Poll(1, time.Millisecond)
-				if err == queue.ErrTimeout || len(task) == 0 {
-					utils.GetLogInstance().Debug("[SYNC] ss.stateSyncTaskQueue poll timeout", "error", err)
-					break
-				}
-				syncTask := task[0].(SyncBlockTask)
-				//id := syncTask.index
-				payload, err := peerConfig.GetBlocks([][]byte{syncTask.blockHash})
-				if err != nil || len(payload) == 0 {
-					count++
-					utils.GetLogInstance().Debug("[SYNC] GetBlocks failed", "failNumber", count)
-					if count > TimesToFail {
-						break
-					}
-					ss.stateSyncTaskQueue.Put(syncTask)
-					continue
-				}
-
-				var blockObj types.Block
-				// currently only send one block a time
-				err = rlp.DecodeBytes(payload[0], &blockObj)
-				if err != nil {
-					count++
-					utils.GetLogInstance().Debug("[SYNC] downloadBlocks: failed to DecodeBytes from received new block")
-					if count > TimesToFail {
-						break
-					}
-					ss.stateSyncTaskQueue.Put(syncTask)
-					continue
-				}
-				ss.syncMux.Lock()
-				ss.commonBlocks[syncTask.index] = &blockObj
-				ss.syncMux.Unlock()
-			}
-		}(ss.stateSyncTaskQueue, bc)
-		return
-	})
-	wg.Wait()
-	utils.GetLogInstance().Info("[SYNC] Finished downloadBlocks.")
-}
-
 // CompareBlockByHash compares two block by hash, it will be used in sort the blocks
 func CompareBlockByHash(a *types.Block, b *types.Block) int {
 	ha := a.Hash()
@@ -414,8 +308,9 @@ func GetHowManyMaxConsensus(blocks []*types.Block) (int, int) {
 
 func (ss *StateSync) getMaxConsensusBlockFromParentHash(parentHash common.Hash) *types.Block {
 	candidateBlocks := []*types.Block{}
-	ss.syncMux.Lock()
 	ss.syncConfig.ForEachPeer(func(peerConfig *SyncPeerConfig) (brk bool) {
+		peerConfig.mux.Lock()
+		defer peerConfig.mux.Unlock()
 		for _, block := range peerConfig.newBlocks {
 			ph := block.ParentHash()
 			if bytes.Compare(ph[:], parentHash[:]) == 0 {
@@ -425,7 +320,6 @@ func (ss *StateSync) getMaxConsensusBlockFromParentHash(parentHash common.Hash)
 		}
 		return
 	})
-	ss.syncMux.Unlock()
 	if len(candidateBlocks) == 0 {
 		return nil
 	}
@@ -438,14 +332,32 @@ func (ss *StateSync) getMaxConsensusBlockFromParentHash(parentHash common.Hash)
 	return candidateBlocks[maxFirstID]
 }
 
-func (ss *StateSync) getBlockFromOldBlocksByParentHash(parentHash common.Hash) *types.Block {
-	for _, block := range ss.commonBlocks {
-		ph := block.ParentHash()
-		if bytes.Compare(ph[:], parentHash[:]) == 0 {
-			return block
+// penalizeNewBlockSenders penalizes the peers which sent the given invalid
+// block, and drops the block.
+func (ss *StateSync) penalizeNewBlockSenders(block *types.Block, err error) {
+	ctxerror.Log15(utils.GetLogInstance().Warn, ctxerror.New("[SYNC] invalid new block from peers",
+		"blockNum", block.NumberU64(),
+		"blockHash", block.Hash(),
+	).WithCause(err))
+	hash := block.Hash()
+	ss.syncConfig.ForEachPeer(func(peerConfig *SyncPeerConfig) (brk bool) {
+		peerConfig.mux.Lock()
+		blocks := peerConfig.newBlocks[:0]
+		sent := false
+		for _, newBlock := range peerConfig.newBlocks {
+			if newBlock.Hash() == hash {
+				sent = true
+				continue
+			}
+			blocks = append(blocks, newBlock)
 		}
-	}
-	return nil
+		peerConfig.newBlocks = blocks
+		peerConfig.mux.Unlock()
+		if sent {
+			ss.penalizePeer(peerConfig, PenaltyBadBlock, err.Error())
+		}
+		return
+	})
 }
 
 func (ss *StateSync) getBlockFromLastMileBlocksByParentHash(parentHash common.Hash) *types.Block {
@@ -474,28 +386,15 @@ func (ss *StateSync) updateBlockAndStatus(block *types.Block, bc *core.BlockChai
 
 // generateNewState will construct most recent state from downloaded blocks
 func (ss *StateSync) generateNewState(bc *core.BlockChain, worker *worker.Worker) {
-	// update blocks created before node start sync
+	// update blocks after node start sync
 	parentHash := bc.CurrentBlock().Hash()
 	for {
-		block := ss.getBlockFromOldBlocksByParentHash(parentHash)
+		block := ss.getMaxConsensusBlockFromParentHash(parentHash)
 		if block == nil {
 			break
 		}
-		ok := ss.updateBlockAndStatus(block, bc, worker)
-		if !ok {
-			break
-		}
-		parentHash = block.Hash()
-	}
-	ss.syncMux.Lock()
-	ss.commonBlocks = make(map[int]*types.Block)
-	ss.syncMux.Unlock()
-
-	// update blocks after node start sync
-	parentHash = bc.CurrentBlock().Hash()
-	for {
-		block := ss.getMaxConsensusBlockFromParentHash(parentHash)
-		if block == nil {
+		if err := bc.ValidateNewBlock(block, common.Address{}); err != nil {
+			ss.penalizeNewBlockSenders(block, err)
 			break
 		}
 		ok := ss.updateBlockAndStatus(block, bc, worker)
@@ -504,14 +403,12 @@ func (ss *StateSync) generateNewState(bc *core.BlockChain, worker *worker.Worker
 		}
 		parentHash = block.Hash()
 	}
-	// TODO ek – Do we need to hold syncMux now that syncConfig has its onw
-	//  mutex?
-	ss.syncMux.Lock()
-	ss.syncConfig.ForEachPeer(func(peer *SyncPeerConfig) (brk bool) {
-		peer.newBlocks = []*types.Block{}
+	// drop the new blocks up to the current block, keeping the ones following
+	// for the next round
+	ss.syncConfig.ForEachPeer(func(peerConfig *SyncPeerConfig) (brk bool) {
+		peerConfig.pruneNewBlocks(bc.CurrentBlock().NumberU64())
 		return
 	})
-	ss.syncMux.Unlock()
 
 	// update last mile blocks if any
 	parentHash = bc.CurrentBlock().Hash()
@@ -530,80 +427,46 @@ func (ss *StateSync) generateNewState(bc *core.BlockChain, worker *worker.Worker
 
 // ProcessStateSync processes state sync from the blocks received but not yet processed so far
 func (ss *StateSync) ProcessStateSync(startHash []byte, bc *core.BlockChain, worker *worker.Worker, isBeacon bool) {
-	if !isBeacon {
-		ss.RegisterNodeInfo()
-	}
-	// Gets consensus hashes.
-	if !ss.GetConsensusHashes(startHash) {
-		utils.GetLogInstance().Debug("[SYNC] ProcessStateSync unable to reach consensus on ss.GetConsensusHashes")
-		return
+	if ss.fastSync && bc.CurrentBlock().NumberU64() == 0 {
+		ss.fastSyncToPivot(bc, worker)
 	}
-	ss.generateStateSyncTaskQueue(bc)
-	// Download blocks.
-	if ss.stateSyncTaskQueue.Len() > 0 {
-		ss.downloadBlocks(bc)
+	// Download the blocks created before node start sync, header first.
+	ss.downloadChain(bc, worker)
+	if !isBeacon {
+		// Have the blocks created from now on streamed.
+		ss.subscribeNewBlocks(bc)
 	}
 	ss.generateNewState(bc, worker)
 }
 
-func (peerConfig *SyncPeerConfig) registerToBroadcast(peerHash []byte, ip, port string) error {
-	response := peerConfig.client.Register(peerHash, ip, port)
-	if response == nil || response.Type == pb.DownloaderResponse_FAIL {
-		return ErrRegistrationFail
-	} else if response.Type == pb.DownloaderResponse_SUCCESS {
-		return nil
-	}
-	return ErrRegistrationFail
-}
-
-// RegisterNodeInfo will register node to peers to accept future new block broadcasting
-// return number of successful registration
-func (ss *StateSync) RegisterNodeInfo() int {
-	registrationNumber := RegistrationNumber
-	utils.GetLogInstance().Debug("[SYNC] node registration to peers",
-		"registrationNumber", registrationNumber,
-		"activePeerNumber", len(ss.syncConfig.peers))
-
-	count := 0
-	ss.syncConfig.ForEachPeer(func(peerConfig *SyncPeerConfig) (brk bool) {
-		if count >= registrationNumber {
-			brk = true
-			return
-		}
-		if peerConfig.ip == ss.selfip && peerConfig.port == GetSyncingPort(ss.selfport) {
-			utils.GetLogInstance().Debug("[SYNC] skip self", "peerport", peerConfig.port, "selfport", ss.selfport, "selfsyncport", GetSyncingPort(ss.selfport))
-			return
-		}
-		err := peerConfig.registerToBroadcast(ss.selfPeerHash[:], ss.selfip, ss.selfport)
-		if err != nil {
-			utils.GetLogInstance().Debug("[SYNC] register failed to peer", "ip", peerConfig.ip, "port", peerConfig.port, "selfPeerHash", ss.selfPeerHash)
-			return
-		}
-		utils.GetLogInstance().Debug("[SYNC] register success", "ip", peerConfig.ip, "port", peerConfig.port)
-		count++
-		return
-	})
-	return count
-}
-
-// getMaxPeerHeight gets the maximum blockchain heights from peers
+// getMaxPeerHeight gets the maximum blockchain heights from peers, leaving out
+// and penalizing the peers claiming a height out of line with the others.
 func (ss *StateSync) getMaxPeerHeight() uint64 {
-	maxHeight := uint64(0)
+	peers := []*SyncPeerConfig{}
+	heights := []uint64{}
 	var wg sync.WaitGroup
 	ss.syncConfig.ForEachPeer(func(peerConfig *SyncPeerConfig) (brk bool) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			response := peerConfig.client.GetBlockChainHeight()
-			ss.syncMux.Lock()
-			if response != nil && maxHeight < response.BlockHeight {
-				maxHeight = response.BlockHeight
+			if response == nil {
+				ss.penalizePeer(peerConfig, PenaltyTimeout, "no block height")
+				return
 			}
+			ss.syncMux.Lock()
+			peers = append(peers, peerConfig)
+			heights = append(heights, response.BlockHeight)
 			ss.syncMux.Unlock()
 		}()
 		return
 	})
 	wg.Wait()
+	maxHeight, wrong := checkPeerHeights(heights)
+	for _, i := range wrong {
+		utils.GetLogInstance().Info("[SYNC] peer height out of line", "ip", peers[i].ip, "port", peers[i].port, "height", heights[i], "maxHeight", maxHeight)
+		ss.penalizePeer(peers[i], PenaltyWrongHeight, "wrong block height")
+	}
 	return maxHeight
 }
 
@@ -618,8 +481,17 @@ func (ss *StateSync) IsOutOfSync(bc *core.BlockChain) bool {
 // SyncLoop will keep syncing with peers until catches up
 func (ss *StateSync) SyncLoop(bc *core.BlockChain, worker *worker.Worker, willJoinConsensus bool, isBeacon bool) {
 	for {
+		ss.removeBannedPeers()
+		if ss.GetActivePeerNumber() == 0 {
+			utils.GetLogInstance().Info("[SYNC] no peer left to sync with")
+			return
+		}
 		if !ss.IsOutOfSync(bc) {
 			utils.GetLogInstance().Info("[SYNC] Node is now IN SYNC!")
+			ss.syncConfig.ForEachPeer(func(peerConfig *SyncPeerConfig) (brk bool) {
+				peerConfig.unsubscribe()
+				return
+			})
 			return
 		}
 		startHash := bc.CurrentBlock().Hash()
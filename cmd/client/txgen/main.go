@@ -47,6 +47,7 @@ const (
 type Settings struct {
 	NumOfAddress      int
 	MaxNumTxsPerBatch int
+	CrossShardRatio   int
 }
 
 func printVersion(me string) {
@@ -64,7 +65,7 @@ var (
 	logFolder         = flag.String("log_folder", "latest", "the folder collecting the logs of this execution")
 	duration          = flag.Int("duration", 30, "duration of the tx generation in second. If it's negative, the experiment runs forever.")
 	versionFlag       = flag.Bool("version", false, "Output version info")
-	crossShardRatio   = flag.Int("cross_shard_ratio", 30, "The percentage of cross shard transactions.") //Keeping this for backward compatibility
+	crossShardRatio   = flag.Int("cross_shard_ratio", 30, "The percentage of cross shard transactions.")
 	shardIDFlag       = flag.Int("shardID", 0, "The shardID the node belongs to.")
 	// Key file to store the private key
 	keyFile = flag.String("key", "./.txgenkey", "the private key file of the txgen")
@@ -138,8 +139,8 @@ func main() {
 	setting := Settings{
 		NumOfAddress:      10000,
 		MaxNumTxsPerBatch: *maxNumTxsPerBatch,
+		CrossShardRatio:   *crossShardRatio,
 	}
-	utils.GetLogInstance().Debug("Cross Shard Ratio Is Set But not used", "cx ratio", *crossShardRatio)
 
 	// TODO(Richard): refactor this chuck to a single method
 	// Setup a logger to stdout and log file.
@@ -236,13 +237,19 @@ func SendTxsToShard(clientNode *node.Node, txs types.Transactions) {
 
 // GenerateSimulatedTransactionsAccount generates simulated transaction for account model.
 func GenerateSimulatedTransactionsAccount(shardID uint32, node *node.Node, setting Settings) (types.Transactions, error) {
-	_ = setting // TODO: make use of settings
 	txs := make([]*types.Transaction, 100)
 	for i := 0; i < 100; i++ {
 		baseNonce := node.Worker.GetCurrentState().GetNonce(crypto.PubkeyToAddress(node.TestBankKeys[i].PublicKey))
 		randomUserAddress := crypto.PubkeyToAddress(node.TestBankKeys[rand.Intn(100)].PublicKey)
-		randAmount := rand.Float32()
-		tx, _ := types.SignTx(types.NewTransaction(baseNonce+uint64(0), randomUserAddress, shardID, big.NewInt(int64(params.Ether*randAmount)), params.TxGas, nil, nil), types.HomesteadSigner{}, node.TestBankKeys[i])
+		randAmount := big.NewInt(int64(params.Ether * rand.Float32()))
+		var tx *types.Transaction
+		if core.GenesisShardNum > 1 && rand.Intn(100) < setting.CrossShardRatio {
+			// Send to any other shard
+			toShardID := (shardID + 1 + uint32(rand.Intn(core.GenesisShardNum-1))) % core.GenesisShardNum
+			tx, _ = types.SignTx(types.NewCrossShardTransaction(baseNonce, randomUserAddress, shardID, toShardID, randAmount, params.TxGas, nil), types.HomesteadSigner{}, node.TestBankKeys[i])
+		} else {
+			tx, _ = types.SignTx(types.NewTransaction(baseNonce, randomUserAddress, shardID, randAmount, params.TxGas, nil, nil), types.HomesteadSigner{}, node.TestBankKeys[i])
+		}
 		txs[i] = tx
 	}
 	return txs, nil
@@ -79,6 +79,7 @@ var (
 	transferReceiverPtr   = transferCommand.String("to", "", "Specify the receiver account")
 	transferAmountPtr     = transferCommand.Float64("amount", 0, "Specify the amount to transfer")
 	transferShardIDPtr    = transferCommand.Int("shardID", 0, "Specify the shard ID for the transfer")
+	transferToShardIDPtr  = transferCommand.Int("toShardID", -1, "Specify the shard ID of the receiver, if different from the sender's")
 	transferInputDataPtr  = transferCommand.String("inputData", "", "Base64-encoded input data to embed in the transaction")
 	transferSenderPassPtr = transferCommand.String("pass", "", "Passphrase of the sender's private key")
 
@@ -133,6 +134,7 @@ func main() {
 		fmt.Println("        --to             - The receiver account's address")
 		fmt.Println("        --amount         - The amount of token to transfer")
 		fmt.Println("        --shardID        - The shard Id for the transfer")
+		fmt.Println("        --toShardID      - The shard Id of the receiver, for a cross-shard transfer")
 		fmt.Println("        --inputData      - Base64-encoded input data to embed in the transaction")
 		fmt.Println("        --pass           - Passphrase of sender's private key")
 		os.Exit(1)
@@ -381,6 +383,7 @@ func processTransferCommand() {
 	receiver := *transferReceiverPtr
 	amount := *transferAmountPtr
 	shardID := *transferShardIDPtr
+	toShardID := *transferToShardIDPtr
 	base64InputData := *transferInputDataPtr
 	senderPass := *transferSenderPassPtr
 
@@ -395,6 +398,13 @@ func processTransferCommand() {
 		fmt.Println("Please specify the shard ID for the transfer (e.g. --shardID=0)")
 		return
 	}
+	if toShardID == -1 {
+		toShardID = shardID
+	}
+	if toShardID != shardID && len(inputData) > 0 {
+		fmt.Println("Input data cannot be embedded in a cross-shard transfer")
+		return
+	}
 	if amount <= 0 {
 		fmt.Println("Please specify positive amount to transfer")
 		return
@@ -437,9 +447,16 @@ func processTransferCommand() {
 		return
 	}
 
-	tx := types.NewTransaction(
-		state.nonce, receiverAddress, uint32(shardID), amountBigInt,
-		gas, nil, inputData)
+	var tx *types.Transaction
+	if toShardID != shardID {
+		tx = types.NewCrossShardTransaction(
+			state.nonce, receiverAddress, uint32(shardID), uint32(toShardID),
+			amountBigInt, gas, nil)
+	} else {
+		tx = types.NewTransaction(
+			state.nonce, receiverAddress, uint32(shardID), amountBigInt,
+			gas, nil, inputData)
+	}
 
 	account, err := ks.Find(accounts.Account{Address: senderAddress})
 	if err != nil {
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+
+	"github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/core/vm"
+	"github.com/harmony-one/harmony/internal/storage"
+)
+
+var (
+	version string
+	builtBy string
+	builtAt string
+	commit  string
+)
+
+var (
+	dbPath      = flag.String("db", "", "path of the database, overriding -ip, -port and -is_beacon")
+	dbType      = flag.String("db_type", storage.LevelDB, "the kind of database: "+strings.Join(storage.Kinds, ", "))
+	ip          = flag.String("ip", "127.0.0.1", "IP of the node owning the database")
+	port        = flag.String("port", "9000", "port of the node owning the database")
+	isBeacon    = flag.Bool("is_beacon", false, "true means the beacon chain database kept by a shard node")
+	versionFlag = flag.Bool("version", false, "Output version info")
+)
+
+// commands are the subcommands of harmony-db, with their arguments and help.
+// Only the commands marked anyVersion run on a database whose schema version
+// isn't the one of this software.
+var commands = []struct {
+	name       string
+	args       string
+	help       string
+	anyVersion bool
+	run        func(db ethdb.Database, args []string) error
+}{
+	{"version", "", "print the schema version of the database", true, printSchemaVersion},
+	{"heads", "", "print the head header, block and fast block", false, printHeads},
+	{"header", "<number|hash>", "dump the header of a block", false, dumpHeader},
+	{"block", "<number|hash>", "dump a block", false, dumpBlock},
+	{"receipts", "<number|hash>", "dump the receipts of a block", false, dumpReceipts},
+	{"receipt", "<tx hash>", "dump the receipt of a transaction", false, dumpReceipt},
+	{"shardstates", "", "list the shard states stored per epoch", false, listShardStates},
+	{"check", "[first block number]", "check that the canonical chain and its indexes are consistent", false, checkChain},
+	{"sethead", "<number>", "rewind the head of the chain to the given block", false, setHead},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [flags] <command> [args]\n\nInspects and repairs the database of a stopped harmony node.\n\nCommands:\n", path.Base(os.Args[0]))
+	for _, command := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %-22s %s\n", command.name, command.args, command.help)
+	}
+	fmt.Fprintf(os.Stderr, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+func printVersion(me string) {
+	fmt.Fprintf(os.Stderr, "Harmony (C) 2018. %v, version %v-%v (%v %v)\n", path.Base(me), version, commit, builtBy, builtAt)
+	os.Exit(0)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if *versionFlag {
+		printVersion(os.Args[0])
+	}
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	for _, command := range commands {
+		if command.name != flag.Arg(0) {
+			continue
+		}
+		fileName := *dbPath
+		if fileName == "" {
+			if *isBeacon {
+				fileName = fmt.Sprintf("./db/harmony_beacon_%s_%s", *ip, *port)
+			} else {
+				fileName = fmt.Sprintf("./db/harmony_%s_%s", *ip, *port)
+			}
+		}
+		if _, err := os.Stat(fileName); err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot open the database: %v\n", err)
+			os.Exit(1)
+		}
+		db, err := storage.Open(*dbType, fileName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot open the database: %v\n", err)
+			os.Exit(1)
+		}
+		if !command.anyVersion {
+			err = checkSchemaVersion(db)
+		}
+		if err == nil {
+			err = command.run(db, flag.Args()[1:])
+		}
+		db.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s failed: %v\n", command.name, err)
+			os.Exit(1)
+		}
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n", flag.Arg(0))
+	usage()
+	os.Exit(2)
+}
+
+// checkSchemaVersion fails unless the database has the schema version of this
+// software, which reads the entries in their current format only.
+func checkSchemaVersion(db ethdb.Database) error {
+	version := rawdb.ReadDatabaseVersion(db)
+	if version < rawdb.SchemaVersion() {
+		return fmt.Errorf("database schema version %d is older than the supported version %d: start the node once to migrate the database", version, rawdb.SchemaVersion())
+	}
+	if version > rawdb.SchemaVersion() {
+		return fmt.Errorf("database schema version %d is newer than the supported version %d: use a newer harmony-db", version, rawdb.SchemaVersion())
+	}
+	return nil
+}
+
+// printJSON prints the value as indented JSON.
+func printJSON(value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// parseBlock returns the hash and number of the block given by its number, in
+// which case the block is the canonical one, or by its hash.
+func parseBlock(db ethdb.Database, args []string) (common.Hash, uint64, error) {
+	if len(args) != 1 {
+		return common.Hash{}, 0, fmt.Errorf("expecting a block number or hash")
+	}
+	arg := args[0]
+	if strings.HasPrefix(arg, "0x") || len(arg) == 2*common.HashLength {
+		hash := common.HexToHash(arg)
+		number := rawdb.ReadHeaderNumber(db, hash)
+		if number == nil {
+			return common.Hash{}, 0, fmt.Errorf("unknown block hash %s", hash.Hex())
+		}
+		return hash, *number, nil
+	}
+	number, err := strconv.ParseUint(arg, 10, 64)
+	if err != nil {
+		return common.Hash{}, 0, fmt.Errorf("invalid block number or hash %q", arg)
+	}
+	hash := rawdb.ReadCanonicalHash(db, number)
+	if hash == (common.Hash{}) {
+		return common.Hash{}, 0, fmt.Errorf("no canonical block %d", number)
+	}
+	return hash, number, nil
+}
+
+func printHeads(db ethdb.Database, args []string) error {
+	for _, head := range []struct {
+		name string
+		hash common.Hash
+	}{
+		{"LastHeader", rawdb.ReadHeadHeaderHash(db)},
+		{"LastBlock", rawdb.ReadHeadBlockHash(db)},
+		{"LastFast", rawdb.ReadHeadFastBlockHash(db)},
+	} {
+		if head.hash == (common.Hash{}) {
+			fmt.Printf("%-12s none\n", head.name+":")
+		} else if number := rawdb.ReadHeaderNumber(db, head.hash); number == nil {
+			fmt.Printf("%-12s %s (unknown header)\n", head.name+":", head.hash.Hex())
+		} else {
+			fmt.Printf("%-12s #%d %s\n", head.name+":", *number, head.hash.Hex())
+		}
+	}
+	if number, ok := rawdb.ReadPrunedBlockNumber(db); ok {
+		fmt.Printf("%-12s #%d\n", "LastPruned:", number)
+	}
+	return nil
+}
+
+func printSchemaVersion(db ethdb.Database, args []string) error {
+	fmt.Printf("%-12s %d (supported: %d)\n", "Version:", rawdb.ReadDatabaseVersion(db), rawdb.SchemaVersion())
+	return nil
+}
+
+func dumpHeader(db ethdb.Database, args []string) error {
+	hash, number, err := parseBlock(db, args)
+	if err != nil {
+		return err
+	}
+	header := rawdb.ReadHeader(db, hash, number)
+	if header == nil {
+		return fmt.Errorf("no header of block %d %s", number, hash.Hex())
+	}
+	return printJSON(header)
+}
+
+func dumpBlock(db ethdb.Database, args []string) error {
+	hash, number, err := parseBlock(db, args)
+	if err != nil {
+		return err
+	}
+	block := rawdb.ReadBlock(db, hash, number)
+	if block == nil {
+		return fmt.Errorf("no block %d %s, or its body was pruned", number, hash.Hex())
+	}
+	return printJSON(struct {
+		Hash         common.Hash          `json:"hash"`
+		Header       *types.Header        `json:"header"`
+		Transactions []*types.Transaction `json:"transactions"`
+	}{hash, block.Header(), block.Transactions()})
+}
+
+func dumpReceipts(db ethdb.Database, args []string) error {
+	hash, number, err := parseBlock(db, args)
+	if err != nil {
+		return err
+	}
+	receipts := rawdb.ReadReceipts(db, hash, number)
+	if receipts == nil {
+		return fmt.Errorf("no receipts of block %d %s", number, hash.Hex())
+	}
+	return printJSON(receipts)
+}
+
+func dumpReceipt(db ethdb.Database, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expecting a transaction hash")
+	}
+	txHash := common.HexToHash(args[0])
+	receipt, blockHash, blockNumber, index := rawdb.ReadReceipt(db, txHash)
+	if receipt == nil {
+		return fmt.Errorf("no receipt of transaction %s", txHash.Hex())
+	}
+	return printJSON(struct {
+		BlockHash   common.Hash    `json:"blockHash"`
+		BlockNumber uint64         `json:"blockNumber"`
+		Index       uint64         `json:"transactionIndex"`
+		Receipt     *types.Receipt `json:"receipt"`
+	}{blockHash, blockNumber, index, receipt})
+}
+
+func listShardStates(db ethdb.Database, args []string) error {
+	headHash := rawdb.ReadHeadHeaderHash(db)
+	head := rawdb.ReadHeaderNumber(db, headHash)
+	if head == nil {
+		return fmt.Errorf("unknown head header %s", headHash.Hex())
+	}
+	for epoch := uint64(0); core.GetBlockNumberFromEpoch(epoch) <= *head; epoch++ {
+		number := core.GetBlockNumberFromEpoch(epoch)
+		hash := rawdb.ReadCanonicalHash(db, number)
+		shardState := rawdb.ReadShardState(db, hash, number)
+		if shardState == nil {
+			fmt.Printf("epoch %d: block #%d, no shard state\n", epoch, number)
+			continue
+		}
+		fmt.Printf("epoch %d: block #%d, shard state %s\n", epoch, number, shardState.Hash().Hex())
+		for _, committee := range shardState {
+			fmt.Printf("  shard %d: %d nodes, leader %s\n", committee.ShardID, len(committee.NodeList), committee.Leader.EcdsaAddress)
+		}
+	}
+	return nil
+}
+
+// checkChain checks the canonical chain from the given block up to the head
+// header: the links between the headers, the indexes of the headers and of the
+// transactions, and that the bodies and receipts match the headers. It fails
+// if any problem was found.
+func checkChain(db ethdb.Database, args []string) error {
+	first := uint64(0)
+	if len(args) > 0 {
+		var err error
+		if first, err = strconv.ParseUint(args[0], 10, 64); err != nil {
+			return fmt.Errorf("invalid block number %q", args[0])
+		}
+	}
+	problems := 0
+	report := func(format string, args ...interface{}) {
+		problems++
+		fmt.Printf("!! "+format+"\n", args...)
+	}
+
+	heads := map[string]uint64{}
+	for _, head := range []struct {
+		name string
+		hash common.Hash
+	}{
+		{"LastHeader", rawdb.ReadHeadHeaderHash(db)},
+		{"LastBlock", rawdb.ReadHeadBlockHash(db)},
+		{"LastFast", rawdb.ReadHeadFastBlockHash(db)},
+	} {
+		number := rawdb.ReadHeaderNumber(db, head.hash)
+		if number == nil {
+			report("%s points to unknown header %s", head.name, head.hash.Hex())
+			continue
+		}
+		heads[head.name] = *number
+		if canonical := rawdb.ReadCanonicalHash(db, *number); canonical != head.hash {
+			report("%s #%d %s isn't canonical, the canonical block is %s", head.name, *number, head.hash.Hex(), canonical.Hex())
+		}
+	}
+	headHeader, ok := heads["LastHeader"]
+	if !ok {
+		return fmt.Errorf("%d problems found", problems)
+	}
+	headBlock := heads["LastBlock"]
+	pruned, hasPruned := rawdb.ReadPrunedBlockNumber(db)
+
+	for number := first; number <= headHeader; number++ {
+		if number%10000 == 0 && number > first {
+			fmt.Printf("checked up to block #%d\n", number)
+		}
+		hash := rawdb.ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			report("block #%d: no canonical hash", number)
+			continue
+		}
+		header := rawdb.ReadHeader(db, hash, number)
+		if header == nil {
+			report("block #%d: no header %s", number, hash.Hex())
+			continue
+		}
+		if header.Hash() != hash {
+			report("block #%d: header hashes to %s instead of %s", number, header.Hash().Hex(), hash.Hex())
+		}
+		if indexed := rawdb.ReadHeaderNumber(db, hash); indexed == nil || *indexed != number {
+			report("block #%d: wrong number index of %s", number, hash.Hex())
+		}
+		if number > 0 {
+			if parent := rawdb.ReadCanonicalHash(db, number-1); header.ParentHash != parent {
+				report("block #%d: parent %s isn't the canonical block %s", number, header.ParentHash.Hex(), parent.Hex())
+			}
+		}
+		if rawdb.ReadTd(db, hash, number) == nil {
+			report("block #%d: no total difficulty", number)
+		}
+		if number > headBlock || (number > 0 && hasPruned && number <= pruned) {
+			continue
+		}
+		body := rawdb.ReadBody(db, hash, number)
+		if body == nil {
+			report("block #%d: no body", number)
+			continue
+		}
+		if txHash := types.DeriveSha(types.Transactions(body.Transactions)); txHash != header.TxHash {
+			report("block #%d: transactions hash to %s instead of %s", number, txHash.Hex(), header.TxHash.Hex())
+		}
+		for i, tx := range body.Transactions {
+			blockHash, blockNumber, index := rawdb.ReadTxLookupEntry(db, tx.Hash())
+			if blockHash != hash || blockNumber != number || index != uint64(i) {
+				report("block #%d: wrong lookup entry of transaction %d %s", number, i, tx.Hash().Hex())
+			}
+		}
+		if number == 0 {
+			continue
+		}
+		receipts := rawdb.ReadReceipts(db, hash, number)
+		if receipts == nil {
+			report("block #%d: no receipts", number)
+		} else if receiptHash := types.DeriveSha(receipts); receiptHash != header.ReceiptHash {
+			report("block #%d: receipts hash to %s instead of %s", number, receiptHash.Hex(), header.ReceiptHash.Hex())
+		}
+	}
+
+	if header := rawdb.ReadHeader(db, rawdb.ReadHeadBlockHash(db), headBlock); header != nil {
+		if _, err := state.New(header.Root, state.NewDatabase(db)); err != nil {
+			report("no state of the head block #%d: %v", headBlock, err)
+		}
+	}
+	if problems > 0 {
+		return fmt.Errorf("%d problems found", problems)
+	}
+	fmt.Printf("checked blocks #%d to #%d, no problem found\n", first, headHeader)
+	return nil
+}
+
+func setHead(db ethdb.Database, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expecting a block number")
+	}
+	number, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid block number %q", args[0])
+	}
+	genesisHash := rawdb.ReadCanonicalHash(db, 0)
+	chainConfig := rawdb.ReadChainConfig(db, genesisHash)
+	if chainConfig == nil {
+		return fmt.Errorf("no chain config of genesis %s", genesisHash.Hex())
+	}
+	engine := consensus.NewVerifier(uint32(chainConfig.ChainID.Uint64()))
+	chain, err := core.NewBlockChain(db, nil, chainConfig, engine, vm.Config{}, nil)
+	if err != nil {
+		return err
+	}
+	defer chain.Stop()
+	if err := chain.SetHead(number); err != nil {
+		return err
+	}
+	fmt.Printf("Rewound the chain to block #%d %s\n", chain.CurrentBlock().NumberU64(), chain.CurrentBlock().Hash().Hex())
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/core/vm"
+)
+
+func TestCheckChain(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	gspec := core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{address: {Balance: big.NewInt(1000000000000000000)}},
+	}
+	genesis := gspec.MustCommit(db)
+	genDB := ethdb.NewMemDatabase()
+	gspec.MustCommit(genDB)
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{Disabled: true}, gspec.Config, consensus.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("cannot create chain: %v", err)
+	}
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, consensus.NewFaker(), genDB, 5, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(address), common.Address{1}, 0, big.NewInt(1000), params.TxGas, nil, nil), types.HomesteadSigner{}, key)
+		b.AddTx(tx)
+	})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("cannot insert blocks: %v", err)
+	}
+	chain.Stop()
+
+	if hash, number, err := parseBlock(db, []string{"3"}); err != nil || hash != blocks[2].Hash() || number != 3 {
+		t.Errorf("block 3 parsed as %v %v, err %v", number, hash.Hex(), err)
+	}
+	if hash, number, err := parseBlock(db, []string{blocks[3].Hash().Hex()}); err != nil || hash != blocks[3].Hash() || number != 4 {
+		t.Errorf("block 4 parsed as %v %v, err %v", number, hash.Hex(), err)
+	}
+	if _, _, err := parseBlock(db, []string{"6"}); err == nil {
+		t.Error("unknown block 6 parsed")
+	}
+
+	if err := checkChain(db, nil); err != nil {
+		t.Errorf("consistent chain failed the check: %v", err)
+	}
+	rawdb.DeleteTxLookupEntry(db, blocks[2].Transactions()[0].Hash())
+	if err := checkChain(db, nil); err == nil {
+		t.Error("missing transaction lookup entry not found")
+	}
+	if err := checkChain(db, []string{"4"}); err != nil {
+		t.Errorf("check from block 4 failed: %v", err)
+	}
+}
+
+func TestCheckSchemaVersion(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	for _, version := range []int{rawdb.SchemaVersion() - 1, rawdb.SchemaVersion() + 1} {
+		rawdb.WriteDatabaseVersion(db, version)
+		if err := checkSchemaVersion(db); err == nil {
+			t.Errorf("database of schema version %d accepted", version)
+		}
+	}
+	rawdb.WriteDatabaseVersion(db, rawdb.SchemaVersion())
+	if err := checkSchemaVersion(db); err != nil {
+		t.Errorf("database of the current schema version refused: %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/internal/storage"
+	"github.com/harmony-one/harmony/node"
+)
+
+// Chain commands
+//
+// The export and import commands operate offline on the database of a node,
+// which must not be running:
+//
+//	harmony export [-db_type TYPE] [-ip IP] [-port PORT] [-shard_id ID] [-from N] [-to N] -file FILE
+//	harmony import [-db_type TYPE] [-ip IP] [-port PORT] [-shard_id ID] -file FILE
+//
+// The chain is exported as a stream of RLP encoded blocks, gzip-compressed if
+// the file name ends with ".gz". On import the blocks missing from the chain
+// are inserted with full validation, seals included.
+
+// chainCommands are the subcommands of harmony operating on a chain database.
+var chainCommands = map[string]func(args []string) error{
+	"export": exportCommand,
+	"import": importCommand,
+}
+
+// chainFlags are the flags selecting the chain database of a node.
+type chainFlags struct {
+	dbType     *string
+	ip         *string
+	port       *string
+	shardID    *int
+	isBeacon   *bool
+	isArchival *bool
+}
+
+func newChainFlagSet(name string) (*flag.FlagSet, *chainFlags) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	return fs, &chainFlags{
+		dbType:     fs.String("db_type", storage.LevelDB, "the kind of database: "+strings.Join(storage.Kinds, ", ")),
+		ip:         fs.String("ip", "127.0.0.1", "IP of the node owning the database"),
+		port:       fs.String("port", "9000", "port of the node owning the database"),
+		shardID:    fs.Int("shard_id", 0, "the shard ID of the chain"),
+		isBeacon:   fs.Bool("is_beacon", false, "true means the beacon chain database kept by a shard node"),
+		isArchival: fs.Bool("is_archival", false, "true means the database of an archival node"),
+	}
+}
+
+// openChain opens the chain database selected by the flags, setting up the
+// genesis block if the database is empty.
+func openChain(flags *chainFlags) (*core.BlockChain, ethdb.Database, error) {
+	shardID := uint32(*flags.shardID)
+	if *flags.isBeacon {
+		shardID = 0
+	}
+	db, err := InitDatabase(*flags.dbType, *flags.ip, *flags.port, false, *flags.isBeacon)
+	if err != nil {
+		return nil, nil, err
+	}
+	engine := consensus.NewVerifier(shardID)
+	gsif, err := consensus.NewGenesisStakeInfoFinder()
+	if err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("cannot initialize stake info: %v", err)
+	}
+	engine.SetStakeInfoFinder(gsif)
+	chainNode := &node.Node{Consensus: engine}
+	chain, err := chainNode.InitBlockChainFromDB(db, engine, *flags.isArchival)
+	if err != nil || chain.CurrentBlock().NumberU64() == 0 {
+		chain, err = chainNode.GenesisBlockSetup(db, shardID, *flags.isArchival)
+	}
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	engine.ChainReader = chain
+	if shardID == 0 {
+		engine.SetStakeInfoFinder(consensus.NewStakeLockInfoFinder(chain, gsif))
+	}
+	return chain, db, nil
+}
+
+// exportChain writes the blocks first to last of the chain to the given file.
+func exportChain(chain *core.BlockChain, fileName string, first, last uint64) error {
+	fh, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var w io.Writer = fh
+	if strings.HasSuffix(fileName, ".gz") {
+		gw := gzip.NewWriter(fh)
+		defer gw.Close()
+		w = gw
+	}
+	return chain.ExportN(w, first, last)
+}
+
+// importChain inserts the blocks of the given file missing from the chain,
+// and returns the number of blocks inserted.
+func importChain(chain *core.BlockChain, fileName string) (int, error) {
+	fh, err := os.Open(fileName)
+	if err != nil {
+		return 0, err
+	}
+	defer fh.Close()
+
+	var r io.Reader = fh
+	if strings.HasSuffix(fileName, ".gz") {
+		gr, err := gzip.NewReader(fh)
+		if err != nil {
+			return 0, err
+		}
+		defer gr.Close()
+		r = gr
+	}
+	return chain.Import(r)
+}
+
+func exportCommand(args []string) error {
+	fs, flags := newChainFlagSet("export")
+	from := fs.Uint64("from", 0, "number of the first block exported")
+	to := fs.Int64("to", -1, "number of the last block exported, -1 means the current block")
+	fileName := fs.String("file", "", "the file the blocks are exported to, gzip-compressed if ending with .gz")
+	fs.Parse(args)
+	if *fileName == "" {
+		return fmt.Errorf("no file given")
+	}
+	log.Root().SetHandler(log.StreamHandler(os.Stdout, log.TerminalFormat(false)))
+
+	chain, db, err := openChain(flags)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	defer chain.Stop()
+
+	last := chain.CurrentBlock().NumberU64()
+	if *to >= 0 {
+		last = uint64(*to)
+	}
+	if err := exportChain(chain, *fileName, *from, last); err != nil {
+		return err
+	}
+	fmt.Printf("Exported blocks %d to %d to %s\n", *from, last, *fileName)
+	return nil
+}
+
+func importCommand(args []string) error {
+	fs, flags := newChainFlagSet("import")
+	fileName := fs.String("file", "", "the file the blocks are imported from, gzip-compressed if ending with .gz")
+	fs.Parse(args)
+	if *fileName == "" {
+		return fmt.Errorf("no file given")
+	}
+	log.Root().SetHandler(log.StreamHandler(os.Stdout, log.TerminalFormat(false)))
+
+	chain, db, err := openChain(flags)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	defer chain.Stop()
+
+	imported, err := importChain(chain, *fileName)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d blocks from %s, current block %d\n", imported, *fileName, chain.CurrentBlock().NumberU64())
+	return nil
+}
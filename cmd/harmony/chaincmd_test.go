@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/vm"
+)
+
+func newTestChain(t *testing.T) *core.BlockChain {
+	database := ethdb.NewMemDatabase()
+	gspec := core.Genesis{Config: params.TestChainConfig}
+	gspec.MustCommit(database)
+	chain, err := core.NewBlockChain(database, nil, gspec.Config, consensus.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("cannot create chain: %v", err)
+	}
+	return chain
+}
+
+func TestExportImportChain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "harmony-chaincmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := newTestChain(t)
+	defer source.Stop()
+	blocks, _ := core.GenerateChain(params.TestChainConfig, source.Genesis(), consensus.NewFaker(), ethdb.NewMemDatabase(), 10, nil)
+	if _, err := source.InsertChain(blocks); err != nil {
+		t.Fatalf("cannot insert blocks: %v", err)
+	}
+
+	for _, fileName := range []string{"chain.rlp", "chain.rlp.gz"} {
+		fileName = filepath.Join(dir, fileName)
+		target := newTestChain(t)
+		if err := exportChain(source, fileName, 0, 5); err != nil {
+			t.Fatalf("cannot export %s: %v", fileName, err)
+		}
+		if imported, err := importChain(target, fileName); err != nil || imported != 5 {
+			t.Errorf("import of blocks 0 to 5 from %s: imported %d, err %v", fileName, imported, err)
+		}
+		if err := exportChain(source, fileName, 0, 10); err != nil {
+			t.Fatalf("cannot export %s: %v", fileName, err)
+		}
+		if imported, err := importChain(target, fileName); err != nil || imported != 5 {
+			t.Errorf("import of blocks 0 to 10 from %s: imported %d, err %v", fileName, imported, err)
+		}
+		if target.CurrentBlock().Hash() != source.CurrentBlock().Hash() {
+			t.Errorf("wrong current block imported from %s: %v, expected %v", fileName, target.CurrentBlock().Number(), source.CurrentBlock().Number())
+		}
+		target.Stop()
+	}
+}
@@ -6,10 +6,14 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"os/signal"
 	"path"
 	"runtime"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
@@ -17,9 +21,11 @@ import (
 
 	"github.com/harmony-one/harmony/consensus"
 	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/rawdb"
 	"github.com/harmony-one/harmony/drand"
 	nodeconfig "github.com/harmony-one/harmony/internal/configs/node"
 	"github.com/harmony-one/harmony/internal/profiler"
+	"github.com/harmony-one/harmony/internal/storage"
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/internal/utils/contract"
 	"github.com/harmony-one/harmony/node"
@@ -34,21 +40,39 @@ var (
 	commit  string
 )
 
-// InitLDBDatabase initializes a LDBDatabase. isGenesis=true will return the beacon chain database for normal shard nodes
-func InitLDBDatabase(ip string, port string, freshDB bool, isBeacon bool) (*ethdb.LDBDatabase, error) {
+// InitDatabase initializes a database of the given kind, upgrading its schema if needed. isBeacon=true will return the beacon chain database for normal shard nodes
+func InitDatabase(dbType string, ip string, port string, freshDB bool, isBeacon bool) (ethdb.Database, error) {
 	var dbFileName string
 	if isBeacon {
 		dbFileName = fmt.Sprintf("./db/harmony_beacon_%s_%s", ip, port)
 	} else {
 		dbFileName = fmt.Sprintf("./db/harmony_%s_%s", ip, port)
 	}
+	return openDatabase(dbType, dbFileName, freshDB)
+}
+
+// openShardDatabase opens the database of the given shard, for the node
+// resharded to it.
+func openShardDatabase(dbType string, ip string, port string, shardID uint32) (ethdb.Database, error) {
+	return openDatabase(dbType, fmt.Sprintf("./db/harmony_%s_%s_shard_%d", ip, port, shardID), false)
+}
+
+func openDatabase(dbType string, dbFileName string, freshDB bool) (ethdb.Database, error) {
 	if freshDB {
 		var err = os.RemoveAll(dbFileName)
 		if err != nil {
 			fmt.Println(err.Error())
 		}
 	}
-	return ethdb.NewLDBDatabase(dbFileName, 0, 0)
+	db, err := storage.Open(dbType, dbFileName)
+	if err != nil {
+		return nil, err
+	}
+	if err := rawdb.InitSchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
 }
 
 func printVersion(me string) {
@@ -74,6 +98,7 @@ var (
 	port             = flag.String("port", "9000", "port of the node.")
 	logFolder        = flag.String("log_folder", "latest", "the folder collecting the logs of this execution")
 	freshDB          = flag.Bool("fresh_db", false, "true means the existing disk based db will be removed")
+	dbType           = flag.String("db_type", storage.LevelDB, "the kind of database storing the chains: "+strings.Join(storage.Kinds, ", "))
 	profile          = flag.Bool("profile", false, "Turn on profiling (CPU, Memory).")
 	metricsReportURL = flag.String("metrics_report_url", "", "If set, reports metrics to this URL.")
 	versionFlag      = flag.Bool("version", false, "Output version info")
@@ -94,6 +119,23 @@ var (
 	shardID      = flag.Int("shard_id", -1, "the shard ID of this node")
 	// logConn logs incoming/outgoing connections
 	logConn = flag.Bool("log_conn", false, "log incoming/outgoing connections")
+	// leaderRotationBlocks is the number of blocks proposed by a leader before the leadership rotates
+	leaderRotationBlocks = flag.Uint64("leader_rotation_blocks", 0, "number of blocks proposed by a leader before rotating to the next leader in the schedule, 0 means a fixed leader")
+	// blockTime is the target interval between blocks, with 0 the leader waits for enough pending transactions instead
+	blockTime = flag.Duration("block_time", node.DefaultBlockTime, "target interval between blocks, 0 means proposing a block once enough transactions are pending")
+	// blockGasLimit and blockSizeLimit limit the transactions packaged in a block
+	blockGasLimit  = flag.Uint64("block_gas_limit", 0, "max gas used by the transactions of a block, 0 means the chain gas limit")
+	blockSizeLimit = flag.Int("block_size_limit", 0, "max size in bytes of the transactions of a block, 0 means no limit")
+	// fastSync makes a node syncing from genesis download the state at a recent block instead of executing all the blocks
+	fastSync = flag.Bool("fast_sync", false, "true means a node syncing from genesis downloads the state at a recent block instead of executing all the blocks, ignored by archival nodes")
+	// prune makes a node delete from disk the state and the blocks it no longer needs
+	prune                   = flag.Bool("prune", false, "true means the state of old blocks, and optionally their bodies and receipts, are deleted from disk, ignored by archival nodes")
+	pruneStateRetention     = flag.Uint64("prune_state_retention", core.DefaultStateRetention, "number of recent blocks whose state is kept when pruning")
+	pruneCheckpointInterval = flag.Uint64("prune_checkpoint_interval", core.DefaultCheckpointInterval, "the state of one block in this many is kept when pruning, 0 means none but genesis")
+	pruneBlockRetention     = flag.Uint64("prune_block_retention", 0, "number of recent blocks whose bodies and receipts are kept when pruning, 0 means all")
+	pruneInterval           = flag.Uint64("prune_interval", core.DefaultPruneInterval, "number of blocks between prunings")
+	// metricsIP is the IP the Prometheus metrics endpoint listens on
+	metricsIP = flag.String("metrics_ip", node.DefaultMetricsIP, "IP the metrics endpoint listens on, 127.0.0.1 means only the local host can scrape the metrics")
 )
 
 func initSetup() {
@@ -180,16 +222,6 @@ func createGlobalConfig() *nodeconfig.ConfigType {
 	}
 	// Key Setup ================= [End]
 
-	// Initialize leveldb for main blockchain and beacon.
-	if nodeConfig.MainDB, err = InitLDBDatabase(*ip, *port, *freshDB, false); err != nil {
-		panic(err)
-	}
-	if myShardID != 0 {
-		if nodeConfig.BeaconDB, err = InitLDBDatabase(*ip, *port, *freshDB, true); err != nil {
-			panic(err)
-		}
-	}
-
 	nodeConfig.SelfPeer = p2p.Peer{IP: *ip, Port: *port, ConsensusPubKey: nodeConfig.ConsensusPubKey}
 
 	if *accountIndex%core.GenesisShardSize == 0 { // The first node in a shard is the leader at genesis
@@ -212,6 +244,20 @@ func createGlobalConfig() *nodeconfig.ConfigType {
 	return nodeConfig
 }
 
+// initDatabases initializes the databases for main blockchain and beacon,
+// upgrading their schema if needed.
+func initDatabases(nodeConfig *nodeconfig.ConfigType) {
+	var err error
+	if nodeConfig.MainDB, err = InitDatabase(*dbType, *ip, *port, *freshDB, false); err != nil {
+		panic(err)
+	}
+	if nodeConfig.ShardID != 0 {
+		if nodeConfig.BeaconDB, err = InitDatabase(*dbType, *ip, *port, *freshDB, true); err != nil {
+			panic(err)
+		}
+	}
+}
+
 func setUpConsensusAndNode(nodeConfig *nodeconfig.ConfigType) (*consensus.Consensus, *node.Node) {
 	// Consensus object.
 	// TODO: consensus object shouldn't start here
@@ -222,11 +268,27 @@ func setUpConsensusAndNode(nodeConfig *nodeconfig.ConfigType) (*consensus.Consen
 		os.Exit(1)
 	}
 	currentConsensus.MinPeers = *minPeers
+	currentConsensus.LeaderRotationBlocks = *leaderRotationBlocks
 
 	// Current node.
 	currentNode := node.New(nodeConfig.Host, currentConsensus, nodeConfig.MainDB, *isArchival)
 	currentNode.NodeConfig.SetRole(nodeconfig.NewNode)
 	currentNode.AccountKey = nodeConfig.StakingPriKey
+	currentNode.BlockTime = *blockTime
+	currentNode.OpenShardDB = func(shardID uint32) (ethdb.Database, error) {
+		return openShardDatabase(*dbType, *ip, *port, shardID)
+	}
+	currentNode.FastSync = *fastSync && !*isArchival
+	currentNode.MetricsIP = *metricsIP
+	if *prune && !*isArchival {
+		currentNode.Blockchain().SetPruning(&core.PruneConfig{
+			StateRetention:     *pruneStateRetention,
+			CheckpointInterval: *pruneCheckpointInterval,
+			BlockRetention:     *pruneBlockRetention,
+			PruneInterval:      *pruneInterval,
+		})
+	}
+	currentNode.Worker.SetBlockLimits(*blockGasLimit, common.StorageSize(*blockSizeLimit))
 	utils.GetLogInstance().Info("node account set",
 		"address", crypto.PubkeyToAddress(currentNode.AccountKey.PublicKey))
 
@@ -302,6 +364,9 @@ func setUpConsensusAndNode(nodeConfig *nodeconfig.ConfigType) (*consensus.Consen
 	if currentConsensus.ShardID != 0 {
 		currentNode.AddBeaconChainDatabase(nodeConfig.BeaconDB)
 	}
+	// Weight the signers by the stakes locked in the beacon chain, still
+	// rewarding the genesis nodes without locked stake
+	currentConsensus.SetStakeInfoFinder(consensus.NewStakeLockInfoFinder(currentNode.Beaconchain(), currentConsensus.StakeInfoFinder()))
 
 	// This needs to be executed after consensus and drand are setup
 	if !*isNewNode || *shardID > -1 { // initial staking new node doesn't need to initialize shard state
@@ -314,14 +379,42 @@ func setUpConsensusAndNode(nodeConfig *nodeconfig.ConfigType) (*consensus.Consen
 	currentConsensus.SetConsensusID(uint32(height))
 	utils.GetLogInstance().Info("Init Blockchain", "height", height)
 
+	// Restore the consensus messages of the round in flight, if any
+	if nodeConfig.MainDB != nil {
+		currentConsensus.SetPbftLog(consensus.NewPersistentPbftLog(nodeConfig.MainDB))
+	}
+
 	// Assign closure functions to the consensus object
 	currentConsensus.BlockVerifier = currentNode.VerifyNewBlock
 	currentConsensus.OnConsensusDone = currentNode.PostConsensusProcessing
+	currentConsensus.OnDoubleSign = currentNode.ReportDoubleSign
 	currentNode.State = node.NodeWaitToJoin
 	return currentConsensus, currentNode
 }
 
+// stopOnSignal stops the node, closing its databases, and exits once the
+// process is interrupted or terminated.
+func stopOnSignal(currentNode *node.Node) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-signals
+	utils.GetLogInstance().Info("Stopping the node", "signal", sig)
+	currentNode.Stop()
+	utils.GetLogInstance().Info("Stopped the node")
+	os.Exit(0)
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := chainCommands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "%s failed: %v\n", os.Args[1], err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	flag.Var(&utils.BootNodes, "bootnodes", "a list of bootnode multiaddress (delimited by ,)")
 	flag.Parse()
 
@@ -332,6 +425,7 @@ func main() {
 
 	// Init logging.
 	loggingInit(*logFolder, nodeConfig.StringRole, *ip, *port, *onlyLogTps)
+	initDatabases(nodeConfig)
 
 	// Start Profiler for leader if profile argument is on
 	if nodeConfig.StringRole == "leader" && (*profile || *metricsReportURL != "") {
@@ -343,7 +437,7 @@ func main() {
 	}
 	consensus, currentNode = setUpConsensusAndNode(nodeConfig)
 	// TODO: put this inside discovery service
-	if consensus.IsLeader {
+	if consensus.IsLeader() {
 		go currentNode.SendPongMessage()
 	}
 	//if consensus.ShardID != 0 {
@@ -355,6 +449,7 @@ func main() {
 	go currentNode.SupportSyncing()
 	currentNode.ServiceManagerSetup()
 	currentNode.StartRPC(*port)
+	go stopOnSignal(currentNode)
 	currentNode.RunServices()
 	currentNode.StartServer()
 }
@@ -7,4 +7,12 @@ const (
 	blockDuration  time.Duration = 5 * time.Second
 	receiveTimeout time.Duration = 5 * time.Second
 	maxLogSize     uint32        = 1000
+	// idleTimeoutDuration is how long a validator waits for the leader to propose the next block
+	idleTimeoutDuration time.Duration = 2 * time.Minute
+	// commitTimeoutDuration is how long a validator waits for an announced block to be committed
+	commitTimeoutDuration time.Duration = 30 * time.Second
+	// viewChangeTimeoutDuration is the base timeout of a view change; view change v+n times out after n*viewChangeTimeoutDuration
+	viewChangeTimeoutDuration time.Duration = 30 * time.Second
+	// viewChangeCheckInterval is the period a validator checks the leader's progress
+	viewChangeCheckInterval time.Duration = time.Second
 )
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"math/big"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -14,6 +15,7 @@ import (
 
 	consensus_engine "github.com/harmony-one/harmony/consensus/engine"
 	"github.com/harmony-one/harmony/contracts/structs"
+	"github.com/harmony-one/harmony/core"
 	"github.com/harmony-one/harmony/core/state"
 	"github.com/harmony-one/harmony/core/types"
 	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
@@ -63,6 +65,16 @@ type Consensus struct {
 	// timeout will be 2*viewChangeDuration; timeout of view change v+n is n*viewChangeDuration
 	viewChangeDuration time.Duration
 
+	// viewID: the current view; the leader of view v is PublicKeys[v % len(PublicKeys)]
+	viewID uint32
+	// the latest block this node has seen prepared, carried in its view change message
+	prepared *preparedBlock
+	// View change messages collected by the leader of viewChangeViewID
+	viewChangeViewID   uint32
+	viewChangeSigs     map[common.Address]*bls.Sign // key is the validator's address
+	viewChangeBitmap   *bls_cosi.Mask
+	viewChangePrepared *preparedBlock
+
 	//TODO depreciate it after implement PbftPhase
 	state State
 	// Commits collected from validators.
@@ -85,6 +97,9 @@ type Consensus struct {
 
 	// Leader's address
 	leader p2p.Peer
+	// Number of blocks proposed by a leader before the leadership rotates
+	// following the leader schedule; 0 keeps the leader until a view change
+	LeaderRotationBlocks uint64
 
 	// Public keys of the committee including leader and validators
 	PublicKeys []*bls.PublicKey
@@ -96,8 +111,9 @@ type Consensus struct {
 	priKey *bls.SecretKey
 	PubKey *bls.PublicKey
 
-	// Whether I am leader. False means I am validator
-	IsLeader bool
+	// Whether I am leader, 1 if so; 0 means I am validator.
+	// Accessed atomically, as the view change ticker flips it.
+	isLeader uint32
 	// Leader or validator address in hex
 	SelfAddress common.Address
 	// Consensus Id (View Id) - 4 byte
@@ -117,20 +133,26 @@ type Consensus struct {
 	// global consensus mutex
 	mutex sync.Mutex
 
-	// Validator specific fields
-	// Blocks received but not done with consensus yet
-	blocksReceived map[uint32]*BlockConsensusStatus
+	// Messages and blocks received but not done with consensus yet
+	pbftLog *PbftLog
+	// The buffered messages of the rounds before replayedID have been replayed
+	replayedID uint32
 
 	// Signal channel for starting a new consensus process
 	ReadySignal chan struct{}
 	// The post-consensus processing func passed from Node object
 	// Called when consensus on a new block is done
 	OnConsensusDone func(*types.Block)
+	// The double sign handler func passed from Node object
+	// Called when a validator is found signing two different blocks in the same round
+	OnDoubleSign func(*types.DoubleSignEvidence)
 	// The verifier func passed from Node object
 	BlockVerifier func(*types.Block) bool
+	// Called with the trace of each committed round, if set
+	OnRoundTraced func(*RoundTrace)
 
-	// verified block to state sync broadcast
-	VerifiedNewBlock chan *types.Block
+	// Traces of the rounds in flight
+	tracer roundTracer
 
 	// will trigger state syncing when consensus ID is low
 	ConsensusIDLowChan chan struct{}
@@ -148,6 +170,26 @@ type Consensus struct {
 
 	// Staking information finder
 	stakeInfoFinder StakeInfoFinder
+
+	// The quorum policy deciding whether a set of signers is enough to reach consensus
+	policy bls_cosi.Policy
+
+	// Accept all seals without verification, used by the faker consensus for testing
+	fakeSeal bool
+}
+
+// IsLeader returns whether this node is the leader of the current view.
+func (consensus *Consensus) IsLeader() bool {
+	return atomic.LoadUint32(&consensus.isLeader) == 1
+}
+
+// SetIsLeader sets whether this node is the leader of the current view.
+func (consensus *Consensus) SetIsLeader(isLeader bool) {
+	var value uint32
+	if isLeader {
+		value = 1
+	}
+	atomic.StoreUint32(&consensus.isLeader, value)
 }
 
 // StakeInfoFinder returns the stake information finder instance this
@@ -162,6 +204,72 @@ func (consensus *Consensus) SetStakeInfoFinder(stakeInfoFinder StakeInfoFinder)
 	consensus.stakeInfoFinder = stakeInfoFinder
 }
 
+// QuorumPolicy returns the policy this consensus uses to decide whether the
+// signers of a multi-signature on the next block reach the quorum.
+func (consensus *Consensus) QuorumPolicy() (bls_cosi.Policy, error) {
+	return consensus.quorumPolicyOfBlock(consensus.nextBlockNumber())
+}
+
+// reachesQuorum returns whether the signers of the mask reach the quorum on
+// the next block. It returns false if the quorum policy can't be read.
+func (consensus *Consensus) reachesQuorum(mask *bls_cosi.Mask) bool {
+	policy, err := consensus.QuorumPolicy()
+	if err != nil {
+		ctxerror.Log15(utils.GetLogInstance().Warn, ctxerror.New("[Consensus] cannot read the quorum policy").WithCause(err))
+		return false
+	}
+	return policy.Check(mask)
+}
+
+// quorumPolicyOfBlock returns the policy deciding whether the signers of the
+// given block reach the quorum.
+// Unless set explicitly, the signers are weighted by the stakes recorded in
+// the committee of the epoch of the signers, see committeePolicy. It fails if
+// the chain doesn't have the committee, rather than weighting the signers
+// otherwise than the other nodes.
+func (consensus *Consensus) quorumPolicyOfBlock(blockNum uint64) (bls_cosi.Policy, error) {
+	if consensus.policy != nil {
+		return consensus.policy, nil
+	}
+	if consensus.ChainReader == nil {
+		return nil, errors.New("ChainReader is nil")
+	}
+	epoch := core.GetSignerEpochFromBlockNumber(blockNum)
+	committee, err := consensus.ChainReader.CommitteeForEpoch(consensus.ShardID, epoch)
+	if err != nil {
+		return nil, ctxerror.New("cannot read the committee signing the block",
+			"blockNum", blockNum,
+			"epoch", epoch,
+		).WithCause(err)
+	}
+	return committeePolicy(committee), nil
+}
+
+// SetQuorumPolicy sets the policy this consensus uses to decide whether the
+// signers of a multi-signature reach the quorum.
+func (consensus *Consensus) SetQuorumPolicy(policy bls_cosi.Policy) {
+	consensus.policy = policy
+}
+
+// committeePolicy returns the policy deciding whether the signers from the
+// given committee reach the quorum: more than two thirds of the members,
+// holding more than two thirds of the stakes recorded in the committee.
+func committeePolicy(committee *types.Committee) bls_cosi.Policy {
+	return bls_cosi.NewStakeWeightedPolicy(committeeStakes(committee))
+}
+
+// committeeStakes returns a function looking up the stake of the member of
+// the committee with the given key, as recorded in the committee.
+func committeeStakes(committee *types.Committee) func(key *bls.PublicKey) *big.Int {
+	return func(key *bls.PublicKey) *big.Int {
+		var pubKey types.BlsPublicKey
+		if err := pubKey.FromLibBLSPublicKey(key); err != nil {
+			return big.NewInt(0)
+		}
+		return committee.StakeOf(pubKey)
+	}
+}
+
 // StakeInfoFinder finds the staking account for the given consensus key.
 type StakeInfoFinder interface {
 	// FindStakeInfoByNodeKey returns a list of staking information matching
@@ -175,17 +283,6 @@ type StakeInfoFinder interface {
 	FindStakeInfoByAccount(addr common.Address) []*structs.StakeInfo
 }
 
-// BlockConsensusStatus used to keep track of the consensus status of multiple blocks received so far
-// This is mainly used in the case that this node is lagging behind and needs to catch up.
-// For example, the consensus moved to round N and this node received message(N).
-// However, this node may still not finished with round N-1, so the newly received message(N)
-// should be stored in this temporary structure. In case the round N-1 finishes, it can catch
-// up to the latest state of round N by using this structure.
-type BlockConsensusStatus struct {
-	block []byte // the block data
-	state State  // the latest state of the consensus
-}
-
 // New creates a new Consensus object
 // TODO: put shardId into chain reader's chain config
 func New(host p2p.Host, ShardID uint32, leader p2p.Peer, blsPriKey *bls.SecretKey) (*Consensus, error) {
@@ -196,9 +293,9 @@ func New(host p2p.Host, ShardID uint32, leader p2p.Peer, blsPriKey *bls.SecretKe
 
 	selfPeer := host.GetSelfPeer()
 	if leader.Port == selfPeer.Port && leader.IP == selfPeer.IP {
-		consensus.IsLeader = true
+		consensus.SetIsLeader(true)
 	} else {
-		consensus.IsLeader = false
+		consensus.SetIsLeader(false)
 	}
 
 	consensus.leader = leader
@@ -230,6 +327,12 @@ func New(host p2p.Host, ShardID uint32, leader p2p.Peer, blsPriKey *bls.SecretKe
 	consensus.aggregatedPrepareSig = nil
 	consensus.aggregatedCommitSig = nil
 
+	consensus.idleTimeout = *utils.NewTimeout(idleTimeoutDuration)
+	consensus.commitTimeout = *utils.NewTimeout(commitTimeoutDuration)
+	consensus.viewChangeDuration = viewChangeTimeoutDuration
+	consensus.viewChangeTimeout = *utils.NewTimeout(consensus.viewChangeDuration)
+	consensus.viewChangeSigs = map[common.Address]*bls.Sign{}
+
 	// For now use socket address as ID
 	// TODO: populate Id derived from address
 	consensus.SelfAddress = utils.GetBlsAddress(selfPeer.ConsensusPubKey)
@@ -246,11 +349,12 @@ func New(host p2p.Host, ShardID uint32, leader p2p.Peer, blsPriKey *bls.SecretKe
 
 	consensus.MsgChan = make(chan []byte)
 
-	// For validators to keep track of all blocks received but not yet committed, so as to catch up to latest consensus if lagged behind.
-	consensus.blocksReceived = make(map[uint32]*BlockConsensusStatus)
+	// For validators to keep track of all messages received but not yet committed, so as to catch up to latest consensus if lagged behind.
+	consensus.pbftLog = NewPbftLog()
 
-	if consensus.IsLeader {
-		consensus.ReadySignal = make(chan struct{})
+	// Validators also need the ready signal as they may become leader after a view change
+	consensus.ReadySignal = make(chan struct{})
+	if consensus.IsLeader() {
 		// send a signal to indicate it's ready to run consensus
 		// this signal is consumed by node object to create a new block and in turn trigger a new consensus on it
 		// this is a goroutine because go channel without buffer will block
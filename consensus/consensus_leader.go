@@ -9,6 +9,7 @@ import (
 	"github.com/ethereum/go-ethereum/rlp"
 	protobuf "github.com/golang/protobuf/proto"
 	"github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/api/proto"
 	msg_pb "github.com/harmony-one/harmony/api/proto/message"
 	"github.com/harmony-one/harmony/api/service/explorer"
 	"github.com/harmony-one/harmony/core"
@@ -16,8 +17,6 @@ import (
 	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
 	"github.com/harmony-one/harmony/internal/profiler"
 	"github.com/harmony-one/harmony/internal/utils"
-	"github.com/harmony-one/harmony/p2p"
-	"github.com/harmony-one/harmony/p2p/host"
 )
 
 var (
@@ -33,61 +32,82 @@ func (consensus *Consensus) WaitForNewBlock(blockChannel chan *types.Block, stop
 	// for new blocks.
 	// The signal to start the first consensus right now is the sending of Pong message (SendPongMessage function in node/node_handler.go
 	// but it can be changed to other conditions later
+	// Every wait also returns on stopChan, so the service can be stopped, e.g.
+	// when the node switches shard, while no block is coming.
 	first := true
 	go func() {
 		defer close(stoppedChan)
 		for {
-			select {
-			default:
-				if first && startChannel != nil {
-					// got the signal to start consensus
-					_ = <-startChannel
+			if first && startChannel != nil {
+				// got the signal to start consensus
+				select {
+				case <-startChannel:
 					first = false
+				case <-stopChan:
+					return
 				}
+			}
 
-				utils.GetLogInstance().Debug("Waiting for block", "consensus", consensus)
-				// keep waiting for new blocks
-				newBlock := <-blockChannel
-				// TODO: think about potential race condition
-
-				if consensus.ShardID == 0 {
-					if core.IsEpochBlock(newBlock) { // Only beacon chain do randomness generation
-						// Receive pRnd from DRG protocol
-						utils.GetLogInstance().Debug("[DRG] Waiting for pRnd")
-						pRndAndBitmap := <-consensus.PRndChannel
-						utils.GetLogInstance().Debug("[DRG] Got pRnd", "pRnd", pRndAndBitmap)
-						pRnd := [32]byte{}
-						copy(pRnd[:], pRndAndBitmap[:32])
-						bitmap := pRndAndBitmap[32:]
-						vrfBitmap, _ := bls_cosi.NewMask(consensus.PublicKeys, consensus.leader.ConsensusPubKey)
-						vrfBitmap.SetMask(bitmap)
-
-						// TODO: check validity of pRnd
-						newBlock.AddRandPreimage(pRnd)
-					}
+			utils.GetLogInstance().Debug("Waiting for block", "consensus", consensus)
+			// keep waiting for new blocks
+			var newBlock *types.Block
+			select {
+			case newBlock = <-blockChannel:
+			case <-stopChan:
+				return
+			}
+			// TODO: think about potential race condition
 
-					rnd, blockHash, err := consensus.GetNextRnd()
-					if err == nil {
-						// Verify the randomness
-						_ = blockHash
-						utils.GetLogInstance().Info("Adding randomness into new block", "rnd", rnd)
-						newBlock.AddRandSeed(rnd)
-					} else {
-						utils.GetLogInstance().Info("Failed to get randomness", "error", err)
+			if !consensus.IsLeader() {
+				// this node is no longer the leader after a view change
+				utils.GetLogInstance().Debug("Dropping new block as not leader", "blockNum", newBlock.NumberU64())
+				continue
+			}
+
+			if consensus.ShardID == 0 {
+				if core.IsEpochBlock(newBlock) { // Only beacon chain do randomness generation
+					// Receive pRnd from DRG protocol
+					utils.GetLogInstance().Debug("[DRG] Waiting for pRnd")
+					var pRndAndBitmap []byte
+					select {
+					case pRndAndBitmap = <-consensus.PRndChannel:
+					case <-stopChan:
+						return
 					}
+					utils.GetLogInstance().Debug("[DRG] Got pRnd", "pRnd", pRndAndBitmap)
+					pRnd := [32]byte{}
+					copy(pRnd[:], pRndAndBitmap[:32])
+					bitmap := pRndAndBitmap[32:]
+					vrfBitmap, _ := bls_cosi.NewMask(consensus.PublicKeys, consensus.leader.ConsensusPubKey)
+					vrfBitmap.SetMask(bitmap)
+
+					// TODO: check validity of pRnd
+					newBlock.AddRandPreimage(pRnd)
 				}
-				startTime = time.Now()
-				utils.GetLogInstance().Debug("STARTING CONSENSUS", "numTxs", len(newBlock.Transactions()), "consensus", consensus, "startTime", startTime, "publicKeys", len(consensus.PublicKeys))
-				for { // Wait until last consensus is finished
-					if consensus.state == Finished {
-						consensus.ResetState()
-						consensus.startConsensus(newBlock)
-						break
-					}
-					time.Sleep(500 * time.Millisecond)
+
+				rnd, blockHash, err := consensus.GetNextRnd()
+				if err == nil {
+					// Verify the randomness
+					_ = blockHash
+					utils.GetLogInstance().Info("Adding randomness into new block", "rnd", rnd)
+					newBlock.AddRandSeed(rnd)
+				} else {
+					utils.GetLogInstance().Info("Failed to get randomness", "error", err)
+				}
+			}
+			startTime = time.Now()
+			utils.GetLogInstance().Debug("STARTING CONSENSUS", "numTxs", len(newBlock.Transactions()), "consensus", consensus, "startTime", startTime, "publicKeys", len(consensus.PublicKeys))
+			for { // Wait until last consensus is finished
+				if consensus.state == Finished {
+					consensus.ResetState()
+					consensus.startConsensus(newBlock)
+					break
+				}
+				select {
+				case <-time.After(500 * time.Millisecond):
+				case <-stopChan:
+					return
 				}
-			case <-stopChan:
-				return
 			}
 		}
 	}()
@@ -103,10 +123,17 @@ func (consensus *Consensus) ProcessMessageLeader(payload []byte) {
 	}
 
 	switch message.Type {
+	case msg_pb.MessageType_PREPARED:
+		// this node may still be leading a view the committee moved on from
+		consensus.catchUpView(message)
 	case msg_pb.MessageType_PREPARE:
 		consensus.processPrepareMessage(message)
 	case msg_pb.MessageType_COMMIT:
 		consensus.processCommitMessage(message)
+	case msg_pb.MessageType_VIEWCHANGE:
+		consensus.processViewChangeMessage(message)
+	case msg_pb.MessageType_NEWVIEW:
+		consensus.processNewViewMessage(message)
 	default:
 		utils.GetLogInstance().Error("Unexpected message type", "msgType", message.Type, "consensus", consensus)
 	}
@@ -132,13 +159,14 @@ func (consensus *Consensus) startConsensus(newBlock *types.Block) {
 
 	// Set state to AnnounceDone
 	consensus.state = AnnounceDone
+	consensus.traceAnnounce()
 
 	// Leader sign the block hash itself
 	consensus.prepareSigs[consensus.SelfAddress] = consensus.priKey.SignHash(consensus.blockHash[:])
 
 	// Construct broadcast p2p message
 	utils.GetLogInstance().Warn("[Consensus]", "sent announce message", len(msgToSend))
-	consensus.host.SendMessageToGroups([]p2p.GroupID{p2p.NewGroupIDByShardID(p2p.ShardID(consensus.ShardID))}, host.ConstructP2pMessage(byte(17), msgToSend))
+	consensus.sendMessage(msg_pb.MessageType_ANNOUNCE, msgToSend)
 }
 
 // processPrepareMessage processes the prepare message sent from validators
@@ -166,6 +194,8 @@ func (consensus *Consensus) processPrepareMessage(message *msg_pb.Message) {
 		return
 	}
 
+	consensus.checkDoubleSign(message)
+
 	if err := consensus.checkConsensusMessage(message, validatorPubKey); err != nil {
 		utils.GetLogInstance().Debug("Failed to check the validator message", "error", err, "validatorAddress", validatorAddress)
 		return
@@ -178,7 +208,7 @@ func (consensus *Consensus) processPrepareMessage(message *msg_pb.Message) {
 		return
 	}
 
-	if len(prepareSigs) >= ((len(consensus.PublicKeys)*2)/3 + 1) {
+	if consensus.reachesQuorum(prepareBitmap) {
 		utils.GetLogInstance().Debug("Received additional prepare message", "validatorAddress", validatorAddress)
 		return
 	}
@@ -199,9 +229,10 @@ func (consensus *Consensus) processPrepareMessage(message *msg_pb.Message) {
 	utils.GetLogInstance().Debug("Received new prepare signature", "numReceivedSoFar", len(prepareSigs), "validatorAddress", validatorAddress, "PublicKeys", len(consensus.PublicKeys))
 	prepareSigs[validatorAddress] = &sign
 	prepareBitmap.SetKey(validatorPubKey, true) // Set the bitmap indicating that this validator signed.
+	consensus.logMessage(message)
 
 	targetState := PreparedDone
-	if len(prepareSigs) >= ((len(consensus.PublicKeys)*2)/3+1) && consensus.state < targetState {
+	if consensus.reachesQuorum(prepareBitmap) && consensus.state < targetState {
 		utils.GetLogInstance().Debug("Enough prepares received with signatures", "num", len(prepareSigs), "state", consensus.state)
 
 		// Construct and broadcast prepared message
@@ -209,14 +240,26 @@ func (consensus *Consensus) processPrepareMessage(message *msg_pb.Message) {
 		consensus.aggregatedPrepareSig = aggSig
 
 		utils.GetLogInstance().Warn("[Consensus]", "sent prepared message", len(msgToSend))
-		consensus.host.SendMessageToGroups([]p2p.GroupID{p2p.NewGroupIDByShardID(p2p.ShardID(consensus.ShardID))}, host.ConstructP2pMessage(byte(17), msgToSend))
+		consensus.sendMessage(msg_pb.MessageType_PREPARED, msgToSend)
 
 		// Set state to targetState
 		consensus.state = targetState
+		consensus.tracePrepared(prepareBitmap)
 
 		// Leader sign the multi-sig and bitmap (for commit phase)
 		multiSigAndBitmap := append(aggSig.Serialize(), prepareBitmap.Bitmap...)
 		consensus.commitSigs[consensus.SelfAddress] = consensus.priKey.SignHash(multiSigAndBitmap)
+
+		// Keep the prepared block so it survives a view change
+		if preparedMessage, err := proto.GetConsensusMessagePayload(msgToSend); err == nil {
+			consensus.prepared = &preparedBlock{
+				block:        consensus.block,
+				sigAndBitmap: multiSigAndBitmap,
+				viewID:       consensus.viewID,
+				message:      preparedMessage,
+			}
+			copy(consensus.prepared.blockHash[:], consensus.blockHash[:])
+		}
 	}
 }
 
@@ -242,6 +285,8 @@ func (consensus *Consensus) processCommitMessage(message *msg_pb.Message) {
 		return
 	}
 
+	consensus.checkDoubleSign(message)
+
 	if err := consensus.checkConsensusMessage(message, validatorPubKey); err != nil {
 		utils.GetLogInstance().Debug("Failed to check the validator message", "validatorAddress", validatorAddress)
 		return
@@ -257,7 +302,7 @@ func (consensus *Consensus) processCommitMessage(message *msg_pb.Message) {
 		return
 	}
 
-	if len((commitSigs)) >= ((len(consensus.PublicKeys)*2)/3 + 1) {
+	if consensus.reachesQuorum(commitBitmap) {
 		utils.GetLogInstance().Debug("Received additional new commit message", "validatorAddress", validatorAddress)
 		return
 	}
@@ -279,9 +324,10 @@ func (consensus *Consensus) processCommitMessage(message *msg_pb.Message) {
 	commitSigs[validatorAddress] = &sign
 	// Set the bitmap indicating that this validator signed.
 	commitBitmap.SetKey(validatorPubKey, true)
+	consensus.logMessage(message)
 
 	targetState := CommittedDone
-	if len(commitSigs) >= ((len(consensus.PublicKeys)*2)/3+1) && consensus.state != targetState {
+	if consensus.reachesQuorum(commitBitmap) && consensus.state != targetState {
 		utils.GetLogInstance().Info("Enough commits received!", "num", len(commitSigs), "state", consensus.state)
 
 		// Construct and broadcast committed message
@@ -289,7 +335,8 @@ func (consensus *Consensus) processCommitMessage(message *msg_pb.Message) {
 		consensus.aggregatedCommitSig = aggSig
 
 		utils.GetLogInstance().Warn("[Consensus]", "sent committed message", len(msgToSend))
-		consensus.host.SendMessageToGroups([]p2p.GroupID{p2p.NewGroupIDByShardID(p2p.ShardID(consensus.ShardID))}, host.ConstructP2pMessage(byte(17), msgToSend))
+		consensus.sendMessage(msg_pb.MessageType_COMMITTED, msgToSend)
+		consensus.traceCommitted(commitBitmap)
 
 		var blockObj types.Block
 		err := rlp.DecodeBytes(consensus.block, &blockObj)
@@ -306,12 +353,7 @@ func (consensus *Consensus) processCommitMessage(message *msg_pb.Message) {
 			consensus.commitBitmap.Bitmap)
 
 		consensus.state = targetState
-
-		select {
-		case consensus.VerifiedNewBlock <- &blockObj:
-		default:
-			utils.GetLogInstance().Info("[SYNC] Failed to send consensus verified block for state sync", "blockHash", blockObj.Hash())
-		}
+		consensus.prepared = nil
 
 		consensus.reportMetrics(blockObj)
 
@@ -321,10 +363,23 @@ func (consensus *Consensus) processCommitMessage(message *msg_pb.Message) {
 		// Reset state to Finished, and clear other data.
 		consensus.ResetState()
 		consensus.consensusID++
+		// The round is finalized, its messages are no longer needed
+		consensus.pbftLog.Prune(consensus.consensusID)
 
 		consensus.OnConsensusDone(&blockObj)
 		utils.GetLogInstance().Debug("HOORAY!!!!!!! CONSENSUS REACHED!!!!!!!", "consensusID", consensus.consensusID, "numOfSignatures", len(commitSigs))
 
+		consensus.rotateLeader(&blockObj)
+		if !consensus.IsLeader() {
+			// the next block is proposed by the next leader in the schedule
+			return
+		}
+
+		// TODO: pipeline the rounds, announcing the next block as soon as this
+		// one is prepared. The next block can't link to this one before it is
+		// sealed, as the block hash covers the commit signature: the hash has
+		// to exclude the seal, and the commit signatures have to be carried by
+		// the next ANNOUNCE.
 		// TODO: remove this temporary delay
 		time.Sleep(500 * time.Millisecond)
 		// Send signal to Node so the new block can be added and new round of consensus can be triggered
@@ -220,3 +220,38 @@ func TestProcessMessageLeaderCommit(test *testing.T) {
 	assert.Equal(test, Finished, consensusLeader.state)
 	time.Sleep(1 * time.Second)
 }
+
+func TestWaitForNewBlockStop(test *testing.T) {
+	ctrl := gomock.NewController(test)
+	defer ctrl.Finish()
+
+	leader := p2p.Peer{IP: ip, Port: "7790"}
+	leaderPriKey := bls_cosi.RandPrivateKey()
+	leader.ConsensusPubKey = leaderPriKey.GetPublicKey()
+	m := mock_host.NewMockHost(ctrl)
+	m.EXPECT().GetSelfPeer().Return(leader)
+	consensusLeader, err := New(m, 0, leader, leaderPriKey)
+	if err != nil {
+		test.Fatalf("Cannot craeate consensus: %v", err)
+	}
+
+	// stopped while waiting for the start signal
+	stopChan, stoppedChan := make(chan struct{}), make(chan struct{})
+	consensusLeader.WaitForNewBlock(make(chan *types.Block), stopChan, stoppedChan, make(chan struct{}))
+	close(stopChan)
+	select {
+	case <-stoppedChan:
+	case <-time.After(time.Second):
+		test.Error("not stopped while waiting for the start signal")
+	}
+
+	// stopped while waiting for a new block
+	stopChan, stoppedChan = make(chan struct{}), make(chan struct{})
+	consensusLeader.WaitForNewBlock(make(chan *types.Block), stopChan, stoppedChan, nil)
+	close(stopChan)
+	select {
+	case <-stoppedChan:
+	case <-time.After(time.Second):
+		test.Error("not stopped while waiting for a new block")
+	}
+}
@@ -3,10 +3,12 @@ package consensus
 import (
 	"bytes"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"reflect"
+	"runtime"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/params"
@@ -16,9 +18,11 @@ import (
 	proto_discovery "github.com/harmony-one/harmony/api/proto/discovery"
 	msg_pb "github.com/harmony-one/harmony/api/proto/message"
 	consensus_engine "github.com/harmony-one/harmony/consensus/engine"
+	"github.com/harmony-one/harmony/core"
 	"github.com/harmony-one/harmony/core/state"
 	"github.com/harmony-one/harmony/core/types"
 	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/internal/ctxerror"
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/p2p"
 	"github.com/harmony-one/harmony/p2p/host"
@@ -102,6 +106,9 @@ func (consensus *Consensus) populateMessageFields(request *msg_pb.ConsensusReque
 	// 4 byte consensus id
 	request.ConsensusId = consensus.consensusID
 
+	// 4 byte view id
+	request.ViewId = consensus.viewID
+
 	// 32 byte block hash
 	request.BlockHash = consensus.blockHash[:]
 
@@ -189,17 +196,28 @@ func (consensus *Consensus) UpdatePublicKeys(pubKeys []*bls.PublicKey) int {
 		consensus.CommitteeAddresses[utils.GetBlsAddress(pubKey)] = true
 	}
 	// TODO: use pubkey to identify leader rather than p2p.Peer.
-	consensus.leader = p2p.Peer{ConsensusPubKey: pubKeys[0]}
-	utils.GetLogInstance().Info("My Leader", "info", hex.EncodeToString(consensus.leader.ConsensusPubKey.Serialize()))
+	if leaderKey := consensus.leaderKeyForView(consensus.viewID); leaderKey != nil {
+		consensus.leader = p2p.Peer{ConsensusPubKey: leaderKey}
+		utils.GetLogInstance().Info("My Leader", "info", hex.EncodeToString(leaderKey.Serialize()))
+	} else {
+		// the leader schedule is unknown until the chain has the epoch block
+		utils.GetLogInstance().Warn("Keeping my leader, the leader of the view is unknown", "viewID", consensus.viewID)
+	}
 	utils.GetLogInstance().Info("My Committee", "info", consensus.PublicKeys)
 	consensus.pubKeyLock.Unlock()
 
 	return len(consensus.PublicKeys)
 }
 
-// NewFaker returns a faker consensus.
+// NewFaker returns a faker consensus, which accepts all seals.
 func NewFaker() *Consensus {
-	return &Consensus{}
+	return &Consensus{fakeSeal: true}
+}
+
+// NewVerifier returns a consensus which only verifies the seals of the blocks
+// of the given shard, for processing blocks off the network.
+func NewVerifier(shardID uint32) *Consensus {
+	return &Consensus{ShardID: shardID}
 }
 
 // VerifyHeader checks whether a header conforms to the consensus rules of the bft engine.
@@ -220,27 +238,193 @@ func (consensus *Consensus) VerifyHeader(chain consensus_engine.ChainReader, hea
 // concurrently. The method returns a quit channel to abort the operations and
 // a results channel to retrieve the async verifications.
 func (consensus *Consensus) VerifyHeaders(chain consensus_engine.ChainReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
-	abort, results := make(chan struct{}), make(chan error, len(headers))
-	for i := 0; i < len(headers); i++ {
-		results <- nil
+	// Nothing to verify, and no worker to feed
+	if len(headers) == 0 {
+		return make(chan struct{}), make(chan error)
+	}
+	// Spawn as many workers as allowed threads
+	workers := runtime.GOMAXPROCS(0)
+	if len(headers) < workers {
+		workers = len(headers)
+	}
+
+	// Create a task channel and spawn the verifiers
+	var (
+		inputs = make(chan int)
+		done   = make(chan int, workers)
+		errs   = make([]error, len(headers))
+		abort  = make(chan struct{})
+	)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for index := range inputs {
+				errs[index] = consensus.verifyHeaderWorker(chain, headers, seals, index)
+				done <- index
+			}
+		}()
+	}
+
+	errorsOut := make(chan error, len(headers))
+	go func() {
+		defer close(inputs)
+		var (
+			in, out = 0, 0
+			checked = make([]bool, len(headers))
+			inputs  = inputs
+		)
+		for {
+			select {
+			case inputs <- in:
+				if in++; in == len(headers) {
+					// Reached end of headers. Stop sending to workers.
+					inputs = nil
+				}
+			case index := <-done:
+				for checked[index] = true; checked[out]; out++ {
+					errorsOut <- errs[out]
+					if out == len(headers)-1 {
+						return
+					}
+				}
+			case <-abort:
+				return
+			}
+		}
+	}()
+	return abort, errorsOut
+}
+
+// verifyHeaderWorker verifies the header at the given index of the batch,
+// using the previous header of the batch as its parent if possible.
+func (consensus *Consensus) verifyHeaderWorker(chain consensus_engine.ChainReader, headers []*types.Header, seals []bool, index int) error {
+	var parent *types.Header
+	if index == 0 {
+		parent = chain.GetHeader(headers[0].ParentHash, headers[0].Number.Uint64()-1)
+	} else if headers[index-1].Hash() == headers[index].ParentHash {
+		parent = headers[index-1]
 	}
-	return abort, results
+	if parent == nil {
+		return consensus_engine.ErrUnknownAncestor
+	}
+	if seals[index] {
+		return consensus.VerifySeal(chain, headers[index])
+	}
+	return nil
 }
 
-// VerifySeal implements consensus.Engine, checking whether the given block satisfies
-// the PoW difficulty requirements.
+// VerifySeal implements consensus.Engine, checking whether the given block is signed
+// by a quorum of the committee in the shard state of the block's signer epoch,
+// weighted by the stakes recorded in the committee.
 func (consensus *Consensus) VerifySeal(chain consensus_engine.ChainReader, header *types.Header) error {
+	if consensus.fakeSeal {
+		return nil
+	}
+	committee, err := readSigningCommittee(chain, header)
+	if err != nil {
+		return ctxerror.New("cannot read committee of the block",
+			"blockNum", header.Number,
+		).WithCause(err)
+	}
+	publicKeys, err := committee.BLSPublicKeys()
+	if err != nil {
+		return ctxerror.New("cannot read the public keys of the committee of the block",
+			"blockNum", header.Number,
+		).WithCause(err)
+	}
+	policy := consensus.policy
+	if policy == nil {
+		policy = committeePolicy(committee)
+	}
+	// The prepare signature is on the block hash before the signatures are set
+	if err := verifyMultiSig(publicKeys, policy, header.PrepareSignature[:], header.PrepareBitmap, unsealedHash(header).Bytes()); err != nil {
+		return ctxerror.New("failed to verify the prepare signature",
+			"blockNum", header.Number,
+		).WithCause(err)
+	}
+	// The commit signature is on the prepare signature and bitmap
+	prepareSigAndBitmap := append(header.PrepareSignature[:], header.PrepareBitmap...)
+	if err := verifyMultiSig(publicKeys, policy, header.CommitSignature[:], header.CommitBitmap, prepareSigAndBitmap); err != nil {
+		return ctxerror.New("failed to verify the commit signature",
+			"blockNum", header.Number,
+		).WithCause(err)
+	}
+	return nil
+}
+
+// readSigningCommittee returns the committee which signs the given header: an
+// epoch block is signed by the committee of the previous epoch, whose shard
+// state is already in the chain.
+func readSigningCommittee(chain consensus_engine.ChainReader, header *types.Header) (*types.Committee, error) {
+	shardID := binary.BigEndian.Uint32(header.ShardID[:])
+	return chain.CommitteeForEpoch(shardID, core.GetSignerEpochFromBlockNumber(header.Number.Uint64()))
+}
+
+// UpdateCommittee updates the public keys to the committee of the shard for
+// the given epoch, as recorded in the chain, and returns them.
+func (consensus *Consensus) UpdateCommittee(epoch uint64) ([]*bls.PublicKey, error) {
+	if consensus.ChainReader == nil {
+		return nil, errors.New("ChainReader is nil")
+	}
+	committee, err := consensus.ChainReader.CommitteeForEpoch(consensus.ShardID, epoch)
+	if err != nil {
+		return nil, err
+	}
+	publicKeys, err := committee.BLSPublicKeys()
+	if err != nil {
+		return nil, err
+	}
+	consensus.UpdatePublicKeys(publicKeys)
+	return publicKeys, nil
+}
+
+// verifyMultiSig checks the aggregated signature of the signers in the bitmap on the given hash,
+// and that the signers reach the quorum according to the given policy.
+func verifyMultiSig(publicKeys []*bls.PublicKey, policy bls_cosi.Policy, sig []byte, bitmap []byte, hash []byte) error {
+	mask, err := bls_cosi.NewMask(publicKeys, nil)
+	if err != nil {
+		return ctxerror.New("cannot create group sig mask").WithCause(err)
+	}
+	if err := mask.SetMask(bitmap); err != nil {
+		return ctxerror.New("cannot set group sig mask bits").WithCause(err)
+	}
+	if !policy.Check(mask) {
+		return ctxerror.New("signers don't reach the quorum",
+			"numSigners", mask.CountEnabled(),
+			"numPublicKeys", len(publicKeys),
+		).WithCause(consensus_engine.ErrNotEnoughSignatures)
+	}
+	var multiSig bls.Sign
+	if err := multiSig.Deserialize(sig); err != nil {
+		return ctxerror.New("cannot deserialize the multi signature").WithCause(err)
+	}
+	if !multiSig.VerifyHash(mask.AggregatePublic, hash) {
+		return consensus_engine.ErrInvalidSeal
+	}
 	return nil
 }
 
+// unsealedHash returns the hash of the header before the consensus signatures are set,
+// i.e. the block hash signed by the committee in the prepare phase.
+func unsealedHash(header *types.Header) common.Hash {
+	unsealed := types.CopyHeader(header)
+	unsealed.PrepareSignature = [48]byte{}
+	unsealed.PrepareBitmap = nil
+	unsealed.CommitSignature = [48]byte{}
+	unsealed.CommitBitmap = nil
+	return unsealed.Hash()
+}
+
 // Finalize implements consensus.Engine, accumulating the block and uncle rewards,
-// setting the final state and assembling the block.
-func (consensus *Consensus) Finalize(chain consensus_engine.ChainReader, header *types.Header, state *state.DB, txs []*types.Transaction, receipts []*types.Receipt) (*types.Block, error) {
+// slashing the double signers, setting the final state and assembling the block.
+func (consensus *Consensus) Finalize(chain consensus_engine.ChainReader, header *types.Header, state *state.DB, txs []*types.Transaction, receipts []*types.Receipt, doubleSigns []*types.DoubleSignEvidence) (*types.Block, error) {
 	// Accumulate any block and uncle rewards and commit the final state root
 	// Header seems complete, assemble into a block and return
 	accumulateRewards(chain.Config(), state, header)
+	if err := consensus.slashDoubleSigners(chain, header, state, doubleSigns); err != nil {
+		return nil, err
+	}
 	header.Root = state.IntermediateRoot(false)
-	return types.NewBlock(header, txs, receipts), nil
+	return types.NewBlock(header, txs, receipts).WithDoubleSigns(doubleSigns), nil
 }
 
 // Author returns the author of the block header.
@@ -321,7 +505,7 @@ func (consensus *Consensus) ResetState() {
 // Returns a string representation of this consensus
 func (consensus *Consensus) String() string {
 	var duty string
-	if consensus.IsLeader {
+	if consensus.IsLeader() {
 		duty = "LDR" // leader
 	} else {
 		duty = "VLD" // validator
@@ -436,6 +620,8 @@ func verifyMessageSig(signerPubKey *bls.PublicKey, message *msg_pb.Message) erro
 	signature := message.Signature
 	message.Signature = nil
 	messageBytes, err := protobuf.Marshal(message)
+	// keep the signature so the message can still be logged or relayed as signed
+	message.Signature = signature
 	if err != nil {
 		return err
 	}
@@ -457,6 +643,24 @@ func (consensus *Consensus) SetConsensusID(height uint32) {
 	consensus.consensusID = height
 }
 
+// PbftLog returns the log of the messages received during PBFT process.
+func (consensus *Consensus) PbftLog() *PbftLog {
+	return consensus.pbftLog
+}
+
+// SetPbftLog sets the log of the messages received during PBFT process,
+// e.g. a log persisted to the database so a restarted node can rebuild its in-flight round.
+// The messages of the finalized rounds are pruned, and the log replaced stops
+// being persisted.
+func (consensus *Consensus) SetPbftLog(pbftLog *PbftLog) {
+	if consensus.pbftLog != nil && consensus.pbftLog != pbftLog {
+		consensus.pbftLog.Close()
+	}
+	pbftLog.Prune(consensus.consensusID)
+	consensus.pbftLog = pbftLog
+	consensus.replayedID = consensus.consensusID
+}
+
 // RegisterPRndChannel registers the channel for receiving randomness preimage from DRG protocol
 func (consensus *Consensus) RegisterPRndChannel(pRndChannel chan []byte) {
 	consensus.PRndChannel = pRndChannel
@@ -501,3 +705,13 @@ func (consensus *Consensus) checkConsensusMessage(message *msg_pb.Message, publi
 	}
 	return nil
 }
+
+// logMessage adds a verified message of the current round into the pbft log.
+func (consensus *Consensus) logMessage(message *msg_pb.Message) {
+	pbftMsg, err := ParsePbftMessage(message)
+	if err != nil {
+		ctxerror.Log15(utils.GetLogInstance().Warn, err)
+		return
+	}
+	consensus.pbftLog.AddMessage(pbftMsg)
+}
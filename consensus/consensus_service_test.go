@@ -2,10 +2,15 @@ package consensus
 
 import (
 	"bytes"
+	"math/big"
+	"runtime"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	bls_core "github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/core"
 
+	"github.com/harmony-one/harmony/core/types"
 	"github.com/harmony-one/harmony/crypto/bls"
 
 	msg_pb "github.com/harmony-one/harmony/api/proto/message"
@@ -112,3 +117,88 @@ func TestSetConsensusID(t *testing.T) {
 		t.Errorf("Cannot set consensus ID. Got: %v, Expected: %v", consensus.consensusID, height)
 	}
 }
+
+type shardStateChainReader struct {
+	MockChainReader
+	shardState types.ShardState
+}
+
+func (cr shardStateChainReader) ReadShardState(epoch uint64) (types.ShardState, error) {
+	return cr.shardState, nil
+}
+
+func (cr shardStateChainReader) CommitteeForEpoch(shardID uint32, epoch uint64) (*types.Committee, error) {
+	if committee := cr.shardState.FindCommitteeByID(shardID); committee != nil {
+		return committee, nil
+	}
+	return nil, core.ErrCommitteeNotFound
+}
+
+// signHeader seals the header with the signatures of the given committee members
+func signHeader(header *types.Header, priKeys []*bls_core.SecretKey, pubKeys []*bls_core.PublicKey, numSigners int) {
+	prepareMask, _ := bls.NewMask(pubKeys, nil)
+	commitMask, _ := bls.NewMask(pubKeys, nil)
+	hash := unsealedHash(header)
+	prepareSigs := []*bls_core.Sign{}
+	for i := 0; i < numSigners; i++ {
+		prepareSigs = append(prepareSigs, priKeys[i].SignHash(hash[:]))
+		prepareMask.SetKey(pubKeys[i], true)
+	}
+	copy(header.PrepareSignature[:], bls.AggregateSig(prepareSigs).Serialize())
+	header.PrepareBitmap = prepareMask.Bitmap
+
+	prepareSigAndBitmap := append(header.PrepareSignature[:], header.PrepareBitmap...)
+	commitSigs := []*bls_core.Sign{}
+	for i := 0; i < numSigners; i++ {
+		commitSigs = append(commitSigs, priKeys[i].SignHash(prepareSigAndBitmap))
+		commitMask.SetKey(pubKeys[i], true)
+	}
+	copy(header.CommitSignature[:], bls.AggregateSig(commitSigs).Serialize())
+	header.CommitBitmap = commitMask.Bitmap
+}
+
+func TestVerifySeal(t *testing.T) {
+	priKeys := []*bls_core.SecretKey{}
+	pubKeys := []*bls_core.PublicKey{}
+	committee := types.Committee{ShardID: 0}
+	for i := 0; i < 4; i++ {
+		priKey := bls.RandPrivateKey()
+		priKeys = append(priKeys, priKey)
+		pubKeys = append(pubKeys, priKey.GetPublicKey())
+		nodeID := types.NodeID{}
+		nodeID.BlsPublicKey.FromLibBLSPublicKey(priKey.GetPublicKey())
+		committee.NodeList = append(committee.NodeList, nodeID)
+	}
+	chain := shardStateChainReader{shardState: types.ShardState{committee}}
+	consensus := &Consensus{}
+
+	header := &types.Header{Number: big.NewInt(1), Time: big.NewInt(0), Difficulty: big.NewInt(0)}
+	signHeader(header, priKeys, pubKeys, 3)
+	if err := consensus.VerifySeal(chain, header); err != nil {
+		t.Errorf("Failed to verify a valid seal: %v", err)
+	}
+
+	header.Number = big.NewInt(2)
+	if err := consensus.VerifySeal(chain, header); err == nil {
+		t.Error("Seal of another header should not be verified")
+	}
+
+	header = &types.Header{Number: big.NewInt(1), Time: big.NewInt(0), Difficulty: big.NewInt(0)}
+	signHeader(header, priKeys, pubKeys, 2)
+	if err := consensus.VerifySeal(chain, header); err == nil {
+		t.Error("Seal without quorum should not be verified")
+	}
+
+	if err := NewFaker().VerifySeal(chain, &types.Header{}); err != nil {
+		t.Errorf("Faker should accept any seal: %v", err)
+	}
+}
+
+func TestVerifyHeadersWithoutHeaders(t *testing.T) {
+	before := runtime.NumGoroutine()
+	abort, _ := (&Consensus{}).VerifyHeaders(nil, nil, nil)
+	close(abort)
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("%d goroutines left verifying no header", after-before)
+	}
+}
@@ -1,8 +1,14 @@
 package consensus
 
 import (
+	"math/big"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
+	bls_core "github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/contracts/structs"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/types"
 	"github.com/harmony-one/harmony/crypto/bls"
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/p2p"
@@ -24,7 +30,7 @@ func TestNew(test *testing.T) {
 		test.Errorf("Consensus Id is initialized to the wrong value: %d", consensus.consensusID)
 	}
 
-	if !consensus.IsLeader {
+	if !consensus.IsLeader() {
 		test.Error("Consensus should belong to a leader")
 	}
 
@@ -32,3 +38,90 @@ func TestNew(test *testing.T) {
 		test.Error("Consensus ReadySignal should be initialized")
 	}
 }
+
+type fixedStakeInfoFinder map[*bls_core.PublicKey]*big.Int
+
+func (f fixedStakeInfoFinder) FindStakeInfoByNodeKey(key *bls_core.PublicKey) []*structs.StakeInfo {
+	return []*structs.StakeInfo{{Amount: f[key]}}
+}
+
+func (f fixedStakeInfoFinder) FindStakeInfoByAccount(addr common.Address) []*structs.StakeInfo {
+	return nil
+}
+
+// epochCommitteeChainReader reads the committees of shard 0 at each epoch.
+type epochCommitteeChainReader struct {
+	MockChainReader
+	committees []types.Committee
+}
+
+func (cr epochCommitteeChainReader) CommitteeForEpoch(shardID uint32, epoch uint64) (*types.Committee, error) {
+	if shardID != 0 || epoch >= uint64(len(cr.committees)) {
+		return nil, core.ErrCommitteeNotFound
+	}
+	return &cr.committees[epoch], nil
+}
+
+// stakedCommittee returns the committee of shard 0 made of the given members
+// with the given stakes.
+func stakedCommittee(publicKeys []*bls_core.PublicKey, stakes ...int64) types.Committee {
+	committee := types.Committee{}
+	for i, publicKey := range publicKeys {
+		nodeID := types.NodeID{}
+		nodeID.BlsPublicKey.FromLibBLSPublicKey(publicKey)
+		committee.NodeList = append(committee.NodeList, nodeID)
+		committee.Stakes = append(committee.Stakes, big.NewInt(stakes[i]))
+	}
+	return committee
+}
+
+func TestQuorumPolicy(test *testing.T) {
+	pubKey1 := bls.RandPrivateKey().GetPublicKey()
+	pubKey2 := bls.RandPrivateKey().GetPublicKey()
+	pubKey3 := bls.RandPrivateKey().GetPublicKey()
+	pubKey4 := bls.RandPrivateKey().GetPublicKey()
+	publicKeys := []*bls_core.PublicKey{pubKey1, pubKey2, pubKey3, pubKey4}
+
+	consensus := &Consensus{}
+	if _, err := consensus.QuorumPolicy(); err == nil {
+		test.Error("Quorum policy should not be found without chain")
+	}
+	consensus.ChainReader = epochCommitteeChainReader{}
+	if _, err := consensus.QuorumPolicy(); err == nil {
+		test.Error("Quorum policy should not be found without committee")
+	}
+
+	consensus.ChainReader = epochCommitteeChainReader{committees: []types.Committee{
+		stakedCommittee(publicKeys, 80, 10, 10, 0),
+		stakedCommittee(publicKeys, 10, 10, 80, 0),
+	}}
+	mask, _ := bls.NewMask(publicKeys, nil)
+	mask.SetKey(pubKey1, true)
+	if consensus.reachesQuorum(mask) {
+		test.Error("A single signer holding 80% of the stake should not reach the quorum")
+	}
+	mask.SetKey(pubKey2, true)
+	mask.SetKey(pubKey4, true)
+	if !consensus.reachesQuorum(mask) {
+		test.Error("3 out of 4 signers holding 90% of the stake should reach the quorum")
+	}
+	mask.SetKey(pubKey1, false)
+	mask.SetKey(pubKey3, true)
+	if consensus.reachesQuorum(mask) {
+		test.Error("Signers holding 20% of the stake should not reach the quorum")
+	}
+
+	// The stakes of the committee of the signers weight the signers
+	epochBlock := core.GetBlockNumberFromEpoch(1)
+	if policy, err := consensus.quorumPolicyOfBlock(epochBlock); err != nil || policy.Check(mask) {
+		test.Errorf("Signers of the epoch block should be weighted by the stakes of the previous epoch: %v", err)
+	}
+	if policy, err := consensus.quorumPolicyOfBlock(epochBlock + 1); err != nil || !policy.Check(mask) {
+		test.Errorf("Signers holding 90%% of the stake of the epoch should reach the quorum: %v", err)
+	}
+
+	consensus.SetQuorumPolicy(bls.CompletePolicy{})
+	if policy, _ := consensus.QuorumPolicy(); policy != (bls.CompletePolicy{}) {
+		test.Errorf("Quorum policy should be the one set explicitly, got %T", policy)
+	}
+}
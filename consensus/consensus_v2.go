@@ -19,17 +19,17 @@ func (consensus *Consensus) Start(stopChan chan struct{}, stoppedChan chan struc
 			msg := consensus.recvWithTimeout(receiveTimeout)
 			consensus.handleMessageUpdate(msg)
 			if consensus.idleTimeout.CheckExpire() {
-				consensus.startViewChange(consensus.consensusID + 1)
+				consensus.startViewChange(consensus.viewID + 1)
 			}
 			if consensus.commitTimeout.CheckExpire() {
-				consensus.startViewChange(consensus.consensusID + 1)
+				consensus.startViewChange(consensus.viewID + 1)
 			}
 			if consensus.viewChangeTimeout.CheckExpire() {
 				if consensus.mode.Mode() == Normal {
 					continue
 				}
-				consensusID := consensus.mode.ConsensusID()
-				consensus.startViewChange(consensusID + 1)
+				viewID := consensus.mode.ViewID()
+				consensus.startViewChange(viewID + 1)
 			}
 		case <-tick.C:
 			consensus.tryPublishBlock()
@@ -72,9 +72,9 @@ func (consensus *Consensus) handleMessageUpdate(payload []byte) {
 	case msg_pb.MessageType_COMMITTED:
 		consensus.onCommitted()
 	case msg_pb.MessageType_VIEWCHANGE:
-		consensus.onViewChange()
+		consensus.onViewChange(msg)
 	case msg_pb.MessageType_NEWVIEW:
-		consensus.onNewView()
+		consensus.onNewView(msg)
 	case msg_pb.MessageType_NEWBLOCK:
 		consensus.onNewBlock()
 	case msg_pb.MessageType_COMMITBLOCK:
@@ -85,7 +85,7 @@ func (consensus *Consensus) handleMessageUpdate(payload []byte) {
 }
 
 func (consensus *Consensus) onAnnounce(msg *msg_pb.Message) {
-	if consensus.IsLeader {
+	if consensus.IsLeader() {
 		return
 	}
 	consensus.processAnnounceMessage(msg)
@@ -104,11 +104,11 @@ func (consensus *Consensus) onCommit() {
 func (consensus *Consensus) onCommitted() {
 	return
 }
-func (consensus *Consensus) onViewChange() {
-	return
+func (consensus *Consensus) onViewChange(msg *msg_pb.Message) {
+	consensus.processViewChangeMessage(msg)
 }
-func (consensus *Consensus) onNewView() {
-	return
+func (consensus *Consensus) onNewView(msg *msg_pb.Message) {
+	consensus.processNewViewMessage(msg)
 }
 
 func (consensus *Consensus) onNewBlock() {
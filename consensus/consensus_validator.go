@@ -9,9 +9,8 @@ import (
 	"github.com/harmony-one/harmony/core/types"
 	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
 	"github.com/harmony-one/harmony/internal/attack"
+	"github.com/harmony-one/harmony/internal/ctxerror"
 	"github.com/harmony-one/harmony/internal/utils"
-	"github.com/harmony-one/harmony/p2p"
-	"github.com/harmony-one/harmony/p2p/host"
 )
 
 // IsValidatorMessage checks if a message is to be sent to a validator.
@@ -27,10 +26,29 @@ func (consensus *Consensus) ProcessMessageValidator(payload []byte) {
 		utils.GetLogInstance().Error("Failed to unmarshal message payload.", "err", err, "consensus", consensus)
 	}
 
-	if !consensus.IsValidatorMessage(message) {
+	// view change messages are sent to the next leader, which is still a validator
+	if !consensus.IsValidatorMessage(message) && message.Type != msg_pb.MessageType_VIEWCHANGE {
 		return
 	}
 
+	consensus.replayBufferedMessages()
+	if consensus.bufferFutureMessage(message) {
+		return
+	}
+
+	if message.Type == msg_pb.MessageType_PREPARED {
+		consensus.catchUpView(message)
+	}
+
+	if consensus.mode.Mode() == ViewChanging {
+		switch message.Type {
+		case msg_pb.MessageType_ANNOUNCE, msg_pb.MessageType_PREPARED, msg_pb.MessageType_COMMITTED:
+			// ignore the messages from the leader being replaced
+			utils.GetLogInstance().Debug("Ignoring message during view change", "msgType", message.Type)
+			return
+		}
+	}
+
 	switch message.Type {
 	case msg_pb.MessageType_ANNOUNCE:
 		consensus.processAnnounceMessage(message)
@@ -38,15 +56,93 @@ func (consensus *Consensus) ProcessMessageValidator(payload []byte) {
 		consensus.processPreparedMessage(message)
 	case msg_pb.MessageType_COMMITTED:
 		consensus.processCommittedMessage(message)
-	case msg_pb.MessageType_PREPARE:
-	case msg_pb.MessageType_COMMIT:
-		// ignore consensus message that is only meant to sent to leader
+	case msg_pb.MessageType_VIEWCHANGE:
+		consensus.processViewChangeMessage(message)
+	case msg_pb.MessageType_NEWVIEW:
+		consensus.processNewViewMessage(message)
+	case msg_pb.MessageType_PREPARE, msg_pb.MessageType_COMMIT:
+		// consensus message that is only meant to sent to leader
 		// since we use pubsub, the relay node will also receive those message
-		// but we should just ignore them
+		// we only watch them for double signs
+		consensus.checkDoubleSign(message)
 
 	default:
 		utils.GetLogInstance().Error("Unexpected message type", "msgType", message.Type, "consensus", consensus)
 	}
+	consensus.replayBufferedMessages()
+}
+
+// bufferFutureMessage keeps a leader message of the next round in the pbft
+// log, to be replayed once this node reaches that round. Only the messages
+// signed by the leader expected to propose the next block are kept; the
+// messages of the rounds after it are dropped, and notify state syncing to
+// start instead, as the node is too far behind to catch up with buffered
+// messages alone.
+// It returns true if the message is of a later round, buffered or not.
+func (consensus *Consensus) bufferFutureMessage(message *msg_pb.Message) bool {
+	switch message.Type {
+	case msg_pb.MessageType_ANNOUNCE, msg_pb.MessageType_PREPARED, msg_pb.MessageType_COMMITTED:
+	default:
+		return false
+	}
+	consensusMsg := message.GetConsensus()
+	if consensusMsg == nil || consensus.ignoreConsensusIDCheck || consensusMsg.ConsensusId <= consensus.consensusID {
+		return false
+	}
+	if consensusMsg.ConsensusId > consensus.consensusID+1 {
+		utils.GetLogInstance().Debug("Not buffering message of a round after the next one", "myConsensusId", consensus.consensusID, "theirConsensusId", consensusMsg.ConsensusId)
+		select {
+		case consensus.ConsensusIDLowChan <- struct{}{}:
+		default:
+		}
+		return true
+	}
+	senderKey, err := bls_cosi.BytesToBlsPublicKey(consensusMsg.SenderPubkey)
+	if err != nil {
+		utils.GetLogInstance().Debug("Failed to deserialize BLS public key", "error", err)
+		return true
+	}
+	leaderKey := consensus.leaderKeyForBlock(consensus.nextBlockNumber()+1, consensus.viewID)
+	if leaderKey == nil || !senderKey.IsEqual(leaderKey) {
+		utils.GetLogInstance().Debug("Not buffering message of a sender other than the next leader", "senderAddress", utils.GetBlsAddress(senderKey))
+		return true
+	}
+	if err := verifyMessageSig(senderKey, message); err != nil {
+		utils.GetLogInstance().Debug("Not buffering message with an invalid signature", "error", err)
+		return true
+	}
+	pbftMsg, err := ParsePbftMessage(message)
+	if err != nil {
+		ctxerror.Log15(utils.GetLogInstance().Warn, err)
+		return false
+	}
+	if consensus.pbftLog.AddMessage(pbftMsg) {
+		utils.GetLogInstance().Debug("Buffered message of the next round", "msgType", message.Type, "myConsensusId", consensus.consensusID, "theirConsensusId", consensusMsg.ConsensusId)
+	}
+	return true
+}
+
+// replayBufferedMessages processes the messages of the current round kept in the pbft log,
+// i.e. those buffered before this node reached the round, or restored after a restart.
+func (consensus *Consensus) replayBufferedMessages() {
+	for consensus.consensusID >= consensus.replayedID {
+		consensusID := consensus.consensusID
+		consensus.replayedID = consensusID + 1
+		for _, pbftMsg := range consensus.pbftLog.GetMessagesByConsensusID(consensusID) {
+			if consensus.consensusID != consensusID {
+				break
+			}
+			utils.GetLogInstance().Debug("Replaying buffered message", "msgType", pbftMsg.MessageType, "consensusID", consensusID)
+			switch pbftMsg.MessageType {
+			case msg_pb.MessageType_ANNOUNCE:
+				consensus.processAnnounceMessage(pbftMsg.Message())
+			case msg_pb.MessageType_PREPARED:
+				consensus.processPreparedMessage(pbftMsg.Message())
+			case msg_pb.MessageType_COMMITTED:
+				consensus.processCommittedMessage(pbftMsg.Message())
+			}
+		}
+	}
 }
 
 // Processes the announce message sent from the leader
@@ -55,15 +151,9 @@ func (consensus *Consensus) processAnnounceMessage(message *msg_pb.Message) {
 
 	consensusMsg := message.GetConsensus()
 
-	consensusID := consensusMsg.ConsensusId
 	blockHash := consensusMsg.BlockHash
 	block := consensusMsg.Payload
 
-	// Add block to received block cache
-	consensus.mutex.Lock()
-	consensus.blocksReceived[consensusID] = &BlockConsensusStatus{block, consensus.state}
-	consensus.mutex.Unlock()
-
 	copy(consensus.blockHash[:], blockHash[:])
 	consensus.block = block
 
@@ -92,12 +182,24 @@ func (consensus *Consensus) processAnnounceMessage(message *msg_pb.Message) {
 		return
 	}
 
+	// Add block to received block cache
+	consensus.logMessage(message)
+
 	// Construct and send prepare message
 	msgToSend := consensus.constructPrepareMessage()
 	utils.GetLogInstance().Warn("[Consensus]", "sent prepare message", len(msgToSend))
-	consensus.host.SendMessageToGroups([]p2p.GroupID{p2p.NewGroupIDByShardID(p2p.ShardID(consensus.ShardID))}, host.ConstructP2pMessage(byte(17), msgToSend))
+	consensus.traceAnnounce()
+	consensus.sendMessage(msg_pb.MessageType_PREPARE, msgToSend)
 
 	consensus.state = PrepareDone
+
+	// the leader proposed a block, now it has to commit it in time.
+	// The idle timer only starts once the leader proposed its first block, so validators
+	// don't give up on a leader still waiting for the committee to be formed.
+	consensus.commitTimeout.Start()
+	if !consensus.idleTimeout.IsActive() {
+		consensus.idleTimeout.Start()
+	}
 }
 
 // Processes the prepared message sent from the leader
@@ -158,14 +260,36 @@ func (consensus *Consensus) processPreparedMessage(message *msg_pb.Message) {
 		utils.GetLogInstance().Warn("Failed to verify the multi signature for prepare phase", "Error", err, "leader Address", leaderAddress, "PubKeys", len(consensus.PublicKeys))
 		return
 	}
+	if !consensus.reachesQuorum(mask) {
+		utils.GetLogInstance().Warn("Not enough prepare signatures", "numSigners", mask.CountEnabled(), "leader Address", leaderAddress)
+		return
+	}
 	consensus.aggregatedPrepareSig = &deserializedMultiSig
 	consensus.prepareBitmap = mask
+	consensus.logMessage(message)
 
 	// Construct and send the commit message
 	multiSigAndBitmap := append(multiSig, bitmap...)
+
+	// Keep the prepared block so it survives a view change, with the message
+	// proving the view it was prepared in
+	preparedMessage, err := protobuf.Marshal(message)
+	if err != nil {
+		utils.GetLogInstance().Warn("Failed to marshal the prepared message", "error", err)
+		return
+	}
+	consensus.prepared = &preparedBlock{
+		block:        consensus.block,
+		sigAndBitmap: multiSigAndBitmap,
+		viewID:       consensusMsg.ViewId,
+		message:      preparedMessage,
+	}
+	copy(consensus.prepared.blockHash[:], blockHash)
+
 	msgToSend := consensus.constructCommitMessage(multiSigAndBitmap)
 	utils.GetLogInstance().Warn("[Consensus]", "sent commit message", len(msgToSend))
-	consensus.host.SendMessageToGroups([]p2p.GroupID{p2p.NewGroupIDByShardID(p2p.ShardID(consensus.ShardID))}, host.ConstructP2pMessage(byte(17), msgToSend))
+	consensus.tracePrepared(mask)
+	consensus.sendMessage(msg_pb.MessageType_COMMIT, msgToSend)
 
 	consensus.state = CommitDone
 }
@@ -226,52 +350,41 @@ func (consensus *Consensus) processCommittedMessage(message *msg_pb.Message) {
 		utils.GetLogInstance().Warn("Failed to verify the multi signature for commit phase", "Error", err, "leader Address", leaderAddress)
 		return
 	}
+	if !consensus.reachesQuorum(mask) {
+		utils.GetLogInstance().Warn("Not enough commit signatures", "numSigners", mask.CountEnabled(), "leader Address", leaderAddress)
+		return
+	}
 	consensus.aggregatedCommitSig = &deserializedMultiSig
 	consensus.commitBitmap = mask
+	consensus.logMessage(message)
+	consensus.traceCommitted(mask)
 
 	consensus.state = CommittedDone
-	// TODO: the block catch up logic is a temporary workaround for full failure node catchup. Implement the full node catchup logic
-	// The logic is to roll up to the latest blocks one by one to try catching up with the leader.
-	// but because of checkConsensusMessage, the catchup logic will never be used here
-	for {
-		val, ok := consensus.blocksReceived[consensus.consensusID]
-		if ok {
-			delete(consensus.blocksReceived, consensus.consensusID)
-
-			consensus.blockHash = [32]byte{}
-			consensus.consensusID = consensusID + 1 // roll up one by one, until the next block is not received yet.
-
-			var blockObj types.Block
-			err := rlp.DecodeBytes(val.block, &blockObj)
-			if err != nil {
-				utils.GetLogInstance().Debug("failed to construct the new block after consensus")
-			}
-			// check block data (transactions
-			if err := consensus.VerifyHeader(consensus.ChainReader, blockObj.Header(), false); err != nil {
-				utils.GetLogInstance().Debug("[WARNING] Block content is not verified successfully", "consensusID", consensus.consensusID)
-				return
-			}
-
-			// Put the signatures into the block
-			blockObj.SetPrepareSig(
-				consensus.aggregatedPrepareSig.Serialize(),
-				consensus.prepareBitmap.Bitmap)
-			blockObj.SetCommitSig(
-				consensus.aggregatedCommitSig.Serialize(),
-				consensus.commitBitmap.Bitmap)
-			utils.GetLogInstance().Info("Adding block to chain", "numTx", len(blockObj.Transactions()))
-			consensus.OnConsensusDone(&blockObj)
-			consensus.ResetState()
-
-			select {
-			case consensus.VerifiedNewBlock <- &blockObj:
-			default:
-				utils.GetLogInstance().Info("[SYNC] consensus verified block send to chan failed", "blockHash", blockObj.Hash())
-				continue
-			}
-		} else {
-			break
-		}
+	consensus.prepared = nil
+	consensus.commitTimeout.Stop()
+	consensus.idleTimeout.Start()
 
+	blockObj := consensus.pbftLog.GetBlockByHash(consensus.blockHash)
+	if blockObj == nil {
+		utils.GetLogInstance().Debug("failed to find the committed block in the pbft log", "blockHash", common.Hash(consensus.blockHash))
+		return
 	}
+	consensus.blockHash = [32]byte{}
+	consensus.consensusID = consensusID + 1
+
+	// Put the signatures into a copy of the block: the logged block has its
+	// unsealed hash cached, and is not to be modified
+	blockObj = blockObj.WithSeal(blockObj.Header())
+	blockObj.SetPrepareSig(
+		consensus.aggregatedPrepareSig.Serialize(),
+		consensus.prepareBitmap.Bitmap)
+	blockObj.SetCommitSig(
+		consensus.aggregatedCommitSig.Serialize(),
+		consensus.commitBitmap.Bitmap)
+	utils.GetLogInstance().Info("Adding block to chain", "numTx", len(blockObj.Transactions()))
+	consensus.OnConsensusDone(blockObj)
+	consensus.ResetState()
+	consensus.rotateLeader(blockObj)
+	// The round is finalized, its messages are no longer needed
+	consensus.pbftLog.Prune(consensus.consensusID)
 }
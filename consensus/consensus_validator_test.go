@@ -12,6 +12,7 @@ import (
 	protobuf "github.com/golang/protobuf/proto"
 	"github.com/harmony-one/harmony/api/proto"
 	msg_pb "github.com/harmony-one/harmony/api/proto/message"
+	"github.com/harmony-one/harmony/core"
 	"github.com/harmony-one/harmony/core/types"
 	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
 	"github.com/harmony-one/harmony/internal/utils"
@@ -48,6 +49,21 @@ func (MockChainReader) GetBlock(hash common.Hash, number uint64) *types.Block {
 	return &types.Block{}
 }
 
+// ReadShardState retrieves sharding state given the epoch number.
+func (MockChainReader) ReadShardState(epoch uint64) (types.ShardState, error) {
+	return types.ShardState{}, nil
+}
+
+// EpochOf returns the epoch of the given block number.
+func (MockChainReader) EpochOf(blockNumber uint64) uint64 {
+	return core.GetEpochFromBlockNumber(blockNumber)
+}
+
+// CommitteeForEpoch returns the committee of the given shard for the given epoch.
+func (MockChainReader) CommitteeForEpoch(shardID uint32, epoch uint64) (*types.Committee, error) {
+	return nil, core.ErrCommitteeNotFound
+}
+
 func TestProcessMessageValidatorAnnounce(test *testing.T) {
 	ctrl := gomock.NewController(test)
 	defer ctrl.Finish()
@@ -268,7 +284,8 @@ func TestProcessMessageValidatorCommitted(test *testing.T) {
 		test.Fatalf("Cannot craeate consensus: %v", err)
 	}
 	consensusValidator1.ChainReader = MockChainReader{}
-	consensusValidator1.OnConsensusDone = func(newBlock *types.Block) {}
+	var committedBlock *types.Block
+	consensusValidator1.OnConsensusDone = func(newBlock *types.Block) { committedBlock = newBlock }
 
 	if err = protobuf.Unmarshal(announceMsg, message); err != nil {
 		test.Errorf("Failed to unmarshal message payload")
@@ -287,5 +304,10 @@ func TestProcessMessageValidatorCommitted(test *testing.T) {
 	consensusValidator1.processCommittedMessage(message)
 
 	assert.Equal(test, Finished, consensusValidator1.state)
+	// the committed block is sealed, and hashed as such
+	if assert.NotNil(test, committedBlock) {
+		assert.Equal(test, committedBlock.Header().Hash(), committedBlock.Hash())
+		assert.NotEqual(test, common.BytesToHash(hashBytes), committedBlock.Hash())
+	}
 	time.Sleep(1 * time.Second)
 }
@@ -0,0 +1,223 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	msg_pb "github.com/harmony-one/harmony/api/proto/message"
+	consensus_engine "github.com/harmony-one/harmony/consensus/engine"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/internal/ctxerror"
+	"github.com/harmony-one/harmony/internal/utils"
+)
+
+// checkDoubleSign records a signed PREPARE or COMMIT message in the pbft log,
+// and reports the double sign evidence if the sender already signed another
+// block in the same phase of the same round and view.
+func (consensus *Consensus) checkDoubleSign(message *msg_pb.Message) {
+	consensusMsg := message.GetConsensus()
+	if consensusMsg == nil {
+		return
+	}
+	senderKey, err := bls_cosi.BytesToBlsPublicKey(consensusMsg.SenderPubkey)
+	if err != nil {
+		utils.GetLogInstance().Debug("[DoubleSign] Failed to deserialize BLS public key", "error", err)
+		return
+	}
+	if err := verifyMessageSig(senderKey, message); err != nil {
+		utils.GetLogInstance().Debug("[DoubleSign] Failed to verify the message signature", "error", err)
+		return
+	}
+	pbftMsg, err := ParsePbftMessage(message)
+	if err != nil {
+		ctxerror.Log15(utils.GetLogInstance().Warn, err)
+		return
+	}
+	logged := consensus.pbftLog.GetMessage(pbftMsg.ConsensusID, pbftMsg.MessageType, pbftMsg.SenderPubkey)
+	if logged == nil || logged.ViewID != pbftMsg.ViewID || logged.BlockHash == pbftMsg.BlockHash {
+		consensus.pbftLog.AddMessage(pbftMsg)
+		return
+	}
+
+	evidence := &types.DoubleSignEvidence{
+		FirstMessage:  logged.encoded,
+		SecondMessage: pbftMsg.encoded,
+	}
+	if err := evidence.PubKey.FromLibBLSPublicKey(senderKey); err != nil {
+		ctxerror.Log15(utils.GetLogInstance().Warn, err)
+		return
+	}
+	utils.GetLogInstance().Warn("[DoubleSign] Detected double sign",
+		"validatorAddress", utils.GetBlsAddress(senderKey),
+		"msgType", pbftMsg.MessageType,
+		"consensusID", pbftMsg.ConsensusID,
+		"viewID", pbftMsg.ViewID,
+		"firstBlockHash", logged.BlockHash,
+		"secondBlockHash", pbftMsg.BlockHash)
+	if consensus.OnDoubleSign != nil {
+		consensus.OnDoubleSign(evidence)
+	}
+}
+
+// VerifyDoubleSignEvidence checks that the evidence holds two PREPARE or two
+// COMMIT messages, signed by the key of the evidence, about different blocks
+// in the same round and view.
+func (consensus *Consensus) VerifyDoubleSignEvidence(evidence *types.DoubleSignEvidence) error {
+	_, err := verifyDoubleSignEvidence(evidence)
+	return err
+}
+
+// CheckDoubleSignEvidence checks that the evidence can slash its double
+// signer in a beacon chain block on top of the given state: it must be valid,
+// the double signer must be in a committee of the epoch of the round, and the
+// offense must not be slashed yet. It returns the hash identifying the
+// offense, the same for all the evidences of the double signer in the round
+// and view.
+func (consensus *Consensus) CheckDoubleSignEvidence(chain consensus_engine.ChainReader, state *state.DB, evidence *types.DoubleSignEvidence) (common.Hash, error) {
+	consensusMsg, err := verifyDoubleSignEvidence(evidence)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	// the round of consensus ID n agrees on block n+1
+	epoch := core.GetSignerEpochFromBlockNumber(uint64(consensusMsg.ConsensusId) + 1)
+	shardState, err := chain.ReadShardState(epoch)
+	if err != nil {
+		return common.Hash{}, ctxerror.New("cannot read the shard state of the double sign round",
+			"epoch", epoch,
+		).WithCause(err)
+	}
+	if !isCommitteeMember(shardState, evidence.PubKey) {
+		return common.Hash{}, ctxerror.New("double signer not in a committee of the round's epoch",
+			"epoch", epoch,
+			"blsPublicKey", evidence.PubKey.Hex(),
+		).WithCause(consensus_engine.ErrInvalidDoubleSignEvidence)
+	}
+	offense := doubleSignOffense(evidence.PubKey, consensusMsg)
+	if core.IsDoubleSignSlashed(state, offense) {
+		return common.Hash{}, ctxerror.New("double sign already slashed",
+			"offense", offense,
+		).WithCause(consensus_engine.ErrInvalidDoubleSignEvidence)
+	}
+	return offense, nil
+}
+
+// doubleSignOffense returns the hash of the double sign offense of the given
+// key in the round and view of the consensus message.
+func doubleSignOffense(pubKey types.BlsPublicKey, consensusMsg *msg_pb.ConsensusRequest) common.Hash {
+	var round [8]byte
+	binary.BigEndian.PutUint32(round[:4], consensusMsg.ConsensusId)
+	binary.BigEndian.PutUint32(round[4:], consensusMsg.ViewId)
+	return crypto.Keccak256Hash(pubKey[:], round[:])
+}
+
+// verifyDoubleSignEvidence verifies the evidence and returns the first of its
+// consensus messages.
+func verifyDoubleSignEvidence(evidence *types.DoubleSignEvidence) (*msg_pb.ConsensusRequest, error) {
+	pubKey := &bls.PublicKey{}
+	if err := evidence.PubKey.ToLibBLSPublicKey(pubKey); err != nil {
+		return nil, ctxerror.New("cannot convert BLS public key",
+			"blsPublicKey", evidence.PubKey.Hex(),
+		).WithCause(err)
+	}
+	first, err := parseSignedConsensusMessage(pubKey, evidence.FirstMessage)
+	if err != nil {
+		return nil, ctxerror.New("invalid first message").WithCause(err)
+	}
+	second, err := parseSignedConsensusMessage(pubKey, evidence.SecondMessage)
+	if err != nil {
+		return nil, ctxerror.New("invalid second message").WithCause(err)
+	}
+	if first.Type != msg_pb.MessageType_PREPARE && first.Type != msg_pb.MessageType_COMMIT {
+		return nil, ctxerror.New("not a signing message", "msgType", first.Type).
+			WithCause(consensus_engine.ErrInvalidDoubleSignEvidence)
+	}
+	firstMsg, secondMsg := first.GetConsensus(), second.GetConsensus()
+	switch {
+	case first.Type != second.Type:
+		return nil, ctxerror.New("messages of different phases",
+			"firstMsgType", first.Type,
+			"secondMsgType", second.Type,
+		).WithCause(consensus_engine.ErrInvalidDoubleSignEvidence)
+	case firstMsg.ConsensusId != secondMsg.ConsensusId || firstMsg.ViewId != secondMsg.ViewId:
+		return nil, ctxerror.New("messages of different rounds",
+			"firstConsensusID", firstMsg.ConsensusId,
+			"secondConsensusID", secondMsg.ConsensusId,
+			"firstViewID", firstMsg.ViewId,
+			"secondViewID", secondMsg.ViewId,
+		).WithCause(consensus_engine.ErrInvalidDoubleSignEvidence)
+	case bytes.Equal(firstMsg.BlockHash, secondMsg.BlockHash):
+		return nil, ctxerror.New("messages about the same block",
+			"blockHash", common.BytesToHash(firstMsg.BlockHash),
+		).WithCause(consensus_engine.ErrInvalidDoubleSignEvidence)
+	}
+	return firstMsg, nil
+}
+
+// parseSignedConsensusMessage deserializes a consensus message and checks it is
+// sent and signed by the given key.
+func parseSignedConsensusMessage(pubKey *bls.PublicKey, encoded []byte) (*msg_pb.Message, error) {
+	message := &msg_pb.Message{}
+	if err := protobuf.Unmarshal(encoded, message); err != nil {
+		return nil, ctxerror.New("cannot deserialize the message").WithCause(err)
+	}
+	consensusMsg := message.GetConsensus()
+	if consensusMsg == nil {
+		return nil, ctxerror.New("not a consensus message", "msgType", message.Type)
+	}
+	if !bytes.Equal(consensusMsg.SenderPubkey, pubKey.Serialize()) {
+		return nil, ctxerror.New("message not sent by the double signer")
+	}
+	if err := verifyMessageSig(pubKey, message); err != nil {
+		return nil, ctxerror.New("cannot verify the message signature").WithCause(err)
+	}
+	return message, nil
+}
+
+// slashDoubleSigners verifies the double sign evidences included in the block,
+// and slashes the stake locked by each double signer in the StakeLockContract.
+// Evidences are only allowed in the beacon chain, which holds the stakes and
+// the shard states of all the shards. Each offense is slashed only once.
+func (consensus *Consensus) slashDoubleSigners(chain consensus_engine.ChainReader, header *types.Header, state *state.DB, doubleSigns []*types.DoubleSignEvidence) error {
+	if len(doubleSigns) == 0 {
+		return nil
+	}
+	if shardID := binary.BigEndian.Uint32(header.ShardID[:]); shardID != 0 {
+		return ctxerror.New("double sign evidences outside the beacon chain",
+			"shardID", shardID,
+		).WithCause(consensus_engine.ErrInvalidDoubleSignEvidence)
+	}
+	for _, evidence := range doubleSigns {
+		offense, err := consensus.CheckDoubleSignEvidence(chain, state, evidence)
+		if err != nil {
+			return ctxerror.New("invalid double sign evidence",
+				"evidence", evidence.Hash(),
+			).WithCause(err)
+		}
+		core.MarkDoubleSignSlashed(state, offense)
+		amount := core.SlashLockedStake(state, evidence.PubKey)
+		utils.GetLogInstance().Info("[DoubleSign] Slashed double signer",
+			"blockNum", header.Number,
+			"blsPublicKey", evidence.PubKey.Hex(),
+			"amount", amount)
+	}
+	return nil
+}
+
+// isCommitteeMember returns whether the key is in a committee of the shard state.
+func isCommitteeMember(shardState types.ShardState, pubKey types.BlsPublicKey) bool {
+	for _, committee := range shardState {
+		for _, nodeID := range committee.NodeList {
+			if nodeID.BlsPublicKey == pubKey {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	protobuf "github.com/golang/protobuf/proto"
+	msg_pb "github.com/harmony-one/harmony/api/proto/message"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/crypto/bls"
+)
+
+func signTestMessage(t *testing.T, consensus *Consensus, msgType msg_pb.MessageType, consensusID uint32, viewID uint32, blockHash []byte) []byte {
+	msg := &msg_pb.Message{
+		ServiceType: msg_pb.ServiceType_CONSENSUS,
+		Type:        msgType,
+		Request: &msg_pb.Message_Consensus{
+			Consensus: &msg_pb.ConsensusRequest{
+				ConsensusId:  consensusID,
+				ViewId:       viewID,
+				BlockHash:    blockHash,
+				SenderPubkey: consensus.PubKey.Serialize(),
+			},
+		},
+	}
+	if err := consensus.signConsensusMessage(msg); err != nil {
+		t.Fatalf("cannot sign message: %v", err)
+	}
+	encoded, err := protobuf.Marshal(msg)
+	if err != nil {
+		t.Fatalf("cannot serialize message: %v", err)
+	}
+	return encoded
+}
+
+func TestVerifyDoubleSignEvidence(t *testing.T) {
+	priKey := bls.RandPrivateKey()
+	consensus := &Consensus{priKey: priKey, PubKey: priKey.GetPublicKey()}
+	var pubKey types.BlsPublicKey
+	pubKey.FromLibBLSPublicKey(consensus.PubKey)
+
+	first := signTestMessage(t, consensus, msg_pb.MessageType_PREPARE, 3, 1, []byte{1})
+	tests := []struct {
+		name   string
+		second []byte
+		valid  bool
+	}{
+		{"different block", signTestMessage(t, consensus, msg_pb.MessageType_PREPARE, 3, 1, []byte{2}), true},
+		{"same block", signTestMessage(t, consensus, msg_pb.MessageType_PREPARE, 3, 1, []byte{1}), false},
+		{"different view", signTestMessage(t, consensus, msg_pb.MessageType_PREPARE, 3, 2, []byte{2}), false},
+		{"different round", signTestMessage(t, consensus, msg_pb.MessageType_PREPARE, 4, 1, []byte{2}), false},
+		{"different phase", signTestMessage(t, consensus, msg_pb.MessageType_COMMIT, 3, 1, []byte{2}), false},
+	}
+	for _, test := range tests {
+		evidence := &types.DoubleSignEvidence{PubKey: pubKey, FirstMessage: first, SecondMessage: test.second}
+		if err := consensus.VerifyDoubleSignEvidence(evidence); (err == nil) != test.valid {
+			t.Errorf("%s: VerifyDoubleSignEvidence() error = %v, expected valid = %v", test.name, err, test.valid)
+		}
+	}
+
+	otherKey := bls.RandPrivateKey()
+	other := &Consensus{priKey: otherKey, PubKey: otherKey.GetPublicKey()}
+	evidence := &types.DoubleSignEvidence{
+		PubKey:        pubKey,
+		FirstMessage:  first,
+		SecondMessage: signTestMessage(t, other, msg_pb.MessageType_PREPARE, 3, 1, []byte{2}),
+	}
+	if err := consensus.VerifyDoubleSignEvidence(evidence); err == nil {
+		t.Error("accepted a message signed by another validator")
+	}
+}
+
+func TestCheckDoubleSignEvidence(t *testing.T) {
+	priKey := bls.RandPrivateKey()
+	consensus := &Consensus{priKey: priKey, PubKey: priKey.GetPublicKey()}
+	var pubKey types.BlsPublicKey
+	pubKey.FromLibBLSPublicKey(consensus.PubKey)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	evidence := &types.DoubleSignEvidence{
+		PubKey:        pubKey,
+		FirstMessage:  signTestMessage(t, consensus, msg_pb.MessageType_PREPARE, 3, 1, []byte{1}),
+		SecondMessage: signTestMessage(t, consensus, msg_pb.MessageType_PREPARE, 3, 1, []byte{2}),
+	}
+
+	outsider := shardStateChainReader{shardState: types.ShardState{{ShardID: 1}}}
+	if _, err := consensus.CheckDoubleSignEvidence(outsider, statedb, evidence); err == nil {
+		t.Error("accepted the evidence of a validator outside the committees")
+	}
+	chain := shardStateChainReader{shardState: types.ShardState{{ShardID: 1, NodeList: []types.NodeID{{EcdsaAddress: "node1", BlsPublicKey: pubKey}}}}}
+	offense, err := consensus.CheckDoubleSignEvidence(chain, statedb, evidence)
+	if err != nil {
+		t.Fatalf("cannot check the evidence: %v", err)
+	}
+	core.MarkDoubleSignSlashed(statedb, offense)
+	// the same offense proven by other messages
+	swapped := &types.DoubleSignEvidence{PubKey: pubKey, FirstMessage: evidence.SecondMessage, SecondMessage: evidence.FirstMessage}
+	if _, err := consensus.CheckDoubleSignEvidence(chain, statedb, swapped); err == nil {
+		t.Error("accepted the evidence of an offense already slashed")
+	}
+	later := &types.DoubleSignEvidence{
+		PubKey:        pubKey,
+		FirstMessage:  signTestMessage(t, consensus, msg_pb.MessageType_COMMIT, 4, 1, []byte{1}),
+		SecondMessage: signTestMessage(t, consensus, msg_pb.MessageType_COMMIT, 4, 1, []byte{2}),
+	}
+	if _, err := consensus.CheckDoubleSignEvidence(chain, statedb, later); err != nil {
+		t.Errorf("rejected the evidence of another offense: %v", err)
+	}
+}
@@ -28,6 +28,18 @@ type ChainReader interface {
 
 	// GetBlock retrieves a block from the database by hash and number.
 	GetBlock(hash common.Hash, number uint64) *types.Block
+
+	// ReadShardState retrieves sharding state given the epoch number.
+	// This api reads the shard state cached or saved on the chaindb.
+	// Thus, only should be used to read the shard state of the current chain.
+	ReadShardState(epoch uint64) (types.ShardState, error)
+
+	// EpochOf returns the epoch of the given block number.
+	EpochOf(blockNumber uint64) uint64
+
+	// CommitteeForEpoch returns the committee of the given shard for the given
+	// epoch, as recorded in the shard state of the chain.
+	CommitteeForEpoch(shardID uint32, epoch uint64) (*types.Committee, error)
 }
 
 // Engine is an algorithm agnostic consensus engine.
@@ -56,12 +68,12 @@ type Engine interface {
 	// rules of a particular engine. The changes are executed inline.
 	Prepare(chain ChainReader, header *types.Header) error
 
-	// Finalize runs any post-transaction state modifications (e.g. block rewards)
-	// and assembles the final block.
+	// Finalize runs any post-transaction state modifications (e.g. block rewards,
+	// slashing of double signers) and assembles the final block.
 	// Note: The block header and state database might be updated to reflect any
 	// consensus rules that happen at finalization (e.g. block rewards).
 	Finalize(chain ChainReader, header *types.Header, state *state.DB, txs []*types.Transaction,
-		receipts []*types.Receipt) (*types.Block, error)
+		receipts []*types.Receipt, doubleSigns []*types.DoubleSignEvidence) (*types.Block, error)
 
 	// Seal generates a new sealing request for the given input block and pushes
 	// the result into the given channel.
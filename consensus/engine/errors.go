@@ -40,4 +40,13 @@ var (
 
 	// ErrInvalidConsensusMessage is returned is the consensus message received is invalid
 	ErrInvalidConsensusMessage = errors.New("invalid consensus message")
+
+	// ErrInvalidSeal is returned if the aggregated signature of a header doesn't verify
+	ErrInvalidSeal = errors.New("invalid seal")
+
+	// ErrNotEnoughSignatures is returned if the signers of a header don't reach the quorum
+	ErrNotEnoughSignatures = errors.New("not enough signatures")
+
+	// ErrInvalidDoubleSignEvidence is returned if a double sign evidence doesn't prove a double sign
+	ErrInvalidDoubleSignEvidence = errors.New("invalid double sign evidence")
 )
@@ -0,0 +1,146 @@
+package consensus
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+	"sort"
+
+	"github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/ctxerror"
+	"github.com/harmony-one/harmony/internal/utils"
+)
+
+// Leader rotation
+//
+// With LeaderRotationBlocks set to N, the leadership rotates every N blocks
+// following a schedule every node computes from the chain: the blocks of an
+// epoch are led by the schedule seeded by the RandSeed of the epoch block, so
+// that nobody can predict, nor bias, the leaders before the epoch starts.
+// Only the beacon chain generates randomness: the epoch blocks of the shard
+// chains carry the RandSeed of the beacon epoch block, announced with the
+// shard state.
+// A view change moves to the next slot of the schedule.
+// The first block is proposed by the bootstrap leader, the first member of
+// the committee, as no seed is known before. Without the epoch block, the
+// schedule is unknown and the leadership doesn't rotate.
+
+// leaderKeyForBlock returns the public key of the leader proposing the block
+// of the given number in the given view, or nil if the schedule is unknown.
+func (consensus *Consensus) leaderKeyForBlock(blockNum uint64, viewID uint32) *bls.PublicKey {
+	if len(consensus.PublicKeys) == 0 {
+		return nil
+	}
+	if consensus.LeaderRotationBlocks == 0 || blockNum <= 1 {
+		return consensus.PublicKeys[viewID%uint32(len(consensus.PublicKeys))]
+	}
+	// The epoch block itself is still led by the schedule of the previous epoch
+	epoch := core.GetEpochFromBlockNumber(blockNum - 1)
+	seedNum := core.GetBlockNumberFromEpoch(epoch)
+	slot := (blockNum-1-seedNum)/consensus.LeaderRotationBlocks + uint64(viewID)
+
+	if consensus.ChainReader == nil {
+		return nil
+	}
+	committee, err := consensus.ChainReader.CommitteeForEpoch(consensus.ShardID, core.GetSignerEpochFromBlockNumber(blockNum))
+	if err != nil {
+		ctxerror.Log15(utils.GetLogInstance().Warn, ctxerror.New("[Rotation] cannot read the committee of the leader schedule",
+			"blockNum", blockNum,
+		).WithCause(err))
+		return nil
+	}
+	seed, err := consensus.scheduleSeed(epoch, seedNum)
+	if err != nil {
+		ctxerror.Log15(utils.GetLogInstance().Warn, err)
+		return nil
+	}
+	return leaderForSlot(consensus.PublicKeys, seed, slot, committeeStakes(committee))
+}
+
+// scheduleSeed returns the seed of the leader schedule of the given epoch,
+// derived from the RandSeed of the epoch block. It fails if the chain doesn't
+// have the epoch block yet.
+func (consensus *Consensus) scheduleSeed(epoch uint64, seedNum uint64) ([32]byte, error) {
+	header := consensus.ChainReader.GetHeaderByNumber(seedNum)
+	if header == nil {
+		return [32]byte{}, ctxerror.New("[Rotation] cannot find the seed block of the leader schedule",
+			"epoch", epoch,
+			"blockNum", seedNum)
+	}
+	buffer := make([]byte, 40)
+	copy(buffer[:32], header.RandSeed[:])
+	binary.BigEndian.PutUint64(buffer[32:], epoch)
+	return sha256.Sum256(buffer), nil
+}
+
+// leaderForSlot returns the leader of the given slot of the schedule seeded
+// by seed. If stakeOf is given, each slot is led by a member drawn with a
+// probability proportional to its stake; otherwise the members take turns.
+// Either way the members are ordered by the seed, so that the schedule doesn't
+// depend on the order of pubKeys.
+func leaderForSlot(pubKeys []*bls.PublicKey, seed [32]byte, slot uint64, stakeOf func(*bls.PublicKey) *big.Int) *bls.PublicKey {
+	order := make([][32]byte, len(pubKeys))
+	indexes := make([]int, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		order[i] = sha256.Sum256(append(seed[:], pubKey.Serialize()...))
+		indexes[i] = i
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return bytes.Compare(order[indexes[i]][:], order[indexes[j]][:]) < 0
+	})
+
+	if stakeOf != nil {
+		stakes := make([]*big.Int, len(pubKeys))
+		total := big.NewInt(0)
+		for i, pubKey := range pubKeys {
+			stakes[i] = big.NewInt(0)
+			if stake := stakeOf(pubKey); stake != nil && stake.Sign() > 0 {
+				stakes[i] = stake
+				total.Add(total, stake)
+			}
+		}
+		if total.Sign() > 0 {
+			buffer := make([]byte, 40)
+			copy(buffer[:32], seed[:])
+			binary.BigEndian.PutUint64(buffer[32:], slot)
+			hash := sha256.Sum256(buffer)
+			draw := new(big.Int).Mod(new(big.Int).SetBytes(hash[:]), total)
+			for _, i := range indexes {
+				if draw.Cmp(stakes[i]) < 0 {
+					return pubKeys[i]
+				}
+				draw.Sub(draw, stakes[i])
+			}
+		}
+	}
+	return pubKeys[indexes[slot%uint64(len(pubKeys))]]
+}
+
+// rotateLeader hands the leadership over to the leader of the block following
+// the committed one, if the schedule says so. The new leader is signaled to
+// propose the next block.
+// It must be called with the consensus mutex held.
+func (consensus *Consensus) rotateLeader(committed *types.Block) {
+	if consensus.LeaderRotationBlocks == 0 {
+		return
+	}
+	leaderKey := consensus.leaderKeyForBlock(committed.NumberU64()+1, consensus.viewID)
+	if leaderKey == nil || leaderKey.IsEqual(consensus.leader.ConsensusPubKey) {
+		return
+	}
+	utils.GetLogInstance().Info("[Rotation] Rotating leader",
+		"blockNum", committed.NumberU64()+1,
+		"viewID", consensus.viewID,
+		"leader", utils.GetBlsAddress(leaderKey))
+	wasLeader := consensus.IsLeader()
+	consensus.setLeader(leaderKey)
+	consensus.ResetState()
+	if consensus.IsLeader() && !wasLeader {
+		go func() {
+			consensus.ReadySignal <- struct{}{}
+		}()
+	}
+}
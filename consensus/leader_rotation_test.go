@@ -0,0 +1,103 @@
+package consensus
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	bls_core "github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/crypto/bls"
+)
+
+func TestLeaderForSlot(t *testing.T) {
+	pubKeys := []*bls_core.PublicKey{}
+	for i := 0; i < 4; i++ {
+		pubKeys = append(pubKeys, bls.RandPrivateKey().GetPublicKey())
+	}
+	seed := [32]byte{1, 2, 3}
+
+	// Round robin: every member leads once in each cycle of the schedule
+	led := map[string]bool{}
+	for slot := uint64(0); slot < 4; slot++ {
+		leader := leaderForSlot(pubKeys, seed, slot, nil)
+		if !leader.IsEqual(leaderForSlot(pubKeys, seed, slot, nil)) {
+			t.Errorf("leader of slot %d is not deterministic", slot)
+		}
+		if !leader.IsEqual(leaderForSlot(pubKeys, seed, slot+4, nil)) {
+			t.Errorf("leader of slot %d differs in the next cycle", slot)
+		}
+		led[hex.EncodeToString(leader.Serialize())] = true
+	}
+	if len(led) != 4 {
+		t.Errorf("%d members led a cycle of 4 slots, expected 4", len(led))
+	}
+
+	// Stake weighted: members without stake never lead
+	stakeOf := func(pubKey *bls_core.PublicKey) *big.Int {
+		if pubKey.IsEqual(pubKeys[2]) {
+			return big.NewInt(100)
+		}
+		return big.NewInt(0)
+	}
+	for slot := uint64(0); slot < 20; slot++ {
+		if leader := leaderForSlot(pubKeys, seed, slot, stakeOf); !leader.IsEqual(pubKeys[2]) {
+			t.Errorf("slot %d is led by a member without stake", slot)
+		}
+	}
+
+	// The schedule doesn't depend on the order of the members
+	reversed := []*bls_core.PublicKey{pubKeys[3], pubKeys[2], pubKeys[1], pubKeys[0]}
+	evenStakes := func(pubKey *bls_core.PublicKey) *big.Int { return big.NewInt(10) }
+	for slot := uint64(0); slot < 8; slot++ {
+		if !leaderForSlot(pubKeys, seed, slot, nil).IsEqual(leaderForSlot(reversed, seed, slot, nil)) {
+			t.Errorf("round robin leader of slot %d depends on the member order", slot)
+		}
+		if !leaderForSlot(pubKeys, seed, slot, evenStakes).IsEqual(leaderForSlot(reversed, seed, slot, evenStakes)) {
+			t.Errorf("stake weighted leader of slot %d depends on the member order", slot)
+		}
+	}
+}
+
+// seedlessChainReader misses the epoch blocks seeding the leader schedules.
+type seedlessChainReader struct {
+	epochCommitteeChainReader
+}
+
+func (seedlessChainReader) GetHeaderByNumber(number uint64) *types.Header {
+	return nil
+}
+
+func TestLeaderKeyForBlock(t *testing.T) {
+	pubKeys := []*bls_core.PublicKey{}
+	for i := 0; i < 4; i++ {
+		pubKeys = append(pubKeys, bls.RandPrivateKey().GetPublicKey())
+	}
+	consensus := &Consensus{PublicKeys: pubKeys}
+	for viewID := uint32(0); viewID < 8; viewID++ {
+		if !consensus.leaderKeyForBlock(10, viewID).IsEqual(pubKeys[viewID%4]) {
+			t.Errorf("Wrong leader for view %d without rotation", viewID)
+		}
+	}
+
+	consensus.LeaderRotationBlocks = 3
+	consensus.ChainReader = epochCommitteeChainReader{committees: []types.Committee{stakedCommittee(pubKeys, 0, 0, 0, 0)}}
+	if !consensus.leaderKeyForBlock(1, 0).IsEqual(pubKeys[0]) {
+		t.Error("The first block should be proposed by the bootstrap leader")
+	}
+	// blocks 2, 3 and 4 are in the same rotation period
+	leader := consensus.leaderKeyForBlock(2, 0)
+	for blockNum := uint64(3); blockNum <= 4; blockNum++ {
+		if !consensus.leaderKeyForBlock(blockNum, 0).IsEqual(leader) {
+			t.Errorf("Leader of block %d should lead the whole period", blockNum)
+		}
+	}
+	if !consensus.leaderKeyForBlock(5, 0).IsEqual(consensus.leaderKeyForBlock(2, 1)) {
+		t.Error("A view change should move to the next slot of the schedule")
+	}
+
+	consensus.ChainReader = seedlessChainReader{epochCommitteeChainReader{committees: []types.Committee{stakedCommittee(pubKeys, 0, 0, 0, 0)}}}
+	if leader := consensus.leaderKeyForBlock(2, 0); leader != nil {
+		t.Error("The leader should be unknown without the seed block")
+	}
+}
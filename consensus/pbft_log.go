@@ -1,76 +1,366 @@
 package consensus
 
 import (
-	mapset "github.com/deckarep/golang-set"
+	"sort"
+	"sync"
+
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	protobuf "github.com/golang/protobuf/proto"
+	msg_pb "github.com/harmony-one/harmony/api/proto/message"
+	"github.com/harmony-one/harmony/core/rawdb"
 	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/ctxerror"
+	"github.com/harmony-one/harmony/internal/utils"
 )
 
-// PbftLog represents the log stored by a node during PBFT process
+// PbftLog represents the log stored by a node during PBFT process.
+// Messages are indexed by (consensusID, phase, sender) and by block hash.
+// If backed by a database, the messages are persisted so that a restarted
+// node can rebuild the rounds in flight. The database is written in the
+// background, in the order of the changes to the log, so that the consensus
+// doesn't wait for the disk.
 type PbftLog struct {
-	blocks     mapset.Set //store blocks received in PBFT
-	messages   mapset.Set // store messages received in PBFT
-	maxLogSize uint32
+	mutex       sync.Mutex
+	blocks      map[common.Hash]*types.Block    // blocks received in PBFT, by block hash
+	messages    map[pbftMessageKey]*PbftMessage // messages received in PBFT
+	byBlockHash map[common.Hash][]*PbftMessage  // messages received in PBFT, by block hash
+	rounds      map[uint32][]*PbftMessage       // messages received in PBFT, by consensusID in arrival order
+	db          ethdb.Database                  // database persisting the messages, or nil
+	persisted   map[uint32]uint32               // number of messages persisted, by consensusID
+	writes      chan func()                     // database writes queued for the writer goroutine
+	written     chan struct{}                   // closed once the writer goroutine is done
+	maxLogSize  uint32
+}
+
+// pbftMessageKey is the (consensusID, phase, sender) index of a pbft message.
+type pbftMessageKey struct {
+	consensusID  uint32
+	messageType  msg_pb.MessageType
+	senderPubkey string
 }
 
 // PbftMessage is the record of pbft messages received by a node during PBFT process
 type PbftMessage struct {
-	msg       []byte
-	signature *bls.Sign
+	MessageType  msg_pb.MessageType
+	ConsensusID  uint32
+	ViewID       uint32
+	BlockHash    common.Hash
+	SenderPubkey []byte
+	Payload      []byte
+
+	message *msg_pb.Message // the original message
+	encoded []byte          // the serialized original message
+}
+
+// ParsePbftMessage returns the pbft message record of the given consensus message.
+func ParsePbftMessage(msg *msg_pb.Message) (*PbftMessage, error) {
+	consensusMsg := msg.GetConsensus()
+	if consensusMsg == nil {
+		return nil, ctxerror.New("not a consensus message", "msgType", msg.Type)
+	}
+	encoded, err := protobuf.Marshal(msg)
+	if err != nil {
+		return nil, ctxerror.New("cannot serialize pbft message").WithCause(err)
+	}
+	return &PbftMessage{
+		MessageType:  msg.Type,
+		ConsensusID:  consensusMsg.ConsensusId,
+		ViewID:       consensusMsg.ViewId,
+		BlockHash:    common.BytesToHash(consensusMsg.BlockHash),
+		SenderPubkey: consensusMsg.SenderPubkey,
+		Payload:      consensusMsg.Payload,
+		message:      msg,
+		encoded:      encoded,
+	}, nil
+}
+
+// Message returns the original consensus message of the record.
+func (m *PbftMessage) Message() *msg_pb.Message {
+	return m.message
+}
+
+func (m *PbftMessage) key() pbftMessageKey {
+	return pbftMessageKey{
+		consensusID:  m.ConsensusID,
+		messageType:  m.MessageType,
+		senderPubkey: string(m.SenderPubkey),
+	}
 }
 
 // NewPbftLog returns new instance of PbftLog
 func NewPbftLog() *PbftLog {
-	blocks := mapset.NewSet()
-	messages := mapset.NewSet()
-	logSize := maxLogSize
-	pbftLog := PbftLog{blocks: blocks, messages: messages, maxLogSize: logSize}
-	return &pbftLog
+	return &PbftLog{
+		blocks:      make(map[common.Hash]*types.Block),
+		messages:    make(map[pbftMessageKey]*PbftMessage),
+		byBlockHash: make(map[common.Hash][]*PbftMessage),
+		rounds:      make(map[uint32][]*PbftMessage),
+		persisted:   make(map[uint32]uint32),
+		maxLogSize:  maxLogSize,
+	}
 }
 
-// Blocks return the blocks stored in the log
-func (log *PbftLog) Blocks() mapset.Set {
-	return log.blocks
+// NewPersistentPbftLog returns a new PbftLog persisted to the given database,
+// restoring the messages persisted previously.
+func NewPersistentPbftLog(db ethdb.Database) *PbftLog {
+	log := NewPbftLog()
+	for _, consensusID := range rawdb.ReadPbftLogConsensusIDs(db) {
+		messages := rawdb.ReadPbftMessages(db, consensusID)
+		log.persisted[consensusID] = uint32(len(messages))
+		for _, encoded := range messages {
+			msg := &msg_pb.Message{}
+			if err := protobuf.Unmarshal(encoded, msg); err != nil {
+				utils.GetLogInstance().Warn("[PbftLog] cannot restore message", "consensusID", consensusID, "error", err)
+				continue
+			}
+			pbftMsg, err := ParsePbftMessage(msg)
+			if err != nil {
+				ctxerror.Log15(utils.GetLogInstance().Warn, err)
+				continue
+			}
+			log.AddMessage(pbftMsg)
+		}
+	}
+	// only persist from now on, the restored messages are already in the database
+	log.db = db
+	log.writes = make(chan func(), maxLogSize)
+	log.written = make(chan struct{})
+	go log.writeLoop()
+	utils.GetLogInstance().Info("[PbftLog] restored messages", "numMessages", log.Len())
+	return log
 }
 
-// Messages return the messages stored in the log
-func (log *PbftLog) Messages() mapset.Set {
-	return log.messages
+// writeLoop applies the queued database writes until the log is closed.
+func (log *PbftLog) writeLoop() {
+	for write := range log.writes {
+		write()
+	}
+	close(log.written)
+}
+
+// persist queues a database write, if the log is persisted.
+// It must be called with the log mutex held.
+func (log *PbftLog) persist(write func(db ethdb.Database)) {
+	if log.db == nil {
+		return
+	}
+	db := log.db
+	log.writes <- func() { write(db) }
+}
+
+// Close stops persisting the log, once the queued writes are done.
+func (log *PbftLog) Close() {
+	log.mutex.Lock()
+	if log.db == nil {
+		log.mutex.Unlock()
+		return
+	}
+	log.db = nil
+	close(log.writes)
+	log.mutex.Unlock()
+	<-log.written
+}
+
+// Len returns the number of messages in the log.
+func (log *PbftLog) Len() int {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	return len(log.messages)
 }
 
 // AddBlock add a new block into the log
 func (log *PbftLog) AddBlock(block *types.Block) {
-	log.blocks.Add(block)
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	log.blocks[block.Hash()] = block
 }
 
 // GetBlockByHash returns the block matches the given block hash
 func (log *PbftLog) GetBlockByHash(hash common.Hash) *types.Block {
-	var found *types.Block
-	it := log.Blocks().Iterator()
-	for block := range it.C {
-		if block.(*types.Block).Hash() == hash {
-			found = block.(*types.Block)
-			it.Stop()
-		}
-	}
-	return found
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	return log.blocks[hash]
 }
 
 // GetBlockByNumber returns teh block matches the given block number
 func (log *PbftLog) GetBlockByNumber(id uint64) *types.Block {
-	var found *types.Block
-	it := log.Blocks().Iterator()
-	for block := range it.C {
-		if block.(*types.Block).NumberU64() == id {
-			found = block.(*types.Block)
-			it.Stop()
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	for _, block := range log.blocks {
+		if block.NumberU64() == id {
+			return block
+		}
+	}
+	return nil
+}
+
+// AddMessage adds a pbft message into the log.
+// The block proposed by an announce message is added as well.
+// A message of the same phase from the same sender in the round is replaced
+// if it was sent in an earlier view.
+// Once the log is full, messages are evicted to make room, see evict.
+// It returns false if the log already has such a message of the same or a
+// later view, or if the log is full of messages of earlier rounds.
+func (log *PbftLog) AddMessage(msg *PbftMessage) bool {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	key := msg.key()
+	if existing, ok := log.messages[key]; ok {
+		if existing.ViewID >= msg.ViewID {
+			return false
+		}
+		log.removeMessage(existing)
+	}
+	for uint32(len(log.messages)) >= log.maxLogSize {
+		if !log.evict(msg.ConsensusID) {
+			utils.GetLogInstance().Warn("[PbftLog] log is full, dropping message",
+				"consensusID", msg.ConsensusID, "msgType", msg.MessageType)
+			return false
 		}
 	}
+	if msg.MessageType == msg_pb.MessageType_ANNOUNCE {
+		var block types.Block
+		if err := rlp.DecodeBytes(msg.Payload, &block); err == nil {
+			log.blocks[block.Hash()] = &block
+		}
+	}
+	log.messages[key] = msg
+	log.byBlockHash[msg.BlockHash] = append(log.byBlockHash[msg.BlockHash], msg)
+	_, knownRound := log.rounds[msg.ConsensusID]
+	log.rounds[msg.ConsensusID] = append(log.rounds[msg.ConsensusID], msg)
+
+	if log.db != nil {
+		if !knownRound {
+			consensusIDs := log.consensusIDs()
+			log.persist(func(db ethdb.Database) { rawdb.WritePbftLogConsensusIDs(db, consensusIDs) })
+		}
+		// a replaced message stays in the database, and is replaced again on restore
+		consensusID, seq, encoded := msg.ConsensusID, log.persisted[msg.ConsensusID], msg.encoded
+		log.persist(func(db ethdb.Database) { rawdb.WritePbftMessage(db, consensusID, seq, encoded) })
+		log.persisted[msg.ConsensusID]++
+	}
+	return true
+}
+
+// evict makes room for a message of the given round. The earliest round of
+// the log is the one running, the later ones are buffered ahead of it: a
+// message of a later round never evicts those of an earlier one. A message of
+// the earliest round evicts the latest later round, or else the oldest message
+// of its own round.
+// It returns false if nothing can be evicted for the message.
+// It must be called with the log mutex held.
+func (log *PbftLog) evict(consensusID uint32) bool {
+	first, last := consensusID, consensusID
+	for id, msgs := range log.rounds {
+		if len(msgs) == 0 {
+			continue
+		}
+		if id < first {
+			first = id
+		}
+		if id > last {
+			last = id
+		}
+	}
+	switch {
+	case consensusID > first:
+		return false
+	case last > consensusID:
+		log.dropRound(last)
+	default:
+		log.removeMessage(log.rounds[consensusID][0])
+	}
+	return true
+}
+
+// GetMessage returns the message of the given phase sent by the given sender
+// in the given round, or nil if there is none.
+func (log *PbftLog) GetMessage(consensusID uint32, msgType msg_pb.MessageType, senderPubkey []byte) *PbftMessage {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	return log.messages[pbftMessageKey{consensusID, msgType, string(senderPubkey)}]
+}
+
+// GetMessagesByConsensusID returns the messages of the given round, ordered by phase.
+func (log *PbftLog) GetMessagesByConsensusID(consensusID uint32) []*PbftMessage {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	found := append([]*PbftMessage{}, log.rounds[consensusID]...)
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].MessageType < found[j].MessageType
+	})
 	return found
 }
 
-// AddMessage adds a pbft message into the log
-func (log *PbftLog) AddMessage(msg *PbftMessage) {
-	log.messages.Add(msg)
+// GetMessagesByBlockHash returns the messages about the block of the given hash.
+func (log *PbftLog) GetMessagesByBlockHash(hash common.Hash) []*PbftMessage {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	return append([]*PbftMessage{}, log.byBlockHash[hash]...)
+}
+
+// Prune removes the messages of the rounds before the given consensusID,
+// together with their blocks, once those rounds are finalized.
+func (log *PbftLog) Prune(consensusID uint32) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	for id := range log.rounds {
+		if id < consensusID {
+			log.dropRound(id)
+		}
+	}
+}
+
+// dropRound removes the messages of the given round, together with their
+// blocks.
+// It must be called with the log mutex held.
+func (log *PbftLog) dropRound(consensusID uint32) {
+	for _, msg := range log.rounds[consensusID] {
+		delete(log.messages, msg.key())
+		delete(log.byBlockHash, msg.BlockHash)
+		delete(log.blocks, msg.BlockHash)
+	}
+	delete(log.rounds, consensusID)
+	if log.db != nil {
+		count, consensusIDs := log.persisted[consensusID], log.consensusIDs()
+		log.persist(func(db ethdb.Database) {
+			rawdb.DeletePbftMessages(db, consensusID, count)
+			rawdb.WritePbftLogConsensusIDs(db, consensusIDs)
+		})
+	}
+	delete(log.persisted, consensusID)
+}
+
+// removeMessage removes the given message from the in-memory indexes.
+// It must be called with the log mutex held.
+func (log *PbftLog) removeMessage(msg *PbftMessage) {
+	delete(log.messages, msg.key())
+	log.byBlockHash[msg.BlockHash] = removePbftMessage(log.byBlockHash[msg.BlockHash], msg)
+	if len(log.byBlockHash[msg.BlockHash]) == 0 {
+		delete(log.byBlockHash, msg.BlockHash)
+	}
+	log.rounds[msg.ConsensusID] = removePbftMessage(log.rounds[msg.ConsensusID], msg)
+}
+
+func removePbftMessage(msgs []*PbftMessage, msg *PbftMessage) []*PbftMessage {
+	for i, m := range msgs {
+		if m == msg {
+			return append(msgs[:i:i], msgs[i+1:]...)
+		}
+	}
+	return msgs
+}
+
+// consensusIDs returns the sorted consensus IDs of the rounds in the log.
+// It must be called with the log mutex held.
+func (log *PbftLog) consensusIDs() []uint32 {
+	ids := []uint32{}
+	for id := range log.rounds {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
 }
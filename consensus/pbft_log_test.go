@@ -0,0 +1,142 @@
+package consensus
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	msg_pb "github.com/harmony-one/harmony/api/proto/message"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/core/types"
+)
+
+func newTestPbftMessage(t *testing.T, msgType msg_pb.MessageType, consensusID uint32, blockHash []byte, sender []byte, payload []byte) *PbftMessage {
+	msg := &msg_pb.Message{
+		ServiceType: msg_pb.ServiceType_CONSENSUS,
+		Type:        msgType,
+		Request: &msg_pb.Message_Consensus{
+			Consensus: &msg_pb.ConsensusRequest{
+				ConsensusId:  consensusID,
+				BlockHash:    blockHash,
+				SenderPubkey: sender,
+				Payload:      payload,
+			},
+		},
+	}
+	pbftMsg, err := ParsePbftMessage(msg)
+	if err != nil {
+		t.Fatalf("cannot parse pbft message: %v", err)
+	}
+	return pbftMsg
+}
+
+func TestPbftLogIndexes(t *testing.T) {
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(5)})
+	blockBytes, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		t.Fatalf("cannot encode block: %v", err)
+	}
+	blockHash := block.Hash()
+	leader := []byte("leader")
+
+	log := NewPbftLog()
+	announce := newTestPbftMessage(t, msg_pb.MessageType_ANNOUNCE, 5, blockHash[:], leader, blockBytes)
+	if !log.AddMessage(announce) {
+		t.Fatal("failed to add announce message")
+	}
+	if log.AddMessage(newTestPbftMessage(t, msg_pb.MessageType_ANNOUNCE, 5, blockHash[:], leader, blockBytes)) {
+		t.Error("added the same announce message twice")
+	}
+	prepared := newTestPbftMessage(t, msg_pb.MessageType_PREPARED, 5, blockHash[:], leader, nil)
+	log.AddMessage(prepared)
+	log.AddMessage(newTestPbftMessage(t, msg_pb.MessageType_ANNOUNCE, 6, []byte{1}, leader, nil))
+
+	if found := log.GetMessage(5, msg_pb.MessageType_ANNOUNCE, leader); found != announce {
+		t.Errorf("GetMessage returned %v, expected the announce message", found)
+	}
+	if found := log.GetMessagesByBlockHash(blockHash); len(found) != 2 {
+		t.Errorf("found %d messages by block hash, expected 2", len(found))
+	}
+	if found := log.GetBlockByHash(blockHash); found == nil || found.NumberU64() != 5 {
+		t.Errorf("GetBlockByHash returned %v, expected the announced block", found)
+	}
+	if found := log.GetBlockByNumber(5); found == nil {
+		t.Error("GetBlockByNumber didn't find the announced block")
+	}
+
+	log.Prune(6)
+	if log.Len() != 1 {
+		t.Errorf("log has %d messages after pruning, expected 1", log.Len())
+	}
+	if found := log.GetBlockByHash(blockHash); found != nil {
+		t.Error("the block of a pruned round is still in the log")
+	}
+}
+
+func TestPersistentPbftLog(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	log := NewPersistentPbftLog(db)
+	log.AddMessage(newTestPbftMessage(t, msg_pb.MessageType_ANNOUNCE, 1, []byte{1}, []byte("leader"), nil))
+	log.AddMessage(newTestPbftMessage(t, msg_pb.MessageType_PREPARED, 2, []byte{2}, []byte("leader"), nil))
+	log.AddMessage(newTestPbftMessage(t, msg_pb.MessageType_COMMITTED, 2, []byte{2}, []byte("leader"), nil))
+	// the announce of a later view replaces the earlier one
+	log.AddMessage(newTestPbftMessage(t, msg_pb.MessageType_ANNOUNCE, 3, []byte{3}, []byte("leader"), nil))
+	nextView := newTestPbftMessage(t, msg_pb.MessageType_ANNOUNCE, 3, []byte{4}, []byte("leader"), nil).Message()
+	nextView.GetConsensus().ViewId = 1
+	pbftMsg, err := ParsePbftMessage(nextView)
+	if err != nil {
+		t.Fatalf("cannot parse pbft message: %v", err)
+	}
+	log.AddMessage(pbftMsg)
+	log.Prune(2)
+	log.Close()
+
+	restored := NewPersistentPbftLog(db)
+	if restored.Len() != 3 {
+		t.Fatalf("restored %d messages, expected 3", restored.Len())
+	}
+	if found := restored.GetMessagesByConsensusID(3); len(found) != 1 || found[0].ViewID != 1 {
+		t.Errorf("restored messages of round 3 are %v, expected the announce of view 1", found)
+	}
+	found := restored.GetMessagesByConsensusID(2)
+	if len(found) != 2 || found[0].MessageType != msg_pb.MessageType_PREPARED || found[1].MessageType != msg_pb.MessageType_COMMITTED {
+		t.Errorf("restored messages of round 2 are %v, expected PREPARED and COMMITTED", found)
+	}
+	if found := restored.GetMessagesByConsensusID(1); len(found) != 0 {
+		t.Errorf("restored %d messages of a pruned round", len(found))
+	}
+
+	restored.Prune(4)
+	restored.Close()
+	if messages := rawdb.ReadPbftMessages(db, 3); len(messages) != 0 {
+		t.Errorf("%d messages of a pruned round still persisted", len(messages))
+	}
+}
+
+func TestPbftLogEviction(t *testing.T) {
+	log := NewPbftLog()
+	log.maxLogSize = 3
+	log.AddMessage(newTestPbftMessage(t, msg_pb.MessageType_ANNOUNCE, 5, []byte{1}, []byte("leader"), nil))
+	log.AddMessage(newTestPbftMessage(t, msg_pb.MessageType_PREPARE, 5, []byte{1}, []byte("a"), nil))
+	log.AddMessage(newTestPbftMessage(t, msg_pb.MessageType_ANNOUNCE, 6, []byte{2}, []byte("leader"), nil))
+
+	// a message of the running round evicts the later rounds first
+	if !log.AddMessage(newTestPbftMessage(t, msg_pb.MessageType_PREPARE, 5, []byte{1}, []byte("b"), nil)) {
+		t.Fatal("message of the running round refused")
+	}
+	if found := log.GetMessagesByConsensusID(6); len(found) != 0 {
+		t.Errorf("%d messages of the later round kept", len(found))
+	}
+	// a message of a later round never evicts the running round
+	if log.AddMessage(newTestPbftMessage(t, msg_pb.MessageType_ANNOUNCE, 6, []byte{2}, []byte("leader"), nil)) {
+		t.Error("message of a later round added to a full log")
+	}
+	// then the oldest messages of the running round make room
+	if !log.AddMessage(newTestPbftMessage(t, msg_pb.MessageType_PREPARE, 5, []byte{1}, []byte("c"), nil)) {
+		t.Fatal("message of the running round refused")
+	}
+	if log.Len() != 3 || log.GetMessage(5, msg_pb.MessageType_ANNOUNCE, []byte("leader")) != nil {
+		t.Errorf("wrong messages kept after evicting the oldest: %v", log.GetMessagesByConsensusID(5))
+	}
+}
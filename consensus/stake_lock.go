@@ -0,0 +1,150 @@
+package consensus
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/contracts/structs"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/ctxerror"
+	"github.com/harmony-one/harmony/internal/utils"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const stakeSnapshotCacheLimit = 16
+
+// BeaconStateReader reads the states of the beacon chain, which holds the
+// StakeLockContract.
+type BeaconStateReader interface {
+	CurrentHeader() *types.Header
+	GetHeaderByNumber(number uint64) *types.Header
+	ReadLockedStakes(header *types.Header) (map[common.Address]*structs.StakeInfo, error)
+}
+
+// StakeLockInfoFinder is a stake info finder reading the stakes locked in the
+// StakeLockContract of the beacon chain.
+// The stakes of an epoch are the ones locked as of the block preceding the
+// epoch block, the ones resharding records in the committees of the epoch.
+// Node keys and accounts without locked stake are looked up in the fallback
+// finder, e.g. to keep rewarding the genesis nodes.
+type StakeLockInfoFinder struct {
+	beaconChain BeaconStateReader
+	fallback    StakeInfoFinder
+	snapshots   *lru.Cache // epoch => *stakeSnapshot
+}
+
+// NewStakeLockInfoFinder returns a stake info finder reading the stakes
+// locked in the StakeLockContract of the given beacon chain.
+func NewStakeLockInfoFinder(beaconChain BeaconStateReader, fallback StakeInfoFinder) *StakeLockInfoFinder {
+	snapshots, _ := lru.New(stakeSnapshotCacheLimit)
+	return &StakeLockInfoFinder{
+		beaconChain: beaconChain,
+		fallback:    fallback,
+		snapshots:   snapshots,
+	}
+}
+
+// FindStakeInfoByNodeKey returns the stakes locked with the given node key as
+// of the current epoch of the beacon chain, or nil if they can't be read.
+func (f *StakeLockInfoFinder) FindStakeInfoByNodeKey(key *bls.PublicKey) []*structs.StakeInfo {
+	snapshot, err := f.AtEpoch(f.currentEpoch())
+	if err != nil {
+		ctxerror.Log15(utils.GetLogInstance().Warn, ctxerror.New("[Stake] cannot read the locked stakes").WithCause(err))
+		return nil
+	}
+	return snapshot.FindStakeInfoByNodeKey(key)
+}
+
+// FindStakeInfoByAccount returns the stakes locked by the given account as of
+// the current epoch of the beacon chain, or nil if they can't be read.
+func (f *StakeLockInfoFinder) FindStakeInfoByAccount(addr common.Address) []*structs.StakeInfo {
+	snapshot, err := f.AtEpoch(f.currentEpoch())
+	if err != nil {
+		ctxerror.Log15(utils.GetLogInstance().Warn, ctxerror.New("[Stake] cannot read the locked stakes").WithCause(err))
+		return nil
+	}
+	return snapshot.FindStakeInfoByAccount(addr)
+}
+
+// AtEpoch returns the finder of the stakes locked as of the block preceding
+// the epoch block of the given epoch. It fails if the state of that block is
+// not available, rather than finding other stakes than the other nodes.
+func (f *StakeLockInfoFinder) AtEpoch(epoch uint64) (StakeInfoFinder, error) {
+	if snapshot, ok := f.snapshots.Get(epoch); ok {
+		return snapshot.(*stakeSnapshot), nil
+	}
+	snapshot, err := f.readSnapshot(epoch)
+	if err != nil {
+		return nil, ctxerror.New("cannot read the locked stakes",
+			"epoch", epoch,
+		).WithCause(err)
+	}
+	f.snapshots.Add(epoch, snapshot)
+	return snapshot, nil
+}
+
+// currentEpoch returns the epoch of the head of the beacon chain.
+func (f *StakeLockInfoFinder) currentEpoch() uint64 {
+	header := f.beaconChain.CurrentHeader()
+	if header == nil {
+		return core.GenesisEpoch
+	}
+	return core.GetEpochFromBlockNumber(header.Number.Uint64())
+}
+
+// readSnapshot reads the stakes locked as of the block preceding the epoch
+// block of the given epoch, or as of the genesis block for the genesis epoch.
+func (f *StakeLockInfoFinder) readSnapshot(epoch uint64) (*stakeSnapshot, error) {
+	blockNum := core.GetBlockNumberFromEpoch(epoch)
+	if blockNum > 0 {
+		blockNum--
+	}
+	header := f.beaconChain.GetHeaderByNumber(blockNum)
+	if header == nil {
+		return nil, ctxerror.New("cannot find the block preceding the epoch block", "blockNum", blockNum)
+	}
+	stakes, err := f.beaconChain.ReadLockedStakes(header)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &stakeSnapshot{
+		byNodeKey: make(map[types.BlsPublicKey][]*structs.StakeInfo),
+		byAccount: make(map[common.Address][]*structs.StakeInfo),
+		fallback:  f.fallback,
+	}
+	for address, stakeInfo := range stakes {
+		snapshot.byNodeKey[stakeInfo.BlsPublicKey] = append(snapshot.byNodeKey[stakeInfo.BlsPublicKey], stakeInfo)
+		snapshot.byAccount[address] = append(snapshot.byAccount[address], stakeInfo)
+	}
+	return snapshot, nil
+}
+
+// stakeSnapshot is the finder of the stakes locked as of an epoch block.
+type stakeSnapshot struct {
+	byNodeKey map[types.BlsPublicKey][]*structs.StakeInfo
+	byAccount map[common.Address][]*structs.StakeInfo
+	fallback  StakeInfoFinder
+}
+
+// FindStakeInfoByNodeKey returns the stakes locked with the given node key.
+func (s *stakeSnapshot) FindStakeInfoByNodeKey(key *bls.PublicKey) []*structs.StakeInfo {
+	var pk types.BlsPublicKey
+	if err := pk.FromLibBLSPublicKey(key); err != nil {
+		ctxerror.Log15(utils.GetLogInstance().Warn, ctxerror.New(
+			"cannot convert BLS public key",
+		).WithCause(err))
+		return nil
+	}
+	if l := s.byNodeKey[pk]; len(l) > 0 {
+		return l
+	}
+	return s.fallback.FindStakeInfoByNodeKey(key)
+}
+
+// FindStakeInfoByAccount returns the stakes locked by the given account.
+func (s *stakeSnapshot) FindStakeInfoByAccount(addr common.Address) []*structs.StakeInfo {
+	if l := s.byAccount[addr]; len(l) > 0 {
+		return l
+	}
+	return s.fallback.FindStakeInfoByAccount(addr)
+}
@@ -0,0 +1,78 @@
+package consensus
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/harmony-one/harmony/contracts/structs"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/crypto/bls"
+)
+
+// testBeaconChain is a beacon chain made of the given headers and the stakes
+// locked as of each of them.
+type testBeaconChain struct {
+	headers []*types.Header
+	stakes  map[uint64]map[common.Address]*structs.StakeInfo
+}
+
+func (c *testBeaconChain) CurrentHeader() *types.Header {
+	return c.headers[len(c.headers)-1]
+}
+
+func (c *testBeaconChain) GetHeaderByNumber(number uint64) *types.Header {
+	for _, header := range c.headers {
+		if header.Number.Uint64() == number {
+			return header
+		}
+	}
+	return nil
+}
+
+func (c *testBeaconChain) ReadLockedStakes(header *types.Header) (map[common.Address]*structs.StakeInfo, error) {
+	return c.stakes[header.Number.Uint64()], nil
+}
+
+func TestStakeLockInfoFinder(t *testing.T) {
+	key := bls.RandPrivateKey().GetPublicKey()
+	var pubKey types.BlsPublicKey
+	pubKey.FromLibBLSPublicKey(key)
+	account := common.HexToAddress("0x1234")
+	epochBlock := core.GetBlockNumberFromEpoch(1)
+
+	chain := &testBeaconChain{
+		headers: []*types.Header{
+			{Number: big.NewInt(0)},
+			{Number: new(big.Int).SetUint64(epochBlock - 1)},
+			{Number: new(big.Int).SetUint64(epochBlock)},
+		},
+		stakes: map[uint64]map[common.Address]*structs.StakeInfo{
+			epochBlock - 1: {account: {Account: account, BlsPublicKey: pubKey, Amount: big.NewInt(100)}},
+		},
+	}
+	finder := NewStakeLockInfoFinder(chain, fixedStakeInfoFinder{})
+
+	genesisStakes, err := finder.AtEpoch(0)
+	if err != nil {
+		t.Fatalf("cannot find the stakes of the genesis epoch: %v", err)
+	}
+	if stakeInfos := genesisStakes.FindStakeInfoByNodeKey(key); len(stakeInfos) != 1 || stakeInfos[0].Amount != nil {
+		t.Errorf("stake locked after the genesis block found at genesis epoch: %v", stakeInfos)
+	}
+	epochStakes, err := finder.AtEpoch(1)
+	if err != nil {
+		t.Fatalf("cannot find the stakes of epoch 1: %v", err)
+	}
+	stakeInfos := epochStakes.FindStakeInfoByNodeKey(key)
+	if len(stakeInfos) != 1 || stakeInfos[0].Amount.Cmp(big.NewInt(100)) != 0 || stakeInfos[0].Account != account {
+		t.Errorf("wrong stake at epoch 1: %v", stakeInfos)
+	}
+	if stakeInfos := finder.FindStakeInfoByAccount(account); len(stakeInfos) != 1 || stakeInfos[0].BlsPublicKey != pubKey {
+		t.Errorf("wrong stake of the account at the current epoch: %v", stakeInfos)
+	}
+	if _, err := finder.AtEpoch(3); err == nil {
+		t.Error("stakes found at an epoch without epoch block")
+	}
+}
@@ -0,0 +1,185 @@
+package consensus
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	msg_pb "github.com/harmony-one/harmony/api/proto/message"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/internal/metrics"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/p2p/host"
+)
+
+// RoundTrace is the trace of a consensus round seen by this node.
+type RoundTrace struct {
+	ConsensusID uint32
+	ViewID      uint32
+	BlockHash   common.Hash
+	IsLeader    bool
+
+	Announced time.Time // when the block was announced, or the announce received
+	Prepared  time.Time // when the prepare quorum was reached
+	Committed time.Time // when the commit quorum was reached
+
+	NumPrepareSigs int
+	NumCommitSigs  int
+	// BLS public keys, in hex, of the committee members who didn't sign
+	MissingPrepareSigners []string
+	MissingCommitSigners  []string
+
+	BytesSent int // bytes of consensus messages sent by this node in the round
+}
+
+// PrepareDuration returns the time spent from the announce to the prepare quorum.
+func (trace *RoundTrace) PrepareDuration() time.Duration {
+	if trace.Announced.IsZero() || trace.Prepared.IsZero() {
+		return 0
+	}
+	return trace.Prepared.Sub(trace.Announced)
+}
+
+// CommitDuration returns the time spent from the prepare quorum to the commit quorum.
+func (trace *RoundTrace) CommitDuration() time.Duration {
+	if trace.Prepared.IsZero() || trace.Committed.IsZero() {
+		return 0
+	}
+	return trace.Committed.Sub(trace.Prepared)
+}
+
+// roundTracer keeps the traces of the rounds in flight.
+type roundTracer struct {
+	mutex  sync.Mutex
+	rounds map[uint32]*RoundTrace
+}
+
+// trace returns the trace of the given round, starting it if needed.
+// It must be called with the tracer mutex held.
+func (tracer *roundTracer) trace(consensusID uint32) *RoundTrace {
+	if tracer.rounds == nil {
+		tracer.rounds = make(map[uint32]*RoundTrace)
+	}
+	trace, ok := tracer.rounds[consensusID]
+	if !ok {
+		trace = &RoundTrace{ConsensusID: consensusID}
+		tracer.rounds[consensusID] = trace
+	}
+	return trace
+}
+
+// traceAnnounce records the announce of the block of the current round.
+func (consensus *Consensus) traceAnnounce() {
+	consensus.tracer.mutex.Lock()
+	defer consensus.tracer.mutex.Unlock()
+	trace := consensus.tracer.trace(consensus.consensusID)
+	trace.ViewID = consensus.viewID
+	trace.BlockHash = consensus.blockHash
+	trace.IsLeader = consensus.IsLeader()
+	trace.Announced = time.Now()
+}
+
+// tracePrepared records the prepare quorum of the current round, signed by the given mask.
+func (consensus *Consensus) tracePrepared(mask *bls_cosi.Mask) {
+	consensus.tracer.mutex.Lock()
+	defer consensus.tracer.mutex.Unlock()
+	trace := consensus.tracer.trace(consensus.consensusID)
+	trace.Prepared = time.Now()
+	trace.NumPrepareSigs = mask.CountEnabled()
+	trace.MissingPrepareSigners = missingSigners(mask)
+}
+
+// traceCommitted records the commit quorum of the current round, signed by
+// the given mask, and reports the trace of the finished round.
+func (consensus *Consensus) traceCommitted(mask *bls_cosi.Mask) {
+	consensus.tracer.mutex.Lock()
+	trace := consensus.tracer.trace(consensus.consensusID)
+	trace.Committed = time.Now()
+	trace.NumCommitSigs = mask.CountEnabled()
+	trace.MissingCommitSigners = missingSigners(mask)
+	for id := range consensus.tracer.rounds {
+		// drop the rounds which didn't make it as well
+		if id <= trace.ConsensusID {
+			delete(consensus.tracer.rounds, id)
+		}
+	}
+	consensus.tracer.mutex.Unlock()
+
+	utils.GetLogInstance().Info("[Tracer] Consensus round",
+		"consensusID", trace.ConsensusID,
+		"viewID", trace.ViewID,
+		"blockHash", trace.BlockHash,
+		"isLeader", trace.IsLeader,
+		"prepareDuration", trace.PrepareDuration(),
+		"commitDuration", trace.CommitDuration(),
+		"numPrepareSigs", trace.NumPrepareSigs,
+		"numCommitSigs", trace.NumCommitSigs,
+		"missingPrepareSigners", trace.MissingPrepareSigners,
+		"missingCommitSigners", trace.MissingCommitSigners,
+		"bytesSent", trace.BytesSent)
+	consensus.recordRoundMetrics(trace)
+	if consensus.OnRoundTraced != nil {
+		consensus.OnRoundTraced(trace)
+	}
+}
+
+// sendMessage broadcasts the consensus message to the shard, accounting its
+// size in the trace of the current round.
+func (consensus *Consensus) sendMessage(msgType msg_pb.MessageType, msgToSend []byte) error {
+	consensus.tracer.mutex.Lock()
+	consensus.tracer.trace(consensus.consensusID).BytesSent += len(msgToSend)
+	consensus.tracer.mutex.Unlock()
+	metrics.DefaultRegistry.Add("harmony_consensus_sent_bytes_total", "Bytes of consensus messages sent, by message type.",
+		metrics.Labels{"shard": fmt.Sprint(consensus.ShardID), "type": msgType.String()}, float64(len(msgToSend)))
+	return consensus.host.SendMessageToGroups([]p2p.GroupID{p2p.NewGroupIDByShardID(p2p.ShardID(consensus.ShardID))}, host.ConstructP2pMessage(byte(17), msgToSend))
+}
+
+// recordRoundMetrics exports the trace of a finished round as metrics.
+func (consensus *Consensus) recordRoundMetrics(trace *RoundTrace) {
+	shard := fmt.Sprint(consensus.ShardID)
+	role := "validator"
+	if trace.IsLeader {
+		role = "leader"
+	}
+	registry := metrics.DefaultRegistry
+	registry.Add("harmony_consensus_rounds_total", "Number of consensus rounds committed.",
+		metrics.Labels{"shard": shard, "role": role}, 1)
+	registry.Set("harmony_consensus_consensus_id", "Consensus ID of the last committed round.",
+		metrics.Labels{"shard": shard}, float64(trace.ConsensusID))
+	registry.Set("harmony_consensus_view_id", "View ID of the last committed round.",
+		metrics.Labels{"shard": shard}, float64(trace.ViewID))
+	for phase, duration := range map[string]time.Duration{
+		"prepare": trace.PrepareDuration(),
+		"commit":  trace.CommitDuration(),
+	} {
+		labels := metrics.Labels{"shard": shard, "phase": phase}
+		registry.Set("harmony_consensus_phase_seconds", "Duration of the phases of the last committed round.", labels, duration.Seconds())
+		registry.Add("harmony_consensus_phase_seconds_total", "Total duration of the phases of the committed rounds.", labels, duration.Seconds())
+	}
+	registry.Set("harmony_consensus_signatures", "Number of signatures of the phases of the last committed round.",
+		metrics.Labels{"shard": shard, "phase": "prepare"}, float64(trace.NumPrepareSigs))
+	registry.Set("harmony_consensus_signatures", "Number of signatures of the phases of the last committed round.",
+		metrics.Labels{"shard": shard, "phase": "commit"}, float64(trace.NumCommitSigs))
+	for phase, signers := range map[string][]string{
+		"prepare": trace.MissingPrepareSigners,
+		"commit":  trace.MissingCommitSigners,
+	} {
+		for _, signer := range signers {
+			registry.Add("harmony_consensus_missing_signatures_total", "Number of committed rounds a validator didn't sign, by phase and BLS public key.",
+				metrics.Labels{"shard": shard, "phase": phase, "validator": signer}, 1)
+		}
+	}
+}
+
+// missingSigners returns the BLS public keys, in hex, of the committee members
+// who didn't sign according to the mask.
+func missingSigners(mask *bls_cosi.Mask) []string {
+	missing := []string{}
+	for _, pubKey := range mask.GetPubKeyFromMask(false) {
+		missing = append(missing, hex.EncodeToString(pubKey.Serialize()))
+	}
+	return missing
+}
@@ -0,0 +1,57 @@
+package consensus
+
+import (
+	"encoding/hex"
+	"testing"
+
+	bls_core "github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/crypto/bls"
+)
+
+func TestTraceRound(t *testing.T) {
+	pubKeys := []*bls_core.PublicKey{}
+	for i := 0; i < 3; i++ {
+		pubKeys = append(pubKeys, bls.RandPrivateKey().GetPublicKey())
+	}
+	consensus := &Consensus{PublicKeys: pubKeys, consensusID: 5, viewID: 2, isLeader: 1}
+	var traced *RoundTrace
+	consensus.OnRoundTraced = func(trace *RoundTrace) {
+		traced = trace
+	}
+
+	mask, _ := bls.NewMask(pubKeys, nil)
+	mask.SetKey(pubKeys[0], true)
+	mask.SetKey(pubKeys[1], true)
+
+	// a stale round which never committed
+	consensus.tracer.mutex.Lock()
+	consensus.tracer.trace(4)
+	consensus.tracer.mutex.Unlock()
+
+	consensus.traceAnnounce()
+	consensus.tracePrepared(mask)
+	mask.SetKey(pubKeys[2], true)
+	consensus.traceCommitted(mask)
+
+	if traced == nil {
+		t.Fatal("round not reported")
+	}
+	if traced.ConsensusID != 5 || traced.ViewID != 2 || !traced.IsLeader {
+		t.Errorf("wrong round traced: %+v", traced)
+	}
+	if traced.NumPrepareSigs != 2 || traced.NumCommitSigs != 3 {
+		t.Errorf("wrong number of signatures: prepare %v, commit %v", traced.NumPrepareSigs, traced.NumCommitSigs)
+	}
+	if len(traced.MissingPrepareSigners) != 1 || traced.MissingPrepareSigners[0] != hex.EncodeToString(pubKeys[2].Serialize()) {
+		t.Errorf("wrong missing prepare signers: %v", traced.MissingPrepareSigners)
+	}
+	if len(traced.MissingCommitSigners) != 0 {
+		t.Errorf("wrong missing commit signers: %v", traced.MissingCommitSigners)
+	}
+	if traced.PrepareDuration() < 0 || traced.CommitDuration() < 0 {
+		t.Error("negative phase duration")
+	}
+	if len(consensus.tracer.rounds) != 0 {
+		t.Errorf("%v rounds left in flight", len(consensus.tracer.rounds))
+	}
+}
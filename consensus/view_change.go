@@ -1,6 +1,23 @@
 package consensus
 
-import "sync"
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	msg_pb "github.com/harmony-one/harmony/api/proto/message"
+	"github.com/harmony-one/harmony/core/types"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/internal/ctxerror"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+)
 
 // PbftPhase  PBFT phases: pre-prepare, prepare and commit
 type PbftPhase int
@@ -22,11 +39,11 @@ const (
 	ViewChanging
 )
 
-// PbftMode contains mode and consensusID of viewchanging
+// PbftMode contains mode and viewID of viewchanging
 type PbftMode struct {
-	mode        Mode
-	consensusID uint32
-	mux         sync.Mutex
+	mode   Mode
+	viewID uint32
+	mux    sync.Mutex
 }
 
 // Mode return the current node mode
@@ -41,21 +58,502 @@ func (pm *PbftMode) SetMode(m Mode) {
 	pm.mode = m
 }
 
-// ConsensusID return the current viewchanging id
-func (pm *PbftMode) ConsensusID() uint32 {
-	return pm.consensusID
+// ViewID return the current viewchanging id
+func (pm *PbftMode) ViewID() uint32 {
+	return pm.viewID
 }
 
-// SetConsensusID sets the viewchanging id accordingly
-func (pm *PbftMode) SetConsensusID(consensusID uint32) {
+// SetViewID sets the viewchanging id accordingly
+func (pm *PbftMode) SetViewID(viewID uint32) {
 	pm.mux.Lock()
 	defer pm.mux.Unlock()
-	pm.consensusID = consensusID
+	pm.viewID = viewID
+}
+
+// preparedBlock is a block prepared by a quorum of the committee together with its proof.
+// It is carried through a view change so the new leader re-proposes it instead of losing it.
+type preparedBlock struct {
+	blockHash    [32]byte
+	block        []byte
+	sigAndBitmap []byte // 48 bytes aggregated prepare signature followed by the prepare bitmap
+	viewID       uint32 // the view the block was prepared in
+	message      []byte // the PREPARED message signed by the leader of the view, proving the view
+}
+
+// GetViewID returns the current view id
+func (consensus *Consensus) GetViewID() uint32 {
+	return consensus.viewID
+}
+
+// leaderKeyForView returns the public key of the leader of the given view
+// for the next block.
+// The leader is picked deterministically by rotating through the committee,
+// or through the leader schedule if leader rotation is on.
+func (consensus *Consensus) leaderKeyForView(viewID uint32) *bls.PublicKey {
+	return consensus.leaderKeyForBlock(consensus.nextBlockNumber(), viewID)
+}
+
+// nextBlockNumber returns the number of the block the committee is going to agree on.
+func (consensus *Consensus) nextBlockNumber() uint64 {
+	if consensus.ChainReader != nil {
+		if header := consensus.ChainReader.CurrentHeader(); header != nil {
+			return header.Number.Uint64() + 1
+		}
+	}
+	return uint64(consensus.consensusID) + 1
+}
+
+// viewChangeHash returns the hash signed by the validators to agree on moving to the given view.
+// The view IDs only increase from round to round, so the validators lagging
+// behind the round of the new leader still sign the same hash.
+func viewChangeHash(viewID uint32) []byte {
+	buffer := make([]byte, 4)
+	binary.BigEndian.PutUint32(buffer, viewID)
+	hash := sha256.Sum256(buffer)
+	return hash[:]
+}
+
+// WaitForViewChange watches the progress of the leader and starts a view change
+// if the leader fails to propose or commit a block in time.
+func (consensus *Consensus) WaitForViewChange(stopChan chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(viewChangeCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if consensus.IsLeader() {
+					continue
+				}
+				if consensus.mode.Mode() == ViewChanging {
+					if consensus.viewChangeTimeout.CheckExpire() {
+						consensus.startViewChange(consensus.mode.ViewID() + 1)
+					}
+					continue
+				}
+				if consensus.idleTimeout.CheckExpire() || consensus.commitTimeout.CheckExpire() {
+					consensus.startViewChange(consensus.viewID + 1)
+				}
+			case <-stopChan:
+				return
+			}
+		}
+	}()
 }
 
 // startViewChange start a new view change
-func (consensus *Consensus) startViewChange(consensusID uint32) {
+func (consensus *Consensus) startViewChange(viewID uint32) {
+	consensus.mutex.Lock()
+	defer consensus.mutex.Unlock()
+
+	if viewID <= consensus.viewID || (consensus.mode.Mode() == ViewChanging && viewID < consensus.mode.ViewID()) {
+		return
+	}
 	consensus.mode.SetMode(ViewChanging)
-	consensus.mode.SetConsensusID(consensusID)
-	// TODO (cm): implement the actual logic
+	consensus.mode.SetViewID(viewID)
+	consensus.idleTimeout.Stop()
+	consensus.commitTimeout.Stop()
+
+	// timeout of view change v+n is n*viewChangeDuration
+	consensus.viewChangeTimeout = *utils.NewTimeout(time.Duration(viewID-consensus.viewID) * consensus.viewChangeDuration)
+	consensus.viewChangeTimeout.Start()
+
+	nextLeader := consensus.leaderKeyForView(viewID)
+	if nextLeader == nil {
+		utils.GetLogInstance().Warn("[ViewChange] no committee to pick the next leader from", "viewID", viewID)
+		return
+	}
+	utils.GetLogInstance().Warn("[ViewChange] starting view change", "viewID", viewID, "consensusID", consensus.consensusID, "nextLeader", utils.GetBlsAddress(nextLeader))
+
+	if nextLeader.IsEqual(consensus.PubKey) {
+		// The next leader counts its own view change directly
+		consensus.collectViewChange(viewID)
+		return
+	}
+
+	msgToSend := consensus.constructViewChangeMessage(viewID)
+	utils.GetLogInstance().Warn("[Consensus]", "sent viewchange message", len(msgToSend))
+	consensus.sendMessage(msg_pb.MessageType_VIEWCHANGE, msgToSend)
+}
+
+// collectViewChange makes the node start collecting view change messages as the leader of the given view.
+// It must be called with the consensus mutex held.
+func (consensus *Consensus) collectViewChange(viewID uint32) {
+	if consensus.viewChangeViewID == viewID && consensus.viewChangeBitmap != nil {
+		return
+	}
+	consensus.viewChangeViewID = viewID
+	consensus.viewChangeSigs = map[common.Address]*bls.Sign{}
+	consensus.viewChangeBitmap, _ = bls_cosi.NewMask(consensus.PublicKeys, nil)
+	consensus.viewChangePrepared = consensus.prepared
+
+	consensus.viewChangeSigs[consensus.SelfAddress] = consensus.priKey.SignHash(viewChangeHash(viewID))
+	consensus.viewChangeBitmap.SetKey(consensus.PubKey, true)
+	consensus.tryNewView()
+}
+
+// processViewChangeMessage processes the view change message sent to the leader of the new view
+func (consensus *Consensus) processViewChangeMessage(message *msg_pb.Message) {
+	viewChangeMsg := message.GetViewchange()
+	if viewChangeMsg == nil {
+		utils.GetLogInstance().Debug("[ViewChange] missing viewchange request")
+		return
+	}
+	senderKey, err := bls_cosi.BytesToBlsPublicKey(viewChangeMsg.SenderPubkey)
+	if err != nil {
+		utils.GetLogInstance().Debug("Failed to deserialize BLS public key", "error", err)
+		return
+	}
+	senderAddress := utils.GetBlsAddress(senderKey)
+	viewID := viewChangeMsg.ViewId
+
+	consensus.mutex.Lock()
+	defer consensus.mutex.Unlock()
+
+	if !consensus.IsValidatorInCommittee(senderAddress) {
+		utils.GetLogInstance().Error("Invalid validator", "validatorAddress", senderAddress)
+		return
+	}
+	if err := verifyMessageSig(senderKey, message); err != nil {
+		utils.GetLogInstance().Warn("[ViewChange] Failed to verify the message signature", "error", err, "validatorAddress", senderAddress)
+		return
+	}
+	if viewID <= consensus.viewID || viewID < consensus.viewChangeViewID {
+		utils.GetLogInstance().Debug("[ViewChange] Received stale viewchange message", "viewID", viewID, "myViewID", consensus.viewID)
+		return
+	}
+	if viewChangeMsg.ConsensusId > consensus.consensusID {
+		// this node can't lead a round it hasn't reached yet
+		utils.GetLogInstance().Warn("[ViewChange] viewchange message of a later round", "myConsensusId", consensus.consensusID, "theirConsensusId", viewChangeMsg.ConsensusId)
+		select {
+		case consensus.ConsensusIDLowChan <- struct{}{}:
+		default:
+		}
+		return
+	}
+	nextLeader := consensus.leaderKeyForView(viewID)
+	if nextLeader == nil || !nextLeader.IsEqual(consensus.PubKey) {
+		// the message is for another leader
+		return
+	}
+
+	var sign bls.Sign
+	if err := sign.Deserialize(viewChangeMsg.ViewchangeSig); err != nil {
+		utils.GetLogInstance().Error("Failed to deserialize bls signature", "validatorAddress", senderAddress)
+		return
+	}
+	if !sign.VerifyHash(senderKey, viewChangeHash(viewID)) {
+		utils.GetLogInstance().Error("Received invalid BLS signature", "validatorAddress", senderAddress)
+		return
+	}
+
+	consensus.collectViewChange(viewID)
+	if viewID <= consensus.viewID {
+		// already moved to the new view
+		return
+	}
+	if _, ok := consensus.viewChangeSigs[senderAddress]; ok {
+		utils.GetLogInstance().Debug("[ViewChange] Already received viewchange message from the validator", "validatorAddress", senderAddress)
+		return
+	}
+	if consensus.reachesQuorum(consensus.viewChangeBitmap) {
+		utils.GetLogInstance().Debug("[ViewChange] Received additional viewchange message", "validatorAddress", senderAddress)
+		return
+	}
+
+	// A validator lagging behind only has prepared blocks of earlier rounds
+	if len(viewChangeMsg.BlockHash) != 0 && viewChangeMsg.ConsensusId == consensus.consensusID {
+		prepared, err := consensus.verifyPreparedBlock(viewID, viewChangeMsg.BlockHash, viewChangeMsg.Block, viewChangeMsg.PreparedSig, viewChangeMsg.PreparedMessage)
+		if err != nil {
+			ctxerror.Log15(utils.GetLogInstance().Warn, ctxerror.New("[ViewChange] invalid prepared block",
+				"validatorAddress", senderAddress,
+			).WithCause(err))
+			return
+		}
+		// Carry over the block prepared in the latest view, which supersedes
+		// the blocks prepared in the earlier views
+		if consensus.viewChangePrepared == nil || prepared.viewID > consensus.viewChangePrepared.viewID {
+			consensus.viewChangePrepared = prepared
+		}
+	}
+
+	utils.GetLogInstance().Debug("[ViewChange] Received new viewchange message", "numReceivedSoFar", len(consensus.viewChangeSigs), "viewID", viewID, "validatorAddress", senderAddress)
+	consensus.viewChangeSigs[senderAddress] = &sign
+	consensus.viewChangeBitmap.SetKey(senderKey, true)
+	consensus.tryNewView()
+}
+
+// tryNewView sends the new view message once enough view change messages are collected,
+// and takes over as the leader of the new view.
+// It must be called with the consensus mutex held.
+func (consensus *Consensus) tryNewView() {
+	if !consensus.reachesQuorum(consensus.viewChangeBitmap) {
+		return
+	}
+	viewID := consensus.viewChangeViewID
+	utils.GetLogInstance().Info("[ViewChange] Enough viewchange messages received", "num", len(consensus.viewChangeSigs), "viewID", viewID)
+
+	msgToSend := consensus.constructNewViewMessage()
+	utils.GetLogInstance().Warn("[Consensus]", "sent newview message", len(msgToSend))
+	consensus.sendMessage(msg_pb.MessageType_NEWVIEW, msgToSend)
+
+	prepared := consensus.viewChangePrepared
+	consensus.switchToView(viewID, consensus.PubKey)
+
+	if prepared != nil {
+		// Re-propose the block prepared in the previous view so that it can't get lost
+		var blockObj types.Block
+		if err := rlp.DecodeBytes(prepared.block, &blockObj); err == nil {
+			utils.GetLogInstance().Info("[ViewChange] Re-proposing prepared block", "blockHash", blockObj.Hash())
+			startTime = time.Now()
+			consensus.startConsensus(&blockObj)
+			return
+		}
+	}
+	go func() {
+		consensus.ReadySignal <- struct{}{}
+	}()
+}
+
+// processNewViewMessage processes the new view message sent from the leader of the new view
+func (consensus *Consensus) processNewViewMessage(message *msg_pb.Message) {
+	newViewMsg := message.GetViewchange()
+	if newViewMsg == nil {
+		utils.GetLogInstance().Debug("[ViewChange] missing newview request")
+		return
+	}
+	senderKey, err := bls_cosi.BytesToBlsPublicKey(newViewMsg.SenderPubkey)
+	if err != nil {
+		utils.GetLogInstance().Debug("Failed to deserialize BLS public key", "error", err)
+		return
+	}
+	viewID := newViewMsg.ViewId
+
+	consensus.mutex.Lock()
+	defer consensus.mutex.Unlock()
+
+	if viewID <= consensus.viewID {
+		utils.GetLogInstance().Debug("[ViewChange] Received stale newview message", "viewID", viewID, "myViewID", consensus.viewID)
+		return
+	}
+	if newViewMsg.ConsensusId < consensus.consensusID {
+		// the new leader would propose a block of an earlier round
+		utils.GetLogInstance().Debug("[ViewChange] Received newview message of an earlier round", "myConsensusId", consensus.consensusID, "theirConsensusId", newViewMsg.ConsensusId)
+		return
+	}
+	leaderKey := consensus.leaderKeyForView(viewID)
+	if leaderKey == nil || !leaderKey.IsEqual(senderKey) {
+		utils.GetLogInstance().Warn("[ViewChange] newview message is not sent by the leader of the view", "viewID", viewID, "sender", utils.GetBlsAddress(senderKey))
+		return
+	}
+	if err := verifyMessageSig(senderKey, message); err != nil {
+		utils.GetLogInstance().Warn("[ViewChange] Failed to verify the message signature", "error", err)
+		return
+	}
+
+	// Verify the aggregated view change signature reaches the quorum
+	multiSigAndBitmap := newViewMsg.ViewchangeMultisig
+	if len(multiSigAndBitmap) < 48 {
+		utils.GetLogInstance().Warn("[ViewChange] newview message has no valid multi signature", "viewID", viewID)
+		return
+	}
+	multiSig := bls.Sign{}
+	if err := multiSig.Deserialize(multiSigAndBitmap[:48]); err != nil {
+		utils.GetLogInstance().Warn("Failed to deserialize the multi signature for view change", "Error", err)
+		return
+	}
+	mask, err := bls_cosi.NewMask(consensus.PublicKeys, nil)
+	if err != nil {
+		utils.GetLogInstance().Warn("[ViewChange] Failed to create mask", "Error", err)
+		return
+	}
+	if err := mask.SetMask(multiSigAndBitmap[48:]); err != nil {
+		utils.GetLogInstance().Warn("[ViewChange] Failed to set mask", "Error", err)
+		return
+	}
+	if !consensus.reachesQuorum(mask) {
+		utils.GetLogInstance().Warn("[ViewChange] Not enough view change signatures", "num", mask.CountEnabled())
+		return
+	}
+	if !multiSig.VerifyHash(mask.AggregatePublic, viewChangeHash(viewID)) {
+		utils.GetLogInstance().Warn("Failed to verify the multi signature for view change", "viewID", viewID)
+		return
+	}
+
+	if newViewMsg.ConsensusId > consensus.consensusID {
+		// follow the new view, and catch up with the round of the new leader
+		utils.GetLogInstance().Warn("[ViewChange] newview message of a later round", "myConsensusId", consensus.consensusID, "theirConsensusId", newViewMsg.ConsensusId)
+		select {
+		case consensus.ConsensusIDLowChan <- struct{}{}:
+		default:
+		}
+	} else if len(newViewMsg.BlockHash) != 0 {
+		prepared, err := consensus.verifyPreparedBlock(viewID, newViewMsg.BlockHash, newViewMsg.Block, newViewMsg.PreparedSig, newViewMsg.PreparedMessage)
+		if err != nil {
+			ctxerror.Log15(utils.GetLogInstance().Warn, ctxerror.New("[ViewChange] invalid prepared block in newview message",
+				"viewID", viewID,
+			).WithCause(err))
+			return
+		}
+		consensus.prepared = prepared
+	}
+
+	utils.GetLogInstance().Info("[ViewChange] Moving to new view", "viewID", viewID, "leader", utils.GetBlsAddress(leaderKey))
+	consensus.switchToView(viewID, leaderKey)
+}
+
+// switchToView moves the node into the given view under the given leader.
+// It must be called with the consensus mutex held.
+func (consensus *Consensus) switchToView(viewID uint32, leaderKey *bls.PublicKey) {
+	consensus.viewID = viewID
+	consensus.mode.SetMode(Normal)
+	consensus.mode.SetViewID(viewID)
+	consensus.viewChangeTimeout.Stop()
+	consensus.commitTimeout.Stop()
+
+	consensus.viewChangeSigs = map[common.Address]*bls.Sign{}
+	consensus.viewChangeBitmap = nil
+	consensus.viewChangePrepared = nil
+
+	consensus.setLeader(leaderKey)
+	consensus.blockHash = [32]byte{}
+	consensus.ResetState()
+}
+
+// setLeader makes the node follow the given leader, or lead if the key is its own.
+// It must be called with the consensus mutex held.
+func (consensus *Consensus) setLeader(leaderKey *bls.PublicKey) {
+	if leaderKey.IsEqual(consensus.PubKey) {
+		consensus.leader = consensus.host.GetSelfPeer()
+		consensus.SetIsLeader(true)
+		consensus.idleTimeout.Stop()
+	} else {
+		if peer := consensus.GetPeerByAddress(utils.GetBlsAddress(leaderKey).Hex()); peer != nil {
+			consensus.leader = *peer
+		} else {
+			consensus.leader = p2p.Peer{ConsensusPubKey: leaderKey}
+		}
+		consensus.SetIsLeader(false)
+		consensus.idleTimeout.Start()
+	}
+}
+
+// verifyPreparedBlock checks the block of the current round was prepared by a
+// quorum of the committee in a view earlier than the given one, as proven by
+// the PREPARED message of the leader of that view.
+func (consensus *Consensus) verifyPreparedBlock(viewID uint32, blockHash []byte, block []byte, sigAndBitmap []byte, preparedMessage []byte) (*preparedBlock, error) {
+	var blockObj types.Block
+	if err := rlp.DecodeBytes(block, &blockObj); err != nil {
+		return nil, ctxerror.New("cannot decode prepared block").WithCause(err)
+	}
+	if blockObj.Hash() != common.BytesToHash(blockHash) {
+		return nil, ctxerror.New("prepared block hash mismatch",
+			"blockHash", common.BytesToHash(blockHash),
+			"actualHash", blockObj.Hash(),
+		)
+	}
+	if blockObj.NumberU64() != consensus.nextBlockNumber() {
+		return nil, ctxerror.New("prepared block of another round",
+			"blockNum", blockObj.NumberU64(),
+			"nextBlockNum", consensus.nextBlockNumber(),
+		)
+	}
+	message := &msg_pb.Message{}
+	if err := protobuf.Unmarshal(preparedMessage, message); err != nil {
+		return nil, ctxerror.New("cannot decode the PREPARED message").WithCause(err)
+	}
+	consensusMsg := message.GetConsensus()
+	if consensusMsg == nil || !bytes.Equal(consensusMsg.BlockHash, blockHash) || !bytes.Equal(consensusMsg.Payload, sigAndBitmap) {
+		return nil, ctxerror.New("PREPARED message of another prepared block")
+	}
+	if consensusMsg.ViewId >= viewID {
+		return nil, ctxerror.New("block prepared in a later view",
+			"preparedViewID", consensusMsg.ViewId,
+			"viewID", viewID,
+		)
+	}
+	if err := consensus.verifyPreparedMessage(message); err != nil {
+		return nil, err
+	}
+	prepared := &preparedBlock{
+		block:        block,
+		sigAndBitmap: sigAndBitmap,
+		viewID:       consensusMsg.ViewId,
+		message:      preparedMessage,
+	}
+	copy(prepared.blockHash[:], blockHash)
+	return prepared, nil
+}
+
+// verifyPreparedMessage checks the PREPARED message is signed by the leader of
+// the view it was sent in, and carries the prepare signatures of a quorum of
+// the committee on its block.
+func (consensus *Consensus) verifyPreparedMessage(message *msg_pb.Message) error {
+	consensusMsg := message.GetConsensus()
+	if message.Type != msg_pb.MessageType_PREPARED || consensusMsg == nil {
+		return ctxerror.New("not a PREPARED message", "msgType", message.Type)
+	}
+	leaderKey := consensus.leaderKeyForView(consensusMsg.ViewId)
+	if leaderKey == nil {
+		return ctxerror.New("no leader of the view", "viewID", consensusMsg.ViewId)
+	}
+	if err := verifyMessageSig(leaderKey, message); err != nil {
+		return ctxerror.New("PREPARED message not signed by the leader of the view",
+			"viewID", consensusMsg.ViewId,
+		).WithCause(err)
+	}
+	sigAndBitmap := consensusMsg.Payload
+	if len(sigAndBitmap) < 48 {
+		return ctxerror.New("prepared signature too short", "len", len(sigAndBitmap))
+	}
+	mask, err := bls_cosi.NewMask(consensus.PublicKeys, nil)
+	if err != nil {
+		return ctxerror.New("cannot create prepare mask").WithCause(err)
+	}
+	if err := mask.SetMask(sigAndBitmap[48:]); err != nil {
+		return ctxerror.New("cannot set prepare mask bits").WithCause(err)
+	}
+	if !consensus.reachesQuorum(mask) {
+		return ctxerror.New("not enough prepare signatures",
+			"num", mask.CountEnabled(),
+		)
+	}
+	multiSig := bls.Sign{}
+	if err := multiSig.Deserialize(sigAndBitmap[:48]); err != nil {
+		return ctxerror.New("cannot deserialize prepare multi signature").WithCause(err)
+	}
+	if !multiSig.VerifyHash(mask.AggregatePublic, consensusMsg.BlockHash) {
+		return ctxerror.New("invalid prepare multi signature")
+	}
+	return nil
+}
+
+// catchUpView moves this node to the view of a PREPARED message of the current
+// round if the view is later than its own. The view is only kept in memory, so
+// a node restarting, or having missed the newview message, follows the leader
+// that a quorum of the committee prepared a block with, as the message proves.
+func (consensus *Consensus) catchUpView(message *msg_pb.Message) {
+	consensusMsg := message.GetConsensus()
+	if consensusMsg == nil {
+		return
+	}
+	consensus.mutex.Lock()
+	defer consensus.mutex.Unlock()
+
+	viewID := consensusMsg.ViewId
+	if viewID <= consensus.viewID {
+		return
+	}
+	if !consensus.ignoreConsensusIDCheck && consensusMsg.ConsensusId != consensus.consensusID {
+		return
+	}
+	if err := consensus.verifyPreparedMessage(message); err != nil {
+		ctxerror.Log15(utils.GetLogInstance().Debug, ctxerror.New("[ViewChange] cannot catch up with the view of the PREPARED message",
+			"viewID", viewID,
+		).WithCause(err))
+		return
+	}
+	leaderKey := consensus.leaderKeyForView(viewID)
+	utils.GetLogInstance().Info("[ViewChange] Catching up with the view of the committee", "viewID", viewID, "myViewID", consensus.viewID, "leader", utils.GetBlsAddress(leaderKey))
+	consensus.switchToView(viewID, leaderKey)
 }
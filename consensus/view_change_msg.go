@@ -0,0 +1,96 @@
+package consensus
+
+import (
+	"bytes"
+
+	"github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/api/proto"
+	msg_pb "github.com/harmony-one/harmony/api/proto/message"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/internal/utils"
+)
+
+// Construct the view change message sent to the leader of the given view
+func (consensus *Consensus) constructViewChangeMessage(viewID uint32) []byte {
+	message := &msg_pb.Message{
+		ReceiverType: msg_pb.ReceiverType_LEADER,
+		ServiceType:  msg_pb.ServiceType_CONSENSUS,
+		Type:         msg_pb.MessageType_VIEWCHANGE,
+		Request: &msg_pb.Message_Viewchange{
+			Viewchange: &msg_pb.ViewChangeRequest{},
+		},
+	}
+
+	viewChangeMsg := message.GetViewchange()
+	viewChangeMsg.ViewId = viewID
+	viewChangeMsg.ConsensusId = consensus.consensusID
+	viewChangeMsg.SenderPubkey = consensus.PubKey.Serialize()
+
+	// 48 byte of bls signature on the new view
+	sign := consensus.priKey.SignHash(viewChangeHash(viewID))
+	if sign != nil {
+		viewChangeMsg.ViewchangeSig = sign.Serialize()
+	}
+
+	// the latest prepared block, if any
+	if consensus.prepared != nil {
+		viewChangeMsg.BlockHash = consensus.prepared.blockHash[:]
+		viewChangeMsg.PreparedSig = consensus.prepared.sigAndBitmap
+		viewChangeMsg.Block = consensus.prepared.block
+		viewChangeMsg.PreparedMessage = consensus.prepared.message
+	}
+
+	marshaledMessage, err := consensus.signAndMarshalConsensusMessage(message)
+	if err != nil {
+		utils.GetLogInstance().Error("Failed to sign and marshal the ViewChange message", "error", err)
+	}
+	return proto.ConstructConsensusMessage(marshaledMessage)
+}
+
+// Construct the new view message sent by the new leader, carrying the aggregated view change signatures
+func (consensus *Consensus) constructNewViewMessage() []byte {
+	message := &msg_pb.Message{
+		ReceiverType: msg_pb.ReceiverType_VALIDATOR,
+		ServiceType:  msg_pb.ServiceType_CONSENSUS,
+		Type:         msg_pb.MessageType_NEWVIEW,
+		Request: &msg_pb.Message_Viewchange{
+			Viewchange: &msg_pb.ViewChangeRequest{},
+		},
+	}
+
+	newViewMsg := message.GetViewchange()
+	newViewMsg.ViewId = consensus.viewChangeViewID
+	newViewMsg.ConsensusId = consensus.consensusID
+	newViewMsg.SenderPubkey = consensus.PubKey.Serialize()
+
+	//// Payload
+	buffer := bytes.NewBuffer([]byte{})
+
+	// 48 bytes aggregated signature
+	sigs := []*bls.Sign{}
+	for _, sig := range consensus.viewChangeSigs {
+		sigs = append(sigs, sig)
+	}
+	aggSig := bls_cosi.AggregateSig(sigs)
+	buffer.Write(aggSig.Serialize())
+
+	// Bitmap
+	buffer.Write(consensus.viewChangeBitmap.Bitmap)
+
+	newViewMsg.ViewchangeMultisig = buffer.Bytes()
+	//// END Payload
+
+	// the prepared block to be re-proposed, if any
+	if consensus.viewChangePrepared != nil {
+		newViewMsg.BlockHash = consensus.viewChangePrepared.blockHash[:]
+		newViewMsg.PreparedSig = consensus.viewChangePrepared.sigAndBitmap
+		newViewMsg.Block = consensus.viewChangePrepared.block
+		newViewMsg.PreparedMessage = consensus.viewChangePrepared.message
+	}
+
+	marshaledMessage, err := consensus.signAndMarshalConsensusMessage(message)
+	if err != nil {
+		utils.GetLogInstance().Error("Failed to sign and marshal the NewView message", "error", err)
+	}
+	return proto.ConstructConsensusMessage(marshaledMessage)
+}
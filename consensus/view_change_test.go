@@ -0,0 +1,152 @@
+package consensus
+
+import (
+	"testing"
+
+	protobuf "github.com/golang/protobuf/proto"
+	bls_core "github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/api/proto"
+	msg_pb "github.com/harmony-one/harmony/api/proto/message"
+	"github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/p2p/p2pimpl"
+)
+
+func TestLeaderKeyForView(test *testing.T) {
+	leader := p2p.Peer{IP: "127.0.0.1", Port: "9902"}
+	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "9902")
+	host, err := p2pimpl.NewHost(&leader, priKey)
+	if err != nil {
+		test.Fatalf("newhost failure: %v", err)
+	}
+	consensus, err := New(host, 0, leader, bls.RandPrivateKey())
+	if err != nil {
+		test.Fatalf("Cannot craeate consensus: %v", err)
+	}
+	pubKeys := []*bls_core.PublicKey{}
+	for i := 0; i < 4; i++ {
+		pubKeys = append(pubKeys, bls.RandPrivateKey().GetPublicKey())
+	}
+	consensus.UpdatePublicKeys(pubKeys)
+
+	for viewID := uint32(0); viewID < 8; viewID++ {
+		if !consensus.leaderKeyForView(viewID).IsEqual(pubKeys[viewID%4]) {
+			test.Errorf("Wrong leader for view %d", viewID)
+		}
+	}
+	if !consensus.GetLeaderPubKey().IsEqual(pubKeys[0]) {
+		test.Error("Leader of view 0 should be the first committee member")
+	}
+}
+
+func TestConstructViewChangeMessage(test *testing.T) {
+	leader := p2p.Peer{IP: "127.0.0.1", Port: "9902"}
+	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "9902")
+	host, err := p2pimpl.NewHost(&leader, priKey)
+	if err != nil {
+		test.Fatalf("newhost failure: %v", err)
+	}
+	blsPriKey := bls.RandPrivateKey()
+	consensus, err := New(host, 0, leader, blsPriKey)
+	if err != nil {
+		test.Fatalf("Cannot craeate consensus: %v", err)
+	}
+	consensus.consensusID = 5
+	msgBytes := consensus.constructViewChangeMessage(3)
+	msgBytes, err = proto.GetConsensusMessagePayload(msgBytes)
+	if err != nil {
+		test.Error("Error when getting consensus message", "error", err)
+	}
+
+	msg := &msg_pb.Message{}
+	if err := protobuf.Unmarshal(msgBytes, msg); err != nil {
+		test.Fatal("Can not parse the message", err)
+	}
+	if msg.Type != msg_pb.MessageType_VIEWCHANGE {
+		test.Error("it did not create viewchange message")
+	}
+	viewChangeMsg := msg.GetViewchange()
+	if viewChangeMsg == nil {
+		test.Fatal("Wrong message")
+	}
+	if viewChangeMsg.ViewId != 3 || viewChangeMsg.ConsensusId != 5 {
+		test.Errorf("Wrong view id %d or consensus id %d", viewChangeMsg.ViewId, viewChangeMsg.ConsensusId)
+	}
+	var sign bls_core.Sign
+	if err := sign.Deserialize(viewChangeMsg.ViewchangeSig); err != nil {
+		test.Fatal("Cannot deserialize viewchange signature", err)
+	}
+	if !sign.VerifyHash(blsPriKey.GetPublicKey(), viewChangeHash(3)) {
+		test.Error("Invalid viewchange signature")
+	}
+	if len(viewChangeMsg.BlockHash) != 0 {
+		test.Error("No prepared block should be carried")
+	}
+}
+
+// preparedMessage returns a PREPARED message of the given view, signed by the
+// leader, with the prepare signatures of the given number of members.
+func preparedMessage(viewID uint32, blockHash []byte, leaderKey *bls_core.SecretKey, priKeys []*bls_core.SecretKey, pubKeys []*bls_core.PublicKey, numSigners int) *msg_pb.Message {
+	mask, _ := bls.NewMask(pubKeys, nil)
+	sigs := []*bls_core.Sign{}
+	for i := 0; i < numSigners; i++ {
+		sigs = append(sigs, priKeys[i].SignHash(blockHash))
+		mask.SetKey(pubKeys[i], true)
+	}
+	message := &msg_pb.Message{
+		ReceiverType: msg_pb.ReceiverType_VALIDATOR,
+		ServiceType:  msg_pb.ServiceType_CONSENSUS,
+		Type:         msg_pb.MessageType_PREPARED,
+		Request: &msg_pb.Message_Consensus{
+			Consensus: &msg_pb.ConsensusRequest{
+				ViewId:       viewID,
+				BlockHash:    blockHash,
+				SenderPubkey: leaderKey.GetPublicKey().Serialize(),
+				Payload:      append(bls.AggregateSig(sigs).Serialize(), mask.Bitmap...),
+			},
+		},
+	}
+	(&Consensus{priKey: leaderKey}).signConsensusMessage(message)
+	return message
+}
+
+func TestCatchUpView(test *testing.T) {
+	leader := p2p.Peer{IP: "127.0.0.1", Port: "9902"}
+	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "9902")
+	host, err := p2pimpl.NewHost(&leader, priKey)
+	if err != nil {
+		test.Fatalf("newhost failure: %v", err)
+	}
+	priKeys := []*bls_core.SecretKey{}
+	pubKeys := []*bls_core.PublicKey{}
+	for i := 0; i < 4; i++ {
+		priKeys = append(priKeys, bls.RandPrivateKey())
+		pubKeys = append(pubKeys, priKeys[i].GetPublicKey())
+	}
+	consensus, err := New(host, 0, leader, priKeys[0])
+	if err != nil {
+		test.Fatalf("Cannot craeate consensus: %v", err)
+	}
+	consensus.UpdatePublicKeys(pubKeys)
+	consensus.SetQuorumPolicy(bls.SuperMajorityPolicy{})
+	blockHash := make([]byte, 32)
+
+	// Without a quorum of prepare signatures, the view is not proven
+	consensus.catchUpView(preparedMessage(2, blockHash, priKeys[2], priKeys, pubKeys, 2))
+	if consensus.viewID != 0 {
+		test.Errorf("Moved to view %d without quorum", consensus.viewID)
+	}
+	// A PREPARED message of another leader than the one of the view
+	consensus.catchUpView(preparedMessage(2, blockHash, priKeys[1], priKeys, pubKeys, 3))
+	if consensus.viewID != 0 {
+		test.Errorf("Moved to view %d of another leader", consensus.viewID)
+	}
+	consensus.catchUpView(preparedMessage(2, blockHash, priKeys[2], priKeys, pubKeys, 3))
+	if consensus.viewID != 2 || !consensus.GetLeaderPubKey().IsEqual(pubKeys[2]) {
+		test.Errorf("Did not catch up with view 2, at view %d", consensus.viewID)
+	}
+	if consensus.IsLeader() {
+		test.Error("Should no longer lead after catching up")
+	}
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"errors"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -15,6 +16,7 @@ import (
 
 // HmyAPIBackend ...
 type HmyAPIBackend struct {
+	mutex          sync.RWMutex // guards blockchain and txPool, swapped by SetChain
 	blockchain     *BlockChain
 	txPool         *TxPool
 	accountManager *accounts.Manager
@@ -22,22 +24,44 @@ type HmyAPIBackend struct {
 
 // NewBackend ...
 func NewBackend(blockchain *BlockChain, txPool *TxPool, accountManager *accounts.Manager) *HmyAPIBackend {
-	return &HmyAPIBackend{blockchain, txPool, accountManager}
+	return &HmyAPIBackend{blockchain: blockchain, txPool: txPool, accountManager: accountManager}
+}
+
+// SetChain makes the backend serve the given chain and transaction pool, as
+// the node switches shard.
+func (b *HmyAPIBackend) SetChain(blockchain *BlockChain, txPool *TxPool) {
+	b.mutex.Lock()
+	b.blockchain, b.txPool = blockchain, txPool
+	b.mutex.Unlock()
+}
+
+// chain returns the chain served.
+func (b *HmyAPIBackend) chain() *BlockChain {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.blockchain
+}
+
+// pool returns the transaction pool served.
+func (b *HmyAPIBackend) pool() *TxPool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.txPool
 }
 
 // ChainDb ...
 func (b *HmyAPIBackend) ChainDb() ethdb.Database {
-	return b.blockchain.db
+	return b.chain().db
 }
 
 // GetBlock ...
 func (b *HmyAPIBackend) GetBlock(ctx context.Context, hash common.Hash) (*types.Block, error) {
-	return b.blockchain.GetBlockByHash(hash), nil
+	return b.chain().GetBlockByHash(hash), nil
 }
 
 // GetPoolTransaction ...
 func (b *HmyAPIBackend) GetPoolTransaction(hash common.Hash) *types.Transaction {
-	return b.txPool.Get(hash)
+	return b.pool().Get(hash)
 }
 
 // BlockByNumber ...
@@ -48,9 +72,9 @@ func (b *HmyAPIBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumb
 	}
 	// Otherwise resolve and return the block
 	if blockNr == rpc.LatestBlockNumber {
-		return b.blockchain.CurrentBlock(), nil
+		return b.chain().CurrentBlock(), nil
 	}
-	return b.blockchain.GetBlockByNumber(uint64(blockNr)), nil
+	return b.chain().GetBlockByNumber(uint64(blockNr)), nil
 }
 
 // StateAndHeaderByNumber ...
@@ -64,7 +88,7 @@ func (b *HmyAPIBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.
 	if header == nil || err != nil {
 		return nil, nil, err
 	}
-	stateDb, err := b.blockchain.StateAt(header.Root)
+	stateDb, err := b.chain().StateAt(header.Root)
 	return stateDb, header, err
 }
 
@@ -76,32 +100,48 @@ func (b *HmyAPIBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNum
 	}
 	// Otherwise resolve and return the block
 	if blockNr == rpc.LatestBlockNumber {
-		return b.blockchain.CurrentBlock().Header(), nil
+		return b.chain().CurrentBlock().Header(), nil
 	}
-	return b.blockchain.GetHeaderByNumber(uint64(blockNr)), nil
+	return b.chain().GetHeaderByNumber(uint64(blockNr)), nil
 }
 
 // GetPoolNonce ...
 func (b *HmyAPIBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
-	return b.txPool.State().GetNonce(addr), nil
+	return b.pool().State().GetNonce(addr), nil
 }
 
 // SendTx ...
 func (b *HmyAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
-	return b.txPool.Add(ctx, signedTx)
+	return b.pool().Add(ctx, signedTx)
 }
 
 // ChainConfig ...
 func (b *HmyAPIBackend) ChainConfig() *params.ChainConfig {
-	return b.blockchain.chainConfig
+	return b.chain().chainConfig
 }
 
 // CurrentBlock ...
 func (b *HmyAPIBackend) CurrentBlock() *types.Block {
-	return types.NewBlockWithHeader(b.blockchain.CurrentHeader())
+	return types.NewBlockWithHeader(b.chain().CurrentHeader())
 }
 
 // AccountManager ...
 func (b *HmyAPIBackend) AccountManager() *accounts.Manager {
 	return b.accountManager
 }
+
+// GetValidatorUptime returns the uptime counters of the validators of each shard in the given epoch.
+func (b *HmyAPIBackend) GetValidatorUptime(ctx context.Context, epoch uint64) ([]*types.EpochUptime, error) {
+	chain := b.chain()
+	return chain.ReadValidatorUptime(chain.CurrentHeader(), epoch)
+}
+
+// GetLatestCrossLink returns the latest cross-link of the given shard in the beacon chain.
+func (b *HmyAPIBackend) GetLatestCrossLink(ctx context.Context, shardID uint32) *types.CrossLink {
+	return b.chain().ReadLatestCrossLink(shardID)
+}
+
+// GetCrossLink returns the cross-link of the block of the given shard and number in the beacon chain.
+func (b *HmyAPIBackend) GetCrossLink(ctx context.Context, shardID uint32, number uint64) *types.CrossLink {
+	return b.chain().ReadCrossLink(shardID, number)
+}
@@ -19,6 +19,7 @@ package core
 import (
 	"fmt"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/params"
 	consensus_engine "github.com/harmony-one/harmony/consensus/engine"
 	"github.com/harmony-one/harmony/core/state"
@@ -64,10 +65,19 @@ func (v *BlockValidator) ValidateBody(block *types.Block) error {
 	//if err := v.engine.VerifyUncles(v.bc, block); err != nil {
 	//	return err
 	//}
-	if hash := types.DeriveSha(block.Transactions()); hash != header.TxHash {
-		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, header.TxHash)
+	if err := ValidateBodyHashes(header, block.Body()); err != nil {
+		return err
 	}
-	return nil
+	if err := validateShardStatePresence(block); err != nil {
+		return err
+	}
+	if err := v.bc.ValidateNewShardState(block); err != nil {
+		return err
+	}
+	if err := v.bc.ValidateCrossLinks(block); err != nil {
+		return err
+	}
+	return v.bc.ValidateIncomingReceipts(block)
 }
 
 // ValidateState validates the various changes that happen after a state
@@ -134,3 +144,27 @@ func CalcGasLimit(parent *types.Block, gasFloor, gasCeil uint64) uint64 {
 	}
 	return limit
 }
+
+// ValidateBodyHashes checks that the header commits to the body.
+func ValidateBodyHashes(header *types.Header, body *types.Body) error {
+	if hash := types.DeriveSha(types.Transactions(body.Transactions)); hash != header.TxHash {
+		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, header.TxHash)
+	}
+	if hash := types.DoubleSignsHash(body.DoubleSigns); hash != header.DoubleSignHash {
+		return fmt.Errorf("double sign root hash mismatch: have %x, want %x", hash, header.DoubleSignHash)
+	}
+	if hash := types.IncomingReceiptsHash(body.IncomingReceipts); hash != header.IncomingReceiptHash {
+		return fmt.Errorf("incoming receipt root hash mismatch: have %x, want %x", hash, header.IncomingReceiptHash)
+	}
+	if hash := types.CrossLinksHash(body.CrossLinks); hash != header.CrossLinkHash {
+		return fmt.Errorf("cross-link root hash mismatch: have %x, want %x", hash, header.CrossLinkHash)
+	}
+	shardStateHash := common.Hash{}
+	if len(body.ShardState) > 0 {
+		shardStateHash = body.ShardState.Root()
+	}
+	if shardStateHash != header.ShardStateHash {
+		return fmt.Errorf("shard state root hash mismatch: have %x, want %x", shardStateHash, header.ShardStateHash)
+	}
+	return nil
+}
@@ -46,6 +46,7 @@ import (
 	"github.com/harmony-one/harmony/core/state"
 	"github.com/harmony-one/harmony/core/types"
 	"github.com/harmony-one/harmony/core/vm"
+	"github.com/harmony-one/harmony/internal/ctxerror"
 	"github.com/harmony-one/harmony/internal/utils"
 )
 
@@ -66,6 +67,8 @@ const (
 	badBlockLimit       = 10
 	triesInMemory       = 128
 	shardCacheLimit     = 2
+	committeeCacheLimit = 16
+	importBatchSize     = 2500
 
 	// BlocksPerEpoch is the number of blocks in one epoch
 	// currently set to small number for testing
@@ -82,6 +85,7 @@ type CacheConfig struct {
 	Disabled      bool          // Whether to disable trie write caching (archive node)
 	TrieNodeLimit int           // Memory limit (MB) at which to flush the current in-memory trie to disk
 	TrieTimeLimit time.Duration // Time limit after which to flush the current in-memory trie to disk
+	Pruning       *PruneConfig  // Configuration of the deletion of the state and blocks no longer retained, nil means none
 }
 
 // BlockChain represents the canonical chain given a database with a genesis
@@ -106,6 +110,10 @@ type BlockChain struct {
 	triegc *prque.Prque   // Priority queue mapping block numbers to tries to gc
 	gcproc time.Duration  // Accumulates canonical block processing for trie dumping
 
+	lastPrune uint64     // Number of the current block at the last pruning
+	pruning   int32      // Whether a background pruning is running, must be accessed atomically
+	pruneMu   sync.Mutex // Pruning lock, one pruning at a time
+
 	hc            *HeaderChain
 	rmLogsFeed    event.Feed
 	chainFeed     event.Feed
@@ -130,6 +138,7 @@ type BlockChain struct {
 	blockCache      *lru.Cache     // Cache for the most recent entire blocks
 	futureBlocks    *lru.Cache     // future blocks are blocks added for later processing
 	shardStateCache *lru.Cache
+	committeeCache  *lru.Cache // Cache for the committees of the recent epochs, by shard
 
 	quit    chan struct{} // blockchain quit channel
 	running int32         // running must be called atomically
@@ -144,6 +153,8 @@ type BlockChain struct {
 
 	badBlocks      *lru.Cache              // Bad block cache
 	shouldPreserve func(*types.Block) bool // Function used to determine whether should preserve the given block.
+
+	beaconChain *BlockChain // Beacon chain checking the incoming receipts of a non-beacon chain, guarded by mu
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -163,6 +174,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	futureBlocks, _ := lru.New(maxFutureBlocks)
 	badBlocks, _ := lru.New(badBlockLimit)
 	shardCache, _ := lru.New(shardCacheLimit)
+	committeeCache, _ := lru.New(committeeCacheLimit)
 
 	bc := &BlockChain{
 		chainConfig:     chainConfig,
@@ -178,6 +190,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		blockCache:      blockCache,
 		futureBlocks:    futureBlocks,
 		shardStateCache: shardCache,
+		committeeCache:  committeeCache,
 		engine:          engine,
 		vmConfig:        vmConfig,
 		badBlocks:       badBlocks,
@@ -210,6 +223,19 @@ func (bc *BlockChain) ValidateNewBlock(block *types.Block, address common.Addres
 		return err
 	}
 
+	if err := ValidateBodyHashes(block.Header(), block.Body()); err != nil {
+		return err
+	}
+	if err := validateShardStatePresence(block); err != nil {
+		return err
+	}
+	if err := bc.ValidateNewShardState(block); err != nil {
+		return err
+	}
+	if err := bc.ValidateCrossLinks(block); err != nil {
+		return err
+	}
+
 	// Process block using the parent state as reference point.
 	receipts, _, usedGas, err := bc.processor.Process(block, state, bc.vmConfig)
 	if err != nil {
@@ -310,6 +336,12 @@ func (bc *BlockChain) SetHead(head uint64) error {
 
 	// Rewind the header chain, deleting all block bodies until then
 	delFn := func(db rawdb.DatabaseDeleter, hash common.Hash, num uint64) {
+		if body := rawdb.ReadBody(bc.db, hash, num); body != nil {
+			for _, crossLink := range body.CrossLinks {
+				rawdb.DeleteCrossLink(db, crossLink.ShardID(), crossLink.NumberU64())
+			}
+		}
+		rawdb.DeleteCrossLinkHeads(db, hash)
 		rawdb.DeleteBody(db, hash, num)
 	}
 	bc.hc.SetHead(head, delFn)
@@ -322,6 +354,7 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	bc.blockCache.Purge()
 	bc.futureBlocks.Purge()
 	bc.shardStateCache.Purge()
+	bc.committeeCache.Purge()
 
 	// Rewind the block chain, ensuring we don't end up with a stateless head block
 	if currentBlock := bc.CurrentBlock(); currentBlock != nil && currentHeader.Number.Uint64() < currentBlock.NumberU64() {
@@ -378,6 +411,25 @@ func (bc *BlockChain) ShardID() uint32 {
 	return uint32(bc.chainConfig.ChainID.Int64())
 }
 
+// SetBeaconChain sets the beacon chain which keeps the committees and the
+// cross-links the incoming receipts of the blocks are checked against.
+func (bc *BlockChain) SetBeaconChain(beaconChain *BlockChain) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.beaconChain = beaconChain
+}
+
+// BeaconChain returns the beacon chain, which is the chain itself in the beacon
+// shard, or nil if a non-beacon chain has no beacon chain set.
+func (bc *BlockChain) BeaconChain() *BlockChain {
+	if bc.ShardID() == 0 {
+		return bc
+	}
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.beaconChain
+}
+
 // GasLimit returns the gas limit of the current HEAD block.
 func (bc *BlockChain) GasLimit() uint64 {
 	return bc.CurrentBlock().GasLimit()
@@ -515,6 +567,53 @@ func (bc *BlockChain) ExportN(w io.Writer, first uint64, last uint64) error {
 	return nil
 }
 
+// Import reads a stream of RLP encoded blocks, as written by Export, from the
+// given reader and inserts the blocks not in the chain yet, with full
+// validation. It returns the number of blocks inserted.
+func (bc *BlockChain) Import(r io.Reader) (int, error) {
+	stream := rlp.NewStream(r, 0)
+	start, reported := time.Now(), time.Now()
+	imported := 0
+	blocks := make(types.Blocks, 0, importBatchSize)
+	insert := func() error {
+		if len(blocks) == 0 {
+			return nil
+		}
+		if n, err := bc.InsertChain(blocks); err != nil {
+			return fmt.Errorf("import failed on #%d: %v", blocks[n].NumberU64(), err)
+		}
+		imported += len(blocks)
+		blocks = blocks[:0]
+		if time.Since(reported) >= statsReportLimit {
+			log.Info("Importing blocks", "imported", imported, "number", bc.CurrentBlock().NumberU64(), "elapsed", common.PrettyDuration(time.Since(start)))
+			reported = time.Now()
+		}
+		return nil
+	}
+	for read := 0; ; read++ {
+		block := new(types.Block)
+		if err := stream.Decode(block); err == io.EOF {
+			break
+		} else if err != nil {
+			return imported, fmt.Errorf("import failed: cannot decode block %d of the stream: %v", read, err)
+		}
+		if bc.HasBlock(block.Hash(), block.NumberU64()) {
+			continue
+		}
+		blocks = append(blocks, block)
+		if len(blocks) == importBatchSize {
+			if err := insert(); err != nil {
+				return imported, err
+			}
+		}
+	}
+	if err := insert(); err != nil {
+		return imported, err
+	}
+	log.Info("Imported blocks", "imported", imported, "number", bc.CurrentBlock().NumberU64(), "elapsed", common.PrettyDuration(time.Since(start)))
+	return imported, nil
+}
+
 // insert injects a new head block into the current block chain. This method
 // assumes that the block is indeed a true head. It will also reset the head
 // header and the head fast sync block to this very same block if they are older
@@ -851,6 +950,11 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 		start = time.Now()
 		bytes = 0
 		batch = bc.db.NewBatch()
+
+		// the cross-link heads as of the last block written, not in the db
+		// until the batch is
+		crossLinkHeads     map[uint32]uint64
+		crossLinkHeadsHash common.Hash
 	)
 	for i, block := range blockChain {
 		receipts := receiptChain[i]
@@ -875,6 +979,13 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 		rawdb.WriteBody(batch, block.Hash(), block.NumberU64(), block.Body())
 		rawdb.WriteReceipts(batch, block.Hash(), block.NumberU64(), receipts)
 		rawdb.WriteTxLookupEntries(batch, block)
+		writeShardState(batch, block)
+		parentHeads := crossLinkHeads
+		if block.ParentHash() != crossLinkHeadsHash {
+			parentHeads = rawdb.ReadCrossLinkHeads(bc.db, block.ParentHash())
+		}
+		crossLinkHeads, crossLinkHeadsHash = writeCrossLinkHeads(batch, block, parentHeads), block.Hash()
+		writeCrossLinks(batch, block)
 
 		stats.processed++
 
@@ -892,6 +1003,7 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 			return 0, err
 		}
 	}
+	bc.committeeCache.Purge()
 
 	// Update the head fast sync block if better
 	bc.mu.Lock()
@@ -990,8 +1102,10 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 			header := bc.GetHeaderByNumber(current - triesInMemory)
 			chosen := header.Number.Uint64()
 
-			// If we exceeded out time allowance, flush an entire trie to disk
-			if bc.gcproc > bc.cacheConfig.TrieTimeLimit {
+			// If we exceeded out time allowance, flush an entire trie to disk.
+			// When pruning, the state of the checkpoint blocks is always flushed.
+			checkpoint := bc.cacheConfig.Pruning != nil && bc.cacheConfig.Pruning.isStateCheckpoint(chosen)
+			if bc.gcproc > bc.cacheConfig.TrieTimeLimit || checkpoint {
 				// If we're exceeding limits but haven't reached a large enough memory gap,
 				// warn the user that the system is becoming unstable.
 				if chosen < lastWrite+triesInMemory && bc.gcproc >= 2*bc.cacheConfig.TrieTimeLimit {
@@ -1017,6 +1131,8 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	// Write other block data using a batch.
 	batch := bc.db.NewBatch()
 	rawdb.WriteReceipts(batch, block.Hash(), block.NumberU64(), receipts)
+	writeShardState(batch, block)
+	writeCrossLinkHeads(batch, block, rawdb.ReadCrossLinkHeads(bc.db, block.ParentHash()))
 
 	// If the total difficulty is higher than our known, add it to the canonical chain
 	// Second clause in the if statement reduces the vulnerability to selfish mining.
@@ -1046,6 +1162,7 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 		// Write the positional metadata for transaction/receipt lookups and preimages
 		rawdb.WriteTxLookupEntries(batch, block)
 		rawdb.WritePreimages(batch, block.NumberU64(), state.Preimages())
+		writeCrossLinks(batch, block)
 
 		status = CanonStatTy
 	} else {
@@ -1058,6 +1175,9 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	// Set new head.
 	if status == CanonStatTy {
 		bc.insert(block)
+		if len(block.ShardState()) > 0 {
+			bc.committeeCache.Purge()
+		}
 	}
 	bc.futureBlocks.Remove(block.Hash())
 	return status, nil
@@ -1072,6 +1192,7 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
 	n, events, logs, err := bc.insertChain(chain)
 	bc.PostChainEvents(events, logs)
+	bc.maybePrune()
 	return n, err
 }
 
@@ -1402,6 +1523,10 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 	} else {
 		log.Error("Impossible reorg, please file an issue", "oldnum", oldBlock.Number(), "oldhash", oldBlock.Hash(), "newnum", newBlock.Number(), "newhash", newBlock.Hash())
 	}
+	// The cross-links of the old chain are no longer canonical
+	for _, block := range oldChain {
+		deleteCrossLinks(bc.db, block)
+	}
 	// Insert the new chain, taking care of the proper incremental order
 	var addedTxs types.Transactions
 	for i := len(newChain) - 1; i >= 0; i-- {
@@ -1409,8 +1534,11 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		bc.insert(newChain[i])
 		// write lookup entries for hash based transaction/receipt searches
 		rawdb.WriteTxLookupEntries(bc.db, newChain[i])
+		writeCrossLinks(bc.db, newChain[i])
 		addedTxs = append(addedTxs, newChain[i].Transactions()...)
 	}
+	// the committees may have changed along with the canonical epoch blocks
+	bc.committeeCache.Purge()
 	// calculate the difference between deleted and added transactions
 	diff := types.TxDifference(deletedTxs, addedTxs)
 	// When transactions get deleted from the database that means the
@@ -1626,6 +1754,9 @@ func (bc *BlockChain) Config() *params.ChainConfig { return bc.chainConfig }
 // Engine retrieves the blockchain's consensus engine.
 func (bc *BlockChain) Engine() consensus_engine.Engine { return bc.engine }
 
+// ChainDb retrieves the database the blockchain is stored in.
+func (bc *BlockChain) ChainDb() ethdb.Database { return bc.db }
+
 // SubscribeRemovedLogsEvent registers a subscription of RemovedLogsEvent.
 func (bc *BlockChain) SubscribeRemovedLogsEvent(ch chan<- RemovedLogsEvent) event.Subscription {
 	return bc.scope.Track(bc.rmLogsFeed.Subscribe(ch))
@@ -1674,6 +1805,23 @@ func (bc *BlockChain) GetShardStateByNumber(number uint64) types.ShardState {
 	return bc.GetShardState(hash, number)
 }
 
+// ReadShardState retrieves sharding state given the epoch number.
+func (bc *BlockChain) ReadShardState(epoch uint64) (types.ShardState, error) {
+	shardState := bc.GetShardStateByNumber(GetBlockNumberFromEpoch(epoch))
+	if shardState == nil {
+		// the shard chains keep the shard states announced by the beacon chain
+		shardState = rawdb.ReadEpochShardState(bc.db, epoch)
+	}
+	if shardState == nil {
+		if epoch == GenesisEpoch {
+			// The genesis shard state is only stored in the beacon chain
+			return GetInitShardState(), nil
+		}
+		return nil, ErrShardStateNotFound
+	}
+	return shardState, nil
+}
+
 // GetShardStateByHash retrieves the shard state given the blockhash, return nil if not exist
 func (bc *BlockChain) GetShardStateByHash(hash common.Hash) types.ShardState {
 	number := bc.hc.GetBlockNumber(hash)
@@ -1701,46 +1849,60 @@ func (bc *BlockChain) GetRandPreimageByNumber(number uint64) [32]byte {
 	return header.RandPreimage
 }
 
-// GetNewShardState will calculate (if not exist) and get the new shard state for epoch block or nil if block is not epoch block
-// epoch block is where the new shard state stored
-func (bc *BlockChain) GetNewShardState(block *types.Block, stakeInfo *map[common.Address]*structs.StakeInfo) types.ShardState {
-	hash := block.Hash()
-	// just ignore non-epoch block
-	if !IsEpochBlock(block) {
+// ValidateNewShardState validates the shard state carried by an epoch block:
+// the beacon chain carries the one computed from the previous epoch and the
+// validator uptime and stakes as of the parent block, and the shard chains
+// the one announced by the beacon chain, along with the announced RandSeed.
+func (bc *BlockChain) ValidateNewShardState(block *types.Block) error {
+	if !IsEpochBlock(block) || block.NumberU64() == 0 {
 		return nil
 	}
-	number := block.NumberU64()
-	shardState := bc.GetShardState(hash, number)
-	if shardState == nil {
-		epoch := GetEpochFromBlockNumber(number)
-		shardState = CalculateNewShardState(bc, epoch, stakeInfo)
-		bc.shardStateCache.Add(hash, shardState)
-	}
-	return shardState
-}
-
-// ValidateNewShardState validate whether the new shard state root matches
-func (bc *BlockChain) ValidateNewShardState(block *types.Block, stakeInfo *map[common.Address]*structs.StakeInfo) error {
-	shardState := bc.GetNewShardState(block, stakeInfo)
-	if shardState == nil {
+	epoch := GetEpochFromBlockNumber(block.NumberU64())
+	if bc.ShardID() != 0 {
+		announced := rawdb.ReadEpochShardState(bc.db, epoch)
+		if announced == nil {
+			return ctxerror.New("shard state not announced by the beacon chain yet", "epoch", epoch).WithCause(ErrShardStateNotFound)
+		}
+		if block.ShardState().Root() != announced.Root() {
+			return ctxerror.New("shard state different from the announced one", "epoch", epoch).WithCause(ErrShardStateNotMatch)
+		}
+		if randSeed, ok := rawdb.ReadEpochRandSeed(bc.db, epoch); !ok || block.Header().RandSeed != randSeed {
+			return ctxerror.New("rand seed different from the announced one", "epoch", epoch).WithCause(ErrShardStateNotMatch)
+		}
 		return nil
 	}
-	if shardState.Hash() != block.Header().ShardStateHash {
-		return ErrShardStateNotMatch
+	prevShardState, err := bc.ReadShardState(epoch - 1)
+	if err != nil {
+		return ctxerror.New("cannot read the previous shard state", "epoch", epoch-1).WithCause(err)
+	}
+	parent := bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	stakes, err := bc.ReadLockedStakes(parent)
+	if err != nil {
+		return ctxerror.New("cannot read the stakes", "epoch", epoch).WithCause(err)
+	}
+	uptimes, err := bc.ReadValidatorUptime(parent, epoch-1)
+	if err != nil {
+		return ctxerror.New("cannot read the validator uptime", "epoch", epoch-1).WithCause(err)
+	}
+	prevNumber := GetBlockNumberFromEpoch(epoch - 1)
+	if err := ValidateShardState(block.ShardState(), prevShardState, bc.GetHeaderByNumber(prevNumber), uptimes, stakes); err != nil {
+		return ctxerror.New("invalid new shard state", "epoch", epoch).WithCause(err)
 	}
-	utils.GetLogInstance().Debug("[resharding] validate new shard state successfully", "shardStateHash", shardState.Hash())
+	utils.GetLogInstance().Debug("[resharding] validate new shard state successfully", "shardStateHash", block.Header().ShardStateHash)
 	return nil
 }
 
-// StoreNewShardState insert new shard state into epoch block
-func (bc *BlockChain) StoreNewShardState(block *types.Block, stakeInfo *map[common.Address]*structs.StakeInfo) types.ShardState {
-	// write state into db.
-	shardState := bc.GetNewShardState(block, stakeInfo)
-	if shardState != nil {
-		hash := block.Hash()
-		number := block.NumberU64()
-		rawdb.WriteShardState(bc.db, hash, number, shardState)
-		utils.GetLogInstance().Debug("[Resharding] Saved new shard state successfully", "epoch", GetEpochFromBlockNumber(block.NumberU64()))
+// ReadLockedStakes returns the tokens locked in the StakeLockContract as of
+// the block of the given header, by account.
+func (bc *BlockChain) ReadLockedStakes(header *types.Header) (map[common.Address]*structs.StakeInfo, error) {
+	if header == nil {
+		return nil, ctxerror.New("missing block")
 	}
-	return shardState
+	stateDB, err := bc.StateAt(header.Root)
+	if err != nil {
+		return nil, ctxerror.New("cannot read the state of the block",
+			"blockNum", header.Number,
+		).WithCause(err)
+	}
+	return ReadLockedStakes(stateDB), nil
 }
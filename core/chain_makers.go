@@ -43,6 +43,9 @@ type BlockGen struct {
 	receipts []*types.Receipt
 	uncles   []*types.Header
 
+	incomingReceipts []*types.CXReceiptProof
+	crossLinks       []*types.CrossLink
+
 	config *params.ChainConfig
 	engine consensus_engine.Engine
 }
@@ -108,6 +111,23 @@ func (b *BlockGen) AddTxWithChain(bc *BlockChain, tx *types.Transaction) {
 	b.receipts = append(b.receipts, receipt)
 }
 
+// AddIncomingReceipt credits the cross-shard transfer proven by the given
+// receipt in the generated block. It shall be called after adding the
+// transactions, which the block applies first.
+//
+// AddIncomingReceipt panics if the receipt is invalid or already credited.
+func (b *BlockGen) AddIncomingReceipt(proof *types.CXReceiptProof) {
+	if err := ApplyIncomingReceipts(b.statedb, uint32(b.config.ChainID.Int64()), types.CXReceiptProofs{proof}); err != nil {
+		panic(err)
+	}
+	b.incomingReceipts = append(b.incomingReceipts, proof)
+}
+
+// AddCrossLink anchors the given shard block header in the generated block.
+func (b *BlockGen) AddCrossLink(crossLink *types.CrossLink) {
+	b.crossLinks = append(b.crossLinks, crossLink)
+}
+
 // Number returns the block number of the block being generated.
 func (b *BlockGen) Number() *big.Int {
 	return new(big.Int).Set(b.header.Number)
@@ -189,7 +209,15 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, engine conse
 		}
 		if b.engine != nil {
 			// Finalize and seal the block
-			block, _ := b.engine.Finalize(chainreader, b.header, statedb, b.txs, b.receipts)
+			b.header.IncomingReceiptHash = types.IncomingReceiptsHash(b.incomingReceipts)
+			b.header.CrossLinkHash = types.CrossLinksHash(b.crossLinks)
+			block, _ := b.engine.Finalize(chainreader, b.header, statedb, b.txs, b.receipts, nil)
+			if len(b.incomingReceipts) > 0 {
+				block = block.WithIncomingReceipts(b.incomingReceipts)
+			}
+			if len(b.crossLinks) > 0 {
+				block = block.WithCrossLinks(b.crossLinks)
+			}
 
 			// Write state changes to db
 			root, err := statedb.Commit(config.IsEIP158(b.header.Number))
@@ -273,3 +301,10 @@ func (cr *fakeChainReader) GetHeaderByNumber(number uint64) *types.Header
 func (cr *fakeChainReader) GetHeaderByHash(hash common.Hash) *types.Header          { return nil }
 func (cr *fakeChainReader) GetHeader(hash common.Hash, number uint64) *types.Header { return nil }
 func (cr *fakeChainReader) GetBlock(hash common.Hash, number uint64) *types.Block   { return nil }
+func (cr *fakeChainReader) ReadShardState(epoch uint64) (types.ShardState, error)   { return nil, nil }
+func (cr *fakeChainReader) EpochOf(blockNumber uint64) uint64 {
+	return GetEpochFromBlockNumber(blockNumber)
+}
+func (cr *fakeChainReader) CommitteeForEpoch(shardID uint32, epoch uint64) (*types.Committee, error) {
+	return nil, ErrCommitteeNotFound
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"math/big"
+
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/ctxerror"
+)
+
+// Committees
+//
+// The committee of each shard for an epoch is recorded in the shard state of
+// the epoch, which every chain carries in its epoch block: the beacon chain
+// computes it, and the shard chains copy it as announced by the beacon chain.
+// The epoch block itself is signed by the committee of the previous epoch, so
+// that the shard state is stored as soon as the epoch block is inserted, by
+// consensus, syncing or import alike. Before its epoch block is inserted, the
+// shard state announced by the beacon chain is kept aside, to propose and
+// verify the epoch block.
+// The chain is the source of truth of the committees for consensus, randomness
+// generation, seal verification and the explorer; the committees of the recent
+// epochs are cached.
+
+// committeeKey identifies the committee of a shard for an epoch.
+type committeeKey struct {
+	shardID uint32
+	epoch   uint64
+}
+
+// EpochOf returns the epoch of the given block number.
+func (bc *BlockChain) EpochOf(blockNumber uint64) uint64 {
+	return GetEpochFromBlockNumber(blockNumber)
+}
+
+// CommitteeForEpoch returns the committee of the given shard for the given
+// epoch, as recorded in the stored shard state of the epoch.
+func (bc *BlockChain) CommitteeForEpoch(shardID uint32, epoch uint64) (*types.Committee, error) {
+	key := committeeKey{shardID: shardID, epoch: epoch}
+	if cached, ok := bc.committeeCache.Get(key); ok {
+		return copyCommittee(cached.(*types.Committee)), nil
+	}
+	shardState, err := bc.ReadShardState(epoch)
+	if err != nil {
+		return nil, ctxerror.New("cannot read shard state",
+			"epoch", epoch,
+		).WithCause(err)
+	}
+	committee := shardState.FindCommitteeByID(shardID)
+	if committee == nil {
+		return nil, ctxerror.New("cannot find committee",
+			"shardID", shardID,
+			"epoch", epoch,
+		).WithCause(ErrCommitteeNotFound)
+	}
+	committee = copyCommittee(committee)
+	bc.committeeCache.Add(key, committee)
+	return copyCommittee(committee), nil
+}
+
+// WriteEpochShardState keeps the shard state of an epoch announced by the
+// beacon chain, and the RandSeed announced with it, until the epoch block
+// carrying them is inserted.
+func (bc *BlockChain) WriteEpochShardState(epochShardState *types.EpochShardState) {
+	rawdb.WriteEpochShardState(bc.db, epochShardState.Epoch, epochShardState.ShardState)
+	rawdb.WriteEpochRandSeed(bc.db, epochShardState.Epoch, epochShardState.RandSeed)
+	bc.committeeCache.Purge()
+}
+
+// ReadEpochRandSeed returns the RandSeed announced by the beacon chain with
+// the shard state of the given epoch, if any.
+func (bc *BlockChain) ReadEpochRandSeed(epoch uint64) ([32]byte, bool) {
+	return rawdb.ReadEpochRandSeed(bc.db, epoch)
+}
+
+// writeShardState stores the shard state carried by the block, if any.
+func writeShardState(db rawdb.DatabaseWriter, block *types.Block) {
+	if shardState := block.ShardState(); len(shardState) > 0 {
+		rawdb.WriteShardState(db, block.Hash(), block.NumberU64(), shardState)
+	}
+}
+
+// validateShardStatePresence checks that the block carries a shard state if and
+// only if it is an epoch block, other than the genesis block. That the header
+// commits to the shard state is checked by ValidateBodyHashes.
+func validateShardStatePresence(block *types.Block) error {
+	shardState := block.ShardState()
+	isEpochBlock := IsEpochBlock(block) && block.NumberU64() > 0
+	if isEpochBlock && len(shardState) == 0 {
+		return ctxerror.New("epoch block without shard state",
+			"blockNum", block.NumberU64(),
+		).WithCause(ErrShardStateNotMatch)
+	}
+	if !isEpochBlock && len(shardState) > 0 {
+		return ctxerror.New("shard state in a block other than an epoch block",
+			"blockNum", block.NumberU64(),
+		).WithCause(ErrShardStateNotMatch)
+	}
+	return nil
+}
+
+// copyCommittee returns a copy of the committee not sharing its node list and
+// stakes.
+func copyCommittee(committee *types.Committee) *types.Committee {
+	c := *committee
+	c.NodeList = append([]types.NodeID{}, committee.NodeList...)
+	c.Stakes = append([]*big.Int(nil), committee.Stakes...)
+	return &c
+}
@@ -0,0 +1,84 @@
+package core_test
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/core/vm"
+)
+
+func TestCommitteeForEpoch(t *testing.T) {
+	config := *params.TestChainConfig
+	config.ChainID = big.NewInt(1)
+	gspec := core.Genesis{Config: &config, ShardID: 1}
+	db := ethdb.NewMemDatabase()
+	gspec.MustCommit(db)
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{Disabled: true}, gspec.Config, consensus.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("cannot create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if epoch := chain.EpochOf(core.BlocksPerEpoch + 1); epoch != 1 {
+		t.Errorf("wrong epoch of block %d: %d", core.BlocksPerEpoch+1, epoch)
+	}
+
+	// the genesis committees are known to all the chains
+	committee, err := chain.CommitteeForEpoch(1, core.GenesisEpoch)
+	if err != nil {
+		t.Fatalf("cannot read the genesis committee: %v", err)
+	}
+	if expected := core.GetInitShardState().FindCommitteeByID(1); committee.ShardID != 1 || len(committee.NodeList) != len(expected.NodeList) {
+		t.Errorf("wrong genesis committee: %v", committee)
+	}
+	publicKeys, err := committee.BLSPublicKeys()
+	if err != nil || len(publicKeys) != len(committee.NodeList) {
+		t.Errorf("cannot read the public keys of the genesis committee: %v", err)
+	}
+
+	if _, err := chain.CommitteeForEpoch(1, 1); err == nil || !strings.Contains(err.Error(), core.ErrShardStateNotFound.Error()) {
+		t.Errorf("committee of an unknown epoch found: %v", err)
+	}
+
+	// the shard states announced by the beacon chain
+	nodeID := types.NodeID{EcdsaAddress: "one1"}
+	nodeID.BlsPublicKey[0] = 1
+	shardState := types.ShardState{{ShardID: 1, Leader: nodeID, NodeList: []types.NodeID{nodeID}}}
+	chain.WriteEpochShardState(&types.EpochShardState{Epoch: 1, ShardState: shardState, RandSeed: [32]byte{1}})
+	if randSeed, ok := chain.ReadEpochRandSeed(1); !ok || randSeed != [32]byte{1} {
+		t.Errorf("wrong rand seed announced for epoch 1: %x", randSeed)
+	}
+	if _, ok := chain.ReadEpochRandSeed(2); ok {
+		t.Error("rand seed found for an epoch not announced")
+	}
+	committee, err = chain.CommitteeForEpoch(1, 1)
+	if err != nil {
+		t.Fatalf("cannot read the committee of epoch 1: %v", err)
+	}
+	if committee.Leader != nodeID || len(committee.NodeList) != 1 || committee.NodeList[0] != nodeID {
+		t.Errorf("wrong committee of epoch 1: %v", committee)
+	}
+	if _, err := chain.CommitteeForEpoch(2, 1); err == nil || !strings.Contains(err.Error(), core.ErrCommitteeNotFound.Error()) {
+		t.Errorf("committee of an unknown shard found: %v", err)
+	}
+
+	// the cached committees are not shared with the callers
+	committee.NodeList[0] = types.NodeID{}
+	if committee, _ := chain.CommitteeForEpoch(1, 1); committee.NodeList[0] != nodeID {
+		t.Error("cached committee modified")
+	}
+
+	// a new shard state replaces the cached committees
+	other := types.NodeID{EcdsaAddress: "one2"}
+	other.BlsPublicKey[0] = 2
+	chain.WriteEpochShardState(&types.EpochShardState{Epoch: 1, ShardState: types.ShardState{{ShardID: 1, Leader: other, NodeList: []types.NodeID{other}}}})
+	if committee, _ := chain.CommitteeForEpoch(1, 1); committee.Leader != other {
+		t.Errorf("stale committee of epoch 1: %v", committee)
+	}
+}
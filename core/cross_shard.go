@@ -0,0 +1,281 @@
+package core
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/ctxerror"
+)
+
+// Cross-shard transactions
+//
+// A cross-shard transaction transfers value from an account of its shard to
+// an account of its destination shard, ToShardID. Its shard debits the value,
+// moved to CrossShardAddress, and records the transfer in a log of the receipt
+// of the transaction. The receipt is relayed to the destination shard with its
+// Merkle proof against the receipt root of the block header, sealed by the
+// committee of the source shard. The destination shard includes the proven
+// receipt in a block crediting the value to the recipient, and records in the
+// storage of CrossShardAddress the transfers credited, so that each is credited
+// once only. A cross-shard transaction can't create or call a contract.
+
+var (
+	// CrossShardAddress holds the value sent to other shards, and records the
+	// transfers credited from other shards.
+	CrossShardAddress = common.BytesToAddress([]byte("harmony-cross-shard"))
+
+	// crossShardTransferTopic is the topic of the receipt logs recording
+	// cross-shard transfers.
+	crossShardTransferTopic = crypto.Keccak256Hash([]byte("CrossShardTransfer(address,uint32,uint256)"))
+
+	// creditedMarker is stored under the ID of the transfers credited.
+	creditedMarker = common.BytesToHash([]byte{1})
+)
+
+// CrossShardTransfer is a transfer of value from another shard, proven by a
+// cross-shard receipt.
+type CrossShardTransfer struct {
+	ID        common.Hash // ID of the cross-shard receipt proof
+	ShardID   uint32      // source shard
+	ToShardID uint32      // destination shard
+	To        common.Address
+	Amount    *big.Int
+}
+
+// validateToShard checks that the destination shard of the cross-shard
+// transaction exists; resharding keeps the number of shards.
+func validateToShard(tx *types.Transaction) error {
+	if tx.ToShardID() >= GenesisShardNum {
+		return ErrInvalidToShard
+	}
+	return nil
+}
+
+// crossShardMessage returns the message debiting the value of the cross-shard
+// transaction to CrossShardAddress.
+func crossShardMessage(tx *types.Transaction, msg types.Message) (types.Message, error) {
+	if msg.To() == nil || len(msg.Data()) > 0 {
+		return msg, ErrCrossShardContract
+	}
+	if err := validateToShard(tx); err != nil {
+		return msg, err
+	}
+	return types.NewMessage(msg.From(), &CrossShardAddress, msg.Nonce(), msg.Value(), msg.Gas(), msg.GasPrice(), nil, msg.CheckNonce()), nil
+}
+
+// crossShardTransferLog returns the receipt log recording the transfer of the
+// cross-shard transaction.
+func crossShardTransferLog(tx *types.Transaction, header *types.Header) *types.Log {
+	return &types.Log{
+		Address: CrossShardAddress,
+		Topics: []common.Hash{
+			crossShardTransferTopic,
+			tx.To().Hash(),
+			common.BigToHash(new(big.Int).SetUint64(uint64(tx.ToShardID()))),
+		},
+		Data:        common.BigToHash(tx.Value()).Bytes(),
+		BlockNumber: header.Number.Uint64(),
+		TxHash:      tx.Hash(),
+	}
+}
+
+// receiptKey returns the key of the receipt of the given transaction index in
+// the receipt trie, as in types.DeriveSha.
+func receiptKey(index uint64) []byte {
+	key, _ := rlp.EncodeToBytes(uint(index))
+	return key
+}
+
+// proofList collects the trie nodes of a Merkle proof, from the root.
+type proofList [][]byte
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, value)
+	return nil
+}
+
+// MakeCXReceiptProofs returns the proofs of the receipts of the cross-shard
+// transactions of the block, by destination shard.
+func MakeCXReceiptProofs(block *types.Block, receipts types.Receipts) (map[uint32]types.CXReceiptProofs, error) {
+	proofs := make(map[uint32]types.CXReceiptProofs)
+	var receiptTrie *trie.Trie
+	for i, tx := range block.Transactions() {
+		if !tx.IsCrossShard() {
+			continue
+		}
+		if receiptTrie == nil {
+			if len(receipts) != block.Transactions().Len() {
+				return nil, ctxerror.New("wrong number of receipts",
+					"blockNum", block.Number(),
+					"numTxs", block.Transactions().Len(),
+					"numReceipts", len(receipts))
+			}
+			receiptTrie = new(trie.Trie)
+			for j := range receipts {
+				receiptTrie.Update(receiptKey(uint64(j)), receipts.GetRlp(j))
+			}
+			if root := receiptTrie.Hash(); root != block.ReceiptHash() {
+				return nil, ctxerror.New("receipts don't match the block",
+					"blockNum", block.Number(),
+					"receiptHash", root,
+					"expectedReceiptHash", block.ReceiptHash())
+			}
+		}
+		var proof proofList
+		if err := receiptTrie.Prove(receiptKey(uint64(i)), 0, &proof); err != nil {
+			return nil, ctxerror.New("cannot prove the receipt",
+				"blockNum", block.Number(),
+				"txIndex", i,
+			).WithCause(err)
+		}
+		proofs[tx.ToShardID()] = append(proofs[tx.ToShardID()], &types.CXReceiptProof{
+			Header:  block.Header(),
+			TxIndex: uint64(i),
+			Proof:   proof,
+		})
+	}
+	return proofs, nil
+}
+
+// VerifyCXReceiptProof checks the proof of a cross-shard receipt against the
+// header of the source block, and returns the transfer to the shard shardID it
+// proves. The seal of the header is to be verified by the caller.
+func VerifyCXReceiptProof(proof *types.CXReceiptProof, shardID uint32) (*CrossShardTransfer, error) {
+	if proof.Header == nil || proof.Header.Number == nil {
+		return nil, ctxerror.New("cross-shard receipt without header")
+	}
+	sourceShardID := binary.BigEndian.Uint32(proof.Header.ShardID[:])
+	if sourceShardID == shardID {
+		return nil, ctxerror.New("cross-shard receipt from the same shard", "shardID", shardID)
+	}
+	proofDB := ethdb.NewMemDatabase()
+	for _, node := range proof.Proof {
+		proofDB.Put(crypto.Keccak256(node), node)
+	}
+	value, _, err := trie.VerifyProof(proof.Header.ReceiptHash, receiptKey(proof.TxIndex), proofDB)
+	if err != nil || value == nil {
+		return nil, ctxerror.New("invalid cross-shard receipt proof",
+			"blockNum", proof.Header.Number,
+			"shardID", sourceShardID,
+			"txIndex", proof.TxIndex,
+		).WithCause(err)
+	}
+	receipt := new(types.Receipt)
+	if err := rlp.DecodeBytes(value, receipt); err != nil {
+		return nil, ctxerror.New("cannot decode cross-shard receipt").WithCause(err)
+	}
+	for _, log := range receipt.Logs {
+		if log.Address != CrossShardAddress || len(log.Topics) != 3 || log.Topics[0] != crossShardTransferTopic {
+			continue
+		}
+		transfer := &CrossShardTransfer{
+			ID:        proof.ID(),
+			ShardID:   sourceShardID,
+			ToShardID: uint32(log.Topics[2].Big().Uint64()),
+			To:        common.BytesToAddress(log.Topics[1].Bytes()),
+			Amount:    new(big.Int).SetBytes(log.Data),
+		}
+		if transfer.ToShardID != shardID {
+			return nil, ctxerror.New("cross-shard receipt to another shard",
+				"shardID", shardID,
+				"toShardID", transfer.ToShardID)
+		}
+		return transfer, nil
+	}
+	return nil, ctxerror.New("receipt without cross-shard transfer",
+		"blockNum", proof.Header.Number,
+		"shardID", sourceShardID,
+		"txIndex", proof.TxIndex)
+}
+
+// VerifyCXReceiptSource checks that the source block of the cross-shard
+// receipt is final: in the canonical beacon chain, or cross-linked in it.
+func VerifyCXReceiptSource(beaconChain *BlockChain, proof *types.CXReceiptProof) error {
+	shardID := binary.BigEndian.Uint32(proof.Header.ShardID[:])
+	number := proof.Header.Number.Uint64()
+	hash := proof.Header.Hash()
+	if shardID == 0 {
+		if header := beaconChain.GetHeaderByNumber(number); header == nil || header.Hash() != hash {
+			return ctxerror.New("source block of the cross-shard receipt not in the beacon chain",
+				"blockNum", number,
+				"blockHash", hash)
+		}
+		return nil
+	}
+	if crossLink := beaconChain.ReadCrossLink(shardID, number); crossLink == nil || crossLink.Hash() != hash {
+		return ctxerror.New("source block of the cross-shard receipt not cross-linked",
+			"shardID", shardID,
+			"blockNum", number,
+			"blockHash", hash)
+	}
+	return nil
+}
+
+// ValidateIncomingReceipts checks the incoming cross-shard receipts of the
+// block: their proofs, the seals of their source block headers, and that their
+// source blocks are final, against the beacon chain.
+func (bc *BlockChain) ValidateIncomingReceipts(block *types.Block) error {
+	proofs := block.IncomingReceipts()
+	if len(proofs) == 0 {
+		return nil
+	}
+	beaconChain := bc.BeaconChain()
+	if beaconChain == nil {
+		return ctxerror.New("no beacon chain to check the incoming receipts against",
+			"blockNum", block.Number(),
+			"shardID", bc.ShardID())
+	}
+	for _, proof := range proofs {
+		if _, err := VerifyCXReceiptProof(proof, bc.ShardID()); err != nil {
+			return ctxerror.New("invalid incoming receipt", "blockNum", block.Number()).WithCause(err)
+		}
+		if err := bc.engine.VerifySeal(beaconChain, proof.Header); err != nil {
+			return ctxerror.New("invalid seal of the source block of an incoming receipt",
+				"blockNum", block.Number(),
+				"sourceBlockNum", proof.Header.Number,
+			).WithCause(err)
+		}
+		if err := VerifyCXReceiptSource(beaconChain, proof); err != nil {
+			return ctxerror.New("incoming receipt from a block not final", "blockNum", block.Number()).WithCause(err)
+		}
+	}
+	return nil
+}
+
+// IsCXReceiptCredited returns whether the transfer proven by the cross-shard
+// receipt with the given ID is credited in the state.
+func IsCXReceiptCredited(statedb *state.DB, id common.Hash) bool {
+	return statedb.GetState(CrossShardAddress, id) == creditedMarker
+}
+
+// ApplyIncomingReceipts credits the transfers proven by the cross-shard
+// receipts to the shard shardID, and records them as credited. It fails if a
+// receipt is invalid or already credited.
+func ApplyIncomingReceipts(statedb *state.DB, shardID uint32, proofs types.CXReceiptProofs) error {
+	for _, proof := range proofs {
+		transfer, err := VerifyCXReceiptProof(proof, shardID)
+		if err != nil {
+			return err
+		}
+		if IsCXReceiptCredited(statedb, transfer.ID) {
+			return ctxerror.New("cross-shard receipt already credited",
+				"id", transfer.ID,
+				"shardID", transfer.ShardID,
+			).WithCause(ErrCXReceiptCredited)
+		}
+		if statedb.GetNonce(CrossShardAddress) == 0 {
+			// Keep the account from being deleted as empty, with the transfers credited
+			statedb.SetNonce(CrossShardAddress, 1)
+		}
+		statedb.SetState(CrossShardAddress, transfer.ID, creditedMarker)
+		statedb.AddBalance(transfer.To, transfer.Amount)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package core_test
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/core/vm"
+)
+
+func TestCrossShardTransfer(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	recipient := common.Address{1}
+	amount := big.NewInt(1000)
+
+	// Send from shard 1 to shard 2
+	sourceConfig := *params.TestChainConfig
+	sourceConfig.ChainID = big.NewInt(1)
+	source := core.Genesis{
+		Config:  &sourceConfig,
+		ShardID: 1,
+		Alloc:   core.GenesisAlloc{address: {Balance: big.NewInt(1000000000000000000)}},
+	}
+	sourceDB := ethdb.NewMemDatabase()
+	blocks, receipts := core.GenerateChain(source.Config, source.MustCommit(sourceDB), consensus.NewFaker(), sourceDB, 1, func(i int, b *core.BlockGen) {
+		b.SetShardID(types.EncodeShardID(1))
+		tx, _ := types.SignTx(types.NewCrossShardTransaction(b.TxNonce(address), recipient, 1, 2, amount, params.TxGas, nil), types.HomesteadSigner{}, key)
+		b.AddTx(tx)
+	})
+	proofs, err := core.MakeCXReceiptProofs(blocks[0], receipts[0])
+	if err != nil {
+		t.Fatalf("cannot make the receipt proofs: %v", err)
+	}
+	if len(proofs) != 1 || len(proofs[2]) != 1 {
+		t.Fatalf("wrong receipt proofs: %v", proofs)
+	}
+	proof := proofs[2][0]
+	transfer, err := core.VerifyCXReceiptProof(proof, 2)
+	if err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+	if transfer.ShardID != 1 || transfer.To != recipient || transfer.Amount.Cmp(amount) != 0 {
+		t.Errorf("wrong transfer: %+v", transfer)
+	}
+	if _, err := core.VerifyCXReceiptProof(proof, 3); err == nil {
+		t.Error("proof accepted by the wrong shard")
+	}
+	// the transfer is identified the same once its source block is sealed
+	sealed := *proof
+	sealed.Header = types.CopyHeader(proof.Header)
+	sealed.Header.CommitBitmap = []byte{1}
+	if sealed.ID() != proof.ID() {
+		t.Error("sealing the source block changed the transfer ID")
+	}
+	tampered := *proof
+	tampered.TxIndex++
+	if _, err := core.VerifyCXReceiptProof(&tampered, 2); err == nil {
+		t.Error("tampered proof accepted")
+	}
+
+	// Credit the transfer on shard 2
+	destConfig := *params.TestChainConfig
+	destConfig.ChainID = big.NewInt(2)
+	dest := core.Genesis{Config: &destConfig, ShardID: 2}
+	db, genDB := ethdb.NewMemDatabase(), ethdb.NewMemDatabase()
+	genesis := dest.MustCommit(db)
+	dest.MustCommit(genDB)
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{Disabled: true}, dest.Config, consensus.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("cannot create chain: %v", err)
+	}
+	defer chain.Stop()
+	destBlocks, _ := core.GenerateChain(dest.Config, genesis, consensus.NewFaker(), genDB, 1, func(i int, b *core.BlockGen) {
+		b.SetShardID(types.EncodeShardID(2))
+		b.AddIncomingReceipt(proof)
+	})
+	if _, err := chain.InsertChain(destBlocks); err == nil {
+		t.Fatal("incoming receipt accepted without a beacon chain")
+	}
+
+	// The source block is final once cross-linked in the beacon chain
+	beaconConfig := *params.TestChainConfig
+	beaconConfig.ChainID = big.NewInt(0)
+	beacon := core.Genesis{Config: &beaconConfig}
+	beaconDB, beaconGenDB := ethdb.NewMemDatabase(), ethdb.NewMemDatabase()
+	beaconGenesis := beacon.MustCommit(beaconDB)
+	beacon.MustCommit(beaconGenDB)
+	beaconChain, err := core.NewBlockChain(beaconDB, &core.CacheConfig{Disabled: true}, beacon.Config, consensus.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("cannot create beacon chain: %v", err)
+	}
+	defer beaconChain.Stop()
+	chain.SetBeaconChain(beaconChain)
+	if _, err := chain.InsertChain(destBlocks); err == nil {
+		t.Fatal("incoming receipt accepted from a block not cross-linked")
+	}
+	beaconBlocks, _ := core.GenerateChain(beacon.Config, beaconGenesis, consensus.NewFaker(), beaconGenDB, 1, func(i int, b *core.BlockGen) {
+		b.AddCrossLink(types.NewCrossLink(blocks[0].Header()))
+	})
+	if _, err := beaconChain.InsertChain(beaconBlocks); err != nil {
+		t.Fatalf("cannot insert the beacon block: %v", err)
+	}
+	if _, err := chain.InsertChain(destBlocks); err != nil {
+		t.Fatalf("cannot insert the block: %v", err)
+	}
+	statedb, err := chain.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance := statedb.GetBalance(recipient); balance.Cmp(amount) != 0 {
+		t.Errorf("recipient balance %v, want %v", balance, amount)
+	}
+	if !core.IsCXReceiptCredited(statedb, proof.ID()) {
+		t.Error("transfer not recorded as credited")
+	}
+
+	// A transfer is credited once only
+	err = core.ApplyIncomingReceipts(statedb, 2, types.CXReceiptProofs{proof})
+	if err == nil || !strings.Contains(err.Error(), core.ErrCXReceiptCredited.Error()) {
+		t.Errorf("transfer credited twice, err %v", err)
+	}
+}
+
+func TestCrossShardTransferToUnknownShard(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	config := *params.TestChainConfig
+	config.ChainID = big.NewInt(1)
+	gspec := core.Genesis{
+		Config:  &config,
+		ShardID: 1,
+		Alloc:   core.GenesisAlloc{address: {Balance: big.NewInt(1000000000000000000)}},
+	}
+	genDB := ethdb.NewMemDatabase()
+	genesis := gspec.MustCommit(genDB)
+	statedb, err := state.New(genesis.Root(), state.NewDatabase(genDB))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, _ := types.SignTx(types.NewCrossShardTransaction(0, common.Address{1}, 1, core.GenesisShardNum, big.NewInt(1000), params.TxGas, nil), types.HomesteadSigner{}, key)
+	header := &types.Header{Number: big.NewInt(1), GasLimit: params.TxGas, Time: big.NewInt(0)}
+	usedGas := uint64(0)
+	_, _, err = core.ApplyTransaction(gspec.Config, nil, &common.Address{}, new(core.GasPool).AddGas(params.TxGas), statedb, header, tx, &usedGas, vm.Config{})
+	if err != core.ErrInvalidToShard {
+		t.Errorf("transfer to an unknown shard applied, err %v", err)
+	}
+}
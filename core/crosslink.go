@@ -0,0 +1,109 @@
+package core
+
+import (
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/ctxerror"
+)
+
+// Cross-links
+//
+// The leaders of the shards send the headers of the blocks they commit to the
+// beacon committee, which includes them as cross-links in the beacon chain
+// blocks. A cross-link is valid if its header is sealed by the committee of its
+// shard for the epoch, as recorded in the beacon chain, and follows the latest
+// cross-link of the shard in the ancestors of the block. The numbers of the
+// latest cross-links of the shards are stored as of each beacon chain block,
+// so that they follow the canonical chain through reorgs. The cross-links of
+// the canonical beacon chain are stored by shard and number, rewritten on
+// reorgs and removed when rewinding.
+
+// ValidateCrossLinks checks the cross-links of the block.
+func (bc *BlockChain) ValidateCrossLinks(block *types.Block) error {
+	crossLinks := block.CrossLinks()
+	if len(crossLinks) == 0 {
+		return nil
+	}
+	if bc.ShardID() != 0 {
+		return ctxerror.New("cross-links outside the beacon chain",
+			"blockNum", block.Number(),
+			"shardID", bc.ShardID())
+	}
+	// number of the latest block cross-linked by shard
+	latest := rawdb.ReadCrossLinkHeads(bc.db, block.ParentHash())
+	for _, crossLink := range crossLinks {
+		if crossLink.Header == nil || crossLink.Header.Number == nil {
+			return ctxerror.New("cross-link without header", "blockNum", block.Number())
+		}
+		shardID, number := crossLink.ShardID(), crossLink.NumberU64()
+		if shardID == bc.ShardID() {
+			return ctxerror.New("cross-link of a beacon chain block",
+				"blockNum", block.Number(),
+				"crossLinkNum", number)
+		}
+		if last, ok := latest[shardID]; ok && number <= last {
+			return ctxerror.New("cross-link not following the latest cross-link of its shard",
+				"blockNum", block.Number(),
+				"shardID", shardID,
+				"crossLinkNum", number,
+				"latestCrossLinkNum", latest[shardID])
+		}
+		if err := bc.engine.VerifySeal(bc, crossLink.Header); err != nil {
+			return ctxerror.New("invalid cross-link seal",
+				"blockNum", block.Number(),
+				"shardID", shardID,
+				"crossLinkNum", number,
+			).WithCause(err)
+		}
+		latest[shardID] = number
+	}
+	return nil
+}
+
+// writeCrossLinkHeads stores the numbers of the latest cross-links of the
+// shards as of the block, given the ones as of its parent, and returns them.
+func writeCrossLinkHeads(db rawdb.DatabaseWriter, block *types.Block, parentHeads map[uint32]uint64) map[uint32]uint64 {
+	heads := make(map[uint32]uint64, len(parentHeads))
+	for shardID, number := range parentHeads {
+		heads[shardID] = number
+	}
+	for _, crossLink := range block.CrossLinks() {
+		heads[crossLink.ShardID()] = crossLink.NumberU64()
+	}
+	if len(heads) > 0 {
+		rawdb.WriteCrossLinkHeads(db, block.Hash(), heads)
+	}
+	return heads
+}
+
+// writeCrossLinks stores the cross-links of the block, validated and
+// canonical, to db.
+func writeCrossLinks(db rawdb.DatabaseWriter, block *types.Block) {
+	for _, crossLink := range block.CrossLinks() {
+		rawdb.WriteCrossLink(db, crossLink)
+	}
+}
+
+// deleteCrossLinks removes the cross-links of the block, no longer canonical,
+// from db.
+func deleteCrossLinks(db rawdb.DatabaseDeleter, block *types.Block) {
+	for _, crossLink := range block.CrossLinks() {
+		rawdb.DeleteCrossLink(db, crossLink.ShardID(), crossLink.NumberU64())
+	}
+}
+
+// ReadCrossLink retrieves the cross-link of the block of the given shard and
+// number, or nil if the block is not cross-linked.
+func (bc *BlockChain) ReadCrossLink(shardID uint32, number uint64) *types.CrossLink {
+	return rawdb.ReadCrossLink(bc.db, shardID, number)
+}
+
+// ReadLatestCrossLink retrieves the latest cross-link of the given shard in the
+// canonical chain, or nil if none of its blocks is cross-linked yet.
+func (bc *BlockChain) ReadLatestCrossLink(shardID uint32) *types.CrossLink {
+	number, ok := rawdb.ReadCrossLinkHeads(bc.db, bc.CurrentBlock().Hash())[shardID]
+	if !ok {
+		return nil
+	}
+	return rawdb.ReadCrossLink(bc.db, shardID, number)
+}
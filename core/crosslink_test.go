@@ -0,0 +1,135 @@
+package core_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/core/vm"
+)
+
+func TestCrossLinks(t *testing.T) {
+	shardHeader := func(number int64) *types.Header {
+		return &types.Header{ShardID: types.EncodeShardID(1), Number: big.NewInt(number), Extra: []byte("shard block")}
+	}
+
+	config := *params.TestChainConfig
+	config.ChainID = big.NewInt(0)
+	gspec := core.Genesis{Config: &config}
+	db, genDB := ethdb.NewMemDatabase(), ethdb.NewMemDatabase()
+	genesis := gspec.MustCommit(db)
+	gspec.MustCommit(genDB)
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{Disabled: true}, gspec.Config, consensus.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("cannot create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	blocks, receipts := core.GenerateChain(gspec.Config, genesis, consensus.NewFaker(), genDB, 2, func(i int, b *core.BlockGen) {
+		b.AddCrossLink(types.NewCrossLink(shardHeader(int64(2*i + 1))))
+		b.AddCrossLink(types.NewCrossLink(shardHeader(int64(2*i + 2))))
+	})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("cannot insert blocks: %v", err)
+	}
+	if latest := chain.ReadLatestCrossLink(1); latest == nil || latest.Hash() != shardHeader(4).Hash() {
+		t.Errorf("wrong latest cross-link: %v", latest)
+	}
+	if crossLink := chain.ReadCrossLink(1, 3); crossLink == nil || crossLink.Hash() != shardHeader(3).Hash() {
+		t.Errorf("wrong cross-link of block 3: %v", crossLink)
+	}
+	if chain.ReadLatestCrossLink(2) != nil {
+		t.Error("cross-link of shard 2 found")
+	}
+
+	// A fast synced chain has the cross-links of the blocks completed with their receipts
+	fastDB := ethdb.NewMemDatabase()
+	gspec.MustCommit(fastDB)
+	fastChain, err := core.NewBlockChain(fastDB, &core.CacheConfig{Disabled: true}, gspec.Config, consensus.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("cannot create fast chain: %v", err)
+	}
+	defer fastChain.Stop()
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	if _, err := fastChain.InsertHeaderChain(headers, 1); err != nil {
+		t.Fatalf("cannot insert headers: %v", err)
+	}
+	if _, err := fastChain.InsertReceiptChain(blocks, receipts); err != nil {
+		t.Fatalf("cannot insert receipts: %v", err)
+	}
+	if latest := fastChain.ReadLatestCrossLink(1); latest == nil || latest.Hash() != shardHeader(4).Hash() {
+		t.Errorf("wrong latest cross-link of the fast chain: %v", latest)
+	}
+
+	invalid := map[string]*types.Header{
+		"superseded": shardHeader(4),
+		"beacon":     {ShardID: types.EncodeShardID(0), Number: big.NewInt(5)},
+		"no number":  {ShardID: types.EncodeShardID(1)},
+	}
+	for name, header := range invalid {
+		block := types.NewBlockWithHeader(&types.Header{ParentHash: blocks[1].Hash(), Number: big.NewInt(3)}).
+			WithCrossLinks([]*types.CrossLink{{Header: header}})
+		if err := chain.ValidateCrossLinks(block); err == nil {
+			t.Errorf("%s cross-link accepted", name)
+		}
+	}
+	outOfOrder := types.NewBlockWithHeader(&types.Header{ParentHash: blocks[1].Hash(), Number: big.NewInt(3)}).
+		WithCrossLinks([]*types.CrossLink{types.NewCrossLink(shardHeader(6)), types.NewCrossLink(shardHeader(5))})
+	if err := chain.ValidateCrossLinks(outOfOrder); err == nil {
+		t.Error("cross-links out of order accepted")
+	}
+	next := types.NewBlockWithHeader(&types.Header{ParentHash: blocks[1].Hash(), Number: big.NewInt(3)}).
+		WithCrossLinks([]*types.CrossLink{types.NewCrossLink(shardHeader(5)), types.NewCrossLink(shardHeader(6))})
+	if err := chain.ValidateCrossLinks(next); err != nil {
+		t.Errorf("valid cross-links rejected: %v", err)
+	}
+	// The cross-links of a fork follow the ones of its own ancestors
+	fork := types.NewBlockWithHeader(&types.Header{ParentHash: blocks[0].Hash(), Number: big.NewInt(2)}).
+		WithCrossLinks([]*types.CrossLink{types.NewCrossLink(shardHeader(3))})
+	if err := chain.ValidateCrossLinks(fork); err != nil {
+		t.Errorf("cross-links of a fork rejected: %v", err)
+	}
+
+	// Rewinding the chain removes the cross-links of the blocks rewound
+	if err := chain.SetHead(1); err != nil {
+		t.Fatalf("cannot rewind chain: %v", err)
+	}
+	if latest := chain.ReadLatestCrossLink(1); latest == nil || latest.Hash() != shardHeader(2).Hash() {
+		t.Errorf("wrong latest cross-link after rewind: %v", latest)
+	}
+	if crossLink := chain.ReadCrossLink(1, 3); crossLink != nil {
+		t.Errorf("cross-link of block 3 kept after rewind: %v", crossLink)
+	}
+}
+
+func TestValidateBodyHashes(t *testing.T) {
+	crossLinks := []*types.CrossLink{
+		types.NewCrossLink(&types.Header{ShardID: types.EncodeShardID(1), Number: big.NewInt(1)}),
+		types.NewCrossLink(&types.Header{ShardID: types.EncodeShardID(1), Number: big.NewInt(2)}),
+	}
+	header := &types.Header{TxHash: types.EmptyRootHash, CrossLinkHash: types.CrossLinksHash(crossLinks)}
+	if err := core.ValidateBodyHashes(header, &types.Body{CrossLinks: crossLinks}); err != nil {
+		t.Errorf("valid body rejected: %v", err)
+	}
+	invalid := map[string][]*types.CrossLink{
+		"missing":  crossLinks[:1],
+		"extra":    append(crossLinks[:2:2], types.NewCrossLink(&types.Header{ShardID: types.EncodeShardID(1), Number: big.NewInt(3)})),
+		"replaced": {crossLinks[0], types.NewCrossLink(&types.Header{ShardID: types.EncodeShardID(2), Number: big.NewInt(2)})},
+		"empty":    nil,
+	}
+	for name, body := range invalid {
+		if err := core.ValidateBodyHashes(header, &types.Body{CrossLinks: body}); err == nil {
+			t.Errorf("%s cross-links accepted", name)
+		}
+	}
+	if err := core.ValidateBodyHashes(&types.Header{TxHash: types.EmptyRootHash}, &types.Body{CrossLinks: crossLinks}); err == nil {
+		t.Error("cross-links accepted by a header without a cross-link root")
+	}
+}
@@ -0,0 +1,27 @@
+package core
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/harmony-one/harmony/core/state"
+)
+
+// DoubleSignMarkerAddress is the system account marking in its storage the
+// double sign offenses already slashed, so that the same offense can't be
+// used again to slash the tokens locked afterwards.
+var DoubleSignMarkerAddress = common.BytesToAddress([]byte("doubleSignMarker"))
+
+// IsDoubleSignSlashed returns whether the double sign offense with the given
+// hash is already slashed in the state.
+func IsDoubleSignSlashed(state *state.DB, offense common.Hash) bool {
+	return state.GetState(DoubleSignMarkerAddress, offense) != (common.Hash{})
+}
+
+// MarkDoubleSignSlashed marks the double sign offense with the given hash as
+// slashed in the state.
+func MarkDoubleSignSlashed(state *state.DB, offense common.Hash) {
+	if state.GetNonce(DoubleSignMarkerAddress) == 0 {
+		// keep the account from being deleted as empty
+		state.SetNonce(DoubleSignMarkerAddress, 1)
+	}
+	state.SetState(DoubleSignMarkerAddress, offense, common.BytesToHash([]byte{1}))
+}
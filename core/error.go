@@ -35,4 +35,19 @@ var (
 
 	// ErrShardStateNotMatch is returned if the calculated shardState hash not equal that in the block header
 	ErrShardStateNotMatch = errors.New("shard state root hash not match")
+
+	// ErrShardStateNotFound is returned if the shard state of an epoch is not stored in the chain
+	ErrShardStateNotFound = errors.New("shard state not found")
+
+	// ErrCommitteeNotFound is returned if a shard has no committee in the shard state of an epoch
+	ErrCommitteeNotFound = errors.New("committee not found in shard state")
+
+	// ErrCrossShardContract is returned if a cross-shard transaction creates or calls a contract
+	ErrCrossShardContract = errors.New("cross-shard transaction can't create or call a contract")
+
+	// ErrInvalidToShard is returned if a cross-shard transaction is sent to a shard that doesn't exist
+	ErrInvalidToShard = errors.New("cross-shard transaction to an unknown shard")
+
+	// ErrCXReceiptCredited is returned if the transfer of a cross-shard receipt is already credited
+	ErrCXReceiptCredited = errors.New("cross-shard receipt already credited")
 )
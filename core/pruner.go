@@ -0,0 +1,426 @@
+package core
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/ctxerror"
+	"github.com/harmony-one/harmony/internal/utils"
+)
+
+// Pruning
+//
+// A node which isn't archival keeps the state of the recent blocks in memory
+// and writes it to disk from time to time, but never deletes the state it
+// wrote, so its database grows about as fast as the database of an archival
+// node. In pruning mode, every PruneInterval blocks, a background pruning
+// deletes from disk the state trie nodes and contract codes of the canonical
+// blocks which are no longer retained, unless they are reachable from the
+// state of one of the last StateRetention blocks or of a checkpoint block,
+// one in CheckpointInterval. So are the bodies, receipts and transaction
+// lookups of the blocks older than the last BlockRetention blocks, if
+// BlockRetention is set. The headers are kept.
+//
+// The states are walked without blocking block insertion. Only the deletion
+// of the unreachable nodes blocks it, after the states of the blocks inserted
+// meanwhile are walked as well, so that no node they share is deleted.
+
+// Default pruning configuration.
+const (
+	DefaultStateRetention     = triesInMemory
+	DefaultCheckpointInterval = BlocksPerEpoch
+	DefaultPruneInterval      = 1000
+	pruningTrieNodeLimit      = 256             // MB
+	pruningTrieTimeLimit      = 5 * time.Minute // the state of a block in this long is written to disk
+)
+
+var (
+	emptyCodeHash = crypto.Keccak256Hash(nil)
+
+	errPruningInterrupted = errors.New("[PRUNE] interrupted by the chain stopping")
+)
+
+// PruneConfig is the configuration of the pruning mode.
+type PruneConfig struct {
+	StateRetention     uint64 // Number of recent blocks whose state is retained, at least the number of states kept in memory
+	CheckpointInterval uint64 // The state of one block in CheckpointInterval is retained, 0 means none but genesis
+	BlockRetention     uint64 // Number of recent blocks whose bodies and receipts are retained, 0 means all
+	PruneInterval      uint64 // Number of blocks inserted between prunings, 0 means DefaultPruneInterval
+}
+
+// DefaultPruneConfig returns the default pruning configuration, which keeps
+// the bodies and receipts of all the blocks.
+func DefaultPruneConfig() *PruneConfig {
+	return &PruneConfig{
+		StateRetention:     DefaultStateRetention,
+		CheckpointInterval: DefaultCheckpointInterval,
+		PruneInterval:      DefaultPruneInterval,
+	}
+}
+
+// PruneStats reports what a pruning of the chain database deleted.
+type PruneStats struct {
+	StateNodes int                // number of state trie nodes and contract codes deleted
+	Blocks     int                // number of blocks whose body and receipts were deleted
+	Reclaimed  common.StorageSize // size of the keys and values deleted
+	Elapsed    time.Duration
+}
+
+// stateRetention returns the number of recent blocks whose state is retained,
+// which is at least the number of blocks whose state is kept in memory.
+func (c *PruneConfig) stateRetention() uint64 {
+	if c.StateRetention < triesInMemory {
+		return triesInMemory
+	}
+	return c.StateRetention
+}
+
+// pruneInterval returns the number of blocks between prunings.
+func (c *PruneConfig) pruneInterval() uint64 {
+	if c.PruneInterval == 0 {
+		return DefaultPruneInterval
+	}
+	return c.PruneInterval
+}
+
+// isStateCheckpoint returns whether the state of the given block is retained
+// however old the block is. The genesis state always is.
+func (c *PruneConfig) isStateCheckpoint(number uint64) bool {
+	return number == 0 || (c.CheckpointInterval > 0 && number%c.CheckpointInterval == 0)
+}
+
+// SetPruning turns on the pruning mode with the given configuration, or turns
+// it off if config is nil. It shall be called before inserting blocks. The
+// chain of an archival node isn't pruned.
+func (bc *BlockChain) SetPruning(config *PruneConfig) {
+	cacheConfig := *bc.cacheConfig
+	cacheConfig.Pruning = config
+	if config != nil && !cacheConfig.Disabled {
+		// Write the state of a block to disk once in a while instead of every block
+		if cacheConfig.TrieNodeLimit == 0 {
+			cacheConfig.TrieNodeLimit = pruningTrieNodeLimit
+		}
+		if cacheConfig.TrieTimeLimit == 0 {
+			cacheConfig.TrieTimeLimit = pruningTrieTimeLimit
+		}
+	}
+	bc.cacheConfig = &cacheConfig
+}
+
+// maybePrune starts a background pruning of the chain database if pruning is
+// enabled, enough blocks were inserted since the last pruning and no pruning
+// is running.
+func (bc *BlockChain) maybePrune() {
+	config := bc.cacheConfig.Pruning
+	if config == nil || bc.cacheConfig.Disabled || bc.getProcInterrupt() {
+		return
+	}
+	if bc.CurrentBlock().NumberU64() < atomic.LoadUint64(&bc.lastPrune)+config.pruneInterval() {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&bc.pruning, 0, 1) {
+		return
+	}
+	bc.wg.Add(1)
+	go func() {
+		defer bc.wg.Done()
+		defer atomic.StoreInt32(&bc.pruning, 0)
+		if _, err := bc.prune(); err != nil {
+			ctxerror.Log15(utils.GetLogInstance().Warn, err)
+		}
+	}()
+}
+
+// Prune deletes from disk the state and the blocks not retained by the pruning
+// configuration, or the default one if pruning is off, and reports what it
+// deleted. Block insertion is only blocked while the state is deleted.
+func (bc *BlockChain) Prune() (PruneStats, error) {
+	bc.wg.Add(1)
+	defer bc.wg.Done()
+	return bc.prune()
+}
+
+// prune prunes the chain database, one pruning at a time.
+func (bc *BlockChain) prune() (PruneStats, error) {
+	bc.pruneMu.Lock()
+	defer bc.pruneMu.Unlock()
+
+	start := time.Now()
+	stats := PruneStats{}
+	current := bc.CurrentBlock().NumberU64()
+	atomic.StoreUint64(&bc.lastPrune, current)
+	if bc.cacheConfig.Disabled {
+		return stats, ctxerror.New("[PRUNE] cannot prune an archival chain")
+	}
+	config := bc.cacheConfig.Pruning
+	if config == nil {
+		config = DefaultPruneConfig()
+	}
+	if fastBlock := bc.CurrentFastBlock(); fastBlock != nil && fastBlock.NumberU64() > current {
+		return stats, ctxerror.New("[PRUNE] cannot prune while fast syncing",
+			"blockNum", current,
+			"fastBlockNum", fastBlock.NumberU64())
+	}
+
+	numNodes, size, err := bc.pruneState(config, current)
+	if err != nil {
+		return stats, err
+	}
+	stats.StateNodes, stats.Reclaimed = numNodes, size
+	numBlocks, size, err := bc.pruneBlocks(config, current)
+	if err != nil {
+		return stats, err
+	}
+	stats.Blocks, stats.Reclaimed = numBlocks, stats.Reclaimed+size
+	stats.Elapsed = time.Since(start)
+	utils.GetLogInstance().Info("[PRUNE] pruned the chain database",
+		"blockNum", current,
+		"stateNodes", stats.StateNodes,
+		"blocks", stats.Blocks,
+		"reclaimed", stats.Reclaimed,
+		"elapsed", common.PrettyDuration(stats.Elapsed))
+	return stats, nil
+}
+
+// pruneState deletes the state trie nodes and contract codes of the canonical
+// blocks whose state is no longer retained, which are reachable from neither
+// the state of the last blocks nor the state of the checkpoint blocks.
+// It returns the number of entries deleted and their size.
+func (bc *BlockChain) pruneState(config *PruneConfig, current uint64) (int, common.StorageSize, error) {
+	first := uint64(0)
+	if retention := config.stateRetention(); current > retention {
+		first = current - retention
+	}
+	pruned := uint64(0)
+	if number, ok := rawdb.ReadPrunedStateNumber(bc.db); ok {
+		pruned = number
+	}
+	if pruned+1 >= first {
+		return 0, 0, nil
+	}
+
+	// Mark the retained states
+	retained := make(map[common.Hash]struct{})
+	markRoot := func(number uint64) error {
+		header := bc.GetHeaderByNumber(number)
+		if header == nil || !bc.HasState(header.Root) {
+			return nil
+		}
+		if err := markState(bc.stateCache, header.Root, retained, nil); err != nil {
+			return ctxerror.New("[PRUNE] cannot walk through the state",
+				"blockNum", number,
+				"root", header.Root,
+			).WithCause(err)
+		}
+		return nil
+	}
+	numbers := []uint64{0}
+	if interval := config.CheckpointInterval; interval > 0 {
+		for number := interval; number < first; number += interval {
+			numbers = append(numbers, number)
+		}
+	}
+	for number := first; number <= current; number++ {
+		numbers = append(numbers, number)
+	}
+	for _, number := range numbers {
+		if bc.getProcInterrupt() {
+			return 0, 0, errPruningInterrupted
+		}
+		if err := markRoot(number); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	// Collect the nodes of the states no longer retained which aren't
+	// reachable from the retained ones
+	garbage := make(map[common.Hash]struct{})
+	for number := pruned + 1; number < first; number++ {
+		if bc.getProcInterrupt() {
+			return 0, 0, errPruningInterrupted
+		}
+		if config.isStateCheckpoint(number) {
+			continue
+		}
+		header := bc.GetHeaderByNumber(number)
+		if header == nil || !bc.HasState(header.Root) {
+			continue
+		}
+		if err := markState(bc.stateCache, header.Root, garbage, retained); err != nil {
+			// a state partly deleted by an interrupted pruning
+			ctxerror.Log15(utils.GetLogInstance().Debug, ctxerror.New("[PRUNE] cannot walk through the pruned state",
+				"blockNum", number,
+				"root", header.Root,
+			).WithCause(err))
+		}
+	}
+
+	// Keep the nodes shared with the states of the blocks inserted meanwhile,
+	// or with the states in memory, then delete the garbage
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+	for number := first; number <= bc.CurrentBlock().NumberU64(); number++ {
+		if err := markRoot(number); err != nil {
+			return 0, 0, err
+		}
+	}
+	for _, hash := range bc.stateCache.TrieDB().Nodes() {
+		retained[hash] = struct{}{}
+	}
+	numNodes, size := 0, common.StorageSize(0)
+	batch := bc.db.NewBatch()
+	for hash := range garbage {
+		if _, ok := retained[hash]; ok {
+			continue
+		}
+		value, _ := bc.db.Get(hash[:])
+		if err := batch.Delete(hash[:]); err != nil {
+			return numNodes, size, ctxerror.New("[PRUNE] cannot delete state trie nodes").WithCause(err)
+		}
+		numNodes++
+		size += common.StorageSize(len(hash) + len(value))
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return numNodes, size, ctxerror.New("[PRUNE] cannot delete state trie nodes").WithCause(err)
+			}
+			batch.Reset()
+		}
+	}
+	rawdb.WritePrunedStateNumber(batch, first-1)
+	if err := batch.Write(); err != nil {
+		return numNodes, size, ctxerror.New("[PRUNE] cannot delete state trie nodes").WithCause(err)
+	}
+	return numNodes, size, nil
+}
+
+// pruneBlocks deletes the bodies, receipts and transaction lookups of the
+// canonical blocks older than the last BlockRetention blocks, except for the
+// genesis block. It returns the number of blocks pruned and the size deleted.
+func (bc *BlockChain) pruneBlocks(config *PruneConfig, current uint64) (int, common.StorageSize, error) {
+	retention := config.BlockRetention
+	if retention == 0 || current <= retention {
+		return 0, 0, nil
+	}
+	last := current - retention
+	first := uint64(1)
+	if pruned, ok := rawdb.ReadPrunedBlockNumber(bc.db); ok {
+		first = pruned + 1
+	}
+	if first > last {
+		return 0, 0, nil
+	}
+
+	numBlocks, size := 0, common.StorageSize(0)
+	batch := bc.db.NewBatch()
+	for number := first; number <= last; number++ {
+		hash := rawdb.ReadCanonicalHash(bc.db, number)
+		if hash == (common.Hash{}) {
+			continue
+		}
+		body := rawdb.ReadBodyRLP(bc.db, hash, number)
+		receipts := rawdb.ReadReceiptsRLP(bc.db, hash, number)
+		if len(body) == 0 && len(receipts) == 0 {
+			continue
+		}
+		if len(body) > 0 {
+			decoded := new(types.Body)
+			if err := rlp.DecodeBytes(body, decoded); err != nil {
+				return numBlocks, size, ctxerror.New("[PRUNE] invalid block body",
+					"blockNum", number,
+				).WithCause(err)
+			}
+			for _, tx := range decoded.Transactions {
+				rawdb.DeleteTxLookupEntry(batch, tx.Hash())
+			}
+		}
+		rawdb.DeleteBody(batch, hash, number)
+		rawdb.DeleteReceipts(batch, hash, number)
+		numBlocks++
+		size += common.StorageSize(len(body) + len(receipts))
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return numBlocks, size, ctxerror.New("[PRUNE] cannot delete blocks").WithCause(err)
+			}
+			batch.Reset()
+		}
+	}
+	rawdb.WritePrunedBlockNumber(batch, last)
+	if err := batch.Write(); err != nil {
+		return numBlocks, size, ctxerror.New("[PRUNE] cannot delete blocks").WithCause(err)
+	}
+	bc.bodyCache.Purge()
+	bc.bodyRLPCache.Purge()
+	bc.receiptsCache.Purge()
+	bc.blockCache.Purge()
+	return numBlocks, size, nil
+}
+
+// markState marks the state trie nodes and contract codes reachable from the
+// given state root, skipping the subtries already marked or excluded.
+func markState(db state.Database, root common.Hash, marked, excluded map[common.Hash]struct{}) error {
+	skip := func(hash common.Hash) bool {
+		if _, ok := marked[hash]; ok {
+			return true
+		}
+		_, ok := excluded[hash]
+		return ok
+	}
+	accounts, err := db.OpenTrie(root)
+	if err != nil {
+		return err
+	}
+	return markTrie(accounts, marked, skip, func(key, value []byte) error {
+		var account state.Account
+		if err := rlp.DecodeBytes(value, &account); err != nil {
+			return err
+		}
+		if codeHash := common.BytesToHash(account.CodeHash); codeHash != emptyCodeHash && !skip(codeHash) {
+			marked[codeHash] = struct{}{}
+		}
+		if skip(account.Root) || account.Root == types.EmptyRootHash {
+			return nil
+		}
+		storage, err := db.OpenStorageTrie(common.BytesToHash(key), account.Root)
+		if err != nil {
+			return err
+		}
+		return markTrie(storage, marked, skip, nil)
+	})
+}
+
+// markTrie marks the nodes of the trie, skipping the subtries for which skip
+// returns true, and calls onLeaf, if any, with the key and value of every
+// leaf reached.
+func markTrie(t state.Trie, marked map[common.Hash]struct{}, skip func(common.Hash) bool, onLeaf func(key, value []byte) error) error {
+	it := t.NodeIterator(nil)
+	for descend := true; it.Next(descend); {
+		descend = true
+		if it.Leaf() {
+			if onLeaf != nil {
+				if err := onLeaf(it.LeafKey(), it.LeafBlob()); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		hash := it.Hash()
+		if hash == (common.Hash{}) {
+			// node embedded in its parent
+			continue
+		}
+		if skip(hash) {
+			descend = false
+			continue
+		}
+		marked[hash] = struct{}{}
+	}
+	return it.Error()
+}
@@ -0,0 +1,102 @@
+package core_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/core/vm"
+)
+
+func TestPrune(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	gspec := core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{address: {Balance: big.NewInt(1000000000000000000)}},
+	}
+	db, genDB := ethdb.NewMemDatabase(), ethdb.NewMemDatabase()
+	genesis := gspec.MustCommit(db)
+	gspec.MustCommit(genDB)
+
+	// Write the state of every block to disk once out of memory
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{TrieNodeLimit: 256, TrieTimeLimit: 1}, gspec.Config, consensus.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("cannot create chain: %v", err)
+	}
+	defer chain.Stop()
+	chain.SetPruning(&core.PruneConfig{
+		StateRetention:     core.DefaultStateRetention,
+		CheckpointInterval: 100,
+		BlockRetention:     200,
+		PruneInterval:      1 << 40,
+	})
+
+	transfer := func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(address), common.Address{1, byte(i >> 8), byte(i)}, 0, big.NewInt(1000), params.TxGas, nil, nil), types.HomesteadSigner{}, key)
+		b.AddTx(tx)
+	}
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, consensus.NewFaker(), genDB, 400, transfer)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("cannot insert blocks: %v", err)
+	}
+	hasState := func(number int) bool {
+		_, err := state.New(blocks[number-1].Root(), state.NewDatabase(db))
+		return err == nil
+	}
+	if !hasState(150) {
+		t.Fatal("state of block 150 not written to disk")
+	}
+
+	stats, err := chain.Prune()
+	if err != nil {
+		t.Fatalf("cannot prune: %v", err)
+	}
+	if stats.StateNodes == 0 || stats.Blocks != 200 || stats.Reclaimed == 0 {
+		t.Errorf("wrong prune stats: %+v", stats)
+	}
+	for _, number := range []int{100, 200, 272, 300, 400} {
+		if !hasState(number) {
+			t.Errorf("state of block %d pruned", number)
+		}
+	}
+	if _, err := state.New(genesis.Root(), state.NewDatabase(db)); err != nil {
+		t.Error("genesis state pruned")
+	}
+	for _, number := range []int{150, 250} {
+		if hasState(number) {
+			t.Errorf("state of block %d not pruned", number)
+		}
+	}
+
+	pruned, kept := blocks[99], blocks[200]
+	if chain.GetBlockByNumber(100) != nil || rawdb.ReadReceipts(db, pruned.Hash(), 100) != nil {
+		t.Error("block 100 not pruned")
+	}
+	if chain.GetHeaderByNumber(100) == nil {
+		t.Error("header of block 100 pruned")
+	}
+	if hash, _, _ := rawdb.ReadTxLookupEntry(db, pruned.Transactions()[0].Hash()); hash != (common.Hash{}) {
+		t.Error("transaction lookup of block 100 not pruned")
+	}
+	if chain.GetBlockByNumber(201) == nil || rawdb.ReadReceipts(db, kept.Hash(), 201) == nil {
+		t.Error("block 201 pruned")
+	}
+
+	if stats, err := chain.Prune(); err != nil || stats.StateNodes != 0 || stats.Blocks != 0 {
+		t.Errorf("pruning again: stats %+v, err %v", stats, err)
+	}
+
+	more, _ := core.GenerateChain(gspec.Config, blocks[len(blocks)-1], consensus.NewFaker(), genDB, 10, transfer)
+	if _, err := chain.InsertChain(more); err != nil {
+		t.Fatalf("cannot insert blocks after pruning: %v", err)
+	}
+}
@@ -271,6 +271,13 @@ func DeleteTd(db DatabaseDeleter, hash common.Hash, number uint64) {
 	}
 }
 
+// ReadReceiptsRLP retrieves all the transaction receipts belonging to a block
+// in RLP encoding.
+func ReadReceiptsRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
+	data, _ := db.Get(blockReceiptsKey(number, hash))
+	return data
+}
+
 // ReadReceipts retrieves all the transaction receipts belonging to a block.
 func ReadReceipts(db DatabaseReader, hash common.Hash, number uint64) types.Receipts {
 	// Retrieve the flattened receipt slice
@@ -330,7 +337,7 @@ func ReadBlock(db DatabaseReader, hash common.Hash, number uint64) *types.Block
 	if body == nil {
 		return nil
 	}
-	return types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles)
+	return types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles).WithDoubleSigns(body.DoubleSigns).WithIncomingReceipts(body.IncomingReceipts).WithCrossLinks(body.CrossLinks).WithShardState(body.ShardState)
 }
 
 // WriteBlock serializes a block into the database, header and body separately.
@@ -398,3 +405,49 @@ func WriteShardState(db DatabaseWriter, hash common.Hash, number uint64, shardSt
 		log.Crit("Failed to store sharding state", "err", err)
 	}
 }
+
+// ReadEpochShardState retrieves the sharding state of the given epoch, as
+// announced by the beacon chain.
+func ReadEpochShardState(db DatabaseReader, epoch uint64) types.ShardState {
+	data, _ := db.Get(epochShardStateKey(epoch))
+	if len(data) == 0 {
+		return nil
+	}
+	shardState := types.ShardState{}
+	if err := rlp.DecodeBytes(data, &shardState); err != nil {
+		log.Error("Fail to decode sharding state", "epoch", epoch, "err", err)
+		return nil
+	}
+	return shardState
+}
+
+// WriteEpochShardState stores the sharding state of the given epoch into database.
+func WriteEpochShardState(db DatabaseWriter, epoch uint64, shardState types.ShardState) {
+	data, err := rlp.EncodeToBytes(shardState)
+	if err != nil {
+		log.Crit("Failed to encode sharding state", "err", err)
+	}
+	if err := db.Put(epochShardStateKey(epoch), data); err != nil {
+		log.Crit("Failed to store sharding state", "err", err)
+	}
+}
+
+// ReadEpochRandSeed retrieves the RandSeed of the beacon epoch block of the
+// given epoch, as announced by the beacon chain.
+func ReadEpochRandSeed(db DatabaseReader, epoch uint64) ([32]byte, bool) {
+	var randSeed [32]byte
+	data, _ := db.Get(epochRandSeedKey(epoch))
+	if len(data) != len(randSeed) {
+		return randSeed, false
+	}
+	copy(randSeed[:], data)
+	return randSeed, true
+}
+
+// WriteEpochRandSeed stores the RandSeed of the beacon epoch block of the
+// given epoch into database.
+func WriteEpochRandSeed(db DatabaseWriter, epoch uint64, randSeed [32]byte) {
+	if err := db.Put(epochRandSeedKey(epoch), randSeed[:]); err != nil {
+		log.Crit("Failed to store rand seed", "err", err)
+	}
+}
@@ -0,0 +1,95 @@
+package rawdb
+
+import (
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/harmony-one/harmony/core/types"
+)
+
+// ReadCrossLink retrieves the cross-link of the block of the given shard and
+// number.
+func ReadCrossLink(db DatabaseReader, shardID uint32, number uint64) *types.CrossLink {
+	data, _ := db.Get(crossLinkKey(shardID, number))
+	if len(data) == 0 {
+		return nil
+	}
+	crossLink := &types.CrossLink{}
+	if err := rlp.DecodeBytes(data, crossLink); err != nil {
+		log.Error("Invalid cross-link RLP", "shardID", shardID, "number", number, "err", err)
+		return nil
+	}
+	return crossLink
+}
+
+// WriteCrossLink stores a cross-link.
+func WriteCrossLink(db DatabaseWriter, crossLink *types.CrossLink) {
+	data, err := rlp.EncodeToBytes(crossLink)
+	if err != nil {
+		log.Crit("Failed to RLP encode cross-link", "err", err)
+	}
+	if err := db.Put(crossLinkKey(crossLink.ShardID(), crossLink.NumberU64()), data); err != nil {
+		log.Crit("Failed to store cross-link", "err", err)
+	}
+}
+
+// DeleteCrossLink removes the cross-link of the block of the given shard and
+// number.
+func DeleteCrossLink(db DatabaseDeleter, shardID uint32, number uint64) {
+	if err := db.Delete(crossLinkKey(shardID, number)); err != nil {
+		log.Crit("Failed to delete cross-link", "err", err)
+	}
+}
+
+// crossLinkHead is the number of the latest cross-linked block of a shard.
+type crossLinkHead struct {
+	ShardID uint32
+	Number  uint64
+}
+
+// ReadCrossLinkHeads retrieves the numbers of the latest cross-linked blocks of
+// the shards, by shard ID, as of the beacon chain block of the given hash.
+func ReadCrossLinkHeads(db DatabaseReader, hash common.Hash) map[uint32]uint64 {
+	heads := make(map[uint32]uint64)
+	data, _ := db.Get(crossLinkHeadsKey(hash))
+	if len(data) == 0 {
+		return heads
+	}
+	list := []crossLinkHead{}
+	if err := rlp.DecodeBytes(data, &list); err != nil {
+		log.Error("Invalid cross-link heads RLP", "hash", hash, "err", err)
+		return heads
+	}
+	for _, head := range list {
+		heads[head.ShardID] = head.Number
+	}
+	return heads
+}
+
+// WriteCrossLinkHeads stores the numbers of the latest cross-linked blocks of
+// the shards as of the beacon chain block of the given hash.
+func WriteCrossLinkHeads(db DatabaseWriter, hash common.Hash, heads map[uint32]uint64) {
+	list := make([]crossLinkHead, 0, len(heads))
+	for shardID, number := range heads {
+		list = append(list, crossLinkHead{ShardID: shardID, Number: number})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ShardID < list[j].ShardID })
+	data, err := rlp.EncodeToBytes(list)
+	if err != nil {
+		log.Crit("Failed to RLP encode cross-link heads", "err", err)
+	}
+	if err := db.Put(crossLinkHeadsKey(hash), data); err != nil {
+		log.Crit("Failed to store cross-link heads", "err", err)
+	}
+}
+
+// DeleteCrossLinkHeads removes the numbers of the latest cross-linked blocks
+// of the shards as of the beacon chain block of the given hash.
+func DeleteCrossLinkHeads(db DatabaseDeleter, hash common.Hash) {
+	if err := db.Delete(crossLinkHeadsKey(hash)); err != nil {
+		log.Crit("Failed to delete cross-link heads", "err", err)
+	}
+}
@@ -0,0 +1,63 @@
+package rawdb
+
+import (
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ReadPbftLogConsensusIDs retrieves the consensus IDs of the rounds whose
+// messages are stored in the pbft log.
+func ReadPbftLogConsensusIDs(db DatabaseReader) []uint32 {
+	data, _ := db.Get(pbftLogKey)
+	if len(data) == 0 {
+		return nil
+	}
+	consensusIDs := []uint32{}
+	if err := rlp.DecodeBytes(data, &consensusIDs); err != nil {
+		log.Error("Invalid pbft log consensus ID list RLP", "err", err)
+		return nil
+	}
+	return consensusIDs
+}
+
+// WritePbftLogConsensusIDs stores the consensus IDs of the rounds whose
+// messages are stored in the pbft log.
+func WritePbftLogConsensusIDs(db DatabaseWriter, consensusIDs []uint32) {
+	data, err := rlp.EncodeToBytes(consensusIDs)
+	if err != nil {
+		log.Crit("Failed to RLP encode pbft log consensus ID list", "err", err)
+	}
+	if err := db.Put(pbftLogKey, data); err != nil {
+		log.Crit("Failed to store pbft log consensus ID list", "err", err)
+	}
+}
+
+// ReadPbftMessages retrieves the serialized pbft messages of the given round,
+// in the order they were stored.
+func ReadPbftMessages(db DatabaseReader, consensusID uint32) [][]byte {
+	messages := [][]byte{}
+	for seq := uint32(0); ; seq++ {
+		data, _ := db.Get(pbftMessageKey(consensusID, seq))
+		if len(data) == 0 {
+			return messages
+		}
+		messages = append(messages, data)
+	}
+}
+
+// WritePbftMessage stores the serialized pbft message of the given round, at
+// the given sequence number within the round.
+func WritePbftMessage(db DatabaseWriter, consensusID uint32, seq uint32, message []byte) {
+	if err := db.Put(pbftMessageKey(consensusID, seq), message); err != nil {
+		log.Crit("Failed to store pbft message", "err", err)
+	}
+}
+
+// DeletePbftMessages removes the given number of pbft messages of the given round.
+func DeletePbftMessages(db DatabaseDeleter, consensusID uint32, count uint32) {
+	for seq := uint32(0); seq < count; seq++ {
+		if err := db.Delete(pbftMessageKey(consensusID, seq)); err != nil {
+			log.Crit("Failed to delete pbft message", "err", err)
+		}
+	}
+}
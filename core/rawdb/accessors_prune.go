@@ -0,0 +1,43 @@
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ReadPrunedBlockNumber retrieves the number of the last block whose body and
+// receipts were pruned, and false if no block was pruned.
+func ReadPrunedBlockNumber(db DatabaseReader) (uint64, bool) {
+	data, _ := db.Get(prunedBlockKey)
+	if len(data) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(data), true
+}
+
+// WritePrunedBlockNumber stores the number of the last block whose body and
+// receipts were pruned.
+func WritePrunedBlockNumber(db DatabaseWriter, number uint64) {
+	if err := db.Put(prunedBlockKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the last pruned block number", "err", err)
+	}
+}
+
+// ReadPrunedStateNumber retrieves the number of the last block whose state
+// was pruned, unless retained, and false if no state was pruned.
+func ReadPrunedStateNumber(db DatabaseReader) (uint64, bool) {
+	data, _ := db.Get(prunedStateKey)
+	if len(data) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(data), true
+}
+
+// WritePrunedStateNumber stores the number of the last block whose state was
+// pruned, unless retained.
+func WritePrunedStateNumber(db DatabaseWriter, number uint64) {
+	if err := db.Put(prunedStateKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the last pruned state number", "err", err)
+	}
+}
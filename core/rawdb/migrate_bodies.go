@@ -0,0 +1,60 @@
+package rawdb
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/harmony/core/types"
+)
+
+// legacyBody is the encoding of the block bodies before the incoming
+// cross-shard receipts, with the double sign evidences as the tail.
+type legacyBody struct {
+	Transactions []*types.Transaction
+	Uncles       []*types.Header
+	DoubleSigns  []*types.DoubleSignEvidence `rlp:"tail"`
+}
+
+// migrateBodies re-encodes the bodies of the canonical blocks with an empty
+// list of incoming cross-shard receipts. The bodies already re-encoded are left
+// as they are.
+func migrateBodies(db ethdb.Database, progress func(done, total uint64)) error {
+	head := ReadHeaderNumber(db, ReadHeadHeaderHash(db))
+	if head == nil {
+		return nil
+	}
+	batch := db.NewBatch()
+	for number := uint64(0); number <= *head; number++ {
+		progress(number, *head)
+		hash := ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			continue
+		}
+		data := ReadBodyRLP(db, hash, number)
+		if len(data) == 0 {
+			// pruned, or not downloaded yet
+			continue
+		}
+		if err := rlp.DecodeBytes(data, new(types.Body)); err == nil {
+			continue
+		}
+		legacy := legacyBody{}
+		if err := rlp.DecodeBytes(data, &legacy); err != nil {
+			return fmt.Errorf("cannot decode the body of block #%d %x: %v", number, hash, err)
+		}
+		WriteBody(batch, hash, number, &types.Body{
+			Transactions: legacy.Transactions,
+			Uncles:       legacy.Uncles,
+			DoubleSigns:  legacy.DoubleSigns,
+		})
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	return batch.Write()
+}
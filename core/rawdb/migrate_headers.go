@@ -0,0 +1,39 @@
+package rawdb
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// errHeadersChanged is returned when upgrading a database holding blocks with
+// double signs, incoming receipts or cross-links their headers don't commit to.
+var errHeadersChanged = errors.New("the database holds blocks with double signs, incoming receipts or cross-links their headers don't commit to: remove the database and sync again")
+
+// checkHeaders checks that the headers of the canonical blocks need no
+// upgrade. The headers of the blocks without double signs, incoming receipts
+// and cross-links keep their encoding and hash, see types.Header, and the ones
+// of the other blocks can't be upgraded, as the seals sign the former headers.
+func checkHeaders(db ethdb.Database, progress func(done, total uint64)) error {
+	head := ReadHeaderNumber(db, ReadHeadHeaderHash(db))
+	if head == nil {
+		return nil
+	}
+	for number := uint64(0); number <= *head; number++ {
+		progress(number, *head)
+		hash := ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			continue
+		}
+		body := ReadBody(db, hash, number)
+		if body == nil {
+			// pruned, or not downloaded yet
+			continue
+		}
+		if len(body.DoubleSigns) > 0 || len(body.IncomingReceipts) > 0 || len(body.CrossLinks) > 0 {
+			return errHeadersChanged
+		}
+	}
+	return nil
+}
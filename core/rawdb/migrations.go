@@ -0,0 +1,118 @@
+package rawdb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Schema versioning
+//
+// The version of the schema of a database is stored under databaseVerisionKey.
+// A new database gets the version of the latest migration. A database created
+// before the schema was versioned has LegacySchemaVersion. A database of a
+// newer version than the software knows is refused, and a database of an older
+// version is upgraded by running the migrations to the following versions, in
+// order. The version is updated after every migration done, so that a
+// migration interrupted, by a crash for example, is run again on the next
+// start. Migrations must hence be idempotent.
+//
+// Any change to the format of the entries defined in schema.go, or to the
+// encoding of the types stored, requires appending a migration upgrading the
+// existing databases to migrations.
+
+// LegacySchemaVersion is the version of the schema of the databases created
+// before the schema was versioned.
+const LegacySchemaVersion = 1
+
+// migrationReportInterval is the interval between two progress reports of a
+// migration.
+const migrationReportInterval = 8 * time.Second
+
+// Migration upgrades the schema of a database from the previous version to
+// Version.
+type Migration struct {
+	Version int
+	Name    string
+	// Migrate upgrades the database, reporting its progress with the given
+	// function. It may be run again after an interruption, so it shall be
+	// idempotent.
+	Migrate func(db ethdb.Database, progress func(done, total uint64)) error
+}
+
+// migrations are the migrations of the schema, by version. The version of
+// each migration follows the one of the previous migration.
+var migrations = []Migration{
+	{
+		Version: 2,
+		Name:    "incoming cross-shard receipts in block bodies",
+		Migrate: migrateBodies,
+	},
+	{
+		Version: 3,
+		Name:    "block headers committing to the whole body",
+		Migrate: checkHeaders,
+	},
+}
+
+// SchemaVersion returns the version of the schema of the databases written by
+// this software.
+func SchemaVersion() int {
+	return latestVersion(migrations)
+}
+
+// InitSchema records the schema version of a new database, or upgrades the
+// database to the current schema version. It fails if the database has a newer
+// schema version, or if a migration fails.
+func InitSchema(db ethdb.Database) error {
+	return initSchema(db, migrations)
+}
+
+func latestVersion(migrations []Migration) int {
+	if len(migrations) == 0 {
+		return LegacySchemaVersion
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+func initSchema(db ethdb.Database, migrations []Migration) error {
+	latest := latestVersion(migrations)
+	if has, err := db.Has(databaseVerisionKey); err != nil {
+		return err
+	} else if !has {
+		if ReadCanonicalHash(db, 0) == (common.Hash{}) {
+			// New database
+			WriteDatabaseVersion(db, latest)
+			return nil
+		}
+		WriteDatabaseVersion(db, LegacySchemaVersion)
+	}
+
+	version := ReadDatabaseVersion(db)
+	if version > latest {
+		return fmt.Errorf("database schema version %d is newer than the supported version %d", version, latest)
+	}
+	for _, migration := range migrations {
+		if migration.Version <= version {
+			continue
+		}
+		log.Info("Migrating the database schema", "from", version, "to", migration.Version, "migration", migration.Name)
+		start, reported := time.Now(), time.Now()
+		progress := func(done, total uint64) {
+			if time.Since(reported) >= migrationReportInterval {
+				log.Info("Migrating the database schema", "migration", migration.Name, "done", done, "total", total, "elapsed", common.PrettyDuration(time.Since(start)))
+				reported = time.Now()
+			}
+		}
+		if err := migration.Migrate(db, progress); err != nil {
+			return fmt.Errorf("migration %q to schema version %d failed: %v", migration.Name, migration.Version, err)
+		}
+		WriteDatabaseVersion(db, migration.Version)
+		version = migration.Version
+		log.Info("Migrated the database schema", "version", version, "migration", migration.Name, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package rawdb
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/harmony/core/types"
+)
+
+func TestInitSchema(t *testing.T) {
+	run := []int{}
+	fail := false
+	migrations := []Migration{}
+	for version := 2; version <= 3; version++ {
+		version := version
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    "test",
+			Migrate: func(db ethdb.Database, progress func(done, total uint64)) error {
+				if fail && version == 3 {
+					return errors.New("interrupted")
+				}
+				run = append(run, version)
+				progress(1, 1)
+				return nil
+			},
+		})
+	}
+
+	// A new database gets the latest version without migrating
+	db := ethdb.NewMemDatabase()
+	if err := initSchema(db, migrations); err != nil || ReadDatabaseVersion(db) != 3 || len(run) != 0 {
+		t.Errorf("new database: version %d, migrations run %v, err %v", ReadDatabaseVersion(db), run, err)
+	}
+
+	// A legacy database is migrated, and migrated again after an interruption
+	db = ethdb.NewMemDatabase()
+	WriteCanonicalHash(db, common.Hash{1}, 0)
+	fail = true
+	if err := initSchema(db, migrations); err == nil || ReadDatabaseVersion(db) != 2 {
+		t.Errorf("interrupted migration: version %d, err %v", ReadDatabaseVersion(db), err)
+	}
+	fail = false
+	if err := initSchema(db, migrations); err != nil || ReadDatabaseVersion(db) != 3 {
+		t.Errorf("resumed migration: version %d, err %v", ReadDatabaseVersion(db), err)
+	}
+	if len(run) != 2 || run[0] != 2 || run[1] != 3 {
+		t.Errorf("wrong migrations run: %v", run)
+	}
+
+	// A database of a newer version is refused
+	WriteDatabaseVersion(db, 4)
+	if err := initSchema(db, migrations); err == nil {
+		t.Error("newer database accepted")
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	version := LegacySchemaVersion
+	for _, migration := range migrations {
+		if migration.Version != version+1 {
+			t.Errorf("migration %q to version %d follows version %d", migration.Name, migration.Version, version)
+		}
+		version = migration.Version
+	}
+	if SchemaVersion() != version {
+		t.Errorf("schema version %d instead of %d", SchemaVersion(), version)
+	}
+}
+
+func TestMigrateBodies(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	header := &types.Header{Number: big.NewInt(1), Extra: []byte("test header")}
+	hash := header.Hash()
+	WriteHeader(db, header)
+	WriteCanonicalHash(db, hash, 1)
+	WriteHeadHeaderHash(db, hash)
+	evidence := &types.DoubleSignEvidence{FirstMessage: []byte{1}, SecondMessage: []byte{2}}
+	data, _ := rlp.EncodeToBytes(&legacyBody{DoubleSigns: []*types.DoubleSignEvidence{evidence}})
+	WriteBodyRLP(db, hash, 1, data)
+
+	for i := 0; i < 2; i++ {
+		if err := migrateBodies(db, func(done, total uint64) {}); err != nil {
+			t.Fatalf("migration %d failed: %v", i, err)
+		}
+		body := ReadBody(db, hash, 1)
+		if body == nil || len(body.DoubleSigns) != 1 || body.DoubleSigns[0].Hash() != evidence.Hash() {
+			t.Fatalf("wrong body after migration %d: %+v", i, body)
+		}
+	}
+}
+
+func TestCheckHeaders(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	if err := checkHeaders(db, func(done, total uint64) {}); err != nil {
+		t.Errorf("empty database refused: %v", err)
+	}
+	header := &types.Header{Number: big.NewInt(1), Extra: []byte("test header")}
+	hash := header.Hash()
+	WriteHeader(db, header)
+	WriteCanonicalHash(db, hash, 1)
+	WriteHeadHeaderHash(db, hash)
+	WriteBody(db, hash, 1, &types.Body{})
+	if err := checkHeaders(db, func(done, total uint64) {}); err != nil {
+		t.Errorf("database with blocks of transactions only refused: %v", err)
+	}
+	evidence := &types.DoubleSignEvidence{FirstMessage: []byte{1}, SecondMessage: []byte{2}}
+	WriteBody(db, hash, 1, &types.Body{DoubleSigns: []*types.DoubleSignEvidence{evidence}})
+	if err := checkHeaders(db, func(done, total uint64) {}); err != errHeadersChanged {
+		t.Errorf("database with blocks of double signs accepted, err %v", err)
+	}
+}
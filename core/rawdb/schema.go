@@ -27,6 +27,7 @@ import (
 // The fields below define the low level database schema prefixing.
 var (
 	// databaseVerisionKey tracks the current database version.
+	// Changes to the format of the entries below require a migration, see migrations.go.
 	databaseVerisionKey = []byte("DatabaseVersion")
 
 	// headHeaderKey tracks the latest know header's hash.
@@ -53,7 +54,18 @@ var (
 	txLookupPrefix  = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
 
-	shardStatePrefix = []byte("ss") // shardStatePrefix + num (uint64 big endian) + hash -> shardState
+	shardStatePrefix      = []byte("ss") // shardStatePrefix + num (uint64 big endian) + hash -> shardState
+	epochShardStatePrefix = []byte("es") // epochShardStatePrefix + epoch (uint64 big endian) -> shardState announced by the beacon chain
+	epochRandSeedPrefix   = []byte("er") // epochRandSeedPrefix + epoch (uint64 big endian) -> randSeed announced by the beacon chain
+
+	pbftLogKey        = []byte("PbftLog") // pbftLogKey -> consensus IDs of the rounds having messages in the pbft log
+	pbftMessagePrefix = []byte("pm")      // pbftMessagePrefix + consensusID (uint32 big endian) + seq (uint32 big endian) -> pbft message
+
+	crossLinkPrefix      = []byte("cl") // crossLinkPrefix + shardID (uint32 big endian) + num (uint64 big endian) -> canonical cross-link
+	crossLinkHeadsPrefix = []byte("cH") // crossLinkHeadsPrefix + hash -> numbers of the latest cross-links of the shards as of the block
+
+	prunedBlockKey = []byte("LastPrunedBlock") // prunedBlockKey -> number of the last block whose body and receipts were pruned
+	prunedStateKey = []byte("LastPrunedState") // prunedStateKey -> number of the last block whose state was pruned
 
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
@@ -138,3 +150,37 @@ func configKey(hash common.Hash) []byte {
 func shardStateKey(number uint64, hash common.Hash) []byte {
 	return append(append(shardStatePrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
+
+// epochShardStateKey = epochShardStatePrefix + epoch (uint64 big endian)
+func epochShardStateKey(epoch uint64) []byte {
+	return append(append([]byte{}, epochShardStatePrefix...), encodeBlockNumber(epoch)...)
+}
+
+// epochRandSeedKey = epochRandSeedPrefix + epoch (uint64 big endian)
+func epochRandSeedKey(epoch uint64) []byte {
+	return append(append([]byte{}, epochRandSeedPrefix...), encodeBlockNumber(epoch)...)
+}
+
+// pbftMessageKey = pbftMessagePrefix + consensusID (uint32 big endian) + seq (uint32 big endian)
+func pbftMessageKey(consensusID uint32, seq uint32) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint32(enc, consensusID)
+	binary.BigEndian.PutUint32(enc[4:], seq)
+	return append(append([]byte{}, pbftMessagePrefix...), enc...)
+}
+
+func encodeShardID(shardID uint32) []byte {
+	enc := make([]byte, 4)
+	binary.BigEndian.PutUint32(enc, shardID)
+	return enc
+}
+
+// crossLinkKey = crossLinkPrefix + shardID (uint32 big endian) + num (uint64 big endian)
+func crossLinkKey(shardID uint32, number uint64) []byte {
+	return append(append(append([]byte{}, crossLinkPrefix...), encodeShardID(shardID)...), encodeBlockNumber(number)...)
+}
+
+// crossLinkHeadsKey = crossLinkHeadsPrefix + hash
+func crossLinkHeadsKey(hash common.Hash) []byte {
+	return append(append([]byte{}, crossLinkHeadsPrefix...), hash.Bytes()...)
+}
@@ -2,12 +2,15 @@ package core
 
 import (
 	"encoding/binary"
-	"math/rand"
+	"math"
+	"math/big"
 	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/harmony-one/bls/ffi/go/bls"
 	"github.com/harmony-one/harmony/contracts/structs"
+	"github.com/harmony-one/harmony/internal/ctxerror"
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/internal/utils/contract"
 
@@ -29,113 +32,265 @@ const (
 	CuckooRate = 0.1
 )
 
+// The bounds of resharding, which all the nodes must agree on.
+const (
+	// MinValidatorUptime is the fraction of the blocks of an epoch a validator
+	// must sign to stay in its committee at the next resharding.
+	MinValidatorUptime = 0.5
+	// MinCommitteeSize is the number of members below which resharding doesn't
+	// move members out of a committee, and fills it first.
+	MinCommitteeSize = 4
+	// MaxCommitteeSize is the number of members above which resharding doesn't
+	// assign members to a committee.
+	// Like CuckooRate, the committee sizes are protocol constants every node
+	// must agree on to compute the same shard states.
+	MaxCommitteeSize = 4 * GenesisShardSize
+)
+
+// Resharding
+//
+// The committees of an epoch are computed from the committees of the previous
+// epoch, the RandSeed of the block of the previous epoch storing them, and the
+// stakes, so that anyone can recompute and audit them:
+//
+//  1. The unavailable validators are removed, see kickOutUnavailable.
+//  2. The leader of each committee, its first member, stays. The other members
+//     are sorted by BLS public key, and the cuckoo rule moves a CuckooRate of
+//     them, drawn at random, out of the committee, down to MinCommitteeSize
+//     members and at least down to MaxCommitteeSize members.
+//  3. The nodes moved out and the newly staked nodes, sorted by BLS public key
+//     and then shuffled, are assigned one by one to the committee with the
+//     least total stake, then the fewest members, then the lowest shard ID,
+//     among the committees below MinCommitteeSize members if any, or else the
+//     ones below MaxCommitteeSize members. A node which no committee can take
+//     is left out until the next epoch.
+//
+// The random draws come from reshardingRand seeded with the RandSeed and the
+// epoch. A node without stake, e.g. a genesis node, weighs nothing.
+// The stakes of the members are recorded in the committees, so that every
+// node weights the signers of the epoch the same way, from the chain alone.
+
 // ShardingState is data structure hold the sharding state
 type ShardingState struct {
-	epoch      uint64 // current epoch
-	rnd        uint64 // random seed for resharding
+	epoch      uint64   // current epoch
+	rnd        [32]byte // random seed for resharding, the RandSeed of the epoch block
 	numShards  int
 	shardState types.ShardState
+
+	minCommitteeSize int // MinCommitteeSize, 0 means no minimum
+	maxCommitteeSize int // MaxCommitteeSize, 0 means no maximum
 }
 
-// sortedCommitteeBySize will sort shards by size
-// Suppose there are N shards, the first N/2 larger shards are called active committees
-// the rest N/2 smaller committees are called inactive committees
-// actually they are all just normal shards
-// TODO: sort the committee weighted by total staking instead of shard size
-func (ss *ShardingState) sortCommitteeBySize() {
-	sort.Slice(ss.shardState, func(i, j int) bool {
-		return len(ss.shardState[i].NodeList) > len(ss.shardState[j].NodeList)
-	})
+// reshardingRand is the pseudo-random generator of resharding. It outputs the
+// Keccak-256 hash of its seed and a counter, as big-endian uint64s, starting
+// from the counter 0. Seeded with the RandSeed of a block, its output can't be
+// predicted before the randomness is revealed, and is reproducible after.
+type reshardingRand struct {
+	seed    common.Hash
+	counter uint64
+	buf     []byte
+}
+
+// newReshardingRand returns the generator of the resharding to the epoch
+// following the given one, seeded with keccak256("harmony resharding",
+// randSeed, epoch as uint64 big-endian).
+func newReshardingRand(randSeed [32]byte, epoch uint64) *reshardingRand {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, epoch)
+	return &reshardingRand{seed: crypto.Keccak256Hash([]byte("harmony resharding"), randSeed[:], enc)}
 }
 
-// assignNewNodes add new nodes into the N/2 active committees evenly
-func (ss *ShardingState) assignNewNodes(newNodeList []types.NodeID) {
-	ss.sortCommitteeBySize()
-	numActiveShards := ss.numShards / 2
-	Shuffle(newNodeList)
-	for i, nid := range newNodeList {
-		id := 0
-		if numActiveShards > 0 {
-			id = i % numActiveShards
+// Uint64 returns the next pseudo-random uint64.
+func (r *reshardingRand) Uint64() uint64 {
+	if len(r.buf) < 8 {
+		enc := make([]byte, 8)
+		binary.BigEndian.PutUint64(enc, r.counter)
+		r.counter++
+		r.buf = crypto.Keccak256(r.seed[:], enc)
+	}
+	n := binary.BigEndian.Uint64(r.buf[:8])
+	r.buf = r.buf[8:]
+	return n
+}
+
+// Intn returns a uniform pseudo-random int in [0, n), for n > 0, rejecting the
+// uint64s above the greatest multiple of n.
+func (r *reshardingRand) Intn(n int) int {
+	bound := uint64(n)
+	limit := math.MaxUint64 - math.MaxUint64%bound
+	for {
+		if x := r.Uint64(); x < limit {
+			return int(x % bound)
 		}
-		if id < len(ss.shardState) {
-			ss.shardState[id].NodeList = append(ss.shardState[id].NodeList, nid)
-		} else {
-			utils.GetLogInstance().Error("assignNewNodes", "index out of range", len(ss.shardState), "id", id)
+	}
+}
+
+// Shuffle shuffles the list with the Fisher-Yates algorithm, swapping each
+// item from the last one with an item drawn at random up to it.
+func (r *reshardingRand) Shuffle(list []types.NodeID) {
+	for i := len(list) - 1; i > 0; i-- {
+		j := r.Intn(i + 1)
+		list[i], list[j] = list[j], list[i]
+	}
+}
+
+// sortNodeIDs sorts the list by BLS public key.
+func sortNodeIDs(list []types.NodeID) {
+	sort.Slice(list, func(i, j int) bool {
+		return types.CompareNodeID(list[i], list[j]) == -1
+	})
+}
+
+// nodeStake returns the stake of the node, 0 if it has none.
+func nodeStake(nodeID types.NodeID, stakes map[common.Address]*structs.StakeInfo) *big.Int {
+	if info, ok := stakes[common.HexToAddress(nodeID.EcdsaAddress)]; ok && info.Amount != nil && info.BlsPublicKey == nodeID.BlsPublicKey {
+		return info.Amount
+	}
+	return new(big.Int)
+}
+
+// kickOutUnavailable removes from the committee of the uptime's shard the
+// validators whose uptime is below minUptime.
+// The leader, who bootstraps the leader schedule, is always kept.
+func (ss *ShardingState) kickOutUnavailable(uptime *types.EpochUptime, minUptime float64) {
+	if minUptime <= 0 {
+		return
+	}
+	committee := ss.shardState.FindCommitteeByID(uptime.ShardID)
+	if committee == nil || len(committee.NodeList) == 0 {
+		return
+	}
+	nodeList := []types.NodeID{committee.NodeList[0]}
+	for _, nodeID := range committee.NodeList[1:] {
+		validator := uptime.FindValidator(nodeID.BlsPublicKey)
+		if validator != nil && validator.Uptime() < minUptime {
+			utils.GetLogInstance().Info("[Resharding] Kicking out unavailable validator",
+				"epoch", uptime.Epoch,
+				"shardID", uptime.ShardID,
+				"blsPublicKey", nodeID.BlsPublicKey.Hex(),
+				"uptime", validator.Uptime())
+			continue
 		}
+		nodeList = append(nodeList, nodeID)
 	}
+	committee.NodeList = nodeList
 }
 
-// cuckooResharding uses cuckoo rule to reshard X% of active committee(shards) into inactive committee(shards)
-func (ss *ShardingState) cuckooResharding(percent float64) {
-	numActiveShards := ss.numShards / 2
+// cuckooResharding moves a percentage of the members of each committee but
+// its leader, drawn at random, out of the committee, within the committee
+// size limits, and returns them.
+func (ss *ShardingState) cuckooResharding(r *reshardingRand, percent float64) []types.NodeID {
 	kickedNodes := []types.NodeID{}
 	for i := range ss.shardState {
-		if i >= numActiveShards {
-			break
+		committee := &ss.shardState[i]
+		if len(committee.NodeList) == 0 {
+			continue
 		}
-		numKicked := int(percent * float64(len(ss.shardState[i].NodeList)))
-		if numKicked == 0 {
-			numKicked++ // At least kick one node out
+		members := append([]types.NodeID{}, committee.NodeList[1:]...)
+		sortNodeIDs(members)
+		numKicked := int(math.Ceil(percent * float64(len(members))))
+		if ss.maxCommitteeSize > 0 && len(committee.NodeList)-numKicked > ss.maxCommitteeSize {
+			numKicked = len(committee.NodeList) - ss.maxCommitteeSize
 		}
-		length := len(ss.shardState[i].NodeList)
-		if length-numKicked <= 0 {
-			continue // Never empty a shard
+		if len(committee.NodeList)-numKicked < ss.minCommitteeSize {
+			numKicked = len(committee.NodeList) - ss.minCommitteeSize
 		}
-		tmp := ss.shardState[i].NodeList[length-numKicked:]
-		kickedNodes = append(kickedNodes, tmp...)
-		ss.shardState[i].NodeList = ss.shardState[i].NodeList[:length-numKicked]
-	}
-
-	Shuffle(kickedNodes)
-	numInactiveShards := ss.numShards - numActiveShards
-	for i, nid := range kickedNodes {
-		id := numActiveShards
-		if numInactiveShards > 0 {
-			id += i % numInactiveShards
+		if numKicked > len(members) {
+			numKicked = len(members)
+		} else if numKicked < 0 {
+			numKicked = 0
 		}
-		ss.shardState[id].NodeList = append(ss.shardState[id].NodeList, nid)
+		for k := 0; k < numKicked; k++ {
+			j := r.Intn(len(members))
+			kickedNodes = append(kickedNodes, members[j])
+			members = append(members[:j], members[j+1:]...)
+		}
+		committee.NodeList = append([]types.NodeID{committee.NodeList[0]}, members...)
 	}
+	return kickedNodes
 }
 
-// Reshard will first add new nodes into shards, then use cuckoo rule to reshard to get new shard state
-func (ss *ShardingState) Reshard(newNodeList []types.NodeID, percent float64) {
-	rand.Seed(int64(ss.rnd))
-	ss.sortCommitteeBySize()
-
-	// Take out and preserve leaders
-	leaders := []types.NodeID{}
-	for i := 0; i < ss.numShards; i++ {
-		if len(ss.shardState[i].NodeList) > 0 {
-			leaders = append(leaders, ss.shardState[i].NodeList[0])
-			ss.shardState[i].NodeList = ss.shardState[i].NodeList[1:]
-			// Also shuffle the rest of the nodes
-			Shuffle(ss.shardState[i].NodeList)
+// lightestCommittee returns the index of the committee to assign a node to,
+// given the total stakes of the committees, or -1 if none can take it.
+func (ss *ShardingState) lightestCommittee(totalStakes []*big.Int) int {
+	belowMin := false
+	for i := range ss.shardState {
+		belowMin = belowMin || len(ss.shardState[i].NodeList) < ss.minCommitteeSize
+	}
+	lightest := -1
+	for i := range ss.shardState {
+		size := len(ss.shardState[i].NodeList)
+		if belowMin && size >= ss.minCommitteeSize || ss.maxCommitteeSize > 0 && size >= ss.maxCommitteeSize {
+			continue
+		}
+		if lightest < 0 {
+			lightest = i
+			continue
+		}
+		if c := totalStakes[i].Cmp(totalStakes[lightest]); c < 0 ||
+			c == 0 && size < len(ss.shardState[lightest].NodeList) ||
+			c == 0 && size == len(ss.shardState[lightest].NodeList) && ss.shardState[i].ShardID < ss.shardState[lightest].ShardID {
+			lightest = i
 		}
 	}
+	return lightest
+}
 
-	ss.assignNewNodes(newNodeList)
-	ss.cuckooResharding(percent)
-
-	// Put leader back
-	if len(leaders) < ss.numShards {
-		utils.GetLogInstance().Error("Not enough leaders to assign to shards")
+// assignNodes assigns the nodes one by one to the committee with the least
+// total stake, within the committee size limits.
+func (ss *ShardingState) assignNodes(nodeList []types.NodeID, stakes map[common.Address]*structs.StakeInfo) {
+	totalStakes := make([]*big.Int, len(ss.shardState))
+	for i := range ss.shardState {
+		totalStakes[i] = new(big.Int)
+		for _, nodeID := range ss.shardState[i].NodeList {
+			totalStakes[i].Add(totalStakes[i], nodeStake(nodeID, stakes))
+		}
 	}
-	for i := 0; i < ss.numShards; i++ {
-		ss.shardState[i].NodeList = append([]types.NodeID{leaders[i]}, ss.shardState[i].NodeList...)
-		ss.shardState[i].Leader = leaders[i]
+	for _, nodeID := range nodeList {
+		i := ss.lightestCommittee(totalStakes)
+		if i < 0 {
+			utils.GetLogInstance().Warn("[Resharding] No committee can take the node",
+				"epoch", ss.epoch,
+				"blsPublicKey", nodeID.BlsPublicKey.Hex())
+			continue
+		}
+		ss.shardState[i].NodeList = append(ss.shardState[i].NodeList, nodeID)
+		totalStakes[i].Add(totalStakes[i], nodeStake(nodeID, stakes))
 	}
 }
 
-// Shuffle will shuffle the list with result uniquely determined by seed, assuming there is no repeat items in the list
-func Shuffle(list []types.NodeID) {
-	// Sort to make sure everyone will generate the same with the same rand seed.
-	sort.Slice(list, func(i, j int) bool {
-		return types.CompareNodeID(list[i], list[j]) == -1
-	})
-	rand.Shuffle(len(list), func(i, j int) {
-		list[i], list[j] = list[j], list[i]
+// Reshard moves a percentage of the members of the committees, and assigns
+// them with the newly staked nodes to the committees, balancing the total
+// stake of the committees. The stakes of the members are recorded in their
+// committees.
+func (ss *ShardingState) Reshard(newNodeList []types.NodeID, stakes map[common.Address]*structs.StakeInfo, percent float64) {
+	sort.Slice(ss.shardState, func(i, j int) bool {
+		return ss.shardState[i].ShardID < ss.shardState[j].ShardID
 	})
+	r := newReshardingRand(ss.rnd, ss.epoch)
+	kickedNodes := ss.cuckooResharding(r, percent)
+
+	nodeList := append([]types.NodeID{}, newNodeList...)
+	sortNodeIDs(nodeList)
+	nodeList = append(nodeList, kickedNodes...)
+	r.Shuffle(nodeList)
+	ss.assignNodes(nodeList, stakes)
+
+	for i := range ss.shardState {
+		if len(ss.shardState[i].NodeList) > 0 {
+			ss.shardState[i].Leader = ss.shardState[i].NodeList[0]
+		}
+		ss.shardState[i].Stakes = make([]*big.Int, len(ss.shardState[i].NodeList))
+		for j, nodeID := range ss.shardState[i].NodeList {
+			ss.shardState[i].Stakes[j] = nodeStake(nodeID, stakes)
+		}
+		if len(ss.shardState[i].NodeList) < ss.minCommitteeSize {
+			utils.GetLogInstance().Warn("[Resharding] Committee below the minimum size",
+				"epoch", ss.epoch,
+				"shardID", ss.shardState[i].ShardID,
+				"size", len(ss.shardState[i].NodeList))
+		}
+	}
 }
 
 // GetBlockNumberFromEpoch calculates the block number where epoch sharding information is stored
@@ -149,44 +304,145 @@ func GetEpochFromBlockNumber(blockNumber uint64) uint64 {
 	return blockNumber / uint64(BlocksPerEpoch)
 }
 
+// GetSignerEpochFromBlockNumber returns the epoch of the committee signing the
+// block of the given number. An epoch block, carrying the shard state of its
+// epoch, is still signed by the committee of the previous epoch.
+func GetSignerEpochFromBlockNumber(blockNumber uint64) uint64 {
+	if blockNumber == 0 {
+		return GenesisEpoch
+	}
+	return GetEpochFromBlockNumber(blockNumber - 1)
+}
+
 // GetShardingStateFromBlockChain will retrieve random seed and shard map from beacon chain for given a epoch
 func GetShardingStateFromBlockChain(bc *BlockChain, epoch uint64) *ShardingState {
 	number := GetBlockNumberFromEpoch(epoch)
 	shardState := bc.GetShardStateByNumber(number)
+	return newShardingState(epoch, shardState, bc.GetRandSeedByNumber(number))
+}
 
-	rndSeedBytes := bc.GetRandSeedByNumber(number)
-	rndSeed := binary.BigEndian.Uint64(rndSeedBytes[:])
-
-	return &ShardingState{epoch: epoch, rnd: rndSeed, shardState: shardState, numShards: len(shardState)}
+// newShardingState returns the sharding state of the epoch, with a copy of the
+// given shard state.
+func newShardingState(epoch uint64, shardState types.ShardState, randSeed [32]byte) *ShardingState {
+	ss := &ShardingState{
+		epoch:            epoch,
+		rnd:              randSeed,
+		numShards:        len(shardState),
+		minCommitteeSize: MinCommitteeSize,
+		maxCommitteeSize: MaxCommitteeSize,
+	}
+	for _, committee := range shardState {
+		committee.NodeList = append([]types.NodeID{}, committee.NodeList...)
+		ss.shardState = append(ss.shardState, committee)
+	}
+	return ss
 }
 
 // CalculateNewShardState get sharding state from previous epoch and calculate sharding state for new epoch
-func CalculateNewShardState(bc *BlockChain, epoch uint64, stakeInfo *map[common.Address]*structs.StakeInfo) types.ShardState {
+// The validator uptime and the stakes are the ones as of the block preceding the epoch block.
+func CalculateNewShardState(bc *BlockChain, epoch uint64) types.ShardState {
 	if epoch == GenesisEpoch {
 		return GetInitShardState()
 	}
-	ss := GetShardingStateFromBlockChain(bc, epoch-1)
-	newNodeList := ss.UpdateShardingState(stakeInfo)
+	number := GetBlockNumberFromEpoch(epoch - 1)
+	prevShardState, err := bc.ReadShardState(epoch - 1)
+	if err != nil {
+		utils.GetLogInstance().Error("[Resharding] cannot read the previous shard state", "epoch", epoch-1, "error", err)
+		return nil
+	}
+	parent := bc.GetHeaderByNumber(GetBlockNumberFromEpoch(epoch) - 1)
+	stakes, err := bc.ReadLockedStakes(parent)
+	if err != nil {
+		utils.GetLogInstance().Error("[Resharding] cannot read the stakes", "epoch", epoch, "error", err)
+		return nil
+	}
+	uptimes, err := bc.ReadValidatorUptime(parent, epoch-1)
+	if err != nil {
+		utils.GetLogInstance().Error("[Resharding] cannot read the validator uptime", "epoch", epoch-1, "error", err)
+		return nil
+	}
+	return ComputeShardState(prevShardState, bc.GetHeaderByNumber(number), uptimes, stakes)
+}
+
+// ComputeShardState computes the shard state of the epoch following the one of
+// the given epoch block header, from the shard state stored in the block, the
+// uptime of the validators of each shard in the epoch, and the stakes.
+func ComputeShardState(shardState types.ShardState, header *types.Header, uptimes []*types.EpochUptime, stakes map[common.Address]*structs.StakeInfo) types.ShardState {
+	var randSeed [32]byte
+	var epoch uint64
+	if header != nil {
+		randSeed, epoch = header.RandSeed, GetEpochFromBlockNumber(header.Number.Uint64())
+	}
+	ss := newShardingState(epoch, shardState, randSeed)
+	for _, uptime := range uptimes {
+		ss.kickOutUnavailable(uptime, MinValidatorUptime)
+	}
+	newNodeList := ss.UpdateShardingState(&stakes)
 	utils.GetLogInstance().Info("Cuckoo Rate", "percentage", CuckooRate)
-	ss.Reshard(newNodeList, CuckooRate)
+	ss.Reshard(newNodeList, stakes, CuckooRate)
 	return ss.shardState
 }
 
-// UpdateShardingState remove the unstaked nodes and returns the newly staked node Ids.
+// ValidateShardState checks that the shard state is the one computed by
+// ComputeShardState from the given inputs, member by member, in order.
+func ValidateShardState(newShardState types.ShardState, shardState types.ShardState, header *types.Header, uptimes []*types.EpochUptime, stakes map[common.Address]*structs.StakeInfo) error {
+	expected := ComputeShardState(shardState, header, uptimes, stakes)
+	if len(newShardState) != len(expected) {
+		return ctxerror.New("wrong number of committees",
+			"numCommittees", len(newShardState),
+			"expectedNumCommittees", len(expected),
+		).WithCause(ErrShardStateNotMatch)
+	}
+	for _, committee := range expected {
+		actual := newShardState.FindCommitteeByID(committee.ShardID)
+		if actual == nil {
+			return ctxerror.New("missing committee", "shardID", committee.ShardID).WithCause(ErrShardStateNotMatch)
+		}
+		if actual.Leader != committee.Leader {
+			return ctxerror.New("wrong committee leader",
+				"shardID", committee.ShardID,
+				"leader", actual.Leader.BlsPublicKey.Hex(),
+				"expectedLeader", committee.Leader.BlsPublicKey.Hex(),
+			).WithCause(ErrShardStateNotMatch)
+		}
+		if len(actual.NodeList) != len(committee.NodeList) || len(actual.Stakes) != len(committee.Stakes) {
+			return ctxerror.New("wrong committee size",
+				"shardID", committee.ShardID,
+				"size", len(actual.NodeList),
+				"numStakes", len(actual.Stakes),
+				"expectedSize", len(committee.NodeList),
+			).WithCause(ErrShardStateNotMatch)
+		}
+		for j, nodeID := range committee.NodeList {
+			if actual.NodeList[j] != nodeID {
+				return ctxerror.New("wrong committee member",
+					"shardID", committee.ShardID,
+					"index", j,
+					"blsPublicKey", actual.NodeList[j].BlsPublicKey.Hex(),
+					"expectedBlsPublicKey", nodeID.BlsPublicKey.Hex(),
+				).WithCause(ErrShardStateNotMatch)
+			}
+			if actual.Stakes[j] == nil || actual.Stakes[j].Cmp(committee.Stakes[j]) != 0 {
+				return ctxerror.New("wrong committee member stake",
+					"shardID", committee.ShardID,
+					"index", j,
+					"stake", actual.Stakes[j],
+					"expectedStake", committee.Stakes[j],
+				).WithCause(ErrShardStateNotMatch)
+			}
+		}
+	}
+	return nil
+}
+
+// UpdateShardingState returns the newly staked node Ids, the staked nodes
+// which are not members of any committee yet.
 func (ss *ShardingState) UpdateShardingState(stakeInfo *map[common.Address]*structs.StakeInfo) []types.NodeID {
 	oldBlsPublicKeys := make(map[types.BlsPublicKey]bool) // map of bls public keys
 	for _, shard := range ss.shardState {
-		newNodeList := shard.NodeList
 		for _, nodeID := range shard.NodeList {
 			oldBlsPublicKeys[nodeID.BlsPublicKey] = true
-			_, ok := (*stakeInfo)[common.HexToAddress(nodeID.EcdsaAddress)]
-			if ok {
-				// newNodeList = append(newNodeList, nodeID)
-			} else {
-				// TODO: Remove the node if it's no longer staked
-			}
 		}
-		shard.NodeList = newNodeList
 	}
 
 	newAddresses := []types.NodeID{}
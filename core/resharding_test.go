@@ -2,10 +2,13 @@ package core
 
 import (
 	"fmt"
+	"math/big"
 	"math/rand"
 	"strconv"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/harmony-one/harmony/contracts/structs"
 	"github.com/harmony-one/harmony/core/types"
 	"github.com/stretchr/testify/assert"
 )
@@ -45,7 +48,7 @@ func fakeGetInitShardState(numberOfShards, numOfNodes int) types.ShardState {
 		for j := 0; j < numOfNodes; j++ {
 			nid := strconv.Itoa(int(rand.Int63()))
 			blsPubKey := [96]byte{}
-			copy(blsPubKey1[:], []byte(nid))
+			copy(blsPubKey[:], []byte(nid))
 			com.NodeList = append(com.NodeList, types.NodeID{nid, blsPubKey})
 		}
 		shardState = append(shardState, com)
@@ -60,7 +63,7 @@ func fakeNewNodeList(seed int64) []types.NodeID {
 	for i := 0; i < numNewNodes; i++ {
 		nid := strconv.Itoa(int(rand.Int63()))
 		blsPubKey := [96]byte{}
-		copy(blsPubKey1[:], []byte(nid))
+		copy(blsPubKey[:], []byte(nid))
 		nodeList = append(nodeList, types.NodeID{nid, blsPubKey})
 	}
 	return nodeList
@@ -87,7 +90,7 @@ func TestShuffle(t *testing.T) {
 
 	cpList := []types.NodeID{}
 	cpList = append(cpList, nodeList...)
-	Shuffle(nodeList)
+	newReshardingRand([32]byte{1}, 1).Shuffle(nodeList)
 	cnt := 0
 	for i := 0; i < 10; i++ {
 		if cpList[i] == nodeList[i] {
@@ -97,21 +100,38 @@ func TestShuffle(t *testing.T) {
 	if cnt == 10 {
 		t.Error("Shuffle list is the same as original list")
 	}
-	return
+
+	// The same seed gives the same order
+	again := append([]types.NodeID{}, cpList...)
+	newReshardingRand([32]byte{1}, 1).Shuffle(again)
+	assert.Equal(t, nodeList, again)
+	other := append([]types.NodeID{}, cpList...)
+	newReshardingRand([32]byte{2}, 1).Shuffle(other)
+	assert.NotEqual(t, nodeList, other)
 }
 
-func TestSortCommitteeBySize(t *testing.T) {
-	shardState := fakeGetInitShardState(6, 10)
-	ss := &ShardingState{epoch: 1, rnd: 42, shardState: shardState, numShards: len(shardState)}
-	ss.sortCommitteeBySize()
-	for i := 0; i < ss.numShards-1; i++ {
-		assert.Equal(t, true, len(ss.shardState[i].NodeList) >= len(ss.shardState[i+1].NodeList))
+func TestReshardingRandIntn(t *testing.T) {
+	r := newReshardingRand([32]byte{1}, 1)
+	counts := make([]int, 3)
+	for i := 0; i < 3000; i++ {
+		counts[r.Intn(3)]++
+	}
+	for _, count := range counts {
+		assert.True(t, count > 900 && count < 1100, "counts %v", counts)
 	}
 }
 
+func TestGetSignerEpochFromBlockNumber(t *testing.T) {
+	assert.Equal(t, uint64(GenesisEpoch), GetSignerEpochFromBlockNumber(0))
+	assert.Equal(t, uint64(0), GetSignerEpochFromBlockNumber(BlocksPerEpoch-1))
+	// the epoch block is signed by the committee of the previous epoch
+	assert.Equal(t, uint64(0), GetSignerEpochFromBlockNumber(BlocksPerEpoch))
+	assert.Equal(t, uint64(1), GetSignerEpochFromBlockNumber(BlocksPerEpoch+1))
+}
+
 func TestUpdateShardState(t *testing.T) {
 	shardState := fakeGetInitShardState(6, 10)
-	ss := &ShardingState{epoch: 1, rnd: 42, shardState: shardState, numShards: len(shardState)}
+	ss := newShardingState(1, shardState, [32]byte{42})
 	newNodeList := []types.NodeID{
 		{"node1", blsPubKey1},
 		{"node2", blsPubKey2},
@@ -121,23 +141,84 @@ func TestUpdateShardState(t *testing.T) {
 		{"node6", blsPubKey6},
 	}
 
-	ss.Reshard(newNodeList, 0.2)
+	ss.Reshard(newNodeList, nil, 0.2)
 	assert.Equal(t, 6, ss.numShards)
-	for _, shard := range ss.shardState {
+	numNodes := 0
+	for i, shard := range ss.shardState {
 		assert.Equal(t, shard.Leader.BlsPublicKey, shard.NodeList[0].BlsPublicKey)
+		assert.Equal(t, shardState[i].NodeList[0], shard.Leader)
+		numNodes += len(shard.NodeList)
 	}
+	assert.Equal(t, 66, numNodes)
 }
 
-func TestAssignNewNodes(t *testing.T) {
+func TestAssignNodes(t *testing.T) {
 	shardState := fakeGetInitShardState(2, 2)
-	ss := &ShardingState{epoch: 1, rnd: 42, shardState: shardState, numShards: len(shardState)}
+	ss := newShardingState(1, shardState, [32]byte{42})
 	newNodes := []types.NodeID{
-		{"node1", blsPubKey1},
-		{"node2", blsPubKey2},
-		{"node3", blsPubKey3},
+		{"0x0000000000000000000000000000000000000001", blsPubKey1},
+		{"0x0000000000000000000000000000000000000002", blsPubKey2},
+		{"0x0000000000000000000000000000000000000003", blsPubKey3},
+	}
+	stakes := map[common.Address]*structs.StakeInfo{
+		common.HexToAddress(newNodes[0].EcdsaAddress): {BlsPublicKey: blsPubKey1, Amount: big.NewInt(100)},
+		common.HexToAddress(newNodes[1].EcdsaAddress): {BlsPublicKey: blsPubKey2, Amount: big.NewInt(60)},
+		common.HexToAddress(newNodes[2].EcdsaAddress): {BlsPublicKey: blsPubKey3, Amount: big.NewInt(30)},
+	}
+
+	// Balanced by stake: 100 in shard 0, 60 and 30 in shard 1
+	ss.assignNodes(newNodes, stakes)
+	assert.Equal(t, 3, len(ss.shardState[0].NodeList))
+	assert.Equal(t, 4, len(ss.shardState[1].NodeList))
+	assert.Equal(t, newNodes[0], ss.shardState[0].NodeList[2])
+
+	// Bounded by the max committee size
+	ss.maxCommitteeSize = 4
+	ss.assignNodes([]types.NodeID{{"node4", blsPubKey4}, {"node5", blsPubKey5}}, stakes)
+	assert.Equal(t, 4, len(ss.shardState[0].NodeList))
+	assert.Equal(t, 4, len(ss.shardState[1].NodeList))
+}
+
+func TestComputeShardState(t *testing.T) {
+	shardState := fakeGetInitShardState(4, 10)
+	header := &types.Header{Number: big.NewInt(0), RandSeed: [32]byte{42}}
+	stakes := map[common.Address]*structs.StakeInfo{}
+	for i := 1; i <= 8; i++ {
+		blsPubKey := types.BlsPublicKey{}
+		copy(blsPubKey[:], []byte(fmt.Sprintf("staker %d", i)))
+		stakes[common.BigToAddress(big.NewInt(int64(i)))] = &structs.StakeInfo{BlsPublicKey: blsPubKey, Amount: big.NewInt(int64(i))}
+	}
+	newShardState := ComputeShardState(shardState, header, nil, stakes)
+	numNodes := 0
+	for _, committee := range newShardState {
+		size := len(committee.NodeList)
+		assert.True(t, size >= MinCommitteeSize && size <= MaxCommitteeSize, "shard %d has %d members", committee.ShardID, size)
+		numNodes += size
+	}
+	assert.Equal(t, 48, numNodes)
+	// The previous shard state is left as it is
+	for _, committee := range shardState {
+		assert.Equal(t, 10, len(committee.NodeList))
+	}
+	assert.NoError(t, ValidateShardState(newShardState, shardState, header, nil, stakes))
+
+	// The same members in another order are not the same committee
+	reordered := newShardState.Copy()
+	reordered[0].NodeList[1], reordered[0].NodeList[2] = reordered[0].NodeList[2], reordered[0].NodeList[1]
+	reordered[0].Stakes[1], reordered[0].Stakes[2] = reordered[0].Stakes[2], reordered[0].Stakes[1]
+	assert.Error(t, ValidateShardState(reordered, shardState, header, nil, stakes))
+
+	// Within tighter committee size limits
+	ss := newShardingState(0, shardState, header.RandSeed)
+	ss.minCommitteeSize, ss.maxCommitteeSize = 9, 12
+	newNodeList := ss.UpdateShardingState(&stakes)
+	ss.Reshard(newNodeList, stakes, CuckooRate)
+	for _, committee := range ss.shardState {
+		size := len(committee.NodeList)
+		assert.True(t, size >= 9 && size <= 12, "shard %d has %d members", committee.ShardID, size)
 	}
 
-	ss.assignNewNodes(newNodes)
-	assert.Equal(t, 2, ss.numShards)
-	assert.Equal(t, 5, len(ss.shardState[0].NodeList))
+	// Another random seed gives other committees
+	otherHeader := &types.Header{Number: big.NewInt(0), RandSeed: [32]byte{43}}
+	assert.Error(t, ValidateShardState(newShardState, shardState, otherHeader, nil, stakes))
 }
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/harmony-one/harmony/contracts/structs"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/utils/contract"
+)
+
+// Storage layout of the StakeLockContract, see contracts/StakeLockContract.sol.
+const (
+	stakeLockedSlot       = 1 // mapping(address => lockedToken) locked
+	stakeAddressListSlot  = 3 // address[] addressList
+	lockedTokenAmount     = 0 // offset of lockedToken._amount
+	lockedTokenBlockNum   = 1 // offset of lockedToken._blockNum
+	lockedTokenPeriods    = 3 // offset of lockedToken._lockPeriodCount
+	lockedTokenBlsPubKey1 = 5 // offset of lockedToken._blsPublicKey1, followed by the 2 other parts
+)
+
+// StakeLockContractAddress is the address of the StakeLockContract deployed by
+// the genesis beacon account with nonce 0, see node.AddStakingContractToPendingTransactions.
+var StakeLockContractAddress = crypto.CreateAddress(crypto.PubkeyToAddress(contract.GenesisBeaconAccountPriKey.PublicKey), 0)
+
+// ReadLockedStakes returns the tokens locked in the StakeLockContract of the
+// given state, by account. Withdrawn and slashed tokens are left out.
+func ReadLockedStakes(state *state.DB) map[common.Address]*structs.StakeInfo {
+	stakes := make(map[common.Address]*structs.StakeInfo)
+	for _, address := range stakeLockAddresses(state) {
+		stakeInfo := readLockedStake(state, address)
+		if stakeInfo.Amount.Sign() > 0 {
+			stakes[address] = stakeInfo
+		}
+	}
+	return stakes
+}
+
+// SlashLockedStake burns the tokens locked in the StakeLockContract with the
+// given BLS key, and returns the slashed amount.
+func SlashLockedStake(state *state.DB, pubKey types.BlsPublicKey) *big.Int {
+	slashed := big.NewInt(0)
+	for _, address := range stakeLockAddresses(state) {
+		stakeInfo := readLockedStake(state, address)
+		if stakeInfo.BlsPublicKey != pubKey {
+			continue
+		}
+		amountSlot := common.BigToHash(new(big.Int).Add(lockedTokenSlot(address), big.NewInt(lockedTokenAmount)))
+		state.SetState(StakeLockContractAddress, amountSlot, common.Hash{})
+		state.SubBalance(StakeLockContractAddress, stakeInfo.Amount)
+		slashed.Add(slashed, stakeInfo.Amount)
+	}
+	return slashed
+}
+
+// stakeLockAddresses returns the addresses which have locked tokens in the StakeLockContract.
+func stakeLockAddresses(state *state.DB) []common.Address {
+	listSlot := common.BigToHash(big.NewInt(stakeAddressListSlot))
+	numAddresses := state.GetState(StakeLockContractAddress, listSlot).Big().Uint64()
+	firstAddressSlot := crypto.Keccak256Hash(listSlot[:]).Big()
+	addresses := make([]common.Address, 0, numAddresses)
+	for i := uint64(0); i < numAddresses; i++ {
+		addressSlot := new(big.Int).Add(firstAddressSlot, new(big.Int).SetUint64(i))
+		addresses = append(addresses, common.BytesToAddress(state.GetState(StakeLockContractAddress, common.BigToHash(addressSlot)).Bytes()))
+	}
+	return addresses
+}
+
+// lockedTokenSlot returns the storage slot of locked[address] in the StakeLockContract.
+func lockedTokenSlot(address common.Address) *big.Int {
+	mappingSlot := common.BigToHash(big.NewInt(stakeLockedSlot))
+	return crypto.Keccak256Hash(common.LeftPadBytes(address.Bytes(), 32), mappingSlot[:]).Big()
+}
+
+// readLockedStake reads the tokens locked by the given address in the StakeLockContract.
+func readLockedStake(state *state.DB, address common.Address) *structs.StakeInfo {
+	lockedSlot := lockedTokenSlot(address)
+	field := func(offset int) common.Hash {
+		slot := new(big.Int).Add(lockedSlot, big.NewInt(int64(offset)))
+		return state.GetState(StakeLockContractAddress, common.BigToHash(slot))
+	}
+	stakeInfo := &structs.StakeInfo{
+		Account:         address,
+		BlockNum:        field(lockedTokenBlockNum).Big(),
+		LockPeriodCount: field(lockedTokenPeriods).Big(),
+		Amount:          field(lockedTokenAmount).Big(),
+	}
+	for part := 0; part < 3; part++ {
+		value := field(lockedTokenBlsPubKey1 + part)
+		copy(stakeInfo.BlsPublicKey[part*32:], value[:])
+	}
+	return stakeInfo
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+)
+
+// lockStake lays out the StakeLockContract storage as if account had locked
+// amount with pubKey.
+func lockStake(statedb *state.DB, account common.Address, pubKey types.BlsPublicKey, amount *big.Int) {
+	listSlot := common.BigToHash(big.NewInt(stakeAddressListSlot))
+	index := statedb.GetState(StakeLockContractAddress, listSlot).Big()
+	statedb.SetState(StakeLockContractAddress, listSlot, common.BigToHash(new(big.Int).Add(index, common.Big1)))
+	addressSlot := new(big.Int).Add(crypto.Keccak256Hash(listSlot[:]).Big(), index)
+	statedb.SetState(StakeLockContractAddress, common.BigToHash(addressSlot), common.BytesToHash(account.Bytes()))
+	lockedSlot := lockedTokenSlot(account)
+	statedb.SetState(StakeLockContractAddress, common.BigToHash(lockedSlot), common.BigToHash(amount))
+	for part := 0; part < 3; part++ {
+		slot := new(big.Int).Add(lockedSlot, big.NewInt(int64(lockedTokenBlsPubKey1+part)))
+		statedb.SetState(StakeLockContractAddress, common.BigToHash(slot), common.BytesToHash(pubKey[part*32:(part+1)*32]))
+	}
+	statedb.AddBalance(StakeLockContractAddress, amount)
+}
+
+func TestReadLockedStakes(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	account1, account2 := common.HexToAddress("0x1234"), common.HexToAddress("0x5678")
+	lockStake(statedb, account1, blsPubKey1, big.NewInt(1000))
+	lockStake(statedb, account2, blsPubKey2, big.NewInt(0))
+
+	stakes := ReadLockedStakes(statedb)
+	if len(stakes) != 1 {
+		t.Fatalf("read %d stakes, expected 1 as the other one is withdrawn", len(stakes))
+	}
+	if stake := stakes[account1]; stake == nil || stake.Account != account1 || stake.BlsPublicKey != blsPubKey1 || stake.Amount.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("wrong stake of account 1: %v", stake)
+	}
+}
+
+func TestSlashLockedStake(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	account := common.HexToAddress("0x1234")
+	amount := big.NewInt(1000)
+
+	lockStake(statedb, account, blsPubKey1, amount)
+	lockedSlot := lockedTokenSlot(account)
+
+	if slashed := SlashLockedStake(statedb, blsPubKey2); slashed.Sign() != 0 {
+		t.Errorf("slashed %v of a validator without stake", slashed)
+	}
+	if slashed := SlashLockedStake(statedb, blsPubKey1); slashed.Cmp(amount) != 0 {
+		t.Errorf("slashed %v, expected %v", slashed, amount)
+	}
+	if balance := statedb.GetBalance(StakeLockContractAddress); balance.Sign() != 0 {
+		t.Errorf("contract balance is %v after slashing, expected 0", balance)
+	}
+	if locked := statedb.GetState(StakeLockContractAddress, common.BigToHash(lockedSlot)); locked != (common.Hash{}) {
+		t.Errorf("locked amount is %v after slashing, expected 0", locked.Big())
+	}
+	if stakes := ReadLockedStakes(statedb); len(stakes) != 0 {
+		t.Errorf("slashed stake still read: %v", stakes)
+	}
+}
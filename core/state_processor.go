@@ -74,8 +74,18 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.DB, cfg vm.C
 		receipts = append(receipts, receipt)
 		allLogs = append(allLogs, receipt.Logs...)
 	}
+	// Credit the transfers from other shards
+	if err := ApplyIncomingReceipts(statedb, p.bc.ShardID(), block.IncomingReceipts()); err != nil {
+		return nil, nil, 0, err
+	}
+	// Count the commit signers of the parent and cross-linked blocks
+	if err := p.bc.CountValidatorUptime(statedb, header, block.CrossLinks()); err != nil {
+		return nil, nil, 0, err
+	}
 	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
-	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), receipts)
+	if _, err := p.engine.Finalize(p.bc, header, statedb, block.Transactions(), receipts, block.DoubleSigns()); err != nil {
+		return nil, nil, 0, err
+	}
 
 	return receipts, allLogs, *usedGas, nil
 }
@@ -89,6 +99,12 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 	if err != nil {
 		return nil, 0, err
 	}
+	if tx.IsCrossShard() {
+		// The value leaves the shard, to be credited on the destination shard
+		if msg, err = crossShardMessage(tx, msg); err != nil {
+			return nil, 0, err
+		}
+	}
 	// Create a new context to be used in the EVM environment
 	context := NewEVMContext(msg, header, bc, author)
 	// Create a new environment which holds all relevant information
@@ -119,6 +135,9 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 	}
 	// Set the receipt logs and create a bloom for filtering
 	//receipt.Logs = statedb.GetLogs(tx.Hash())
+	if tx.IsCrossShard() && !failed {
+		receipt.Logs = []*types.Log{crossShardTransferLog(tx, header)}
+	}
 	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
 
 	return receipt, gas, err
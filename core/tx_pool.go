@@ -577,6 +577,15 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if tx.Value().Sign() < 0 {
 		return ErrNegativeValue
 	}
+	// Cross-shard transactions can only transfer value.
+	if tx.IsCrossShard() && (tx.To() == nil || len(tx.Data()) > 0) {
+		return ErrCrossShardContract
+	}
+	if tx.IsCrossShard() {
+		if err := validateToShard(tx); err != nil {
+			return err
+		}
+	}
 	// Ensure the transaction doesn't exceed the current block limit gas.
 	if pool.currentMaxGas < tx.Gas() {
 		return ErrGasLimit
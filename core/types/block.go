@@ -19,6 +19,7 @@ package types
 
 import (
 	"encoding/binary"
+	"fmt"
 	"io"
 	"math/big"
 	"sort"
@@ -102,6 +103,113 @@ type Header struct {
 	RandPreimage     [32]byte    `json:"randPreimage"`
 	RandSeed         [32]byte    `json:"randSeed"`
 	ShardStateHash   common.Hash `json:"shardStateRoot"`
+	// The roots of the lists of the body other than the transactions, zero if
+	// empty, see headerRLP
+	DoubleSignHash      common.Hash `json:"doubleSignsRoot"`
+	IncomingReceiptHash common.Hash `json:"incomingReceiptsRoot"`
+	CrossLinkHash       common.Hash `json:"crossLinksRoot"`
+}
+
+// headerRLP is the RLP encoding of Header. The roots of the lists of the body
+// other than the transactions are only encoded, in the tail, if one of them
+// isn't zero, so that the headers of the blocks without such lists keep the
+// encoding, and the hash, they had before the header committed to them.
+type headerRLP struct {
+	ParentHash       common.Hash
+	Coinbase         common.Address
+	Root             common.Hash
+	TxHash           common.Hash
+	ReceiptHash      common.Hash
+	Bloom            Bloom
+	Difficulty       *big.Int
+	Number           *big.Int
+	GasLimit         uint64
+	GasUsed          uint64
+	Time             *big.Int
+	Extra            []byte
+	MixDigest        common.Hash
+	Nonce            BlockNonce
+	ShardID          ShardID
+	PrepareSignature [48]byte
+	PrepareBitmap    []byte
+	CommitSignature  [48]byte
+	CommitBitmap     []byte
+	RandPreimage     [32]byte
+	RandSeed         [32]byte
+	ShardStateHash   common.Hash
+	BodyHashes       []common.Hash `rlp:"tail"`
+}
+
+// EncodeRLP implements rlp.Encoder.
+func (h *Header) EncodeRLP(w io.Writer) error {
+	enc := headerRLP{
+		ParentHash:       h.ParentHash,
+		Coinbase:         h.Coinbase,
+		Root:             h.Root,
+		TxHash:           h.TxHash,
+		ReceiptHash:      h.ReceiptHash,
+		Bloom:            h.Bloom,
+		Difficulty:       h.Difficulty,
+		Number:           h.Number,
+		GasLimit:         h.GasLimit,
+		GasUsed:          h.GasUsed,
+		Time:             h.Time,
+		Extra:            h.Extra,
+		MixDigest:        h.MixDigest,
+		Nonce:            h.Nonce,
+		ShardID:          h.ShardID,
+		PrepareSignature: h.PrepareSignature,
+		PrepareBitmap:    h.PrepareBitmap,
+		CommitSignature:  h.CommitSignature,
+		CommitBitmap:     h.CommitBitmap,
+		RandPreimage:     h.RandPreimage,
+		RandSeed:         h.RandSeed,
+		ShardStateHash:   h.ShardStateHash,
+	}
+	if h.DoubleSignHash != (common.Hash{}) || h.IncomingReceiptHash != (common.Hash{}) || h.CrossLinkHash != (common.Hash{}) {
+		enc.BodyHashes = []common.Hash{h.DoubleSignHash, h.IncomingReceiptHash, h.CrossLinkHash}
+	}
+	return rlp.Encode(w, &enc)
+}
+
+// DecodeRLP implements rlp.Decoder.
+func (h *Header) DecodeRLP(s *rlp.Stream) error {
+	var dec headerRLP
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	*h = Header{
+		ParentHash:       dec.ParentHash,
+		Coinbase:         dec.Coinbase,
+		Root:             dec.Root,
+		TxHash:           dec.TxHash,
+		ReceiptHash:      dec.ReceiptHash,
+		Bloom:            dec.Bloom,
+		Difficulty:       dec.Difficulty,
+		Number:           dec.Number,
+		GasLimit:         dec.GasLimit,
+		GasUsed:          dec.GasUsed,
+		Time:             dec.Time,
+		Extra:            dec.Extra,
+		MixDigest:        dec.MixDigest,
+		Nonce:            dec.Nonce,
+		ShardID:          dec.ShardID,
+		PrepareSignature: dec.PrepareSignature,
+		PrepareBitmap:    dec.PrepareBitmap,
+		CommitSignature:  dec.CommitSignature,
+		CommitBitmap:     dec.CommitBitmap,
+		RandPreimage:     dec.RandPreimage,
+		RandSeed:         dec.RandSeed,
+		ShardStateHash:   dec.ShardStateHash,
+	}
+	switch len(dec.BodyHashes) {
+	case 0:
+	case 3:
+		h.DoubleSignHash, h.IncomingReceiptHash, h.CrossLinkHash = dec.BodyHashes[0], dec.BodyHashes[1], dec.BodyHashes[2]
+	default:
+		return fmt.Errorf("rlp: %d body roots in the header, expected 0 or 3", len(dec.BodyHashes))
+	}
+	return nil
 }
 
 // field type overrides for gencodec
@@ -135,10 +243,18 @@ func rlpHash(x interface{}) (h common.Hash) {
 }
 
 // Body is a simple (mutable, non-safe) data container for storing and moving
-// a block's data contents (transactions and uncles) together.
+// a block's data contents (transactions, uncles, double sign evidences,
+// incoming cross-shard receipts, cross-links and the shard state of an epoch
+// block) together.
 type Body struct {
-	Transactions []*Transaction
-	Uncles       []*Header
+	Transactions     []*Transaction
+	Uncles           []*Header
+	DoubleSigns      []*DoubleSignEvidence
+	IncomingReceipts []*CXReceiptProof
+	CrossLinks       []*CrossLink
+	// The shard state is the tail, so that the bodies stored before it keep
+	// decoding.
+	ShardState ShardState `rlp:"tail"`
 }
 
 // Block represents an entire block in the Ethereum blockchain.
@@ -146,6 +262,13 @@ type Block struct {
 	header       *Header
 	uncles       []*Header
 	transactions Transactions
+	doubleSigns  DoubleSignEvidences
+	// the receipts of the cross-shard transactions credited by the block
+	incomingReceipts CXReceiptProofs
+	// the shard block headers anchored by a beacon chain block
+	crossLinks CrossLinks
+	// the committees of the epoch started by an epoch block
+	shardState ShardState
 
 	// caches
 	hash atomic.Value
@@ -198,9 +321,13 @@ type StorageBlock Block
 
 // "external" block encoding. used for eth protocol, etc.
 type extblock struct {
-	Header *Header
-	Txs    []*Transaction
-	Uncles []*Header
+	Header           *Header
+	Txs              []*Transaction
+	Uncles           []*Header
+	DoubleSigns      []*DoubleSignEvidence
+	IncomingReceipts []*CXReceiptProof
+	CrossLinks       []*CrossLink
+	ShardState       ShardState `rlp:"tail"`
 }
 
 // [deprecated by eth/63]
@@ -241,6 +368,33 @@ func NewBlock(header *Header, txs []*Transaction, receipts []*Receipt) *Block {
 	return b
 }
 
+// DoubleSignsHash returns the root of the double sign evidences committed by
+// DoubleSignHash, zero if there is none.
+func DoubleSignsHash(doubleSigns []*DoubleSignEvidence) common.Hash {
+	if len(doubleSigns) == 0 {
+		return common.Hash{}
+	}
+	return rlpHash(doubleSigns)
+}
+
+// IncomingReceiptsHash returns the root of the incoming cross-shard receipts
+// committed by IncomingReceiptHash, zero if there is none.
+func IncomingReceiptsHash(incomingReceipts []*CXReceiptProof) common.Hash {
+	if len(incomingReceipts) == 0 {
+		return common.Hash{}
+	}
+	return rlpHash(incomingReceipts)
+}
+
+// CrossLinksHash returns the root of the cross-links committed by
+// CrossLinkHash, zero if there is none.
+func CrossLinksHash(crossLinks []*CrossLink) common.Hash {
+	if len(crossLinks) == 0 {
+		return common.Hash{}
+	}
+	return rlpHash(crossLinks)
+}
+
 // NewBlockWithHeader creates a block with the given header data. The
 // header data is copied, changes to header and to the field values
 // will not affect the block.
@@ -275,7 +429,8 @@ func (b *Block) DecodeRLP(s *rlp.Stream) error {
 	if err := s.Decode(&eb); err != nil {
 		return err
 	}
-	b.header, b.uncles, b.transactions = eb.Header, eb.Uncles, eb.Txs
+	b.header, b.uncles, b.transactions, b.doubleSigns = eb.Header, eb.Uncles, eb.Txs, eb.DoubleSigns
+	b.incomingReceipts, b.crossLinks, b.shardState = eb.IncomingReceipts, eb.CrossLinks, eb.ShardState
 	b.size.Store(common.StorageSize(rlp.ListSize(size)))
 	return nil
 }
@@ -283,9 +438,13 @@ func (b *Block) DecodeRLP(s *rlp.Stream) error {
 // EncodeRLP serializes b into the Ethereum RLP block format.
 func (b *Block) EncodeRLP(w io.Writer) error {
 	return rlp.Encode(w, extblock{
-		Header: b.header,
-		Txs:    b.transactions,
-		Uncles: b.uncles,
+		Header:           b.header,
+		Txs:              b.transactions,
+		Uncles:           b.uncles,
+		DoubleSigns:      b.doubleSigns,
+		IncomingReceipts: b.incomingReceipts,
+		CrossLinks:       b.crossLinks,
+		ShardState:       b.shardState,
 	})
 }
 
@@ -310,6 +469,28 @@ func (b *Block) Transactions() Transactions {
 	return b.transactions
 }
 
+// DoubleSigns returns the double sign evidences included in the block.
+func (b *Block) DoubleSigns() DoubleSignEvidences {
+	return b.doubleSigns
+}
+
+// IncomingReceipts returns the receipts of the cross-shard transactions
+// credited by the block.
+func (b *Block) IncomingReceipts() CXReceiptProofs {
+	return b.incomingReceipts
+}
+
+// CrossLinks returns the shard block headers anchored by the block.
+func (b *Block) CrossLinks() CrossLinks {
+	return b.crossLinks
+}
+
+// ShardState returns the committees of the epoch started by the block, if it
+// is an epoch block.
+func (b *Block) ShardState() ShardState {
+	return b.shardState
+}
+
 // Transaction returns Transaction.
 func (b *Block) Transaction(hash common.Hash) *Transaction {
 	for _, transaction := range b.transactions {
@@ -372,7 +553,9 @@ func (b *Block) Extra() []byte { return common.CopyBytes(b.header.Extra) }
 func (b *Block) Header() *Header { return CopyHeader(b.header) }
 
 // Body returns the non-header content of the block.
-func (b *Block) Body() *Body { return &Body{b.transactions, b.uncles} }
+func (b *Block) Body() *Body {
+	return &Body{b.transactions, b.uncles, b.doubleSigns, b.incomingReceipts, b.crossLinks, b.shardState}
+}
 
 // Size returns the true RLP encoded storage size of the block, either by encoding
 // and returning it, or returning a previsouly cached value.
@@ -404,9 +587,13 @@ func (b *Block) WithSeal(header *Header) *Block {
 	cpy := *header
 
 	return &Block{
-		header:       &cpy,
-		transactions: b.transactions,
-		uncles:       b.uncles,
+		header:           &cpy,
+		transactions:     b.transactions,
+		uncles:           b.uncles,
+		doubleSigns:      b.doubleSigns,
+		incomingReceipts: b.incomingReceipts,
+		crossLinks:       b.crossLinks,
+		shardState:       b.shardState,
 	}
 }
 
@@ -424,6 +611,69 @@ func (b *Block) WithBody(transactions []*Transaction, uncles []*Header) *Block {
 	return block
 }
 
+// WithDoubleSigns returns a new block with the data from b and the given
+// double sign evidences.
+func (b *Block) WithDoubleSigns(doubleSigns []*DoubleSignEvidence) *Block {
+	block := &Block{
+		header:           CopyHeader(b.header),
+		transactions:     b.transactions,
+		uncles:           b.uncles,
+		doubleSigns:      make(DoubleSignEvidences, len(doubleSigns)),
+		incomingReceipts: b.incomingReceipts,
+		crossLinks:       b.crossLinks,
+		shardState:       b.shardState,
+	}
+	copy(block.doubleSigns, doubleSigns)
+	return block
+}
+
+// WithIncomingReceipts returns a new block with the data from b and the given
+// incoming cross-shard receipts.
+func (b *Block) WithIncomingReceipts(incomingReceipts []*CXReceiptProof) *Block {
+	block := &Block{
+		header:           CopyHeader(b.header),
+		transactions:     b.transactions,
+		uncles:           b.uncles,
+		doubleSigns:      b.doubleSigns,
+		incomingReceipts: make(CXReceiptProofs, len(incomingReceipts)),
+		crossLinks:       b.crossLinks,
+		shardState:       b.shardState,
+	}
+	copy(block.incomingReceipts, incomingReceipts)
+	return block
+}
+
+// WithCrossLinks returns a new block with the data from b and the given
+// cross-links.
+func (b *Block) WithCrossLinks(crossLinks []*CrossLink) *Block {
+	block := &Block{
+		header:           CopyHeader(b.header),
+		transactions:     b.transactions,
+		uncles:           b.uncles,
+		doubleSigns:      b.doubleSigns,
+		incomingReceipts: b.incomingReceipts,
+		crossLinks:       make(CrossLinks, len(crossLinks)),
+		shardState:       b.shardState,
+	}
+	copy(block.crossLinks, crossLinks)
+	return block
+}
+
+// WithShardState returns a new block with the data from b and the given shard
+// state of the epoch started by the block.
+func (b *Block) WithShardState(shardState ShardState) *Block {
+	block := &Block{
+		header:           CopyHeader(b.header),
+		transactions:     b.transactions,
+		uncles:           b.uncles,
+		doubleSigns:      b.doubleSigns,
+		incomingReceipts: b.incomingReceipts,
+		crossLinks:       b.crossLinks,
+		shardState:       shardState.Copy(),
+	}
+	return block
+}
+
 // Hash returns the keccak256 hash of b's header.
 // The hash is computed on the first call and cached thereafter.
 func (b *Block) Hash() common.Hash {
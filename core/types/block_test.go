@@ -19,7 +19,11 @@ package types
 
 import (
 	"bytes"
+	"math/big"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 var (
@@ -110,3 +114,28 @@ func TestBlock_SetCommitSig(t *testing.T) {
 		})
 	}
 }
+
+func TestHeaderRLP(t *testing.T) {
+	header := &Header{Difficulty: big.NewInt(0), Number: big.NewInt(1), Time: big.NewInt(0)}
+	// Without body roots, the header is encoded as before it committed to them
+	legacy, err := rlp.EncodeToBytes(&headerRLP{Difficulty: big.NewInt(0), Number: big.NewInt(1), Time: big.NewInt(0)})
+	if err != nil {
+		t.Fatalf("cannot encode legacy header: %v", err)
+	}
+	if encoded, _ := rlp.EncodeToBytes(header); !bytes.Equal(encoded, legacy) {
+		t.Error("header without body roots not encoded as a legacy header")
+	}
+
+	header.CrossLinkHash = common.Hash{1}
+	encoded, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatalf("cannot encode header: %v", err)
+	}
+	decoded := &Header{}
+	if err := rlp.DecodeBytes(encoded, decoded); err != nil {
+		t.Fatalf("cannot decode header: %v", err)
+	}
+	if decoded.Hash() != header.Hash() || decoded.CrossLinkHash != header.CrossLinkHash {
+		t.Error("header with body roots not decoded as encoded")
+	}
+}
@@ -0,0 +1,43 @@
+package types
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// CrossLink anchors a block header of a shard chain on the beacon chain. The
+// header is sealed by the committee of its shard, so that the beacon chain is
+// the root of trust of the shard chains.
+type CrossLink struct {
+	Header *Header
+}
+
+// NewCrossLink returns the cross-link of the given shard block header.
+func NewCrossLink(header *Header) *CrossLink {
+	return &CrossLink{Header: CopyHeader(header)}
+}
+
+// ShardID returns the shard of the cross-linked block.
+func (cl *CrossLink) ShardID() uint32 {
+	return binary.BigEndian.Uint32(cl.Header.ShardID[:])
+}
+
+// Number returns the number of the cross-linked block.
+func (cl *CrossLink) Number() *big.Int {
+	return new(big.Int).Set(cl.Header.Number)
+}
+
+// NumberU64 returns the number of the cross-linked block as an uint64.
+func (cl *CrossLink) NumberU64() uint64 {
+	return cl.Header.Number.Uint64()
+}
+
+// Hash returns the hash of the cross-linked block.
+func (cl *CrossLink) Hash() common.Hash {
+	return cl.Header.Hash()
+}
+
+// CrossLinks is a list of cross-links.
+type CrossLinks []*CrossLink
@@ -0,0 +1,26 @@
+package types
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// CXReceiptProof proves that the value of a cross-shard transaction was
+// debited on its source shard, so that the destination shard credits it.
+// The receipt of the transaction is proven against the receipt root of the
+// source block header, itself sealed by the committee of the source shard.
+type CXReceiptProof struct {
+	Header  *Header  // header of the source block
+	TxIndex uint64   // index of the transaction in the source block
+	Proof   [][]byte // receipt trie nodes on the path from the root to the receipt
+}
+
+// ID returns the hash identifying the cross-shard transfer, by which the
+// destination shard credits it once only. It identifies the transaction by its
+// position in the source shard, as the hash of the source block changes with
+// its seal.
+func (p *CXReceiptProof) ID() common.Hash {
+	return rlpHash([]interface{}{p.Header.ShardID, p.Header.Number, p.TxIndex})
+}
+
+// CXReceiptProofs is a list of cross-shard receipt proofs.
+type CXReceiptProofs []*CXReceiptProof
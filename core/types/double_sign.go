@@ -0,0 +1,23 @@
+package types
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DoubleSignEvidence proves that a validator signed two different blocks in
+// the same phase of the same consensus round and view.
+// The messages are the serialized consensus messages, each carrying the
+// signature of the validator.
+type DoubleSignEvidence struct {
+	PubKey        BlsPublicKey
+	FirstMessage  []byte
+	SecondMessage []byte
+}
+
+// Hash returns the hash identifying the evidence.
+func (e *DoubleSignEvidence) Hash() common.Hash {
+	return rlpHash(e)
+}
+
+// DoubleSignEvidences is a list of double sign evidences.
+type DoubleSignEvidences []*DoubleSignEvidence
@@ -18,6 +18,7 @@ func (t txdata) MarshalJSON() ([]byte, error) {
 	type txdata struct {
 		AccountNonce hexutil.Uint64  `json:"nonce"    gencodec:"required"`
 		ShardID      uint32          `json:"shardID"  gencodec:"required"`
+		ToShardID    uint32          `json:"toShardID"`
 		Price        *hexutil.Big    `json:"gasPrice" gencodec:"required"`
 		GasLimit     hexutil.Uint64  `json:"gas"      gencodec:"required"`
 		Recipient    *common.Address `json:"to"       rlp:"nil"`
@@ -31,6 +32,7 @@ func (t txdata) MarshalJSON() ([]byte, error) {
 	var enc txdata
 	enc.AccountNonce = hexutil.Uint64(t.AccountNonce)
 	enc.ShardID = t.ShardID
+	enc.ToShardID = t.ToShardID
 	enc.Price = (*hexutil.Big)(t.Price)
 	enc.GasLimit = hexutil.Uint64(t.GasLimit)
 	enc.Recipient = t.Recipient
@@ -48,6 +50,7 @@ func (t *txdata) UnmarshalJSON(input []byte) error {
 	type txdata struct {
 		AccountNonce *hexutil.Uint64 `json:"nonce"    gencodec:"required"`
 		ShardID      *uint32         `json:"shardID"  gencodec:"required"`
+		ToShardID    *uint32         `json:"toShardID"`
 		Price        *hexutil.Big    `json:"gasPrice" gencodec:"required"`
 		GasLimit     *hexutil.Uint64 `json:"gas"      gencodec:"required"`
 		Recipient    *common.Address `json:"to"       rlp:"nil"`
@@ -70,6 +73,9 @@ func (t *txdata) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 'shardID' for txdata")
 	}
 	t.ShardID = *dec.ShardID
+	if dec.ToShardID != nil {
+		t.ToShardID = *dec.ToShardID
+	}
 	if dec.Price == nil {
 		return errors.New("missing required field 'gasPrice' for txdata")
 	}
@@ -3,6 +3,7 @@ package types
 import (
 	"bytes"
 	"encoding/hex"
+	"math/big"
 	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -12,10 +13,13 @@ import (
 	"github.com/harmony-one/harmony/internal/ctxerror"
 )
 
-// EpochShardState is the shard state of an epoch
+// EpochShardState is the shard state of an epoch, announced by the beacon
+// chain along with the RandSeed of its epoch block, which seeds the leader
+// schedules of the shard chains for the epoch.
 type EpochShardState struct {
 	Epoch      uint64
 	ShardState ShardState
+	RandSeed   [32]byte
 }
 
 // ShardState is the collection of all committees
@@ -57,6 +61,10 @@ type Committee struct {
 	ShardID  uint32
 	Leader   NodeID
 	NodeList []NodeID
+	// Stakes are the tokens locked by the members, in the order of NodeList,
+	// as of the block preceding the epoch block. They weight the signers of
+	// the blocks of the epoch. The genesis committees have none.
+	Stakes []*big.Int `rlp:"tail"`
 }
 
 // GetHashFromNodeList will sort the list, then use Keccak256 to hash the list
@@ -77,8 +85,48 @@ func GetHashFromNodeList(nodeList []NodeID) []byte {
 	return d.Sum(nil)
 }
 
+// FindCommitteeByID returns the committee of the given shard, or nil if
+// the shard isn't in the shard state.
+func (ss ShardState) FindCommitteeByID(shardID uint32) *Committee {
+	for i := range ss {
+		if ss[i].ShardID == shardID {
+			return &ss[i]
+		}
+	}
+	return nil
+}
+
+// StakeOf returns the tokens locked by the member with the given key, zero if
+// the committee has no such member or none locked by it.
+func (c *Committee) StakeOf(key BlsPublicKey) *big.Int {
+	for i := range c.NodeList {
+		if c.NodeList[i].BlsPublicKey == key && i < len(c.Stakes) && c.Stakes[i] != nil {
+			return c.Stakes[i]
+		}
+	}
+	return big.NewInt(0)
+}
+
+// BLSPublicKeys returns the BLS public keys of the committee members, in the
+// order of the node list.
+func (c *Committee) BLSPublicKeys() ([]*bls.PublicKey, error) {
+	publicKeys := []*bls.PublicKey{}
+	for i := range c.NodeList {
+		publicKey := &bls.PublicKey{}
+		if err := c.NodeList[i].BlsPublicKey.ToLibBLSPublicKey(publicKey); err != nil {
+			return nil, ctxerror.New("cannot convert BLS public key",
+				"shardID", c.ShardID,
+				"blsPublicKey", c.NodeList[i].BlsPublicKey.Hex(),
+			).WithCause(err)
+		}
+		publicKeys = append(publicKeys, publicKey)
+	}
+	return publicKeys, nil
+}
+
 // Hash is the root hash of ShardState
 func (ss ShardState) Hash() (h common.Hash) {
+	ss = ss.Copy()
 	sort.Slice(ss, func(i, j int) bool {
 		return ss[i].ShardID < ss[j].ShardID
 	})
@@ -91,6 +139,28 @@ func (ss ShardState) Hash() (h common.Hash) {
 	return h
 }
 
+// Root is the hash of the encoding of the shard state, committed by the
+// ShardStateHash of the header of the epoch block carrying it. Unlike Hash, it
+// covers the order of the committee members, which the signature bitmaps
+// refer to, and the leaders.
+func (ss ShardState) Root() common.Hash {
+	return rlpHash(ss)
+}
+
+// Copy returns a copy of the shard state not sharing the node lists.
+func (ss ShardState) Copy() ShardState {
+	if ss == nil {
+		return nil
+	}
+	c := make(ShardState, len(ss))
+	for i := range ss {
+		c[i] = ss[i]
+		c[i].NodeList = append([]NodeID{}, ss[i].NodeList...)
+		c[i].Stakes = append([]*big.Int(nil), ss[i].Stakes...)
+	}
+	return c
+}
+
 // CompareNodeID compares two nodes by their ID; used to sort node list
 func CompareNodeID(n1 NodeID, n2 NodeID) int {
 	return bytes.Compare(n1.BlsPublicKey[:], n2.BlsPublicKey[:])
@@ -94,3 +94,22 @@ func TestHash(t *testing.T) {
 		t.Error("shardState1 and shardState2 should have equal hash")
 	}
 }
+
+func TestRoot(t *testing.T) {
+	com1 := Committee{ShardID: 22, Leader: NodeID{"node11", blsPubKey11}, NodeList: []NodeID{{"node11", blsPubKey11}}}
+	com2 := Committee{ShardID: 2, Leader: NodeID{"node4", blsPubKey4}, NodeList: []NodeID{{"node4", blsPubKey4}}}
+	shardState := ShardState{com1, com2}
+	root := shardState.Root()
+
+	// hashing does not reorder the shard state committed to
+	shardState.Hash()
+	if shardState[0].ShardID != 22 || shardState.Root() != root {
+		t.Error("shardState reordered by Hash")
+	}
+	if copied := shardState.Copy(); copied.Root() != root {
+		t.Error("copied shardState should have equal root")
+	}
+	if reordered := (ShardState{com2, com1}); reordered.Root() == root {
+		t.Error("reordered shardState should have a different root")
+	}
+}
@@ -18,6 +18,7 @@ package types
 
 import (
 	"container/heap"
+	"encoding/json"
 	"errors"
 	"io"
 	"math/big"
@@ -48,6 +49,7 @@ type Transaction struct {
 type txdata struct {
 	AccountNonce uint64          `json:"nonce"    gencodec:"required"`
 	ShardID      uint32          `json:"shardID"  gencodec:"required"`
+	ToShardID    uint32          `json:"toShardID"`
 	Price        *big.Int        `json:"gasPrice" gencodec:"required"`
 	GasLimit     uint64          `json:"gas"      gencodec:"required"`
 	Recipient    *common.Address `json:"to"       rlp:"nil"` // nil means contract creation
@@ -63,6 +65,82 @@ type txdata struct {
 	Hash *common.Hash `json:"hash" rlp:"-"`
 }
 
+// legacyTxdata is the encoding of the transactions within a shard, which
+// leave out the destination shard, so that they keep the hashes they had before
+// cross-shard transactions.
+type legacyTxdata struct {
+	AccountNonce uint64
+	ShardID      uint32
+	Price        *big.Int
+	GasLimit     uint64
+	Recipient    *common.Address `rlp:"nil"`
+	Amount       *big.Int
+	Payload      []byte
+	V            *big.Int
+	R            *big.Int
+	S            *big.Int
+}
+
+// crossShardTxdata is the encoding of the cross-shard transactions.
+type crossShardTxdata txdata
+
+// legacyTxdataSize is the number of fields of a legacy transaction encoding.
+const legacyTxdataSize = 10
+
+// EncodeRLP implements rlp.Encoder
+func (d *txdata) EncodeRLP(w io.Writer) error {
+	if d.ToShardID != d.ShardID {
+		return rlp.Encode(w, (*crossShardTxdata)(d))
+	}
+	return rlp.Encode(w, &legacyTxdata{
+		AccountNonce: d.AccountNonce,
+		ShardID:      d.ShardID,
+		Price:        d.Price,
+		GasLimit:     d.GasLimit,
+		Recipient:    d.Recipient,
+		Amount:       d.Amount,
+		Payload:      d.Payload,
+		V:            d.V,
+		R:            d.R,
+		S:            d.S,
+	})
+}
+
+// DecodeRLP implements rlp.Decoder
+func (d *txdata) DecodeRLP(s *rlp.Stream) error {
+	raw, err := s.Raw()
+	if err != nil {
+		return err
+	}
+	content, _, err := rlp.SplitList(raw)
+	if err != nil {
+		return err
+	}
+	if size, err := rlp.CountValues(content); err != nil {
+		return err
+	} else if size != legacyTxdataSize {
+		return rlp.DecodeBytes(raw, (*crossShardTxdata)(d))
+	}
+	var legacy legacyTxdata
+	if err := rlp.DecodeBytes(raw, &legacy); err != nil {
+		return err
+	}
+	*d = txdata{
+		AccountNonce: legacy.AccountNonce,
+		ShardID:      legacy.ShardID,
+		ToShardID:    legacy.ShardID,
+		Price:        legacy.Price,
+		GasLimit:     legacy.GasLimit,
+		Recipient:    legacy.Recipient,
+		Amount:       legacy.Amount,
+		Payload:      legacy.Payload,
+		V:            legacy.V,
+		R:            legacy.R,
+		S:            legacy.S,
+	}
+	return nil
+}
+
 type txdataMarshaling struct {
 	AccountNonce hexutil.Uint64
 	Price        *hexutil.Big
@@ -76,15 +154,21 @@ type txdataMarshaling struct {
 
 // NewTransaction returns new transaction.
 func NewTransaction(nonce uint64, to common.Address, shardID uint32, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *Transaction {
-	return newTransaction(nonce, &to, shardID, amount, gasLimit, gasPrice, data)
+	return newTransaction(nonce, &to, shardID, shardID, amount, gasLimit, gasPrice, data)
+}
+
+// NewCrossShardTransaction returns new transaction transferring the amount
+// from the sender on the shard shardID to the recipient on the shard toShardID.
+func NewCrossShardTransaction(nonce uint64, to common.Address, shardID uint32, toShardID uint32, amount *big.Int, gasLimit uint64, gasPrice *big.Int) *Transaction {
+	return newTransaction(nonce, &to, shardID, toShardID, amount, gasLimit, gasPrice, nil)
 }
 
 // NewContractCreation returns contract transaction.
 func NewContractCreation(nonce uint64, shardID uint32, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *Transaction {
-	return newTransaction(nonce, nil, shardID, amount, gasLimit, gasPrice, data)
+	return newTransaction(nonce, nil, shardID, shardID, amount, gasLimit, gasPrice, data)
 }
 
-func newTransaction(nonce uint64, to *common.Address, shardID uint32, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *Transaction {
+func newTransaction(nonce uint64, to *common.Address, shardID uint32, toShardID uint32, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *Transaction {
 	if len(data) > 0 {
 		data = common.CopyBytes(data)
 	}
@@ -92,6 +176,7 @@ func newTransaction(nonce uint64, to *common.Address, shardID uint32, amount *bi
 		AccountNonce: nonce,
 		Recipient:    to,
 		ShardID:      shardID,
+		ToShardID:    toShardID,
 		Payload:      data,
 		Amount:       new(big.Int),
 		GasLimit:     gasLimit,
@@ -120,6 +205,16 @@ func (tx *Transaction) ShardID() uint32 {
 	return tx.data.ShardID
 }
 
+// ToShardID returns the shard where the value of the transaction is credited.
+func (tx *Transaction) ToShardID() uint32 {
+	return tx.data.ToShardID
+}
+
+// IsCrossShard returns whether the transaction transfers value to another shard.
+func (tx *Transaction) IsCrossShard() bool {
+	return tx.data.ToShardID != tx.data.ShardID
+}
+
 // Protected returns whether the transaction is protected from replay protection.
 func (tx *Transaction) Protected() bool {
 	return isProtectedV(tx.data.V)
@@ -164,6 +259,16 @@ func (tx *Transaction) UnmarshalJSON(input []byte) error {
 	if err := dec.UnmarshalJSON(input); err != nil {
 		return err
 	}
+	// A transaction without destination shard stays within its shard
+	var shards struct {
+		ToShardID *uint32 `json:"toShardID"`
+	}
+	if err := json.Unmarshal(input, &shards); err != nil {
+		return err
+	}
+	if shards.ToShardID == nil {
+		dec.ToShardID = dec.ShardID
+	}
 
 	withSignature := dec.V.Sign() != 0 || dec.R.Sign() != 0 || dec.S.Sign() != 0
 	if withSignature {
@@ -177,3 +177,51 @@ func TestTransactionJSON(t *testing.T) {
 		}
 	}
 }
+
+func TestCrossShardTransaction(t *testing.T) {
+	key, _ := defaultTestKey()
+	to := common.HexToAddress("b94f5374fce5edbc8e2a8697c15331677e6ebf0b")
+	tx, err := SignTx(NewCrossShardTransaction(1, to, 0, 2, big.NewInt(10), 21000, big.NewInt(1)), HomesteadSigner{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tx.IsCrossShard() || tx.ToShardID() != 2 {
+		t.Fatalf("wrong destination shard %d", tx.ToShardID())
+	}
+
+	// The destination shard is signed
+	other := NewCrossShardTransaction(1, to, 0, 3, big.NewInt(10), 21000, big.NewInt(1))
+	if (HomesteadSigner{}).Hash(tx) == (HomesteadSigner{}).Hash(other) {
+		t.Error("signing hash doesn't cover the destination shard")
+	}
+
+	data, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodeTx(data)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if decoded.Hash() != tx.Hash() || decoded.ToShardID() != 2 {
+		t.Errorf("wrong transaction decoded: %v", decoded)
+	}
+
+	// Intra-shard transactions keep their legacy encoding
+	data, _ = rlp.EncodeToBytes(rightvrsTx)
+	if count, _ := rlp.CountValues(mustSplitList(t, data)); count != 10 {
+		t.Errorf("intra-shard transaction encoded with %d fields", count)
+	}
+	decoded, err = decodeTx(data)
+	if err != nil || decoded.IsCrossShard() || decoded.Hash() != rightvrsTx.Hash() {
+		t.Errorf("wrong legacy transaction decoded: %v, err %v", decoded, err)
+	}
+}
+
+func mustSplitList(t *testing.T, data []byte) []byte {
+	content, _, err := rlp.SplitList(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
@@ -0,0 +1,37 @@
+package types
+
+// ValidatorUptime counts the blocks of an epoch whose commit a validator
+// signed, or missed.
+type ValidatorUptime struct {
+	BlsPublicKey BlsPublicKey
+	Signed       uint64
+	Missed       uint64
+}
+
+// Uptime returns the fraction of the counted blocks the validator signed.
+func (u *ValidatorUptime) Uptime() float64 {
+	total := u.Signed + u.Missed
+	if total == 0 {
+		return 0
+	}
+	return float64(u.Signed) / float64(total)
+}
+
+// EpochUptime is the signing participation of the committee of a shard in
+// an epoch.
+type EpochUptime struct {
+	Epoch      uint64
+	ShardID    uint32
+	Validators []ValidatorUptime
+}
+
+// FindValidator returns the uptime of the validator with the given BLS
+// public key, or nil if it isn't in the committee.
+func (e *EpochUptime) FindValidator(key BlsPublicKey) *ValidatorUptime {
+	for i := range e.Validators {
+		if e.Validators[i].BlsPublicKey == key {
+			return &e.Validators[i]
+		}
+	}
+	return nil
+}
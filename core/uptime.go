@@ -0,0 +1,161 @@
+package core
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/ctxerror"
+)
+
+// Validator uptime
+//
+// Every block carries the bitmap of the committee members who signed its
+// commit. The beacon chain decodes the bitmaps against the committee of the
+// block's signer epoch into per-validator signed/missed counters: those of
+// its own blocks when their child is processed, and those of the shard
+// blocks when they are cross-linked. The counters are kept in the storage of
+// the ValidatorUptimeAddress account of the beacon chain state, so that they
+// are rewound along with the rest of the state on reorg and SetHead, and
+// downloaded along with it by fast sync.
+
+// ValidatorUptimeAddress is the system account holding the validator uptime
+// counters in its storage.
+var ValidatorUptimeAddress = common.BytesToAddress([]byte("validatorUptime"))
+
+// CountValidatorUptime counts in the state the commit signers of the parent
+// of the header and of the cross-linked shard blocks.
+// Only the beacon chain, which holds the shard states of all the shards,
+// counts the uptime. Unsealed headers, such as the genesis block's, are not
+// counted.
+func (bc *BlockChain) CountValidatorUptime(state *state.DB, header *types.Header, crossLinks types.CrossLinks) error {
+	if bc.ShardID() != 0 {
+		return nil
+	}
+	if number := header.Number.Uint64(); number > 0 {
+		parent := bc.GetHeader(header.ParentHash, number-1)
+		if parent == nil {
+			return ctxerror.New("cannot find the parent block", "blockNum", number)
+		}
+		if err := bc.countHeaderSigners(state, parent); err != nil {
+			return err
+		}
+	}
+	for _, crossLink := range crossLinks {
+		if err := bc.countHeaderSigners(state, crossLink.Header); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countHeaderSigners counts the commit signers of the header against the
+// committee of its shard in its signer epoch.
+func (bc *BlockChain) countHeaderSigners(state *state.DB, header *types.Header) error {
+	if len(header.CommitBitmap) == 0 {
+		return nil
+	}
+	number, shardID := header.Number.Uint64(), binary.BigEndian.Uint32(header.ShardID[:])
+	epoch := GetSignerEpochFromBlockNumber(number)
+	shardState, err := bc.ReadShardState(epoch)
+	if err != nil {
+		return ctxerror.New("cannot read shard state", "epoch", epoch).WithCause(err)
+	}
+	committee := shardState.FindCommitteeByID(shardID)
+	if committee == nil {
+		return ctxerror.New("cannot find committee in shard state",
+			"epoch", epoch,
+			"shardID", shardID)
+	}
+	if err := countCommitSigners(state, epoch, committee, header.CommitBitmap); err != nil {
+		return ctxerror.New("cannot count commit signers",
+			"blockNum", number,
+			"shardID", shardID,
+		).WithCause(err)
+	}
+	return nil
+}
+
+// countCommitSigners adds one signed or missed block to the counters of each
+// member of the committee in the given epoch, according to the commit bitmap.
+// Bits 0-7 of byte 0 of the bitmap correspond to members 0-7, and so on.
+func countCommitSigners(state *state.DB, epoch uint64, committee *types.Committee, bitmap []byte) error {
+	if len(bitmap) != (len(committee.NodeList)+7)>>3 {
+		return ctxerror.New("commit bitmap size mismatch",
+			"bitmapLen", len(bitmap),
+			"committeeSize", len(committee.NodeList))
+	}
+	if state.GetNonce(ValidatorUptimeAddress) == 0 {
+		// keep the account from being deleted as empty
+		state.SetNonce(ValidatorUptimeAddress, 1)
+	}
+	for i, nodeID := range committee.NodeList {
+		validator := readValidatorUptime(state, epoch, committee.ShardID, nodeID.BlsPublicKey)
+		if bitmap[i>>3]&(byte(1)<<uint(i&7)) != 0 {
+			validator.Signed++
+		} else {
+			validator.Missed++
+		}
+		writeValidatorUptime(state, epoch, committee.ShardID, validator)
+	}
+	return nil
+}
+
+// validatorUptimeSlot returns the storage slot of the counters of the
+// validator in the committee of the given shard and epoch.
+func validatorUptimeSlot(epoch uint64, shardID uint32, key types.BlsPublicKey) common.Hash {
+	var prefix [12]byte
+	binary.BigEndian.PutUint64(prefix[:8], epoch)
+	binary.BigEndian.PutUint32(prefix[8:], shardID)
+	return crypto.Keccak256Hash(prefix[:], key[:])
+}
+
+// readValidatorUptime reads the counters of the validator in the committee of
+// the given shard and epoch, packed as signed, then missed, in the last 16
+// bytes of the slot.
+func readValidatorUptime(state *state.DB, epoch uint64, shardID uint32, key types.BlsPublicKey) types.ValidatorUptime {
+	value := state.GetState(ValidatorUptimeAddress, validatorUptimeSlot(epoch, shardID, key))
+	return types.ValidatorUptime{
+		BlsPublicKey: key,
+		Signed:       binary.BigEndian.Uint64(value[16:24]),
+		Missed:       binary.BigEndian.Uint64(value[24:]),
+	}
+}
+
+// writeValidatorUptime writes the counters of the validator in the committee
+// of the given shard and epoch.
+func writeValidatorUptime(state *state.DB, epoch uint64, shardID uint32, validator types.ValidatorUptime) {
+	var value common.Hash
+	binary.BigEndian.PutUint64(value[16:24], validator.Signed)
+	binary.BigEndian.PutUint64(value[24:], validator.Missed)
+	state.SetState(ValidatorUptimeAddress, validatorUptimeSlot(epoch, shardID, validator.BlsPublicKey), value)
+}
+
+// ReadValidatorUptime returns the uptime counters of the validators of each
+// committee of the given epoch, as of the block of the given header.
+func (bc *BlockChain) ReadValidatorUptime(header *types.Header, epoch uint64) ([]*types.EpochUptime, error) {
+	if header == nil {
+		return nil, ctxerror.New("missing block")
+	}
+	shardState, err := bc.ReadShardState(epoch)
+	if err != nil {
+		return nil, ctxerror.New("cannot read shard state", "epoch", epoch).WithCause(err)
+	}
+	stateDB, err := bc.StateAt(header.Root)
+	if err != nil {
+		return nil, ctxerror.New("cannot read the state of the block",
+			"blockNum", header.Number,
+		).WithCause(err)
+	}
+	uptimes := make([]*types.EpochUptime, 0, len(shardState))
+	for _, committee := range shardState {
+		uptime := &types.EpochUptime{Epoch: epoch, ShardID: committee.ShardID}
+		for _, nodeID := range committee.NodeList {
+			uptime.Validators = append(uptime.Validators, readValidatorUptime(stateDB, epoch, committee.ShardID, nodeID.BlsPublicKey))
+		}
+		uptimes = append(uptimes, uptime)
+	}
+	return uptimes, nil
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+)
+
+func TestCountCommitSigners(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	committee := &types.Committee{ShardID: 1, NodeList: []types.NodeID{
+		{"node1", blsPubKey1}, {"node2", blsPubKey2}, {"node3", blsPubKey3},
+	}}
+	for _, bitmap := range [][]byte{{0x7}, {0x3}, {0x1}, {0x5}} {
+		if err := countCommitSigners(statedb, 1, committee, bitmap); err != nil {
+			t.Fatalf("cannot count commit signers: %v", err)
+		}
+	}
+	if err := countCommitSigners(statedb, 1, committee, []byte{0x7, 0x0}); err == nil {
+		t.Error("counted a bitmap of the wrong size")
+	}
+	expected := map[types.BlsPublicKey][2]uint64{
+		blsPubKey1: {4, 0},
+		blsPubKey2: {2, 2},
+		blsPubKey3: {2, 2},
+	}
+	for key, counts := range expected {
+		validator := readValidatorUptime(statedb, 1, 1, key)
+		if validator.Signed != counts[0] || validator.Missed != counts[1] {
+			t.Errorf("validator %v signed %v, missed %v, expected %v", key.Hex(), validator.Signed, validator.Missed, counts)
+		}
+	}
+	if validator := readValidatorUptime(statedb, 1, 1, blsPubKey1); validator.Uptime() != 1 {
+		t.Error("wrong uptime")
+	}
+	if validator := readValidatorUptime(statedb, 1, 1, blsPubKey2); validator.Uptime() != 0.5 {
+		t.Error("wrong uptime")
+	}
+	if validator := readValidatorUptime(statedb, 2, 1, blsPubKey1); validator.Signed != 0 || validator.Missed != 0 {
+		t.Errorf("blocks counted in another epoch: %v", validator)
+	}
+	if validator := readValidatorUptime(statedb, 1, 0, blsPubKey1); validator.Signed != 0 || validator.Missed != 0 {
+		t.Errorf("blocks counted in another shard: %v", validator)
+	}
+}
+
+func TestKickOutUnavailable(t *testing.T) {
+	shardState := types.ShardState{
+		{ShardID: 0, NodeList: []types.NodeID{{"node1", blsPubKey1}, {"node2", blsPubKey2}, {"node3", blsPubKey3}, {"node4", blsPubKey4}}},
+		{ShardID: 1, NodeList: []types.NodeID{{"node5", blsPubKey5}}},
+	}
+	ss := &ShardingState{epoch: 1, shardState: shardState, numShards: 2}
+	uptime := &types.EpochUptime{Epoch: 1, ShardID: 0, Validators: []types.ValidatorUptime{
+		{BlsPublicKey: blsPubKey1, Signed: 0, Missed: 10},
+		{BlsPublicKey: blsPubKey2, Signed: 9, Missed: 1},
+		{BlsPublicKey: blsPubKey3, Signed: 5, Missed: 5},
+	}}
+
+	ss.kickOutUnavailable(uptime, 0)
+	if len(ss.shardState[0].NodeList) != 4 {
+		t.Fatal("kicked out validators with the threshold disabled")
+	}
+	ss.kickOutUnavailable(uptime, 0.8)
+	// the leader and the validators not counted are kept
+	nodeList := ss.shardState[0].NodeList
+	if len(nodeList) != 3 || nodeList[0].BlsPublicKey != blsPubKey1 || nodeList[1].BlsPublicKey != blsPubKey2 || nodeList[2].BlsPublicKey != blsPubKey4 {
+		t.Errorf("wrong committee after kicking out: %v", nodeList)
+	}
+	if len(ss.shardState[1].NodeList) != 1 {
+		t.Error("kicked out validators of another shard")
+	}
+}
@@ -3,6 +3,7 @@ package bls
 import (
 	"errors"
 	"fmt"
+	"math/big"
 
 	"github.com/harmony-one/bls/ffi/go/bls"
 )
@@ -242,3 +243,54 @@ func NewThresholdPolicy(thold int) *ThresholdPolicy {
 func (p ThresholdPolicy) Check(m *Mask) bool {
 	return m.CountEnabled() >= p.thold
 }
+
+// SuperMajorityPolicy requires that more than two thirds of the participants
+// have cosigned, i.e. 2f+1 out of 3f+1 participants.
+type SuperMajorityPolicy struct {
+}
+
+// Check verifies that more than two thirds of the participants have
+// contributed to a collective signature.
+func (p SuperMajorityPolicy) Check(m *Mask) bool {
+	return m.CountEnabled()*3 > m.CountTotal()*2
+}
+
+// StakeWeightedPolicy requires that the cosigners are more than two thirds of
+// the participants, as in SuperMajorityPolicy, and that they also hold more
+// than two thirds of the total stake of all participants. The stake of each
+// participant is looked up with the given function. Participants without
+// stake count toward the participants but not the stake, so that a minority
+// of participants holding most of the stake can't reach the quorum alone.
+type StakeWeightedPolicy struct {
+	stakeOf func(public *bls.PublicKey) *big.Int
+}
+
+// NewStakeWeightedPolicy returns a new StakeWeightedPolicy looking up the
+// stake of the participants with the given function.
+func NewStakeWeightedPolicy(stakeOf func(public *bls.PublicKey) *big.Int) *StakeWeightedPolicy {
+	return &StakeWeightedPolicy{stakeOf: stakeOf}
+}
+
+// Check verifies that more than two thirds of the participants, holding more
+// than two thirds of the total stake, have contributed to a collective
+// signature.
+func (p StakeWeightedPolicy) Check(m *Mask) bool {
+	if !(SuperMajorityPolicy{}).Check(m) {
+		return false
+	}
+	totalStake := big.NewInt(0)
+	signedStake := big.NewInt(0)
+	for i, public := range m.publics {
+		stake := p.stakeOf(public)
+		if stake == nil || stake.Sign() <= 0 {
+			continue
+		}
+		totalStake.Add(totalStake, stake)
+		if enabled, _ := m.IndexEnabled(i); enabled {
+			signedStake.Add(signedStake, stake)
+		}
+	}
+	// signedStake / totalStake > 2/3, unless nobody holds any stake
+	return totalStake.Sign() == 0 ||
+		new(big.Int).Mul(signedStake, big.NewInt(3)).Cmp(new(big.Int).Mul(totalStake, big.NewInt(2))) > 0
+}
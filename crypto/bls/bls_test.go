@@ -1,6 +1,7 @@
 package bls
 
 import (
+	"math/big"
 	"strings"
 	"testing"
 
@@ -127,6 +128,102 @@ func TestCompletePolicy(test *testing.T) {
 	}
 }
 
+func TestSuperMajorityPolicy(test *testing.T) {
+	pubKey1 := RandPrivateKey().GetPublicKey()
+	pubKey2 := RandPrivateKey().GetPublicKey()
+	pubKey3 := RandPrivateKey().GetPublicKey()
+	pubKey4 := RandPrivateKey().GetPublicKey()
+
+	mask, err := NewMask([]*bls.PublicKey{pubKey1, pubKey2, pubKey3, pubKey4}, pubKey1)
+
+	if err != nil {
+		test.Errorf("Failed to create a new Mask: %s", err)
+	}
+
+	policy := SuperMajorityPolicy{}
+
+	mask.SetKey(pubKey1, true)
+	mask.SetKey(pubKey2, true)
+
+	if policy.Check(mask) {
+		test.Error("2 out of 4 nodes should not reach super majority")
+	}
+
+	mask.SetKey(pubKey3, true)
+
+	if !policy.Check(mask) {
+		test.Error("3 out of 4 nodes should reach super majority")
+	}
+}
+
+func TestStakeWeightedPolicy(test *testing.T) {
+	pubKey1 := RandPrivateKey().GetPublicKey()
+	pubKey2 := RandPrivateKey().GetPublicKey()
+	pubKey3 := RandPrivateKey().GetPublicKey()
+	pubKey4 := RandPrivateKey().GetPublicKey()
+
+	mask, err := NewMask([]*bls.PublicKey{pubKey1, pubKey2, pubKey3, pubKey4}, pubKey1)
+
+	if err != nil {
+		test.Errorf("Failed to create a new Mask: %s", err)
+	}
+
+	stakes := map[*bls.PublicKey]*big.Int{
+		pubKey1: big.NewInt(70),
+		pubKey2: big.NewInt(20),
+		pubKey3: big.NewInt(10),
+	}
+	policy := NewStakeWeightedPolicy(func(public *bls.PublicKey) *big.Int {
+		return stakes[public]
+	})
+
+	mask.SetKey(pubKey2, true)
+	mask.SetKey(pubKey3, true)
+	mask.SetKey(pubKey4, true)
+
+	if policy.Check(mask) {
+		test.Error("3 out of 4 nodes holding 30% of the stake should not reach quorum")
+	}
+
+	mask.SetKey(pubKey1, true)
+	mask.SetKey(pubKey2, false)
+	mask.SetKey(pubKey3, false)
+
+	if policy.Check(mask) {
+		test.Error("2 out of 4 nodes holding 70% of the stake should not reach quorum")
+	}
+
+	mask.SetKey(pubKey2, true)
+
+	if !policy.Check(mask) {
+		test.Error("3 out of 4 nodes holding 90% of the stake should reach quorum")
+	}
+
+	stakes = map[*bls.PublicKey]*big.Int{pubKey1: big.NewInt(1)}
+	mask.SetKey(pubKey1, true)
+	mask.SetKey(pubKey2, false)
+	mask.SetKey(pubKey4, false)
+
+	if policy.Check(mask) {
+		test.Error("The only staked node should not reach quorum alone")
+	}
+
+	noStake := NewStakeWeightedPolicy(func(public *bls.PublicKey) *big.Int {
+		return nil
+	})
+
+	if noStake.Check(mask) {
+		test.Error("1 out of 4 unstaked nodes should not reach super majority")
+	}
+
+	mask.SetKey(pubKey2, true)
+	mask.SetKey(pubKey3, true)
+
+	if !noStake.Check(mask) {
+		test.Error("3 out of 4 unstaked nodes should reach super majority")
+	}
+}
+
 func TestAggregatedSignature(test *testing.T) {
 	var sec bls.SecretKey
 	sec.SetByCSPRNG()
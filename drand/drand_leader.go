@@ -26,38 +26,38 @@ func (dRand *DRand) WaitForEpochBlock(blockChannel chan *types.Block, stopChan c
 	go func() {
 		defer close(stoppedChan)
 		for {
+			// keep waiting for epoch block
+			var newBlock *types.Block
 			select {
-			default:
-				// keep waiting for epoch block
-				newBlock := <-blockChannel
-				if core.IsEpochLastBlock(newBlock) {
-					dRand.init(newBlock)
-				}
-				pRnd := newBlock.Header().RandPreimage
-				zeros := [32]byte{}
-				if core.IsEpochBlock(newBlock) && !bytes.Equal(pRnd[:], zeros[:]) {
-					// The epoch block should contain the randomness preimage pRnd
-					go func() {
-						vdf := vdf.New(vdfDifficulty, pRnd)
-						outputChannel := vdf.GetOutputChannel()
-						start := time.Now()
-						vdf.Execute()
-						duration := time.Now().Sub(start)
-						utils.GetLogInstance().Info("VDF computation finished", "time spent", duration.String())
-						output := <-outputChannel
-
-						rndBytes := [64]byte{} // The first 32 bytes are the randomness and the last 32 bytes are the hash of the block where the corresponding pRnd was generated
-						copy(rndBytes[:32], output[:])
-
-						blockHash := newBlock.Hash()
-						copy(rndBytes[32:], blockHash[:])
-
-						dRand.RndChannel <- rndBytes
-					}()
-				}
+			case newBlock = <-blockChannel:
 			case <-stopChan:
 				return
 			}
+			if core.IsEpochLastBlock(newBlock) {
+				dRand.init(newBlock)
+			}
+			pRnd := newBlock.Header().RandPreimage
+			zeros := [32]byte{}
+			if core.IsEpochBlock(newBlock) && !bytes.Equal(pRnd[:], zeros[:]) {
+				// The epoch block should contain the randomness preimage pRnd
+				go func() {
+					vdf := vdf.New(vdfDifficulty, pRnd)
+					outputChannel := vdf.GetOutputChannel()
+					start := time.Now()
+					vdf.Execute()
+					duration := time.Now().Sub(start)
+					utils.GetLogInstance().Info("VDF computation finished", "time spent", duration.String())
+					output := <-outputChannel
+
+					rndBytes := [64]byte{} // The first 32 bytes are the randomness and the last 32 bytes are the hash of the block where the corresponding pRnd was generated
+					copy(rndBytes[:32], output[:])
+
+					blockHash := newBlock.Hash()
+					copy(rndBytes[32:], blockHash[:])
+
+					dRand.RndChannel <- rndBytes
+				}()
+			}
 		}
 	}()
 }
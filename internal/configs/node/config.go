@@ -76,8 +76,8 @@ type ConfigType struct {
 	P2pPriKey       p2p_crypto.PrivKey
 	ConsensusPriKey *bls.SecretKey
 	ConsensusPubKey *bls.PublicKey
-	MainDB          *ethdb.LDBDatabase
-	BeaconDB        *ethdb.LDBDatabase
+	MainDB          ethdb.Database
+	BeaconDB        ethdb.Database
 
 	SelfPeer p2p.Peer
 	Leader   p2p.Peer
@@ -47,6 +47,42 @@ func (s *PublicBlockChainAPI) GetBlockByHash(ctx context.Context, blockHash comm
 	return nil, err
 }
 
+// GetValidatorUptime returns the number of blocks of the given epoch each
+// validator of each shard signed, or missed, according to the commit bitmaps
+// counted by the beacon chain.
+func (s *PublicBlockChainAPI) GetValidatorUptime(ctx context.Context, epoch hexutil.Uint64) ([]*RPCEpochUptime, error) {
+	uptimes, err := s.b.GetValidatorUptime(ctx, uint64(epoch))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*RPCEpochUptime, 0, len(uptimes))
+	for _, uptime := range uptimes {
+		result = append(result, newRPCEpochUptime(uptime))
+	}
+	return result, nil
+}
+
+// GetLatestCrossLink returns the latest header of the given shard anchored on
+// the beacon chain. Only the beacon chain nodes have the cross-links.
+func (s *PublicBlockChainAPI) GetLatestCrossLink(ctx context.Context, shardID hexutil.Uint) (*RPCCrossLink, error) {
+	crossLink := s.b.GetLatestCrossLink(ctx, uint32(shardID))
+	if crossLink == nil {
+		return nil, nil
+	}
+	return newRPCCrossLink(crossLink), nil
+}
+
+// GetCrossLink returns the header of the block of the given shard and number,
+// if anchored on the beacon chain. Only the beacon chain nodes have the
+// cross-links.
+func (s *PublicBlockChainAPI) GetCrossLink(ctx context.Context, shardID hexutil.Uint, blockNum hexutil.Uint64) (*RPCCrossLink, error) {
+	crossLink := s.b.GetCrossLink(ctx, uint32(shardID), uint64(blockNum))
+	if crossLink == nil {
+		return nil, nil
+	}
+	return newRPCCrossLink(crossLink), nil
+}
+
 // GetCode returns the code stored at the given address in the state for the given block number.
 func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
 	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
@@ -0,0 +1,49 @@
+package hmyapi
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/harmony-one/harmony/api/service/syncing"
+)
+
+// PublicSyncAPI provides an API to inspect the syncing of the node.
+type PublicSyncAPI struct {
+	reputation *syncing.PeerReputation
+}
+
+// NewPublicSyncAPI creates a new syncing API instance.
+func NewPublicSyncAPI(reputation *syncing.PeerReputation) *PublicSyncAPI {
+	return &PublicSyncAPI{reputation}
+}
+
+// RPCSyncPeerScore represents the reputation of a syncing peer.
+type RPCSyncPeerScore struct {
+	IP          string         `json:"ip"`
+	Port        string         `json:"port"`
+	Score       int            `json:"score"`
+	Bans        hexutil.Uint   `json:"bans"`
+	Banned      bool           `json:"banned"`
+	BannedUntil hexutil.Uint64 `json:"bannedUntil"`
+	LastReason  string         `json:"lastReason"`
+}
+
+// GetSyncPeerScores returns the reputation of the peers the node syncs with.
+func (s *PublicSyncAPI) GetSyncPeerScores(ctx context.Context) []*RPCSyncPeerScore {
+	result := []*RPCSyncPeerScore{}
+	for _, score := range s.reputation.Scores() {
+		peerScore := &RPCSyncPeerScore{
+			IP:         score.IP,
+			Port:       score.Port,
+			Score:      score.Score,
+			Bans:       hexutil.Uint(score.Bans),
+			Banned:     !score.BannedUntil.IsZero(),
+			LastReason: score.LastReason,
+		}
+		if peerScore.Banned {
+			peerScore.BannedUntil = hexutil.Uint64(score.BannedUntil.Unix())
+		}
+		result = append(result, peerScore)
+	}
+	return result
+}
@@ -157,3 +157,55 @@ func newRPCTransactionFromBlockIndex(b *types.Block, index uint64) *RPCTransacti
 	}
 	return newRPCTransaction(txs[index], b.Hash(), b.NumberU64(), index)
 }
+
+// RPCValidatorUptime represents the signing participation of a validator in an epoch.
+type RPCValidatorUptime struct {
+	BlsPublicKey string         `json:"blsPublicKey"`
+	Signed       hexutil.Uint64 `json:"signed"`
+	Missed       hexutil.Uint64 `json:"missed"`
+	Uptime       float64        `json:"uptime"`
+}
+
+// RPCEpochUptime represents the signing participation of the validators of a shard in an epoch.
+type RPCEpochUptime struct {
+	Epoch      hexutil.Uint64        `json:"epoch"`
+	ShardID    hexutil.Uint          `json:"shardID"`
+	Validators []*RPCValidatorUptime `json:"validators"`
+}
+
+// newRPCEpochUptime returns the uptime counters of an epoch that will serialize to the RPC representation.
+func newRPCEpochUptime(uptime *types.EpochUptime) *RPCEpochUptime {
+	result := &RPCEpochUptime{
+		Epoch:      hexutil.Uint64(uptime.Epoch),
+		ShardID:    hexutil.Uint(uptime.ShardID),
+		Validators: []*RPCValidatorUptime{},
+	}
+	for i := range uptime.Validators {
+		validator := &uptime.Validators[i]
+		result.Validators = append(result.Validators, &RPCValidatorUptime{
+			BlsPublicKey: validator.BlsPublicKey.Hex(),
+			Signed:       hexutil.Uint64(validator.Signed),
+			Missed:       hexutil.Uint64(validator.Missed),
+			Uptime:       validator.Uptime(),
+		})
+	}
+	return result
+}
+
+// RPCCrossLink represents a shard block header anchored on the beacon chain.
+type RPCCrossLink struct {
+	ShardID     hexutil.Uint  `json:"shardID"`
+	BlockNumber *hexutil.Big  `json:"blockNumber"`
+	BlockHash   common.Hash   `json:"blockHash"`
+	Header      *types.Header `json:"header"`
+}
+
+// newRPCCrossLink returns a cross-link that will serialize to the RPC representation.
+func newRPCCrossLink(crossLink *types.CrossLink) *RPCCrossLink {
+	return &RPCCrossLink{
+		ShardID:     hexutil.Uint(crossLink.ShardID()),
+		BlockNumber: (*hexutil.Big)(crossLink.Number()),
+		BlockHash:   crossLink.Hash(),
+		Header:      crossLink.Header,
+	}
+}
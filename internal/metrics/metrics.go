@@ -0,0 +1,128 @@
+// Package metrics collects the node metrics and exposes them over HTTP in the
+// Prometheus text exposition format.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Kinds of metrics
+const (
+	Counter = "counter"
+	Gauge   = "gauge"
+)
+
+// Labels are the labels of a metric sample, e.g. {"phase": "prepare"}.
+type Labels map[string]string
+
+// String returns the labels in the exposition format, sorted by name.
+func (labels Labels) String() string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[name])
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", name, value))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+type metric struct {
+	help    string
+	kind    string
+	samples map[string]float64 // by labels
+}
+
+// Registry holds a set of metrics.
+type Registry struct {
+	mutex   sync.Mutex
+	metrics map[string]*metric
+}
+
+// DefaultRegistry is the registry the node metrics are recorded to.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry returns a new empty registry.
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]*metric)}
+}
+
+func (r *Registry) get(name string, help string, kind string) *metric {
+	m, ok := r.metrics[name]
+	if !ok {
+		m = &metric{help: help, kind: kind, samples: make(map[string]float64)}
+		r.metrics[name] = m
+	}
+	return m
+}
+
+// Add adds delta to the counter of the given name and labels.
+func (r *Registry) Add(name string, help string, labels Labels, delta float64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.get(name, help, Counter).samples[labels.String()] += delta
+}
+
+// Set sets the gauge of the given name and labels to value.
+func (r *Registry) Set(name string, help string, labels Labels, value float64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.get(name, help, Gauge).samples[labels.String()] = value
+}
+
+// Value returns the value of the metric of the given name and labels, and
+// whether it was recorded.
+func (r *Registry) Value(name string, labels Labels) (float64, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	m, ok := r.metrics[name]
+	if !ok {
+		return 0, false
+	}
+	value, ok := m.samples[labels.String()]
+	return value, ok
+}
+
+// WriteTo writes the metrics to w in the Prometheus text exposition format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mutex.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buffer := bytes.NewBuffer([]byte{})
+	for _, name := range names {
+		m := r.metrics[name]
+		fmt.Fprintf(buffer, "# HELP %s %s\n", name, m.help)
+		fmt.Fprintf(buffer, "# TYPE %s %s\n", name, m.kind)
+		labels := make([]string, 0, len(m.samples))
+		for label := range m.samples {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		for _, label := range labels {
+			fmt.Fprintf(buffer, "%s%s %v\n", name, label, m.samples[label])
+		}
+	}
+	r.mutex.Unlock()
+	return buffer.WriteTo(w)
+}
+
+// ServeHTTP serves the metrics to the Prometheus scraper.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	r.WriteTo(w)
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	r.Add("rounds_total", "Number of rounds.", nil, 1)
+	r.Add("rounds_total", "Number of rounds.", nil, 2)
+	r.Set("phase_seconds", "Duration of the phases.", Labels{"phase": "prepare", "shard": "0"}, 0.5)
+	r.Set("phase_seconds", "Duration of the phases.", Labels{"shard": "0", "phase": "prepare"}, 1.5)
+
+	if value, ok := r.Value("rounds_total", nil); !ok || value != 3 {
+		t.Errorf("rounds_total = %v, expected 3", value)
+	}
+	if value, ok := r.Value("phase_seconds", Labels{"phase": "prepare", "shard": "0"}); !ok || value != 1.5 {
+		t.Errorf("phase_seconds = %v, expected 1.5", value)
+	}
+	if _, ok := r.Value("phase_seconds", Labels{"phase": "commit"}); ok {
+		t.Error("found a sample never recorded")
+	}
+
+	buffer := bytes.NewBuffer([]byte{})
+	if _, err := r.WriteTo(buffer); err != nil {
+		t.Fatalf("cannot write metrics: %v", err)
+	}
+	expected := `# HELP phase_seconds Duration of the phases.
+# TYPE phase_seconds gauge
+phase_seconds{phase="prepare",shard="0"} 1.5
+# HELP rounds_total Number of rounds.
+# TYPE rounds_total counter
+rounds_total 3
+`
+	if buffer.String() != expected {
+		t.Errorf("wrote\n%s\nexpected\n%s", buffer.String(), expected)
+	}
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/dgraph-io/badger"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+
+	"github.com/harmony-one/harmony/internal/utils"
+)
+
+var (
+	errNotFound    = errors.New("not found")
+	errBatchTooBig = errors.New("batch too big for a single transaction")
+)
+
+// BadgerDatabase is a database backed by Badger, an embedded key-value store
+// which keeps the values apart from the keys, so that its LSM tree is small.
+type BadgerDatabase struct {
+	db *badger.DB
+}
+
+// NewBadgerDatabase opens the Badger database in the given directory, creating
+// it if needed.
+func NewBadgerDatabase(dir string) (*BadgerDatabase, error) {
+	opts := badger.DefaultOptions
+	opts.Dir, opts.ValueDir = dir, dir
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &BadgerDatabase{db: db}, nil
+}
+
+// Put stores the value of the key.
+func (db *BadgerDatabase) Put(key []byte, value []byte) error {
+	return db.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(common.CopyBytes(key), common.CopyBytes(value))
+	})
+}
+
+// Get returns the value of the key.
+func (db *BadgerDatabase) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := db.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+	if err == badger.ErrKeyNotFound {
+		return nil, errNotFound
+	}
+	return value, err
+}
+
+// Has returns whether the key has a value.
+func (db *BadgerDatabase) Has(key []byte) (bool, error) {
+	err := db.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(key)
+		return err
+	})
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Delete removes the key.
+func (db *BadgerDatabase) Delete(key []byte) error {
+	return db.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(common.CopyBytes(key))
+	})
+}
+
+// Close closes the database.
+func (db *BadgerDatabase) Close() {
+	if err := db.db.Close(); err != nil {
+		utils.GetLogInstance().Error("Failed to close the badger database", "err", err)
+	}
+}
+
+// ForEach calls fn with the key and value of every entry until fn fails.
+func (db *BadgerDatabase) ForEach(fn func(key, value []byte) error) error {
+	return db.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			value, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			if err := fn(item.KeyCopy(nil), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// NewBatch returns a batch of writes to the database.
+func (db *BadgerDatabase) NewBatch() ethdb.Batch {
+	return &badgerBatch{db: db.db}
+}
+
+type badgerWrite struct {
+	key, value []byte
+	delete     bool
+}
+
+// badgerBatch is a batch of writes to a Badger database. The writes are done
+// in a single transaction, so that they are atomic: a batch too big for it
+// fails as a whole, and is to be flushed in smaller parts by the caller.
+type badgerBatch struct {
+	db     *badger.DB
+	writes []badgerWrite
+	size   int
+}
+
+func (b *badgerBatch) Put(key, value []byte) error {
+	b.writes = append(b.writes, badgerWrite{key: common.CopyBytes(key), value: common.CopyBytes(value)})
+	b.size += len(value)
+	return nil
+}
+
+func (b *badgerBatch) Delete(key []byte) error {
+	b.writes = append(b.writes, badgerWrite{key: common.CopyBytes(key), delete: true})
+	b.size++
+	return nil
+}
+
+func (b *badgerBatch) ValueSize() int {
+	return b.size
+}
+
+func (b *badgerBatch) Write() error {
+	err := b.db.Update(func(txn *badger.Txn) error {
+		for _, write := range b.writes {
+			var err error
+			if write.delete {
+				err = txn.Delete(write.key)
+			} else {
+				err = txn.Set(write.key, write.value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == badger.ErrTxnTooBig {
+		return errBatchTooBig
+	}
+	return err
+}
+
+func (b *badgerBatch) Reset() {
+	b.writes = b.writes[:0]
+	b.size = 0
+}
@@ -0,0 +1,69 @@
+package storage_test
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/core/vm"
+	"github.com/harmony-one/harmony/internal/storage"
+)
+
+func BenchmarkInsertChainLevelDB(b *testing.B) { benchInsertChain(b, storage.LevelDB) }
+func BenchmarkInsertChainMemory(b *testing.B)  { benchInsertChain(b, storage.Memory) }
+func BenchmarkInsertChainBadger(b *testing.B)  { benchInsertChain(b, storage.Badger) }
+
+// benchInsertChain measures the insertion of blocks of transfers into an
+// archival chain, which writes the state of every block to the database.
+func benchInsertChain(b *testing.B, kind string) {
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	gspec := core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{address: {Balance: big.NewInt(1000000000000000000)}},
+	}
+	genDB := ethdb.NewMemDatabase()
+	genesis := gspec.MustCommit(genDB)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, consensus.NewFaker(), genDB, 100, func(i int, block *core.BlockGen) {
+		for j := 0; j < 20; j++ {
+			tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{1, byte(i), byte(j)}, 0, big.NewInt(1000), params.TxGas, nil, nil), types.HomesteadSigner{}, key)
+			block.AddTx(tx)
+		}
+	})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		dir, err := ioutil.TempDir("", "harmony-storage-bench")
+		if err != nil {
+			b.Fatal(err)
+		}
+		db, err := storage.Open(kind, dir)
+		if err != nil {
+			b.Fatalf("cannot open database: %v", err)
+		}
+		gspec.MustCommit(db)
+		chain, err := core.NewBlockChain(db, &core.CacheConfig{Disabled: true}, gspec.Config, consensus.NewFaker(), vm.Config{}, nil)
+		if err != nil {
+			b.Fatalf("cannot create chain: %v", err)
+		}
+		b.StartTimer()
+
+		if _, err := chain.InsertChain(blocks); err != nil {
+			b.Fatalf("cannot insert blocks: %v", err)
+		}
+
+		b.StopTimer()
+		chain.Stop()
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
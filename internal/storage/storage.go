@@ -0,0 +1,68 @@
+// Package storage provides the key-value stores a node can keep its chains in.
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// Kinds of key-value stores.
+const (
+	LevelDB = "leveldb" // LevelDB files, the default
+	Memory  = "memory"  // in memory, lost when the node stops, for tests and ephemeral nodes
+	Badger  = "badger"  // Badger files, keeping the values apart from the keys
+)
+
+// Kinds lists the kinds of key-value stores available.
+var Kinds = []string{LevelDB, Memory, Badger}
+
+// Open opens the key-value store of the given kind in the given directory,
+// creating it if needed. The directory of a memory store is ignored.
+func Open(kind string, dir string) (ethdb.Database, error) {
+	switch kind {
+	case LevelDB:
+		return ethdb.NewLDBDatabase(dir, 0, 0)
+	case Memory:
+		return ethdb.NewMemDatabase(), nil
+	case Badger:
+		return NewBadgerDatabase(dir)
+	}
+	return nil, fmt.Errorf("unknown database kind %q, expecting one of %s", kind, strings.Join(Kinds, ", "))
+}
+
+// iterable is a database which can iterate over its entries.
+type iterable interface {
+	ForEach(fn func(key, value []byte) error) error
+}
+
+// ForEach calls fn with the key and value of every entry of the database until
+// fn fails. The entries may be modified meanwhile.
+func ForEach(db ethdb.Database, fn func(key, value []byte) error) error {
+	switch db := db.(type) {
+	case *ethdb.LDBDatabase:
+		it := db.NewIterator()
+		defer it.Release()
+		for it.Next() {
+			if err := fn(it.Key(), it.Value()); err != nil {
+				return err
+			}
+		}
+		return it.Error()
+	case *ethdb.MemDatabase:
+		for _, key := range db.Keys() {
+			value, err := db.Get(key)
+			if err != nil {
+				continue
+			}
+			if err := fn(key, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case iterable:
+		return db.ForEach(fn)
+	}
+	return fmt.Errorf("cannot iterate over a database of type %T", db)
+}
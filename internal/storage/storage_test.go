@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func TestDatabases(t *testing.T) {
+	for _, kind := range Kinds {
+		dir, err := ioutil.TempDir("", "harmony-storage")
+		if err != nil {
+			t.Fatal(err)
+		}
+		db, err := Open(kind, dir)
+		if err != nil {
+			t.Fatalf("%s: cannot open: %v", kind, err)
+		}
+		testDatabase(t, kind, db)
+		db.Close()
+		os.RemoveAll(dir)
+	}
+	if _, err := Open("unknown", ""); err == nil {
+		t.Error("unknown kind of database opened")
+	}
+}
+
+func testDatabase(t *testing.T, kind string, db ethdb.Database) {
+	if err := db.Put([]byte("a"), []byte("1")); err != nil {
+		t.Fatalf("%s: cannot put: %v", kind, err)
+	}
+	if value, err := db.Get([]byte("a")); err != nil || !bytes.Equal(value, []byte("1")) {
+		t.Errorf("%s: got %q, err %v", kind, value, err)
+	}
+	if _, err := db.Get([]byte("b")); err == nil {
+		t.Errorf("%s: got a missing key", kind)
+	}
+	if has, err := db.Has([]byte("b")); err != nil || has {
+		t.Errorf("%s: has a missing key, err %v", kind, err)
+	}
+
+	batch := db.NewBatch()
+	for i := 0; i < 100; i++ {
+		batch.Put([]byte(fmt.Sprintf("key%02d", i)), []byte{byte(i)})
+	}
+	batch.Delete([]byte("a"))
+	if batch.ValueSize() != 101 {
+		t.Errorf("%s: wrong batch value size %d", kind, batch.ValueSize())
+	}
+	if has, _ := db.Has([]byte("key00")); has {
+		t.Errorf("%s: batch written before Write", kind)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatalf("%s: cannot write batch: %v", kind, err)
+	}
+	if has, err := db.Has([]byte("a")); err != nil || has {
+		t.Errorf("%s: deleted key in batch still present, err %v", kind, err)
+	}
+	if err := db.Delete([]byte("key99")); err != nil {
+		t.Errorf("%s: cannot delete: %v", kind, err)
+	}
+
+	entries := map[string][]byte{}
+	if err := ForEach(db, func(key, value []byte) error {
+		entries[string(key)] = value
+		return nil
+	}); err != nil {
+		t.Fatalf("%s: cannot iterate: %v", kind, err)
+	}
+	if len(entries) != 99 || !bytes.Equal(entries["key42"], []byte{42}) {
+		t.Errorf("%s: iterated over %d entries, key42 = %v", kind, len(entries), entries["key42"])
+	}
+}
+
+func TestBadgerBatchTooBig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "harmony-storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewBadgerDatabase(dir)
+	if err != nil {
+		t.Fatalf("cannot open: %v", err)
+	}
+	defer db.Close()
+
+	batch := db.NewBatch()
+	for i := 0; i < 1000000; i++ {
+		batch.Put([]byte(fmt.Sprintf("key%07d", i)), []byte{1})
+	}
+	if err := batch.Write(); err != errBatchTooBig {
+		t.Fatalf("wrong error writing a batch too big for a transaction: %v", err)
+	}
+	if has, err := db.Has([]byte("key0000000")); err != nil || has {
+		t.Errorf("part of a failed batch written, err %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package node
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	proto_node "github.com/harmony-one/harmony/api/proto/node"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/ctxerror"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/p2p/host"
+)
+
+// BroadcastCXReceipts relays the proven receipts of the cross-shard
+// transactions of the committed block to their destination shards, whose
+// leaders include them in blocks crediting the transfers.
+func (node *Node) BroadcastCXReceipts(block *types.Block) {
+	crossShard := false
+	for _, tx := range block.Transactions() {
+		crossShard = crossShard || tx.IsCrossShard()
+	}
+	if !crossShard {
+		return
+	}
+	proofs, err := core.MakeCXReceiptProofs(block, node.blockchain.GetReceiptsByHash(block.Hash()))
+	if err != nil {
+		ctxerror.Log15(utils.GetLogInstance().Error, ctxerror.New("[CXReceipts] cannot prove the cross-shard receipts",
+			"blockNum", block.NumberU64(),
+		).WithCause(err))
+		return
+	}
+	for toShardID, shardProofs := range proofs {
+		msg := proto_node.ConstructCXReceiptsMessage(shardProofs)
+		if msg == nil {
+			continue
+		}
+		groupID := p2p.NewGroupIDByShardID(p2p.ShardID(toShardID))
+		if err := node.host.SendMessageToGroups([]p2p.GroupID{groupID}, host.ConstructP2pMessage(byte(0), msg)); err != nil {
+			utils.GetLogInstance().Warn("[CXReceipts] cannot relay cross-shard receipts", "toShardID", toShardID, "error", err)
+			continue
+		}
+		utils.GetLogInstance().Info("[CXReceipts] relayed cross-shard receipts", "blockNum", block.NumberU64(), "toShardID", toShardID, "numReceipts", len(shardProofs))
+	}
+}
+
+func (node *Node) cxReceiptsMessageHandler(msgPayload []byte) {
+	if node.Consensus == nil {
+		return
+	}
+	proofs, err := proto_node.DeserializeCXReceiptsFromMessage(msgPayload)
+	if err != nil {
+		utils.GetLogInstance().Error("Can't get cross-shard receipts message", "error", err)
+		return
+	}
+	for _, proof := range proofs {
+		// the source block may not be cross-linked yet, which is checked
+		// when the receipt is included in a block
+		if err := node.verifyIncomingReceiptProof(proof); err != nil {
+			ctxerror.Log15(utils.GetLogInstance().Warn, ctxerror.New("[CXReceipts] invalid cross-shard receipt").WithCause(err))
+			continue
+		}
+		node.addPendingCXReceipt(proof)
+	}
+}
+
+// verifyIncomingReceipt checks the cross-shard receipt, and that its source
+// block is final: in the canonical beacon chain, or cross-linked in it.
+func (node *Node) verifyIncomingReceipt(proof *types.CXReceiptProof) error {
+	if err := node.verifyIncomingReceiptProof(proof); err != nil {
+		return err
+	}
+	return node.verifyIncomingReceiptSource(proof)
+}
+
+// verifyIncomingReceiptProof checks the proof of the cross-shard receipt, and
+// the seal of its source block header against the committee of the source shard.
+func (node *Node) verifyIncomingReceiptProof(proof *types.CXReceiptProof) error {
+	if _, err := core.VerifyCXReceiptProof(proof, node.Consensus.ShardID); err != nil {
+		return err
+	}
+	return node.Consensus.VerifySeal(node.beaconChainOfShard(), proof.Header)
+}
+
+// verifyIncomingReceiptSource checks that the source block of the cross-shard
+// receipt is in the canonical beacon chain, or cross-linked in it.
+func (node *Node) verifyIncomingReceiptSource(proof *types.CXReceiptProof) error {
+	return core.VerifyCXReceiptSource(node.beaconChainOfShard(), proof)
+}
+
+// beaconChainOfShard returns the beacon chain, which keeps the committees and
+// the cross-links of all the shards.
+func (node *Node) beaconChainOfShard() *core.BlockChain {
+	if node.beaconChain != nil {
+		return node.beaconChain
+	}
+	return node.blockchain
+}
+
+// addPendingCXReceipt adds the cross-shard receipt to the pending list, unless it is already there.
+func (node *Node) addPendingCXReceipt(proof *types.CXReceiptProof) {
+	node.pendingCXReceiptMutex.Lock()
+	defer node.pendingCXReceiptMutex.Unlock()
+	id := proof.ID()
+	for _, pending := range node.pendingCXReceipts {
+		if pending.ID() == id {
+			return
+		}
+	}
+	node.pendingCXReceipts = append(node.pendingCXReceipts, proof)
+	utils.GetLogInstance().Debug("[CXReceipts] Got cross-shard receipt", "id", id, "totalPending", len(node.pendingCXReceipts))
+}
+
+// getIncomingReceiptsForNewBlock returns the pending cross-shard receipts to
+// include in the new block, those whose source block is final.
+func (node *Node) getIncomingReceiptsForNewBlock() []*types.CXReceiptProof {
+	node.pendingCXReceiptMutex.Lock()
+	defer node.pendingCXReceiptMutex.Unlock()
+	proofs := []*types.CXReceiptProof{}
+	for _, proof := range node.pendingCXReceipts {
+		if node.verifyIncomingReceiptSource(proof) == nil {
+			proofs = append(proofs, proof)
+		}
+	}
+	return proofs
+}
+
+// removeIncludedIncomingReceipts removes the cross-shard receipts included in the block from the pending list.
+func (node *Node) removeIncludedIncomingReceipts(block *types.Block) {
+	if len(block.IncomingReceipts()) == 0 {
+		return
+	}
+	included := make(map[common.Hash]bool)
+	for _, proof := range block.IncomingReceipts() {
+		included[proof.ID()] = true
+	}
+	node.pendingCXReceiptMutex.Lock()
+	defer node.pendingCXReceiptMutex.Unlock()
+	pending := []*types.CXReceiptProof{}
+	for _, proof := range node.pendingCXReceipts {
+		if !included[proof.ID()] {
+			pending = append(pending, proof)
+		}
+	}
+	node.pendingCXReceipts = pending
+}
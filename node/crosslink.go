@@ -0,0 +1,136 @@
+package node
+
+import (
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	proto_node "github.com/harmony-one/harmony/api/proto/node"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/ctxerror"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/p2p/host"
+)
+
+// SubmitCrossLink submits the header of the committed shard block to the
+// beacon chain, whose leader includes it as a cross-link in a block.
+func (node *Node) SubmitCrossLink(block *types.Block) {
+	if node.Consensus.ShardID == 0 {
+		return
+	}
+	msg := proto_node.ConstructCrossLinkMessage(types.CrossLinks{types.NewCrossLink(block.Header())})
+	if msg == nil {
+		return
+	}
+	if err := node.host.SendMessageToGroups([]p2p.GroupID{p2p.GroupIDBeacon}, host.ConstructP2pMessage(byte(0), msg)); err != nil {
+		utils.GetLogInstance().Warn("[CrossLink] cannot submit the block header to the beacon chain", "blockNum", block.NumberU64(), "error", err)
+	}
+}
+
+func (node *Node) crossLinkMessageHandler(msgPayload []byte) {
+	// Only the beacon chain anchors the shard block headers
+	if node.Consensus == nil || node.Consensus.ShardID != 0 {
+		return
+	}
+	crossLinks, err := proto_node.DeserializeCrossLinkFromMessage(msgPayload)
+	if err != nil {
+		utils.GetLogInstance().Error("Can't get cross-link message", "error", err)
+		return
+	}
+	for _, crossLink := range crossLinks {
+		if err := node.verifyCrossLink(crossLink); err != nil {
+			ctxerror.Log15(utils.GetLogInstance().Warn, ctxerror.New("[CrossLink] invalid cross-link").WithCause(err))
+			continue
+		}
+		node.addPendingCrossLink(crossLink)
+	}
+}
+
+// verifyCrossLink checks that the shard block header follows the latest
+// cross-link of its shard, and is sealed by the committee of the shard.
+func (node *Node) verifyCrossLink(crossLink *types.CrossLink) error {
+	if crossLink.Header == nil || crossLink.Header.Number == nil {
+		return ctxerror.New("cross-link without header")
+	}
+	shardID, number := crossLink.ShardID(), crossLink.NumberU64()
+	if shardID == 0 {
+		return ctxerror.New("cross-link of a beacon chain block", "blockNum", number)
+	}
+	if latest := node.blockchain.ReadLatestCrossLink(shardID); latest != nil && number <= latest.NumberU64() {
+		return ctxerror.New("cross-link already superseded",
+			"shardID", shardID,
+			"blockNum", number,
+			"latestBlockNum", latest.NumberU64())
+	}
+	return node.Consensus.VerifySeal(node.blockchain, crossLink.Header)
+}
+
+// addPendingCrossLink adds the cross-link to the pending list, unless it is already there.
+func (node *Node) addPendingCrossLink(crossLink *types.CrossLink) {
+	node.pendingCrossLinkMutex.Lock()
+	defer node.pendingCrossLinkMutex.Unlock()
+	hash := crossLink.Hash()
+	for _, pending := range node.pendingCrossLinks {
+		if pending.Hash() == hash {
+			return
+		}
+	}
+	node.pendingCrossLinks = append(node.pendingCrossLinks, crossLink)
+	utils.GetLogInstance().Debug("[CrossLink] Got cross-link", "shardID", crossLink.ShardID(), "blockNum", crossLink.NumberU64(), "totalPending", len(node.pendingCrossLinks))
+}
+
+// getCrossLinksForNewBlock returns the pending cross-links to include in the
+// new block, one per shard and block number, in order.
+func (node *Node) getCrossLinksForNewBlock() []*types.CrossLink {
+	node.pendingCrossLinkMutex.Lock()
+	defer node.pendingCrossLinkMutex.Unlock()
+	crossLinks := append([]*types.CrossLink{}, node.pendingCrossLinks...)
+	sort.SliceStable(crossLinks, func(i, j int) bool {
+		if crossLinks[i].ShardID() != crossLinks[j].ShardID() {
+			return crossLinks[i].ShardID() < crossLinks[j].ShardID()
+		}
+		return crossLinks[i].NumberU64() < crossLinks[j].NumberU64()
+	})
+	selected := []*types.CrossLink{}
+	for _, crossLink := range crossLinks {
+		if latest := node.blockchain.ReadLatestCrossLink(crossLink.ShardID()); latest != nil && crossLink.NumberU64() <= latest.NumberU64() {
+			// Superseded while pending
+			continue
+		}
+		if n := len(selected); n > 0 && selected[n-1].ShardID() == crossLink.ShardID() && selected[n-1].NumberU64() == crossLink.NumberU64() {
+			// Competing headers of the same block, keep the first one
+			continue
+		}
+		selected = append(selected, crossLink)
+	}
+	return selected
+}
+
+// removeIncludedCrossLinks removes from the pending list the cross-links
+// included in the block, and the ones superseded by them.
+func (node *Node) removeIncludedCrossLinks(block *types.Block) {
+	if len(block.CrossLinks()) == 0 {
+		return
+	}
+	included := make(map[common.Hash]bool)
+	latest := make(map[uint32]uint64)
+	for _, crossLink := range block.CrossLinks() {
+		included[crossLink.Hash()] = true
+		if number := crossLink.NumberU64(); number > latest[crossLink.ShardID()] {
+			latest[crossLink.ShardID()] = number
+		}
+	}
+	node.pendingCrossLinkMutex.Lock()
+	defer node.pendingCrossLinkMutex.Unlock()
+	pending := []*types.CrossLink{}
+	for _, crossLink := range node.pendingCrossLinks {
+		if included[crossLink.Hash()] {
+			continue
+		}
+		if number, ok := latest[crossLink.ShardID()]; ok && crossLink.NumberU64() <= number {
+			continue
+		}
+		pending = append(pending, crossLink)
+	}
+	node.pendingCrossLinks = pending
+}
@@ -0,0 +1,107 @@
+package node
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	proto_node "github.com/harmony-one/harmony/api/proto/node"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/ctxerror"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/p2p/host"
+)
+
+// ReportDoubleSign reports the double sign evidence found by consensus to the
+// beacon chain, whose leader includes it in a block to slash the double signer.
+func (node *Node) ReportDoubleSign(evidence *types.DoubleSignEvidence) {
+	if node.Consensus.ShardID == 0 {
+		node.addPendingDoubleSign(evidence)
+	}
+	msg := proto_node.ConstructDoubleSignMessage(evidence)
+	if msg == nil {
+		return
+	}
+	if err := node.host.SendMessageToGroups([]p2p.GroupID{p2p.GroupIDBeacon}, host.ConstructP2pMessage(byte(0), msg)); err != nil {
+		utils.GetLogInstance().Warn("[DoubleSign] cannot broadcast double sign evidence", "error", err)
+	}
+}
+
+func (node *Node) doubleSignMessageHandler(msgPayload []byte) {
+	// Only the beacon chain slashes double signers
+	if node.Consensus == nil || node.Consensus.ShardID != 0 {
+		return
+	}
+	evidence, err := proto_node.DeserializeDoubleSignFromMessage(msgPayload)
+	if err != nil {
+		utils.GetLogInstance().Error("Can't get double sign message", "error", err)
+		return
+	}
+	if err := node.Consensus.VerifyDoubleSignEvidence(evidence); err != nil {
+		ctxerror.Log15(utils.GetLogInstance().Warn, ctxerror.New("[DoubleSign] invalid double sign evidence",
+			"evidence", evidence.Hash(),
+		).WithCause(err))
+		return
+	}
+	node.addPendingDoubleSign(evidence)
+}
+
+// addPendingDoubleSign adds the double sign evidence to the pending list, unless it is already there.
+func (node *Node) addPendingDoubleSign(evidence *types.DoubleSignEvidence) {
+	node.pendingDoubleSignMutex.Lock()
+	defer node.pendingDoubleSignMutex.Unlock()
+	hash := evidence.Hash()
+	for _, pending := range node.pendingDoubleSigns {
+		if pending.Hash() == hash {
+			return
+		}
+	}
+	node.pendingDoubleSigns = append(node.pendingDoubleSigns, evidence)
+	utils.GetLogInstance().Info("[DoubleSign] Got double sign evidence", "evidence", hash, "totalPending", len(node.pendingDoubleSigns))
+}
+
+// getDoubleSignsForNewBlock returns the pending double sign evidences to
+// include in the new block, one per offense not slashed yet, and drops the
+// ones which can't slash anymore from the pending list.
+func (node *Node) getDoubleSignsForNewBlock() []*types.DoubleSignEvidence {
+	node.pendingDoubleSignMutex.Lock()
+	defer node.pendingDoubleSignMutex.Unlock()
+	state := node.Worker.GetCurrentState()
+	offenses := make(map[common.Hash]bool)
+	pending := []*types.DoubleSignEvidence{}
+	selected := []*types.DoubleSignEvidence{}
+	for _, evidence := range node.pendingDoubleSigns {
+		offense, err := node.Consensus.CheckDoubleSignEvidence(node.blockchain, state, evidence)
+		if err != nil {
+			ctxerror.Log15(utils.GetLogInstance().Debug, ctxerror.New("[DoubleSign] dropping double sign evidence",
+				"evidence", evidence.Hash(),
+			).WithCause(err))
+			continue
+		}
+		pending = append(pending, evidence)
+		if !offenses[offense] {
+			offenses[offense] = true
+			selected = append(selected, evidence)
+		}
+	}
+	node.pendingDoubleSigns = pending
+	return selected
+}
+
+// removeIncludedDoubleSigns removes the double sign evidences included in the block from the pending list.
+func (node *Node) removeIncludedDoubleSigns(block *types.Block) {
+	if len(block.DoubleSigns()) == 0 {
+		return
+	}
+	included := make(map[common.Hash]bool)
+	for _, evidence := range block.DoubleSigns() {
+		included[evidence.Hash()] = true
+	}
+	node.pendingDoubleSignMutex.Lock()
+	defer node.pendingDoubleSignMutex.Unlock()
+	pending := []*types.DoubleSignEvidence{}
+	for _, evidence := range node.pendingDoubleSigns {
+		if !included[evidence.Hash()] {
+			pending = append(pending, evidence)
+		}
+	}
+	node.pendingDoubleSigns = pending
+}
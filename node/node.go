@@ -8,8 +8,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/harmony-one/bls/ffi/go/bls"
-
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
@@ -29,6 +27,7 @@ import (
 	"github.com/harmony-one/harmony/crypto/pki"
 	"github.com/harmony-one/harmony/drand"
 	nodeconfig "github.com/harmony-one/harmony/internal/configs/node"
+	"github.com/harmony-one/harmony/internal/ctxerror"
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/node/worker"
 	"github.com/harmony-one/harmony/p2p"
@@ -70,19 +69,12 @@ func (state State) String() string {
 
 const (
 	// ClientServicePortDiff is the positive port diff for client service
-	ClientServicePortDiff       = 5555
-	maxBroadcastNodes           = 10                  // broadcast at most maxBroadcastNodes peers that need in sync
-	broadcastTimeout      int64 = 3 * 60 * 1000000000 // 3 mins
+	ClientServicePortDiff  = 5555
+	maxNewBlockSubscribers = 10 // serve new blocks to at most maxNewBlockSubscribers syncing peers
 	//SyncIDLength is the length of bytes for syncID
 	SyncIDLength = 20
 )
 
-// use to push new block to outofsync node
-type syncConfig struct {
-	timestamp int64
-	client    *downloader.Client
-}
-
 // Node represents a protocol-participating node in the network
 type Node struct {
 	Consensus              *consensus.Consensus // Consensus object containing all Consensus related data (e.g. committee members, signatures, commits)
@@ -92,11 +84,31 @@ type Node struct {
 	pendingTransactions    types.Transactions   // All the transactions received but not yet processed for Consensus
 	transactionInConsensus []*types.Transaction // The transactions selected into the new block and under Consensus process
 	pendingTxMutex         sync.Mutex
+	pendingDoubleSigns     []*types.DoubleSignEvidence // Double sign evidences received but not yet included in the beacon chain
+	pendingDoubleSignMutex sync.Mutex
+	pendingCXReceipts      []*types.CXReceiptProof // Cross-shard receipts received but not yet credited
+	pendingCXReceiptMutex  sync.Mutex
+	pendingCrossLinks      []*types.CrossLink // Shard block headers received but not yet cross-linked in the beacon chain
+	pendingCrossLinkMutex  sync.Mutex
 	DRand                  *drand.DRand // The instance for distributed randomness protocol
 
-	blockchain  *core.BlockChain   // The blockchain for the shard where this node belongs
-	beaconChain *core.BlockChain   // The blockchain for beacon chain.
-	db          *ethdb.LDBDatabase // LevelDB to store blockchain.
+	// The chain, worker, pool and receivers of the node's shard are swapped
+	// when the node switches shard, with the shard mutex held; the messages
+	// are handled with the shard mutex held for reading.
+	shardMutex   sync.RWMutex
+	reshardMutex sync.Mutex // serializes the shard switches
+	// the shard switch due once the old shard commits its epoch block
+	pendingReshard      *pendingReshard
+	pendingReshardMutex sync.Mutex
+
+	blockchain  *core.BlockChain // The blockchain for the shard where this node belongs
+	beaconChain *core.BlockChain // The blockchain for beacon chain.
+	db          ethdb.Database   // Database to store blockchain.
+	isArchival  bool             // Whether the node keeps the state of all the blocks
+
+	// Opens the database of the given shard when the node is resharded to it;
+	// the chain of the new shard is kept in memory if nil.
+	OpenShardDB func(shardID uint32) (ethdb.Database, error)
 
 	ClientPeer *p2p.Peer      // The peer for the harmony tx generator client, used for leaders to return proof-of-accept
 	Client     *client.Client // The presence of a client object means this node will also act as a client
@@ -115,15 +127,29 @@ type Node struct {
 	Worker       *worker.Worker
 	BeaconWorker *worker.Worker // worker for beacon chain
 
+	// Target interval between the blocks proposed as leader; 0 means proposing
+	// a block only once enough transactions are pending
+	BlockTime time.Duration
+
+	// Download the state at a recent block instead of executing all the
+	// blocks when syncing from genesis
+	FastSync bool
+
+	// IP the metrics endpoint listens on
+	MetricsIP string
+
 	// Client server (for wallet requests)
 	clientServer *clientService.Server
 
 	// Syncing component.
-	syncID                 [SyncIDLength]byte // a unique ID for the node during the state syncing process with peers
-	downloaderServer       *downloader.Server
-	stateSync              *syncing.StateSync
-	beaconSync             *syncing.StateSync
-	peerRegistrationRecord map[string]*syncConfig // record registration time (unixtime) of peers begin in syncing
+	syncID              [SyncIDLength]byte // a unique ID for the node during the state syncing process with peers
+	downloaderServer    *downloader.Server
+	stateSync           *syncing.StateSync
+	beaconSync          *syncing.StateSync
+	syncStopChan        chan struct{}           // closed to stop syncing the chain of the shard, e.g. when leaving it
+	syncStoppedChan     chan struct{}           // closed once syncing stopped
+	syncReputation      *syncing.PeerReputation // scores of the syncing peers, shared by stateSync and beaconSync
+	newBlockSubscribers *newBlockSubscribers    // peers subscribed to the new blocks
 
 	// The p2p host used to send/receive p2p messages
 	host p2p.Host
@@ -186,9 +212,44 @@ type Node struct {
 
 // Blockchain returns the blockchain from node
 func (node *Node) Blockchain() *core.BlockChain {
+	node.shardMutex.RLock()
+	defer node.shardMutex.RUnlock()
 	return node.blockchain
 }
 
+// Beaconchain returns the beacon chain, which is the blockchain itself in
+// the beacon shard.
+func (node *Node) Beaconchain() *core.BlockChain {
+	node.shardMutex.RLock()
+	defer node.shardMutex.RUnlock()
+	return node.beaconChainOfShard()
+}
+
+// Stop stops the node before the process exits: its RPC endpoints, services
+// and syncing, then its chains, closing their databases so that no write is
+// lost.
+func (node *Node) Stop() {
+	node.reshardMutex.Lock()
+	defer node.reshardMutex.Unlock()
+
+	node.StopRPC()
+	node.StopServices()
+	node.stopSyncing()
+
+	node.shardMutex.Lock()
+	defer node.shardMutex.Unlock()
+	node.TxPool.Stop()
+	// closes the pbft log, flushing it to the database
+	node.Consensus.SetPbftLog(consensus.NewPbftLog())
+	for _, chain := range []*core.BlockChain{node.blockchain, node.beaconChain} {
+		if chain == nil || (chain == node.beaconChain && chain == node.blockchain) {
+			continue
+		}
+		chain.Stop()
+		chain.ChainDb().Close()
+	}
+}
+
 // Add new transactions to the pending transaction list
 func (node *Node) addPendingTransactions(newTxs types.Transactions) {
 	node.pendingTxMutex.Lock()
@@ -209,6 +270,15 @@ func (node *Node) getTransactionsForNewBlock(maxNumTxs int) types.Transactions {
 	return selected
 }
 
+// restorePendingTransactions puts the transactions selected for a block that
+// couldn't be proposed back in front of the pending transactions.
+func (node *Node) restorePendingTransactions(txs types.Transactions) {
+	node.pendingTxMutex.Lock()
+	node.pendingTransactions = append(append(types.Transactions{}, txs...), node.pendingTransactions...)
+	utils.GetLogInstance().Debug("Restored selected transactions", "restored", len(txs), "totalPending", len(node.pendingTransactions))
+	node.pendingTxMutex.Unlock()
+}
+
 // StartServer starts a server and process the requests by a handler.
 func (node *Node) StartServer() {
 	select {}
@@ -236,6 +306,7 @@ func New(host p2p.Host, consensusObj *consensus.Consensus, db ethdb.Database, is
 	var isFirstTime bool // if cannot get blockchain from database, then isFirstTime = true
 
 	node := Node{}
+	node.isArchival = isArchival
 	copy(node.syncID[:], GenerateRandomString(SyncIDLength))
 	if host != nil {
 		node.host = host
@@ -281,8 +352,7 @@ func New(host p2p.Host, consensusObj *consensus.Consensus, db ethdb.Database, is
 		node.BeaconBlockChannel = make(chan *types.Block)
 		node.TxPool = core.NewTxPool(core.DefaultTxPoolConfig, params.TestChainConfig, chain)
 		node.Worker = worker.New(params.TestChainConfig, chain, node.Consensus, pki.GetAddressFromPublicKey(node.SelfPeer.ConsensusPubKey), node.Consensus.ShardID)
-
-		node.Consensus.VerifiedNewBlock = make(chan *types.Block)
+		node.BlockTime = DefaultBlockTime
 
 		// Add Faucet contract to all shards, so that on testnet, we can demo wallet in explorer
 		// TODO (leo): we need to have support of cross-shard tx later so that the token can be transferred from beacon chain shard to other tx shards.
@@ -314,9 +384,11 @@ func New(host p2p.Host, consensusObj *consensus.Consensus, db ethdb.Database, is
 		}
 	}
 
+	node.MetricsIP = DefaultMetricsIP
+
 	node.ContractCaller = contracts.NewContractCaller(&db, node.blockchain, params.TestChainConfig)
 
-	if consensusObj != nil && consensusObj.IsLeader {
+	if consensusObj != nil && consensusObj.IsLeader() {
 		node.State = NodeLeader
 	} else {
 		node.State = NodeInit
@@ -334,7 +406,8 @@ func New(host p2p.Host, consensusObj *consensus.Consensus, db ethdb.Database, is
 	go node.ReceiveGlobalMessage()
 
 	// Setup initial state of syncing.
-	node.peerRegistrationRecord = make(map[string]*syncConfig)
+	node.newBlockSubscribers = &newBlockSubscribers{wakeUps: make(map[chan struct{}]struct{})}
+	node.syncReputation = syncing.NewPeerReputation()
 
 	node.startConsensus = make(chan struct{})
 
@@ -350,41 +423,55 @@ func New(host p2p.Host, consensusObj *consensus.Consensus, db ethdb.Database, is
 
 // InitShardState initialize genesis shard state and update committee pub keys for consensus and drand
 func (node *Node) InitShardState(isGenesis bool) {
-	shardState := types.ShardState{}
-	if isGenesis {
-		// Store the genesis shard state into db.
-		if node.Consensus != nil {
-			if node.Consensus.ShardID == 0 {
-				shardState = node.blockchain.StoreNewShardState(node.blockchain.CurrentBlock(), nil)
-			} else {
-				shardState = node.beaconChain.StoreNewShardState(node.beaconChain.CurrentBlock(), nil)
-			}
-		}
-	} else {
+	// The genesis shard state is known to all the chains
+	if !isGenesis {
 		epochShardState, err := node.retrieveEpochShardState()
 		if err != nil {
 			utils.GetLogInstance().Error("[Shard State] Failed to decode epoch shard state", "error", err)
+			return
 		}
 		utils.GetLogInstance().Info("Successfully loaded epoch shard state")
-		shardState = epochShardState.ShardState
+		node.keepEpochShardState(epochShardState)
 	}
 
-	// Update validator public keys
-	for _, shard := range shardState {
-		if shard.ShardID == node.Consensus.ShardID {
-			pubKeys := []*bls.PublicKey{}
-			for _, node := range shard.NodeList {
-				blsPubKey := &bls.PublicKey{}
-				blsPubKey.Deserialize(node.BlsPublicKey[:])
-				pubKeys = append(pubKeys, blsPubKey)
-			}
-			node.Consensus.UpdatePublicKeys(pubKeys)
-			node.DRand.UpdatePublicKeys(pubKeys)
-			break
-		}
+	if err := node.updateCommitteeOfHead(); err != nil {
+		ctxerror.Log15(utils.GetLogInstance().Error, ctxerror.New("[Shard State] cannot update the committee").WithCause(err))
+	}
+}
+
+// keepEpochShardState keeps the shard state announced by the beacon chain in
+// the chain of the node's shard, until the chain has the epoch block carrying
+// it.
+func (node *Node) keepEpochShardState(epochShardState *types.EpochShardState) {
+	if _, err := node.blockchain.ReadShardState(epochShardState.Epoch); err != nil {
+		node.blockchain.WriteEpochShardState(epochShardState)
 	}
 }
 
+// updateCommittee updates the committee of consensus and drand to the
+// committee of the node's shard for the given epoch, as recorded in the chain.
+func (node *Node) updateCommittee(epoch uint64) error {
+	publicKeys, err := node.Consensus.UpdateCommittee(epoch)
+	if err != nil {
+		return err
+	}
+	node.DRand.UpdatePublicKeys(publicKeys)
+	return nil
+}
+
+// updateCommitteeOfHead updates the committee of consensus and drand to the
+// committee signing the block following the chain head. The committee of an
+// epoch takes over once the chain has the epoch block.
+func (node *Node) updateCommitteeOfHead() error {
+	return node.updateCommittee(core.GetSignerEpochFromBlockNumber(node.blockchain.CurrentBlock().NumberU64() + 1))
+}
+
+// committeeOfBlock returns the committee of the node's shard which signs the
+// block of the given number.
+func (node *Node) committeeOfBlock(blockNum uint64) (*types.Committee, error) {
+	return node.blockchain.CommitteeForEpoch(node.Consensus.ShardID, core.GetSignerEpochFromBlockNumber(blockNum))
+}
+
 // AddPeers adds neighbors nodes
 func (node *Node) AddPeers(peers []*p2p.Peer) int {
 	count := 0
@@ -438,6 +525,8 @@ func (node *Node) initNodeConfiguration() (service.NodeConfig, chan p2p.Peer) {
 		nodeConfig.Actions[node.NodeConfig.GetShardGroupID()] = p2p.ActionStart
 	}
 
+	node.shardMutex.Lock()
+	defer node.shardMutex.Unlock()
 	var err error
 	node.shardGroupReceiver, err = node.host.GroupReceiver(node.NodeConfig.GetShardGroupID())
 	if err != nil {
@@ -471,6 +560,10 @@ func (node *Node) AddBeaconChainDatabase(db ethdb.Database) {
 	}
 	node.beaconChain = chain
 	node.BeaconWorker = worker.New(params.TestChainConfig, chain, &consensus.Consensus{}, pki.GetAddressFromPublicKey(node.SelfPeer.ConsensusPubKey), node.Consensus.ShardID)
+	if node.blockchain != nil {
+		// the incoming receipts of the shard are checked against the beacon chain
+		node.blockchain.SetBeaconChain(chain)
+	}
 }
 
 // InitBlockChainFromDB retrieves the latest blockchain and state available from the local database
@@ -8,11 +8,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math"
-	"os"
-	"os/exec"
-	"strconv"
 	"sync/atomic"
-	"syscall"
 	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
@@ -31,6 +27,7 @@ import (
 	"github.com/harmony-one/harmony/core/types"
 	"github.com/harmony-one/harmony/crypto/pki"
 	nodeconfig "github.com/harmony-one/harmony/internal/configs/node"
+	"github.com/harmony-one/harmony/internal/ctxerror"
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/p2p"
 	"github.com/harmony-one/harmony/p2p/host"
@@ -46,11 +43,12 @@ const (
 func (node *Node) ReceiveGlobalMessage() {
 	ctx := context.Background()
 	for {
-		if node.globalGroupReceiver == nil {
+		receiver := node.getReceiver(&node.globalGroupReceiver)
+		if receiver == nil {
 			time.Sleep(100 * time.Millisecond)
 			continue
 		}
-		msg, sender, err := node.globalGroupReceiver.Receive(ctx)
+		msg, sender, err := receiver.Receive(ctx)
 		if sender != node.host.GetID() {
 			utils.GetLogInstance().Info("[PUBSUB]", "received global msg", len(msg), "sender", sender)
 			if err == nil {
@@ -65,11 +63,12 @@ func (node *Node) ReceiveGlobalMessage() {
 func (node *Node) ReceiveGroupMessage() {
 	ctx := context.Background()
 	for {
-		if node.shardGroupReceiver == nil {
+		receiver := node.getReceiver(&node.shardGroupReceiver)
+		if receiver == nil {
 			time.Sleep(100 * time.Millisecond)
 			continue
 		}
-		msg, sender, err := node.shardGroupReceiver.Receive(ctx)
+		msg, sender, err := receiver.Receive(ctx)
 		if sender != node.host.GetID() {
 			//			utils.GetLogInstance().Info("[PUBSUB]", "received group msg", len(msg), "sender", sender)
 			if err == nil {
@@ -84,12 +83,13 @@ func (node *Node) ReceiveGroupMessage() {
 func (node *Node) ReceiveClientGroupMessage() {
 	ctx := context.Background()
 	for {
-		if node.clientReceiver == nil {
+		receiver := node.getReceiver(&node.clientReceiver)
+		if receiver == nil {
 			// check less frequent on client messages
 			time.Sleep(100 * time.Millisecond)
 			continue
 		}
-		msg, sender, err := node.clientReceiver.Receive(ctx)
+		msg, sender, err := receiver.Receive(ctx)
 		if sender != node.host.GetID() {
 			utils.GetLogInstance().Info("[CLIENT]", "received group msg", len(msg), "sender", sender, "error", err)
 			if err == nil {
@@ -100,8 +100,19 @@ func (node *Node) ReceiveClientGroupMessage() {
 	}
 }
 
+// getReceiver returns the given receiver of the node, or nil if it is closed.
+func (node *Node) getReceiver(receiver *p2p.GroupReceiver) p2p.GroupReceiver {
+	node.shardMutex.RLock()
+	defer node.shardMutex.RUnlock()
+	return *receiver
+}
+
 // messageHandler parses the message and dispatch the actions
 func (node *Node) messageHandler(content []byte, sender string) {
+	// the node does not switch shard while handling the message
+	node.shardMutex.RLock()
+	defer node.shardMutex.RUnlock()
+
 	msgCategory, err := proto.GetMessageCategory(content)
 	if err != nil {
 		utils.GetLogInstance().Error("Read node type failed", "err", err, "node", node)
@@ -178,6 +189,12 @@ func (node *Node) messageHandler(content []byte, sender string) {
 			node.pongMessageHandler(msgPayload)
 		case proto_node.ShardState:
 			node.epochShardStateMessageHandler(msgPayload)
+		case proto_node.DoubleSign:
+			node.doubleSignMessageHandler(msgPayload)
+		case proto_node.CXReceipts:
+			node.cxReceiptsMessageHandler(msgPayload)
+		case proto_node.CrossLink:
+			node.crossLinkMessageHandler(msgPayload)
 		}
 	default:
 		utils.GetLogInstance().Error("Unknown", "MsgCategory", msgCategory)
@@ -251,31 +268,47 @@ func (node *Node) BroadcastNewBlock(newBlock *types.Block) {
 func (node *Node) VerifyNewBlock(newBlock *types.Block) bool {
 	err := node.blockchain.ValidateNewBlock(newBlock, pki.GetAddressFromPublicKey(node.SelfPeer.ConsensusPubKey))
 	if err != nil {
-		utils.GetLogInstance().Debug("Failed verifying new block", "Error", err, "tx", newBlock.Transactions()[0])
+		utils.GetLogInstance().Debug("Failed verifying new block", "Error", err, "blockNum", newBlock.NumberU64())
 		return false
 	}
 
+	for _, proof := range newBlock.IncomingReceipts() {
+		if err := node.verifyIncomingReceipt(proof); err != nil {
+			utils.GetLogInstance().Debug("Failed verifying incoming receipt", "id", proof.ID(), "Error", err)
+			return false
+		}
+	}
+
 	// TODO: verify the vrf randomness
 	_ = newBlock.Header().RandPreimage
 
-	err = node.blockchain.ValidateNewShardState(newBlock, &node.CurrentStakes)
-	if err != nil {
-		utils.GetLogInstance().Debug("Failed to verify new sharding state", "err", err)
-	}
 	return true
 }
 
 // PostConsensusProcessing is called by consensus participants, after consensus is done, to:
 // 1. add the new block to blockchain
 // 2. [leader] send new block to the client
+// 3. serve the new block to the peers subscribed to the new blocks
 func (node *Node) PostConsensusProcessing(newBlock *types.Block) {
-	if node.Consensus.IsLeader {
+	if node.Consensus.IsLeader() {
 		node.BroadcastNewBlock(newBlock)
 	} else {
 		utils.GetLogInstance().Info("BINGO !!! Reached Consensus", "ConsensusID", node.Consensus.GetConsensusID())
 	}
 
 	node.AddNewBlock(newBlock)
+	node.newBlockSubscribers.notify()
+	// leave the shard once it has committed its epoch block
+	node.reshardIfDue()
+
+	// The transfers in the block are credited, no need to include them again
+	node.removeIncludedIncomingReceipts(newBlock)
+	if node.Consensus.IsLeader() {
+		// Relay the cross-shard transfers of the block to their destination shards
+		node.BroadcastCXReceipts(newBlock)
+		// Anchor the block on the beacon chain
+		node.SubmitCrossLink(newBlock)
+	}
 
 	// Update contract deployer's nonce so default contract like faucet can issue transaction with current nonce
 	nonce := node.GetNonceOfAddress(crypto.PubkeyToAddress(node.ContractDeployerKey.PublicKey))
@@ -297,6 +330,11 @@ func (node *Node) PostConsensusProcessing(newBlock *types.Block) {
 		nonce := node.GetNonceOfAddress(crypto.PubkeyToAddress(node.ContractDeployerKey.PublicKey))
 		atomic.StoreUint64(&node.ContractDeployerCurrentNonce, nonce)
 
+		// The double signers in the block are slashed, no need to include them again
+		node.removeIncludedDoubleSigns(newBlock)
+		// The shard block headers in the block are anchored, no need to include them again
+		node.removeIncludedCrossLinks(newBlock)
+
 		// TODO: enable drand only for beacon chain
 		// ConfirmedBlockChannel which is listened by drand leader who will initiate DRG if its a epoch block (first block of a epoch)
 		if node.DRand != nil {
@@ -315,22 +353,31 @@ func (node *Node) PostConsensusProcessing(newBlock *types.Block) {
 		// TODO: update staking information once per epoch.
 		node.UpdateStakingList(node.QueryStakeInfo())
 		node.printStakingList()
-		if core.IsEpochBlock(newBlock) {
-			shardState := node.blockchain.StoreNewShardState(newBlock, &node.CurrentStakes)
-			if shardState != nil {
-				if node.Consensus.IsLeader {
-					epochShardState := types.EpochShardState{Epoch: core.GetEpochFromBlockNumber(newBlock.NumberU64()), ShardState: shardState}
-					epochShardStateMessage := proto_node.ConstructEpochShardStateMessage(epochShardState)
-					// Broadcast new shard state
-					err := node.host.SendMessageToGroups([]p2p.GroupID{node.NodeConfig.GetClientGroupID()}, host.ConstructP2pMessage(byte(0), epochShardStateMessage))
-					if err != nil {
-						utils.GetLogInstance().Error("[Resharding] failed to broadcast shard state message", "group", node.NodeConfig.GetClientGroupID())
-					} else {
-						utils.GetLogInstance().Info("[Resharding] broadcasted shard state message to", "group", node.NodeConfig.GetClientGroupID())
-					}
+		if shardState := newBlock.ShardState(); len(shardState) > 0 {
+			epochShardState := types.EpochShardState{
+				Epoch:      core.GetEpochFromBlockNumber(newBlock.NumberU64()),
+				ShardState: shardState,
+				RandSeed:   newBlock.Header().RandSeed,
+			}
+			if node.Consensus.IsLeader() {
+				epochShardStateMessage := proto_node.ConstructEpochShardStateMessage(epochShardState)
+				// Broadcast new shard state
+				err := node.host.SendMessageToGroups([]p2p.GroupID{node.NodeConfig.GetClientGroupID()}, host.ConstructP2pMessage(byte(0), epochShardStateMessage))
+				if err != nil {
+					utils.GetLogInstance().Error("[Resharding] failed to broadcast shard state message", "group", node.NodeConfig.GetClientGroupID())
+				} else {
+					utils.GetLogInstance().Info("[Resharding] broadcasted shard state message to", "group", node.NodeConfig.GetClientGroupID())
 				}
-				node.processEpochShardState(&types.EpochShardState{Epoch: core.GetEpochFromBlockNumber(newBlock.NumberU64()), ShardState: shardState})
 			}
+			node.processEpochShardState(&epochShardState)
+			return
+		}
+	}
+
+	if len(newBlock.ShardState()) > 0 {
+		// the committee of the epoch takes over from the next block
+		if err := node.updateCommitteeOfHead(); err != nil {
+			ctxerror.Log15(utils.GetLogInstance().Error, ctxerror.New("[Resharding] cannot update the committee").WithCause(err))
 		}
 	}
 }
@@ -401,9 +448,16 @@ func (node *Node) SendPongMessage() {
 	numPubKeys := len(node.Consensus.PublicKeys)
 	sentMessage := false
 	firstTime := true
+	shardID := node.Consensus.ShardID
 
 	// Send Pong Message only when there is change on the number of peers
 	for {
+		if node.Consensus.ShardID != shardID {
+			// resharded to another shard, where a new pong loop is started if needed
+			tick.Stop()
+			tick2.Stop()
+			return
+		}
 		select {
 		case <-tick.C:
 			peers := node.Consensus.GetValidatorPeers()
@@ -572,34 +626,18 @@ func (node *Node) processEpochShardState(epochShardState *types.EpochShardState)
 	myShardID := uint32(math.MaxUint32)
 	isNextLeader := false
 	myBlsPubKey := node.Consensus.PubKey.Serialize()
-	myShardState := types.Committee{}
 	for _, shard := range shardState {
 		for _, nodeID := range shard.NodeList {
 			if bytes.Compare(nodeID.BlsPublicKey[:], myBlsPubKey) == 0 {
 				myShardID = shard.ShardID
 				isNextLeader = shard.Leader == nodeID
-				myShardState = shard
 			}
 		}
 	}
 
 	if myShardID != uint32(math.MaxUint32) {
-		// Update public keys
-		ss := myShardState
-		publicKeys := []*bls.PublicKey{}
-		for _, nodeID := range ss.NodeList {
-			key := &bls.PublicKey{}
-			err := key.Deserialize(nodeID.BlsPublicKey[:])
-			if err != nil {
-				utils.GetLogInstance().Error("Failed to deserialize BLS public key in shard state", "error", err)
-			}
-			publicKeys = append(publicKeys, key)
-		}
-		node.Consensus.UpdatePublicKeys(publicKeys)
-		node.DRand.UpdatePublicKeys(publicKeys)
-
 		aboutLeader := ""
-		if node.Consensus.IsLeader {
+		if node.Consensus.IsLeader() {
 			aboutLeader = "I am not leader anymore"
 			if isNextLeader {
 				aboutLeader = "I am still leader"
@@ -610,65 +648,28 @@ func (node *Node) processEpochShardState(epochShardState *types.EpochShardState)
 				aboutLeader = "I become the leader"
 			}
 		}
-		if node.blockchain.ShardID() == myShardID {
+		if node.blockchain.ShardID() == myShardID && node.NodeConfig.Role() != nodeconfig.NewNode {
 			utils.GetLogInstance().Info(fmt.Sprintf("[Resharded][epoch:%d] I stay at shard %d, %s", epoch, myShardID, aboutLeader), "BlsPubKey", hex.EncodeToString(myBlsPubKey))
+			node.keepEpochShardState(epochShardState)
+			// the new committee takes over once the chain has the epoch block
+			if err := node.updateCommitteeOfHead(); err != nil {
+				ctxerror.Log15(utils.GetLogInstance().Error, ctxerror.New("[Resharded] cannot update the committee", "epoch", epoch).WithCause(err))
+			}
 		} else {
 			utils.GetLogInstance().Info(fmt.Sprintf("[Resharded][epoch:%d] I got resharded to shard %d from shard %d, %s", epoch, myShardID, node.blockchain.ShardID(), aboutLeader), "BlsPubKey", hex.EncodeToString(myBlsPubKey))
+			// keep the shard state in case the node is restarted in the new shard
 			node.storeEpochShardState(epochShardState)
-
-			execFile, err := getBinaryPath()
-			if err != nil {
-				utils.GetLogInstance().Crit("Failed to get program path when restarting program", "error", err, "file", execFile)
-			}
-			args := getRestartArguments(myShardID)
-			utils.GetLogInstance().Info("Restarting program", "args", args, "env", os.Environ())
-			err = syscall.Exec(execFile, args, os.Environ())
-			if err != nil {
-				utils.GetLogInstance().Crit("Failed to restart program after resharding", "error", err)
+			if node.NodeConfig.Role() != nodeconfig.NewNode {
+				// the old shard needs the shard state for its epoch block
+				node.keepEpochShardState(epochShardState)
 			}
+			node.scheduleReshard(&pendingReshard{epochShardState: epochShardState, shardID: myShardID, isLeader: isNextLeader})
 		}
-	} else {
-		utils.GetLogInstance().Info(fmt.Sprintf("[Resharded][epoch:%d]  Somehow I got kicked out. Exiting", epoch), "BlsPubKey", hex.EncodeToString(myBlsPubKey))
-		os.Exit(8) // 8 represents it's a loop and the program restart itself
-	}
-}
-
-func getRestartArguments(myShardID uint32) []string {
-	args := os.Args
-	hasShardID := false
-	shardIDFlag := "-shard_id"
-	// newNodeFlag := "-is_newnode"
-	for i, arg := range args {
-		if arg == shardIDFlag {
-			if i+1 < len(args) {
-				args[i+1] = strconv.Itoa(int(myShardID))
-			} else {
-				args = append(args, strconv.Itoa(int(myShardID)))
-			}
-			hasShardID = true
-		}
-		// TODO: enable this
-		//if arg == newNodeFlag {
-		//	args[i] = ""  // remove new node flag
-		//}
-	}
-	if !hasShardID {
-		args = append(args, shardIDFlag)
-		args = append(args, strconv.Itoa(int(myShardID)))
-	}
-	return args
-}
-
-// Gets the path of this currently running binary program.
-func getBinaryPath() (argv0 string, err error) {
-	argv0, err = exec.LookPath(os.Args[0])
-	if nil != err {
-		return
-	}
-	if _, err = os.Stat(argv0); nil != err {
-		return
+	} else if node.NodeConfig.Role() != nodeconfig.NewNode {
+		utils.GetLogInstance().Info(fmt.Sprintf("[Resharded][epoch:%d] Somehow I got kicked out. Staking again", epoch), "BlsPubKey", hex.EncodeToString(myBlsPubKey))
+		node.keepEpochShardState(epochShardState)
+		node.scheduleReshard(&pendingReshard{epochShardState: epochShardState, leave: true})
 	}
-	return
 }
 
 // Stores the epoch shard state into local file
@@ -706,7 +707,7 @@ func (node *Node) retrieveEpochShardState() (*types.EpochShardState, error) {
 // ConsensusMessageHandler passes received message in node_handler to consensus
 func (node *Node) ConsensusMessageHandler(msgPayload []byte) {
 	if node.Consensus.ConsensusVersion == "v1" {
-		if node.Consensus.IsLeader {
+		if node.Consensus.IsLeader() {
 			node.Consensus.ProcessMessageLeader(msgPayload)
 		} else {
 			node.Consensus.ProcessMessageValidator(msgPayload)
@@ -3,7 +3,9 @@ package node
 import (
 	"time"
 
+	"github.com/harmony-one/harmony/core"
 	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/ctxerror"
 	"github.com/harmony-one/harmony/internal/utils"
 )
 
@@ -13,26 +15,33 @@ const (
 	FirstTimeThreshold = 2
 	ConsensusTimeOut   = 10
 	PeriodicBlock      = 3 * time.Second
+	// DefaultBlockTime is the default target interval between two blocks proposed by the leader
+	DefaultBlockTime = 5 * time.Second
 )
 
 // WaitForConsensusReady listen for the readiness signal from consensus and generate new block for consensus.
+// With a block time target, the leader proposes a block every BlockTime, with
+// whatever transactions are pending, even none. Otherwise it waits until enough
+// transactions are pending to propose a block.
 func (node *Node) WaitForConsensusReady(readySignal chan struct{}, stopChan chan struct{}, stoppedChan chan struct{}) {
 	go func() {
 		// Setup stoppedChan
 		defer close(stoppedChan)
 
-		utils.GetLogInstance().Debug("Waiting for Consensus ready")
-		time.Sleep(30 * time.Second) // Wait for other nodes to be ready (test-only)
+		utils.GetLogInstance().Debug("Waiting for Consensus ready", "blockTime", node.BlockTime)
 
 		firstTime := true
-		var newBlock *types.Block
+		lastProposal := time.Now()
 		timeoutCount := 0
 		for {
 			// keep waiting for Consensus ready
 			select {
 			case <-readySignal:
-				time.Sleep(1000 * time.Millisecond) // Delay a bit so validator is catched up (test-only).
 			case <-time.After(ConsensusTimeOut * time.Second):
+				if !node.Consensus.IsLeader() {
+					// only the leader proposes new blocks
+					continue
+				}
 				node.Consensus.ResetState()
 				timeoutCount++
 				utils.GetLogInstance().Debug("Consensus timeout, retry!", "count", timeoutCount)
@@ -42,54 +51,147 @@ func (node *Node) WaitForConsensusReady(readySignal chan struct{}, stopChan chan
 				return
 			}
 
-			for {
+			var newBlock *types.Block
+			if node.BlockTime > 0 {
+				newBlock = node.proposeAtBlockTime(&lastProposal, stopChan)
+				if newBlock == nil {
+					utils.GetLogInstance().Debug("Consensus propose new block: STOPPED!")
+					return
+				}
+			} else {
 				// threshold and firstTime are for the test-only built-in smart contract tx.
-				// TODO: remove in production
 				threshold := DefaultThreshold
 				if firstTime {
 					threshold = FirstTimeThreshold
 					firstTime = false
 				}
-				if len(node.pendingTransactions) >= threshold {
-					utils.GetLogInstance().Debug("PROPOSING NEW BLOCK ------------------------------------------------", "blockNum", node.blockchain.CurrentBlock().NumberU64()+1, "threshold", threshold, "pendingTransactions", len(node.pendingTransactions))
-					// Normal tx block consensus
-					selectedTxs := node.getTransactionsForNewBlock(MaxNumberOfTransactionsPerBlock)
-					if len(selectedTxs) != 0 {
-						node.Worker.CommitTransactions(selectedTxs)
-						block, err := node.Worker.Commit()
-						if err != nil {
-							utils.GetLogInstance().Debug("Failed committing new block", "Error", err)
-						} else {
-							if node.Consensus.ShardID == 0 {
-								// add new shard state if it's epoch block
-								// TODO: bug fix - the stored shard state between here and PostConsensusProcessing are different.
-								//node.addNewShardStateHash(block)
-							}
-							newBlock = block
-							utils.GetLogInstance().Debug("Successfully proposed new block", "blockNum", block.NumberU64(), "numTxs", block.Transactions().Len())
-							break
-						}
-					}
+				newBlock = node.waitForTransactionThreshold(threshold, stopChan)
+				if newBlock == nil {
+					utils.GetLogInstance().Debug("Consensus propose new block: STOPPED!")
+					return
 				}
-				// If not enough transactions to run Consensus,
-				// periodically check whether we have enough transactions to package into block.
-				time.Sleep(PeriodicBlock)
 			}
 			// Send the new block to Consensus so it can be confirmed.
-			if newBlock != nil {
-				utils.GetLogInstance().Debug("Consensus sending new block to block channel")
-				node.BlockChannel <- newBlock
-				utils.GetLogInstance().Debug("Consensus sent new block to block channel")
+			utils.GetLogInstance().Debug("Consensus sending new block to block channel")
+			select {
+			case node.BlockChannel <- newBlock:
+			case <-stopChan:
+				utils.GetLogInstance().Debug("Consensus propose new block: STOPPED!")
+				return
 			}
+			utils.GetLogInstance().Debug("Consensus sent new block to block channel")
 		}
 	}()
 }
 
-func (node *Node) addNewShardStateHash(block *types.Block) {
-	shardState := node.blockchain.GetNewShardState(block, &node.CurrentStakes)
-	if shardState != nil {
-		shardHash := shardState.Hash()
-		utils.GetLogInstance().Debug("[Shard State Hash] adding new shard state", "shardHash", shardHash)
-		block.AddShardStateHash(shardHash)
+// proposeAtBlockTime proposes a new block at the block time target following
+// the last proposal, even without any transaction, and retries at the next
+// block time targets while the proposal fails. It updates the time of the last
+// proposal, and returns nil if stopped before.
+func (node *Node) proposeAtBlockTime(lastProposal *time.Time, stopChan chan struct{}) *types.Block {
+	for {
+		select {
+		case <-time.After(time.Until(lastProposal.Add(node.BlockTime))):
+		case <-stopChan:
+			return nil
+		}
+		*lastProposal = time.Now()
+		block, err := node.proposeNewBlock()
+		if err == nil {
+			return block
+		}
+		utils.GetLogInstance().Debug("Failed committing new block, retrying at the next block time", "Error", err)
+	}
+}
+
+// waitForTransactionThreshold periodically checks whether enough transactions
+// are pending to package into a new block, and proposes the block once there are.
+// It returns nil if stopped before.
+func (node *Node) waitForTransactionThreshold(threshold int, stopChan chan struct{}) *types.Block {
+	for {
+		if len(node.pendingTransactions) >= threshold || len(node.getIncomingReceiptsForNewBlock()) > 0 || len(node.getCrossLinksForNewBlock()) > 0 {
+			block, err := node.proposeNewBlock()
+			if err != nil {
+				utils.GetLogInstance().Debug("Failed committing new block", "Error", err)
+			} else if block.Transactions().Len() != 0 || len(block.IncomingReceipts()) != 0 || len(block.CrossLinks()) != 0 {
+				return block
+			}
+		}
+		// If not enough transactions to run Consensus,
+		// periodically check whether we have enough transactions to package into block.
+		select {
+		case <-time.After(PeriodicBlock):
+		case <-stopChan:
+			return nil
+		}
+	}
+}
+
+// proposeNewBlock builds a new block with the pending transactions, within
+// the block gas and size limits of the worker. The transactions selected go
+// back to the pending ones if the block can't be built.
+func (node *Node) proposeNewBlock() (*types.Block, error) {
+	utils.GetLogInstance().Debug("PROPOSING NEW BLOCK ------------------------------------------------", "blockNum", node.blockchain.CurrentBlock().NumberU64()+1, "pendingTransactions", len(node.pendingTransactions))
+	// Normal tx block consensus
+	selectedTxs := node.getTransactionsForNewBlock(MaxNumberOfTransactionsPerBlock)
+	block, err := node.buildNewBlock(selectedTxs)
+	if err != nil {
+		node.restorePendingTransactions(selectedTxs)
+		return nil, err
+	}
+	utils.GetLogInstance().Debug("Successfully proposed new block", "blockNum", block.NumberU64(), "numTxs", block.Transactions().Len())
+	return block, nil
+}
+
+// buildNewBlock builds a new block with the given transactions, and the
+// incoming receipts, double signs, cross-links and shard state due.
+func (node *Node) buildNewBlock(selectedTxs types.Transactions) (*types.Block, error) {
+	if err := node.Worker.CommitTransactions(selectedTxs); err != nil {
+		return nil, err
+	}
+	// Credit the transfers from other shards
+	node.Worker.CommitIncomingReceipts(node.getIncomingReceiptsForNewBlock())
+	if node.Consensus.ShardID == 0 {
+		// slash the double signers reported to the beacon chain
+		node.Worker.CommitDoubleSigns(node.getDoubleSignsForNewBlock())
+		// anchor the shard block headers submitted by the shard leaders
+		node.Worker.CommitCrossLinks(node.getCrossLinksForNewBlock())
+	}
+	if number := node.Worker.GetCurrentHeader().Number.Uint64(); number%core.BlocksPerEpoch == 0 {
+		// the epoch block carries the shard state of its epoch
+		epoch := core.GetEpochFromBlockNumber(number)
+		shardState, err := node.newShardState(epoch)
+		if err != nil {
+			return nil, err
+		}
+		node.Worker.CommitShardState(shardState)
+		if node.Consensus.ShardID != 0 {
+			// the shard epoch block carries the RandSeed of the beacon epoch
+			// block, which seeds the leader schedule of the epoch
+			randSeed, ok := node.blockchain.ReadEpochRandSeed(epoch)
+			if !ok {
+				return nil, ctxerror.New("rand seed not announced by the beacon chain yet", "epoch", epoch)
+			}
+			node.Worker.CommitRandSeed(randSeed)
+		}
+	}
+	return node.Worker.Commit()
+}
+
+// newShardState returns the shard state of the given epoch, to include in its
+// epoch block: the beacon chain computes it from the previous epoch, and the
+// shard chains copy the one announced by the beacon chain.
+func (node *Node) newShardState(epoch uint64) (types.ShardState, error) {
+	if node.Consensus.ShardID == 0 {
+		shardState := core.CalculateNewShardState(node.blockchain, epoch)
+		if len(shardState) == 0 {
+			return nil, ctxerror.New("cannot compute the shard state", "epoch", epoch)
+		}
+		return shardState, nil
+	}
+	shardState, err := node.blockchain.ReadShardState(epoch)
+	if err != nil {
+		return nil, ctxerror.New("shard state not announced by the beacon chain yet", "epoch", epoch).WithCause(err)
 	}
+	return shardState, nil
 }
@@ -2,6 +2,7 @@ package node
 
 import (
 	"bytes"
+	"context"
 	"sync"
 	"time"
 
@@ -11,7 +12,6 @@ import (
 	"github.com/harmony-one/harmony/api/service/syncing/downloader"
 	downloader_pb "github.com/harmony-one/harmony/api/service/syncing/downloader/proto"
 	"github.com/harmony-one/harmony/core"
-	"github.com/harmony-one/harmony/core/types"
 	nodeconfig "github.com/harmony-one/harmony/internal/configs/node"
 	"github.com/harmony-one/harmony/internal/ctxerror"
 	"github.com/harmony-one/harmony/internal/utils"
@@ -24,6 +24,10 @@ const (
 	lastMileThreshold = 4
 	inSyncThreshold   = 1  // unit in number of block
 	SyncFrequency     = 10 // unit in second
+
+	maxBlockHeadersPerRequest = 1024 // maximum number of headers served for a BLOCKHEADER request
+	maxTrieNodesPerRequest    = 384  // maximum number of state trie nodes served for a TRIENODE request
+	maxReceiptsPerRequest     = 128  // maximum number of block receipts served for a RECEIPT request
 )
 
 // getNeighborPeers is a helper function to return list of peers
@@ -42,7 +46,7 @@ func (node *Node) getNeighborPeers(neighbor *sync.Map) []p2p.Peer {
 
 // DoSyncWithoutConsensus gets sync-ed to blockchain without joining consensus
 func (node *Node) DoSyncWithoutConsensus() {
-	go node.DoSyncing(node.blockchain, node.Worker, node.GetSyncingPeers, false) //Don't join consensus
+	go node.DoSyncing(node.blockchain, node.Worker, node.GetSyncingPeers, false, make(chan struct{}), make(chan struct{})) //Don't join consensus
 }
 
 // GetBeaconSyncingPeers returns a list of peers for beaconchain syncing
@@ -62,6 +66,7 @@ func (node *Node) DoBeaconSyncing() {
 		case beaconBlock := <-node.BeaconBlockChannel:
 			if node.beaconSync == nil {
 				node.beaconSync = syncing.CreateStateSync(node.SelfPeer.IP, node.SelfPeer.Port, node.GetSyncID())
+				node.beaconSync.SetPeerReputation(node.syncReputation)
 			}
 			if node.beaconSync.GetActivePeerNumber() == 0 {
 				peers := node.GetBeaconSyncingPeers()
@@ -76,16 +81,24 @@ func (node *Node) DoBeaconSyncing() {
 	}
 }
 
-// DoSyncing keep the node in sync with other peers, willJoinConsensus means the node will try to join consensus after catch up
-func (node *Node) DoSyncing(bc *core.BlockChain, worker *worker.Worker, getPeers func() []p2p.Peer, willJoinConsensus bool) {
+// DoSyncing keep the node in sync with other peers, willJoinConsensus means the node will try to join consensus after catch up.
+// It returns once stopChan is closed, closing stoppedChan.
+func (node *Node) DoSyncing(bc *core.BlockChain, worker *worker.Worker, getPeers func() []p2p.Peer, willJoinConsensus bool, stopChan chan struct{}, stoppedChan chan struct{}) {
+	defer close(stoppedChan)
 	ticker := time.NewTicker(SyncFrequency * time.Second)
+	defer ticker.Stop()
 
 SyncingLoop:
 	for {
 		select {
+		case <-stopChan:
+			utils.GetLogInstance().Debug("[SYNC] stopped syncing", "shardID", bc.ShardID())
+			return
 		case <-ticker.C:
 			if node.stateSync == nil {
 				node.stateSync = syncing.CreateStateSync(node.SelfPeer.IP, node.SelfPeer.Port, node.GetSyncID())
+				node.stateSync.SetFastSync(node.FastSync)
+				node.stateSync.SetPeerReputation(node.syncReputation)
 			}
 			if node.stateSync.GetActivePeerNumber() == 0 {
 				peers := getPeers()
@@ -100,7 +113,13 @@ SyncingLoop:
 				node.State = NodeNotInSync
 				node.stateMutex.Unlock()
 				node.stateSync.SyncLoop(bc, worker, willJoinConsensus, false)
+				// leave the shard if the epoch block was synced
+				node.reshardIfDue()
 				if willJoinConsensus {
+					// the committee may have changed with the epoch blocks synced
+					if err := node.updateCommitteeOfHead(); err != nil {
+						ctxerror.Log15(utils.GetLogInstance().Error, ctxerror.New("[SYNC] cannot update the committee").WithCause(err))
+					}
 					node.stateMutex.Lock()
 					node.State = NodeReadyForConsensus
 					node.stateMutex.Unlock()
@@ -111,7 +130,12 @@ SyncingLoop:
 			node.State = NodeReadyForConsensus
 			node.stateMutex.Unlock()
 			if willJoinConsensus {
-				<-node.Consensus.ConsensusIDLowChan
+				select {
+				case <-node.Consensus.ConsensusIDLowChan:
+				case <-stopChan:
+					utils.GetLogInstance().Debug("[SYNC] stopped syncing", "shardID", bc.ShardID())
+					return
+				}
 			}
 		}
 	}
@@ -126,13 +150,32 @@ func (node *Node) SupportBeaconSyncing() {
 func (node *Node) SupportSyncing() {
 	node.InitSyncingServer()
 	node.StartSyncingServer()
-	go node.SendNewBlockToUnsync()
 
 	if node.NodeConfig.Role() != nodeconfig.ShardLeader && node.NodeConfig.Role() != nodeconfig.BeaconLeader {
-		go node.DoSyncing(node.blockchain, node.Worker, node.GetSyncingPeers, true)
+		node.startSyncing()
 	}
 }
 
+// startSyncing keeps the chain of the node's shard in sync with the peers, and
+// joins consensus after catching up.
+func (node *Node) startSyncing() {
+	node.syncStopChan = make(chan struct{})
+	node.syncStoppedChan = make(chan struct{})
+	go node.DoSyncing(node.blockchain, node.Worker, node.GetSyncingPeers, true, node.syncStopChan, node.syncStoppedChan)
+}
+
+// stopSyncing stops syncing the chain of the node's shard, waiting for the
+// sync in progress, if any, to finish.
+func (node *Node) stopSyncing() {
+	if node.syncStopChan == nil {
+		return
+	}
+	close(node.syncStopChan)
+	<-node.syncStoppedChan
+	node.syncStopChan, node.syncStoppedChan = nil, nil
+	node.stateSync = nil
+}
+
 // InitSyncingServer starts downloader server.
 func (node *Node) InitSyncingServer() {
 	if node.downloaderServer == nil {
@@ -148,48 +191,11 @@ func (node *Node) StartSyncingServer() {
 	}
 }
 
-// SendNewBlockToUnsync send latest verified block to unsync, registered nodes
-func (node *Node) SendNewBlockToUnsync() {
-	for {
-		block := <-node.Consensus.VerifiedNewBlock
-		blockHash, err := rlp.EncodeToBytes(block)
-		if err != nil {
-			utils.GetLogInstance().Warn("[SYNC] unable to encode block to hashes")
-			continue
-		}
-
-		// really need to have a unique id independent of ip/port
-		selfPeerAddress := node.Consensus.SelfAddress
-		utils.GetLogInstance().Debug("[SYNC] peerRegistration Record", "selfPeerAddress", selfPeerAddress, "number", len(node.peerRegistrationRecord))
-
-		for peerID, config := range node.peerRegistrationRecord {
-			elapseTime := time.Now().UnixNano() - config.timestamp
-			if elapseTime > broadcastTimeout {
-				utils.GetLogInstance().Warn("[SYNC] SendNewBlockToUnsync to peer timeout", "peerID", peerID)
-				// send last time and delete
-				config.client.PushNewBlock(node.GetSyncID(), blockHash, true)
-				node.stateMutex.Lock()
-				node.peerRegistrationRecord[peerID].client.Close()
-				delete(node.peerRegistrationRecord, peerID)
-				node.stateMutex.Unlock()
-				continue
-			}
-			response := config.client.PushNewBlock(node.GetSyncID(), blockHash, false)
-			if response != nil && response.Type == downloader_pb.DownloaderResponse_INSYNC {
-				node.stateMutex.Lock()
-				node.peerRegistrationRecord[peerID].client.Close()
-				delete(node.peerRegistrationRecord, peerID)
-				node.stateMutex.Unlock()
-			}
-		}
-	}
-}
-
 // CalculateResponse implements DownloadInterface on Node object.
 func (node *Node) CalculateResponse(request *downloader_pb.DownloaderRequest) (*downloader_pb.DownloaderResponse, error) {
 	response := &downloader_pb.DownloaderResponse{}
 	switch request.Type {
-	case downloader_pb.DownloaderRequest_HEADER:
+	case downloader_pb.DownloaderRequest_BLOCKHASH:
 		var startHeaderHash []byte
 		if request.BlockHash == nil {
 			tmp := node.blockchain.Genesis().Hash()
@@ -206,70 +212,197 @@ func (node *Node) CalculateResponse(request *downloader_pb.DownloaderRequest) (*
 		}
 
 	case downloader_pb.DownloaderRequest_BLOCK:
-		for _, bytes := range request.Hashes {
-			var hash common.Hash
-			hash.SetBytes(bytes)
+		node.sendPayload(request, len(request.Hashes), collectPayload(response))
+
+	case downloader_pb.DownloaderRequest_BLOCKHEADER:
+		var startHash common.Hash
+		startHash.SetBytes(request.BlockHash)
+		startHeader := node.blockchain.GetHeaderByHash(startHash)
+		if startHeader == nil {
+			break
+		}
+		startNum := startHeader.Number.Uint64()
+		if canonical := node.blockchain.GetHeaderByNumber(startNum); canonical == nil || canonical.Hash() != startHash {
+			// the requester is on a fork
+			break
+		}
+		size := uint64(request.Size)
+		if size == 0 || size > maxBlockHeadersPerRequest {
+			size = maxBlockHeadersPerRequest
+		}
+		for num := startNum + 1; num <= startNum+size; num++ {
+			header := node.blockchain.GetHeaderByNumber(num)
+			if header == nil {
+				break
+			}
+			encodedHeader, err := rlp.EncodeToBytes(header)
+			if err != nil {
+				break
+			}
+			response.Payload = append(response.Payload, encodedHeader)
+		}
+
+	case downloader_pb.DownloaderRequest_BLOCKBODY:
+		node.sendPayload(request, len(request.Hashes), collectPayload(response))
+
+	case downloader_pb.DownloaderRequest_TRIENODE:
+		node.sendPayload(request, maxTrieNodesPerRequest, collectPayload(response))
+
+	case downloader_pb.DownloaderRequest_RECEIPT:
+		node.sendPayload(request, maxReceiptsPerRequest, collectPayload(response))
+
+	case downloader_pb.DownloaderRequest_BLOCKHEIGHT:
+		response.BlockHeight = node.blockchain.CurrentBlock().NumberU64()
+
+	case downloader_pb.DownloaderRequest_REGISTER:
+		// new blocks are served over SubscribeNewBlocks instead of pushed
+		response.Type = downloader_pb.DownloaderResponse_FAIL
+	}
+	return response, nil
+}
+
+// collectPayload returns a send function appending the items to the payload
+// of the response.
+func collectPayload(response *downloader_pb.DownloaderResponse) func(payload []byte) error {
+	return func(payload []byte) error {
+		response.Payload = append(response.Payload, payload)
+		return nil
+	}
+}
+
+// StreamResponse implements DownloadInterface on Node object.
+func (node *Node) StreamResponse(request *downloader_pb.DownloaderRequest, send func(payload []byte) error) error {
+	switch request.Type {
+	case downloader_pb.DownloaderRequest_BLOCK,
+		downloader_pb.DownloaderRequest_BLOCKBODY,
+		downloader_pb.DownloaderRequest_TRIENODE,
+		downloader_pb.DownloaderRequest_RECEIPT:
+		return node.sendPayload(request, len(request.Hashes), send)
+	}
+	return ctxerror.New("[SYNC] request type cannot be streamed", "type", request.Type)
+}
+
+// sendPayload passes the payload of a BLOCK, BLOCKBODY, TRIENODE or RECEIPT
+// request to send one item at a time. Unknown blocks are left out, while the
+// other items are kept at the positions of their hashes, empty if unknown or
+// past the first limit hashes.
+func (node *Node) sendPayload(request *downloader_pb.DownloaderRequest, limit int, send func(payload []byte) error) error {
+	for i, bytes := range request.Hashes {
+		hash := common.BytesToHash(bytes)
+		var payload []byte
+		switch {
+		case i >= limit:
+		case request.Type == downloader_pb.DownloaderRequest_BLOCK:
 			block := node.blockchain.GetBlockByHash(hash)
 			if block == nil {
 				continue
 			}
 			encodedBlock, err := rlp.EncodeToBytes(block)
-			if err == nil {
-				response.Payload = append(response.Payload, encodedBlock)
+			if err != nil {
+				continue
+			}
+			payload = encodedBlock
+		case request.Type == downloader_pb.DownloaderRequest_BLOCKBODY:
+			if body := node.blockchain.GetBody(hash); body != nil {
+				payload, _ = rlp.EncodeToBytes(body)
+			}
+		case request.Type == downloader_pb.DownloaderRequest_TRIENODE:
+			payload, _ = node.blockchain.TrieNode(hash)
+		case request.Type == downloader_pb.DownloaderRequest_RECEIPT:
+			if receipts := node.blockchain.GetReceiptsByHash(hash); receipts != nil {
+				payload, _ = rlp.EncodeToBytes(receipts)
 			}
 		}
+		if err := send(payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	case downloader_pb.DownloaderRequest_BLOCKHEIGHT:
-		response.BlockHeight = node.blockchain.CurrentBlock().NumberU64()
+// newBlockSubscribers wakes up the peers subscribed to the new blocks when a
+// block is committed.
+type newBlockSubscribers struct {
+	mutex   sync.Mutex
+	wakeUps map[chan struct{}]struct{}
+}
 
-	// this is the out of sync node acts as grpc server side
-	case downloader_pb.DownloaderRequest_NEWBLOCK:
-		if node.State != NodeNotInSync {
-			utils.GetLogInstance().Debug("[SYNC] new block received, but state is", "state", node.State.String())
-			response.Type = downloader_pb.DownloaderResponse_INSYNC
-			return response, nil
-		}
-		var blockObj types.Block
-		err := rlp.DecodeBytes(request.BlockHash, &blockObj)
-		if err != nil {
-			utils.GetLogInstance().Warn("[SYNC] unable to decode received new block")
-			return response, err
+// add adds a subscriber, returning the channel it is woken up on.
+func (subscribers *newBlockSubscribers) add() (chan struct{}, error) {
+	subscribers.mutex.Lock()
+	defer subscribers.mutex.Unlock()
+	if len(subscribers.wakeUps) >= maxNewBlockSubscribers {
+		return nil, ctxerror.New("[SYNC] too many new block subscribers", "max", maxNewBlockSubscribers)
+	}
+	// a single pending wake up stands for any number of new blocks
+	wakeUp := make(chan struct{}, 1)
+	subscribers.wakeUps[wakeUp] = struct{}{}
+	return wakeUp, nil
+}
+
+// remove removes the subscriber woken up on the given channel.
+func (subscribers *newBlockSubscribers) remove(wakeUp chan struct{}) {
+	subscribers.mutex.Lock()
+	defer subscribers.mutex.Unlock()
+	delete(subscribers.wakeUps, wakeUp)
+}
+
+// notify wakes up all the subscribers, without waiting for any.
+func (subscribers *newBlockSubscribers) notify() {
+	subscribers.mutex.Lock()
+	defer subscribers.mutex.Unlock()
+	for wakeUp := range subscribers.wakeUps {
+		select {
+		case wakeUp <- struct{}{}:
+		default:
 		}
-		node.stateSync.AddNewBlock(request.PeerHash, &blockObj)
+	}
+}
 
-	case downloader_pb.DownloaderRequest_REGISTER:
-		peerID := string(request.PeerHash[:])
-		ip := request.Ip
-		port := request.Port
-		if _, ok := node.peerRegistrationRecord[peerID]; ok {
-			response.Type = downloader_pb.DownloaderResponse_FAIL
-			utils.GetLogInstance().Warn("[SYNC] peerRegistration record already exists", "ip", ip, "port", port)
-			return response, nil
-		} else if len(node.peerRegistrationRecord) >= maxBroadcastNodes {
-			response.Type = downloader_pb.DownloaderResponse_FAIL
-			utils.GetLogInstance().Warn("[SYNC] maximum registration limit exceeds", "ip", ip, "port", port)
-			return response, nil
-		} else {
-			response.Type = downloader_pb.DownloaderResponse_FAIL
-			syncPort := syncing.GetSyncingPort(port)
-			client := downloader.ClientSetup(ip, syncPort)
-			if client == nil {
-				utils.GetLogInstance().Warn("[SYNC] unable to setup client for peerID", "ip", ip, "port", port)
-				return response, nil
+// SubscribeNewBlocks implements DownloadInterface on Node object.
+// The blocks are read from the blockchain by height, so that a subscriber
+// slow to take them only lags behind, neither holding up consensus nor
+// missing blocks.
+func (node *Node) SubscribeNewBlocks(ctx context.Context, fromHeight uint64, send func(payload []byte) error) error {
+	wakeUp, err := node.newBlockSubscribers.add()
+	if err != nil {
+		return err
+	}
+	defer node.newBlockSubscribers.remove(wakeUp)
+	next := fromHeight
+	for {
+		for block := node.blockchain.GetBlockByNumber(next); block != nil; block = node.blockchain.GetBlockByNumber(next) {
+			encodedBlock, err := rlp.EncodeToBytes(block)
+			if err != nil {
+				return err
 			}
-			config := &syncConfig{timestamp: time.Now().UnixNano(), client: client}
-			node.stateMutex.Lock()
-			node.peerRegistrationRecord[peerID] = config
-			node.stateMutex.Unlock()
-			utils.GetLogInstance().Debug("[SYNC] register peerID success", "ip", ip, "port", port)
-			response.Type = downloader_pb.DownloaderResponse_SUCCESS
+			if err := send(encodedBlock); err != nil {
+				return err
+			}
+			next++
 		}
+		select {
+		case <-wakeUp:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
 
-	case downloader_pb.DownloaderRequest_REGISTERTIMEOUT:
-		if node.State == NodeNotInSync {
-			count := node.stateSync.RegisterNodeInfo()
-			utils.GetLogInstance().Debug("[SYNC] extra node registered", "number", count)
+// StreamBlockRange implements DownloadInterface on Node object.
+func (node *Node) StreamBlockRange(from, to uint64, send func(payload []byte) error) error {
+	for num := from; num <= to; num++ {
+		block := node.blockchain.GetBlockByNumber(num)
+		if block == nil {
+			return nil
+		}
+		encodedBlock, err := rlp.EncodeToBytes(block)
+		if err != nil {
+			return err
+		}
+		if err := send(encodedBlock); err != nil {
+			return err
 		}
 	}
-	return response, nil
+	return nil
 }
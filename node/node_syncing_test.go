@@ -0,0 +1,98 @@
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/p2p/p2pimpl"
+)
+
+func TestSubscribeNewBlocks(t *testing.T) {
+	pubKey := bls.RandPrivateKey().GetPublicKey()
+	leader := p2p.Peer{IP: "127.0.0.1", Port: "9884", ConsensusPubKey: pubKey}
+	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "9904")
+	host, err := p2pimpl.NewHost(&leader, priKey)
+	if err != nil {
+		t.Fatalf("newhost failure: %v", err)
+	}
+	consensus, err := consensus.New(host, 0, leader, nil)
+	if err != nil {
+		t.Fatalf("Cannot craeate consensus: %v", err)
+	}
+	node := New(host, consensus, nil, false)
+
+	received := make(chan *types.Block)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- node.SubscribeNewBlocks(ctx, 0, func(payload []byte) error {
+			block := &types.Block{}
+			if err := rlp.DecodeBytes(payload, block); err != nil {
+				return err
+			}
+			received <- block
+			return nil
+		})
+	}()
+	if block := <-received; block.NumberU64() != 0 {
+		t.Errorf("block %v served instead of the genesis block", block.NumberU64())
+	}
+
+	selectedTxs := node.getTransactionsForNewBlock(MaxNumberOfTransactionsPerBlock)
+	node.Worker.CommitTransactions(selectedTxs)
+	block, _ := node.Worker.Commit()
+	node.AddNewBlock(block)
+	node.newBlockSubscribers.notify()
+	select {
+	case newBlock := <-received:
+		if newBlock.Hash() != block.Hash() {
+			t.Errorf("block %v served instead of the new block", newBlock.NumberU64())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("new block not served")
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("subscription ended with %v", err)
+	}
+}
+
+func TestStopSyncing(t *testing.T) {
+	pubKey := bls.RandPrivateKey().GetPublicKey()
+	leader := p2p.Peer{IP: "127.0.0.1", Port: "9885", ConsensusPubKey: pubKey}
+	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "9905")
+	host, err := p2pimpl.NewHost(&leader, priKey)
+	if err != nil {
+		t.Fatalf("newhost failure: %v", err)
+	}
+	consensus, err := consensus.New(host, 0, leader, nil)
+	if err != nil {
+		t.Fatalf("Cannot craeate consensus: %v", err)
+	}
+	node := New(host, consensus, nil, false)
+
+	node.startSyncing()
+	stopped := make(chan struct{})
+	go func() {
+		node.stopSyncing()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("syncing not stopped")
+	}
+	if node.syncStopChan != nil || node.stateSync != nil {
+		t.Error("syncing state not reset after stopping")
+	}
+	// stopping again is a no-op
+	node.stopSyncing()
+}
@@ -0,0 +1,278 @@
+package node
+
+import (
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/contracts"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/crypto/pki"
+	nodeconfig "github.com/harmony-one/harmony/internal/configs/node"
+	"github.com/harmony-one/harmony/internal/ctxerror"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/node/worker"
+	"github.com/harmony-one/harmony/p2p"
+)
+
+// pendingReshard is a shard switch, or a leave of the committees, announced by
+// the beacon chain and due once the old shard has committed its epoch block,
+// which needs the quorum of the old committee.
+type pendingReshard struct {
+	epochShardState *types.EpochShardState
+	shardID         uint32
+	isLeader        bool
+	leave           bool // left out of all the committees
+}
+
+// scheduleReshard schedules the given shard switch, replacing the one
+// scheduled before, and runs it if already due.
+func (node *Node) scheduleReshard(reshard *pendingReshard) {
+	node.pendingReshardMutex.Lock()
+	node.pendingReshard = reshard
+	node.pendingReshardMutex.Unlock()
+	node.reshardIfDue()
+}
+
+// reshardIfDue runs the scheduled shard switch once the chain of the node's
+// shard has the epoch block of the new shard state, or right away if the node
+// isn't in any committee.
+func (node *Node) reshardIfDue() {
+	node.pendingReshardMutex.Lock()
+	reshard := node.pendingReshard
+	if reshard == nil {
+		node.pendingReshardMutex.Unlock()
+		return
+	}
+	epochBlock := core.GetBlockNumberFromEpoch(reshard.epochShardState.Epoch)
+	if node.NodeConfig.Role() != nodeconfig.NewNode && node.Blockchain().CurrentBlock().NumberU64() < epochBlock {
+		node.pendingReshardMutex.Unlock()
+		return
+	}
+	node.pendingReshard = nil
+	node.pendingReshardMutex.Unlock()
+
+	// switch off the goroutine calling, as the switch stops the consensus and
+	// the syncing, and waits for them
+	go func() {
+		if reshard.leave {
+			node.LeaveCommittee()
+			return
+		}
+		if err := node.SwitchShard(reshard.epochShardState, reshard.shardID, reshard.isLeader); err != nil {
+			ctxerror.Log15(utils.GetLogInstance().Error, ctxerror.New("[Resharding] failed to switch shard", "shardID", reshard.shardID).WithCause(err))
+		}
+	}()
+}
+
+// SwitchShard moves the node to the given shard after resharding, without
+// restarting the process. It leaves the consensus, syncing and groups of the
+// old shard, which shall have committed its epoch block, loads the chain of the new shard, then joins the groups of the
+// new shard and syncs, to join consensus as the new committee takes over at
+// the epoch boundary, with the committee of the given shard state.
+// The consensus and the services are stopped first, then the fields of the
+// shard are swapped with the shard mutex held.
+func (node *Node) SwitchShard(epochShardState *types.EpochShardState, shardID uint32, isLeader bool) error {
+	node.reshardMutex.Lock()
+	defer node.reshardMutex.Unlock()
+
+	oldChain := node.Blockchain()
+	var db ethdb.Database
+	if shardID != oldChain.ShardID() && (shardID != 0 || node.beaconChain == nil) {
+		var err error
+		if db, err = node.openShardDB(shardID); err != nil {
+			return ctxerror.New("cannot open the database of the new shard", "shardID", shardID).WithCause(err)
+		}
+	}
+	utils.GetLogInstance().Info("[Resharding] switching shard", "from", oldChain.ShardID(), "to", shardID, "isLeader", isLeader)
+	node.leaveShard()
+
+	node.shardMutex.Lock()
+	node.Consensus.ShardID = shardID
+	var chain *core.BlockChain
+	switch {
+	case shardID == oldChain.ShardID():
+		// a new node joining the committee of the shard it follows
+		chain = oldChain
+	case db == nil:
+		// the beacon chain is already synced by the nodes of the other shards
+		chain = node.beaconChain
+		node.beaconChain, node.BeaconWorker = nil, nil
+	default:
+		var err error
+		chain, err = node.InitBlockChainFromDB(db, node.Consensus, node.isArchival)
+		if err != nil || chain == nil || chain.CurrentBlock().NumberU64() <= 0 {
+			chain, err = node.GenesisBlockSetup(db, shardID, node.isArchival)
+		}
+		if err != nil {
+			node.shardMutex.Unlock()
+			db.Close()
+			return ctxerror.New("cannot set up the chain of the new shard", "shardID", shardID).WithCause(err)
+		}
+	}
+	var retiredChain *core.BlockChain
+	if chain != oldChain {
+		if oldChain.ShardID() == 0 {
+			// keep the beacon chain to follow the shard states
+			node.beaconChain = oldChain
+			node.BeaconWorker = worker.New(params.TestChainConfig, oldChain, &consensus.Consensus{}, pki.GetAddressFromPublicKey(node.SelfPeer.ConsensusPubKey), shardID)
+		} else {
+			retiredChain = oldChain
+		}
+		node.setChain(chain)
+	}
+
+	node.Consensus.SetIsLeader(isLeader)
+	node.Consensus.ChainReader = chain
+	node.Consensus.SetConsensusID(uint32(chain.CurrentBlock().NumberU64()))
+	// closes the pbft log of the old shard, flushing it to the old database
+	node.Consensus.SetPbftLog(consensus.NewPersistentPbftLog(chain.ChainDb()))
+	if retiredChain != nil {
+		retiredChain.Stop()
+		retiredChain.ChainDb().Close()
+	}
+	node.Consensus.ResetState()
+	node.DRand.ShardID = shardID
+	node.keepEpochShardState(epochShardState)
+	if err := node.updateCommitteeOfHead(); err != nil {
+		ctxerror.Log15(utils.GetLogInstance().Error, ctxerror.New("[Resharding] cannot update the committee", "epoch", epochShardState.Epoch).WithCause(err))
+	}
+
+	node.NodeConfig.SetShardID(shardID)
+	node.NodeConfig.SetIsLeader(isLeader)
+	node.NodeConfig.SetIsBeacon(shardID == 0)
+	if shardID == 0 {
+		if isLeader {
+			node.NodeConfig.SetRole(nodeconfig.BeaconLeader)
+		} else {
+			node.NodeConfig.SetRole(nodeconfig.BeaconValidator)
+		}
+		node.NodeConfig.SetShardGroupID(p2p.GroupIDBeacon)
+		node.NodeConfig.SetClientGroupID(p2p.GroupIDBeaconClient)
+	} else {
+		if isLeader {
+			node.NodeConfig.SetRole(nodeconfig.ShardLeader)
+		} else {
+			node.NodeConfig.SetRole(nodeconfig.ShardValidator)
+		}
+		node.NodeConfig.SetShardGroupID(p2p.NewGroupIDByShardID(p2p.ShardID(shardID)))
+		node.NodeConfig.SetClientGroupID(p2p.NewClientGroupIDByShardID(p2p.ShardID(shardID)))
+	}
+	node.shardMutex.Unlock()
+
+	node.ServiceManagerSetup()
+	node.RunServices()
+	if isLeader {
+		node.State = NodeLeader
+		go node.SendPongMessage()
+	} else {
+		node.State = NodeWaitToJoin
+		node.startSyncing()
+	}
+	utils.GetLogInstance().Info("[Resharding] switched shard", "shardID", shardID, "height", chain.CurrentBlock().NumberU64(), "role", node.NodeConfig.Role())
+	return nil
+}
+
+// LeaveCommittee returns the node to the new node state after it is kicked
+// out of all the committees by resharding, so it can stake again. The node
+// follows the beacon chain only, and the chain of a non-beacon shard is closed.
+func (node *Node) LeaveCommittee() {
+	node.reshardMutex.Lock()
+	defer node.reshardMutex.Unlock()
+
+	utils.GetLogInstance().Info("[Resharding] leaving the committee", "shardID", node.Consensus.ShardID)
+	node.leaveShard()
+	node.shardMutex.Lock()
+	var retiredChain *core.BlockChain
+	if node.beaconChain == nil {
+		// the staking service works on the beacon chain
+		node.beaconChain = node.blockchain
+	} else if node.blockchain != node.beaconChain {
+		retiredChain = node.blockchain
+		node.Consensus.ShardID = 0
+		node.setChain(node.beaconChain)
+		node.Consensus.ChainReader = node.beaconChain
+		// closes the pbft log of the old shard, flushing it to the old database
+		node.Consensus.SetPbftLog(consensus.NewPbftLog())
+		node.DRand.ShardID = 0
+		node.NodeConfig.SetShardID(0)
+		node.NodeConfig.SetIsBeacon(true)
+	}
+	node.Consensus.SetIsLeader(false)
+	node.NodeConfig.SetIsLeader(false)
+	node.NodeConfig.SetRole(nodeconfig.NewNode)
+	node.NodeConfig.SetClientGroupID(p2p.GroupIDBeaconClient)
+	node.NodeConfig.SetBeaconGroupID(p2p.GroupIDBeacon)
+	node.State = NodeInit
+	node.shardMutex.Unlock()
+	if retiredChain != nil {
+		retiredChain.Stop()
+		retiredChain.ChainDb().Close()
+	}
+
+	node.ServiceManagerSetup()
+	node.RunServices()
+}
+
+// leaveShard stops the consensus, syncing and services of the node's shard,
+// and leaves its groups. The pending transactions, receipts, double signs and
+// cross-links of the shard are dropped.
+func (node *Node) leaveShard() {
+	node.StopServices()
+	node.stopSyncing()
+	node.shardMutex.Lock()
+	for _, receiver := range []*p2p.GroupReceiver{&node.shardGroupReceiver, &node.globalGroupReceiver, &node.clientReceiver} {
+		if *receiver != nil {
+			(*receiver).Close()
+			*receiver = nil
+		}
+	}
+	node.shardMutex.Unlock()
+	node.Neighbors.Range(func(key, value interface{}) bool {
+		node.Neighbors.Delete(key)
+		return true
+	})
+
+	node.pendingTxMutex.Lock()
+	node.pendingTransactions = types.Transactions{}
+	node.transactionInConsensus = nil
+	node.pendingTxMutex.Unlock()
+	node.pendingCXReceiptMutex.Lock()
+	node.pendingCXReceipts = nil
+	node.pendingCXReceiptMutex.Unlock()
+	node.pendingDoubleSignMutex.Lock()
+	node.pendingDoubleSigns = nil
+	node.pendingDoubleSignMutex.Unlock()
+	node.pendingCrossLinkMutex.Lock()
+	node.pendingCrossLinks = nil
+	node.pendingCrossLinkMutex.Unlock()
+}
+
+// setChain makes the given chain the chain of the node's shard, with a new
+// transaction pool and worker.
+func (node *Node) setChain(chain *core.BlockChain) {
+	maxGas, maxSize := node.Worker.BlockLimits()
+	node.TxPool.Stop()
+
+	node.blockchain = chain
+	if node.beaconChain != nil {
+		chain.SetBeaconChain(node.beaconChain)
+	}
+	node.TxPool = core.NewTxPool(core.DefaultTxPoolConfig, params.TestChainConfig, chain)
+	node.Worker = worker.New(params.TestChainConfig, chain, node.Consensus, pki.GetAddressFromPublicKey(node.SelfPeer.ConsensusPubKey), node.Consensus.ShardID)
+	node.Worker.SetBlockLimits(maxGas, maxSize)
+	db := chain.ChainDb()
+	node.ContractCaller = contracts.NewContractCaller(&db, chain, params.TestChainConfig)
+	if apiBackend != nil {
+		// serve the RPCs from the chain of the new shard
+		apiBackend.SetChain(chain, node.TxPool)
+	}
+}
+
+// openShardDB opens the database of the given shard.
+func (node *Node) openShardDB(shardID uint32) (ethdb.Database, error) {
+	if node.OpenShardDB == nil {
+		return ethdb.NewMemDatabase(), nil
+	}
+	return node.OpenShardDB(shardID)
+}
@@ -3,6 +3,7 @@ package node
 import (
 	"fmt"
 	"net"
+	"net/http"
 	"strconv"
 	"strings"
 
@@ -10,11 +11,17 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/harmony-one/harmony/core"
 	"github.com/harmony-one/harmony/internal/hmyapi"
+	"github.com/harmony-one/harmony/internal/metrics"
 )
 
 const (
 	rpcHTTPPortOffset = 10
 	rpcWSPortOffset   = 20
+	metricsPortOffset = 30
+
+	// DefaultMetricsIP is the default IP the metrics endpoint listens on, so
+	// that the metrics are only served to the local host
+	DefaultMetricsIP = "127.0.0.1"
 )
 
 var (
@@ -36,6 +43,10 @@ var (
 	wsModules = []string{"net", "web3"}
 	wsOrigins = []string{"*"}
 
+	// Metrics
+	metricsListener net.Listener
+	metricsEndpoint = ""
+
 	apiBackend *core.HmyAPIBackend
 )
 
@@ -45,6 +56,12 @@ func (node *Node) StartRPC(nodePort string) error {
 	apiBackend = core.NewBackend(node.blockchain, node.TxPool, node.accountManager)
 
 	apis := hmyapi.GetAPIs(apiBackend)
+	apis = append(apis, rpc.API{
+		Namespace: "hmy",
+		Version:   "1.0",
+		Service:   hmyapi.NewPublicSyncAPI(node.syncReputation),
+		Public:    true,
+	})
 	for _, service := range node.serviceManager.GetServices() {
 		apis = append(apis, service.APIs()...)
 	}
@@ -62,10 +79,24 @@ func (node *Node) StartRPC(nodePort string) error {
 		return err
 	}
 
+	metricsEndpoint = net.JoinHostPort(node.MetricsIP, strconv.Itoa(port+metricsPortOffset))
+	if err := node.startMetrics(metricsEndpoint); err != nil {
+		node.stopWS()
+		node.stopHTTP()
+		return err
+	}
+
 	rpcAPIs = apis
 	return nil
 }
 
+// StopRPC terminates the RPC and metrics endpoints.
+func (node *Node) StopRPC() {
+	node.stopMetrics()
+	node.stopWS()
+	node.stopHTTP()
+}
+
 // startHTTP initializes and starts the HTTP RPC endpoint.
 func (node *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string, vhosts []string, timeouts rpc.HTTPTimeouts) error {
 	// Short circuit if the HTTP endpoint isn't being exposed
@@ -131,3 +162,33 @@ func (node *Node) stopWS() {
 		wsHandler = nil
 	}
 }
+
+// startMetrics initializes and starts the HTTP endpoint serving the node
+// metrics to Prometheus at /metrics.
+func (node *Node) startMetrics(endpoint string) error {
+	// Short circuit if the metrics endpoint isn't being exposed
+	if endpoint == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.DefaultRegistry)
+	go http.Serve(listener, mux)
+	log.Info("Metrics endpoint opened", "url", fmt.Sprintf("http://%s/metrics", listener.Addr()))
+	metricsListener = listener
+
+	return nil
+}
+
+// stopMetrics terminates the metrics HTTP endpoint.
+func (node *Node) stopMetrics() {
+	if metricsListener != nil {
+		metricsListener.Close()
+		metricsListener = nil
+
+		log.Info("Metrics endpoint closed", "url", fmt.Sprintf("http://%s/metrics", metricsEndpoint))
+	}
+}
@@ -31,7 +31,7 @@ func (node *Node) setupForShardLeader() {
 		node.CreateTransactionForPlayMethod, node.CreateTransactionForPayoutMethod, node.CreateTransactionForEndMethod))
 
 	// Register explorer service.
-	node.serviceManager.RegisterService(service.SupportExplorer, explorer.New(&node.SelfPeer, node.Consensus.GetNodeIDs, node.GetBalanceOfAddress))
+	node.serviceManager.RegisterService(service.SupportExplorer, explorer.New(&node.SelfPeer, node.Consensus.GetNodeIDs, node.GetBalanceOfAddress, node.committeeOfBlock))
 	// Register consensus service.
 	node.serviceManager.RegisterService(service.Consensus, consensus.New(node.BlockChannel, node.Consensus, node.startConsensus))
 	// Register new block service.
@@ -49,6 +49,11 @@ func (node *Node) setupForShardValidator() {
 	node.serviceManager.RegisterService(service.PeerDiscovery, discovery.New(node.host, nodeConfig, chanPeer, node.AddBeaconPeer))
 	// Register networkinfo service. "0" is the beacon shard ID
 	node.serviceManager.RegisterService(service.NetworkInfo, networkinfo.New(node.host, node.NodeConfig.GetShardGroupID(), chanPeer, nil))
+	// Register consensus service. Validators don't wait for the start signal and only
+	// propose blocks once they become leader after a view change.
+	node.serviceManager.RegisterService(service.Consensus, consensus.New(node.BlockChannel, node.Consensus, nil))
+	// Register new block service.
+	node.serviceManager.RegisterService(service.BlockProposal, blockproposal.New(node.Consensus.ReadySignal, node.WaitForConsensusReady))
 }
 
 func (node *Node) setupForBeaconLeader() {
@@ -74,7 +79,7 @@ func (node *Node) setupForBeaconLeader() {
 	// Register randomness service
 	node.serviceManager.RegisterService(service.Randomness, randomness.New(node.DRand))
 	// Register explorer service.
-	node.serviceManager.RegisterService(service.SupportExplorer, explorer.New(&node.SelfPeer, node.Consensus.GetNodeIDs, node.GetBalanceOfAddress))
+	node.serviceManager.RegisterService(service.SupportExplorer, explorer.New(&node.SelfPeer, node.Consensus.GetNodeIDs, node.GetBalanceOfAddress, node.committeeOfBlock))
 }
 
 func (node *Node) setupForBeaconValidator() {
@@ -86,6 +91,11 @@ func (node *Node) setupForBeaconValidator() {
 	node.serviceManager.RegisterService(service.PeerDiscovery, discovery.New(node.host, nodeConfig, chanPeer, nil))
 	// Register networkinfo service.
 	node.serviceManager.RegisterService(service.NetworkInfo, networkinfo.New(node.host, node.NodeConfig.GetShardGroupID(), chanPeer, nil))
+	// Register consensus service. Validators don't wait for the start signal and only
+	// propose blocks once they become leader after a view change.
+	node.serviceManager.RegisterService(service.Consensus, consensus.New(node.BlockChannel, node.Consensus, nil))
+	// Register new block service.
+	node.serviceManager.RegisterService(service.BlockProposal, blockproposal.New(node.Consensus.ReadySignal, node.WaitForConsensusReady))
 }
 
 func (node *Node) setupForNewNode() {
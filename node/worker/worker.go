@@ -20,9 +20,13 @@ type environment struct {
 	state   *state.DB     // apply state changes here
 	gasPool *core.GasPool // available gas used to pack transactions
 
-	header   *types.Header
-	txs      []*types.Transaction
-	receipts []*types.Receipt
+	header           *types.Header
+	txs              []*types.Transaction
+	receipts         []*types.Receipt
+	doubleSigns      []*types.DoubleSignEvidence
+	incomingReceipts []*types.CXReceiptProof
+	crossLinks       []*types.CrossLink
+	shardState       types.ShardState
 }
 
 // Worker is the main object which takes care of submitting new work to consensus engine
@@ -38,33 +42,43 @@ type Worker struct {
 	gasFloor uint64
 	gasCeil  uint64
 
+	// Limits of the gas used by and of the size of the transactions of a block, 0 means no limit
+	maxGas  uint64
+	maxSize common.StorageSize
+
 	shardID uint32
 }
 
 // SelectTransactionsForNewBlock selects transactions for new block.
+// Transactions beyond the block limits are left unselected.
 func (w *Worker) SelectTransactionsForNewBlock(txs types.Transactions, maxNumTxs int) (types.Transactions, types.Transactions, types.Transactions) {
 	if w.current.gasPool == nil {
-		w.current.gasPool = new(core.GasPool).AddGas(w.current.header.GasLimit)
+		w.current.gasPool = new(core.GasPool).AddGas(w.blockGasLimit())
 	}
 	selected := types.Transactions{}
 	unselected := types.Transactions{}
 	invalid := types.Transactions{}
+	size := common.StorageSize(0)
 	for _, tx := range txs {
 		if tx.ShardID() != w.shardID {
 			invalid = append(invalid, tx)
+			continue
+		}
+		if len(selected) > maxNumTxs || (w.maxSize > 0 && size+tx.Size() > w.maxSize) {
+			unselected = append(unselected, tx)
+			continue
 		}
-		snap := w.current.state.Snapshot()
 		_, err := w.commitTransaction(tx, w.coinbase)
-		if len(selected) > maxNumTxs {
+		switch err {
+		case nil:
+			selected = append(selected, tx)
+			size += tx.Size()
+		case core.ErrGasLimitReached:
+			// no room for it in this block
 			unselected = append(unselected, tx)
-		} else {
-			if err != nil {
-				w.current.state.RevertToSnapshot(snap)
-				invalid = append(invalid, tx)
-				log.Debug("Invalid transaction", "Error", err)
-			} else {
-				selected = append(selected, tx)
-			}
+		default:
+			invalid = append(invalid, tx)
+			log.Debug("Invalid transaction", "Error", err)
 		}
 	}
 	err := w.UpdateCurrent()
@@ -74,6 +88,26 @@ func (w *Worker) SelectTransactionsForNewBlock(txs types.Transactions, maxNumTxs
 	return selected, unselected, invalid
 }
 
+// SetBlockLimits sets the limits of the gas used by and of the size of the
+// transactions of the blocks built by the worker; 0 means no limit.
+func (w *Worker) SetBlockLimits(maxGas uint64, maxSize common.StorageSize) {
+	w.maxGas = maxGas
+	w.maxSize = maxSize
+}
+
+// BlockLimits returns the limits set by SetBlockLimits.
+func (w *Worker) BlockLimits() (maxGas uint64, maxSize common.StorageSize) {
+	return w.maxGas, w.maxSize
+}
+
+// blockGasLimit returns the gas available to the transactions of the current block.
+func (w *Worker) blockGasLimit() uint64 {
+	if w.maxGas > 0 && w.maxGas < w.current.header.GasLimit {
+		return w.maxGas
+	}
+	return w.current.header.GasLimit
+}
+
 func (w *Worker) commitTransaction(tx *types.Transaction, coinbase common.Address) ([]*types.Log, error) {
 	snap := w.current.state.Snapshot()
 
@@ -91,7 +125,7 @@ func (w *Worker) commitTransaction(tx *types.Transaction, coinbase common.Addres
 // CommitTransactions commits transactions.
 func (w *Worker) CommitTransactions(txs types.Transactions) error {
 	if w.current.gasPool == nil {
-		w.current.gasPool = new(core.GasPool).AddGas(w.current.header.GasLimit)
+		w.current.gasPool = new(core.GasPool).AddGas(w.blockGasLimit())
 	}
 	for _, tx := range txs {
 		snap := w.current.state.Snapshot()
@@ -105,6 +139,48 @@ func (w *Worker) CommitTransactions(txs types.Transactions) error {
 	return nil
 }
 
+// CommitDoubleSigns includes the given double sign evidences into the new block,
+// so that the double signers get slashed.
+func (w *Worker) CommitDoubleSigns(doubleSigns []*types.DoubleSignEvidence) {
+	w.current.doubleSigns = append(w.current.doubleSigns, doubleSigns...)
+}
+
+// CommitIncomingReceipts credits in the new block the cross-shard transfers
+// proven by the given receipts, after the transactions. It leaves out the
+// invalid receipts and the ones already credited, and returns the receipts
+// included.
+func (w *Worker) CommitIncomingReceipts(proofs []*types.CXReceiptProof) []*types.CXReceiptProof {
+	included := []*types.CXReceiptProof{}
+	for _, proof := range proofs {
+		snap := w.current.state.Snapshot()
+		if err := core.ApplyIncomingReceipts(w.current.state, w.shardID, types.CXReceiptProofs{proof}); err != nil {
+			w.current.state.RevertToSnapshot(snap)
+			log.Debug("Invalid incoming receipt", "Error", err)
+			continue
+		}
+		included = append(included, proof)
+	}
+	w.current.incomingReceipts = append(w.current.incomingReceipts, included...)
+	return included
+}
+
+// CommitCrossLinks anchors the given shard block headers, verified and in
+// order, in the new beacon chain block.
+func (w *Worker) CommitCrossLinks(crossLinks []*types.CrossLink) {
+	w.current.crossLinks = append(w.current.crossLinks, crossLinks...)
+}
+
+// CommitShardState includes the shard state of the epoch started by the new
+// block, which must be an epoch block.
+func (w *Worker) CommitShardState(shardState types.ShardState) {
+	w.current.shardState = shardState
+}
+
+// CommitRandSeed sets the RandSeed of the new block.
+func (w *Worker) CommitRandSeed(randSeed [32]byte) {
+	w.current.header.RandSeed = randSeed
+}
+
 // UpdateCurrent updates the current environment with the current state and header.
 func (w *Worker) UpdateCurrent() error {
 	parent := w.chain.CurrentBlock()
@@ -140,6 +216,11 @@ func (w *Worker) GetCurrentState() *state.DB {
 	return w.current.state
 }
 
+// GetCurrentHeader gets a copy of the header of the new block.
+func (w *Worker) GetCurrentHeader() *types.Header {
+	return types.CopyHeader(w.current.header)
+}
+
 // GetCurrentReceipts get the receipts generated starting from the last state.
 func (w *Worker) GetCurrentReceipts() []*types.Receipt {
 	return w.current.receipts
@@ -148,10 +229,29 @@ func (w *Worker) GetCurrentReceipts() []*types.Receipt {
 // Commit generate a new block for the new txs.
 func (w *Worker) Commit() (*types.Block, error) {
 	s := w.current.state.Copy()
-	block, err := w.engine.Finalize(w.chain, w.current.header, s, w.current.txs, w.current.receipts)
+	// the header commits to the whole body
+	if len(w.current.shardState) > 0 {
+		w.current.header.ShardStateHash = w.current.shardState.Root()
+	}
+	w.current.header.DoubleSignHash = types.DoubleSignsHash(w.current.doubleSigns)
+	w.current.header.IncomingReceiptHash = types.IncomingReceiptsHash(w.current.incomingReceipts)
+	w.current.header.CrossLinkHash = types.CrossLinksHash(w.current.crossLinks)
+	if err := w.chain.CountValidatorUptime(s, w.current.header, w.current.crossLinks); err != nil {
+		return nil, err
+	}
+	block, err := w.engine.Finalize(w.chain, w.current.header, s, w.current.txs, w.current.receipts, w.current.doubleSigns)
 	if err != nil {
 		return nil, err
 	}
+	if len(w.current.incomingReceipts) > 0 {
+		block = block.WithIncomingReceipts(w.current.incomingReceipts)
+	}
+	if len(w.current.crossLinks) > 0 {
+		block = block.WithCrossLinks(w.current.crossLinks)
+	}
+	if len(w.current.shardState) > 0 {
+		block = block.WithShardState(w.current.shardState)
+	}
 	return block, nil
 }
 
@@ -82,3 +82,33 @@ func TestCommitTransactions(t *testing.T) {
 		t.Error("Transaction is not committed")
 	}
 }
+
+func TestSelectTransactionsWithinBlockLimits(t *testing.T) {
+	var (
+		database = ethdb.NewMemDatabase()
+		gspec    = core.Genesis{
+			Config:  chainConfig,
+			Alloc:   core.GenesisAlloc{testBankAddress: {Balance: testBankFunds}},
+			ShardID: 10,
+		}
+	)
+
+	gspec.MustCommit(database)
+	chain, _ := core.NewBlockChain(database, nil, gspec.Config, consensus.NewFaker(), vm.Config{}, nil)
+
+	worker := New(params.TestChainConfig, chain, consensus.NewFaker(), testBankAddress, 0)
+	// room for two plain transfers only
+	worker.SetBlockLimits(2*params.TxGas, 0)
+
+	baseNonce := worker.GetCurrentState().GetNonce(testBankAddress)
+	txs := types.Transactions{}
+	for i := uint64(0); i < 3; i++ {
+		tx, _ := types.SignTx(types.NewTransaction(baseNonce+i, testBankAddress, uint32(0), big.NewInt(1), params.TxGas, nil, nil), types.HomesteadSigner{}, testBankKey)
+		txs = append(txs, tx)
+	}
+
+	selected, unselected, invalid := worker.SelectTransactionsForNewBlock(txs, 100)
+	if len(selected) != 2 || len(unselected) != 1 || len(invalid) != 0 {
+		t.Errorf("selected %d, unselected %d, invalid %d transactions, expected 2, 1 and 0", len(selected), len(unselected), len(invalid))
+	}
+}
@@ -3,9 +3,10 @@
 export GO111MODULE=on
 
 declare -A SRC
-SRC[harmony]=cmd/harmony/main.go
+SRC[harmony]="cmd/harmony/main.go cmd/harmony/chaincmd.go"
 SRC[txgen]=cmd/client/txgen/main.go
 SRC[bootnode]=cmd/bootnode/main.go
+SRC[harmony-db]=cmd/harmony-db/main.go
 SRC[wallet]="cmd/client/wallet/main.go cmd/client/wallet/generated_wallet.ini.go"
 
 BINDIR=bin
@@ -61,7 +62,7 @@ ACTION:
    upload      upload binaries to s3
    pubwallet   upload wallet to public bucket (bucket: $PUBBUCKET)
 
-   harmony|txgen|bootnode|wallet
+   harmony|txgen|bootnode|wallet|harmony-db
                only build the specified binary
 
 EXAMPLES:
@@ -193,6 +194,6 @@ case "$ACTION" in
    "build") build_only ;;
    "upload") upload ;;
    "pubwallet") upload_wallet ;;
-   "harmony"|"wallet"|"txgen"|"bootnode") build_only $ACTION ;;
+   "harmony"|"wallet"|"txgen"|"bootnode"|"harmony-db") build_only $ACTION ;;
    *) usage ;;
 esac